@@ -0,0 +1,68 @@
+package foundation
+
+import (
+	"bufio"
+	"bytes"
+	"runtime/pprof"
+	"strconv"
+	"strings"
+)
+
+// RunnerLabel is the profiler label the go routines of each Runner are labelled with when accounting,
+// see WithAccounting. The value is the name of the Runner's F.
+const RunnerLabel = "foundation.runner"
+
+// WithAccounting labels the go routines of every Runner, and any go routines they start, with the name
+// of the Runner's F, see RunnerLabel. Go routines are then counted per runner subtree in the Tree, see
+// Node.Goroutines, and CPU profiles can be broken down by runner, for example with
+// go tool pprof -tagfocus foundation.runner=service.1. Allocations can not be attributed to runners as
+// heap profiles do not record labels.
+func WithAccounting() RunOption {
+	return runConfigFunc(func(cfg *runConfig) {
+		cfg.accounting = true
+	})
+}
+
+// goroutines returns the number of go routines labelled with each runner name.
+func goroutines() map[string]int {
+	var buf bytes.Buffer
+
+	if err := pprof.Lookup("goroutine").WriteTo(&buf, 1); err != nil {
+		return nil
+	}
+
+	counts := make(map[string]int)
+	prefix := strconv.Quote(RunnerLabel) + ":"
+
+	var n int
+
+	// Each stack is a line with the number of go routines followed by a line of their labels, if any.
+	scanner := bufio.NewScanner(&buf)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if count, _, ok := strings.Cut(line, " @ "); ok {
+			n, _ = strconv.Atoi(count)
+
+			continue
+		}
+
+		labels, ok := strings.CutPrefix(line, "# labels: ")
+		if !ok {
+			continue
+		}
+
+		_, value, ok := strings.Cut(labels, prefix)
+		if !ok {
+			continue
+		}
+
+		if name, err := strconv.QuotedPrefix(value); err == nil {
+			name, _ = strconv.Unquote(name)
+			counts[name] += n
+		}
+	}
+
+	return counts
+}
@@ -0,0 +1,119 @@
+// Package accounting reports the go routines of each runner subtree as metrics, helping identify which
+// subsystem of a service is responsible for go routine growth. The foundation must be run with
+// foundation.WithAccounting for go routines to be attributed to runners.
+package accounting
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"go.krak3n.io/foundation"
+	"go.krak3n.io/foundation/metrics"
+	"go.krak3n.io/foundation/tick"
+)
+
+// An Option configures the accounting Runner.
+type Option interface {
+	apply(*config)
+}
+
+// Options is one or more Option.
+type Options []Option
+
+func (opts Options) apply(cfg *config) {
+	for opt := range slices.Values(opts) {
+		if opt != nil {
+			opt.apply(cfg)
+		}
+	}
+}
+
+type optionFunc func(*config)
+
+func (f optionFunc) apply(cfg *config) {
+	f(cfg)
+}
+
+// config holds the accounting Runner configuration.
+type config struct {
+	interval time.Duration
+	depth    int
+}
+
+// WithInterval sets how often go routines are sampled, defaults to 15 seconds.
+func WithInterval(d time.Duration) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.interval = d
+	})
+}
+
+// WithDepth sets how deep into the runner tree subtrees are reported, bounding the number of series,
+// defaults to 2, the runner given to foundation.Run and the runners it runs.
+func WithDepth(n int) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.depth = n
+	})
+}
+
+// A Runner is a foundation.Runner which samples the go routines of each runner subtree, reporting them
+// with the foundation_runner_goroutines gauge labelled with the runner's name.
+type Runner struct {
+	opts []Option
+
+	mtx    sync.Mutex
+	gauges map[string]metrics.Gauge
+}
+
+// Run returns a Runner which reports the go routines of each runner subtree.
+func Run(opts ...Option) *Runner {
+	return &Runner{
+		opts:   opts,
+		gauges: make(map[string]metrics.Gauge),
+	}
+}
+
+// Run samples the go routines of each runner subtree every interval until stopped.
+func (r *Runner) Run(ctx context.Context, f foundation.F) {
+	cfg := config{
+		interval: 15 * time.Second,
+		depth:    2,
+	}
+
+	Options(r.opts).apply(&cfg)
+
+	tick.Run(ctx, f, cfg.interval, func(context.Context, tick.Ticker) {
+		r.sample(foundation.Tree(f), 0, cfg.depth)
+	})
+}
+
+// sample reports the go routines of the node and its children down to the given depth, the root itself
+// is not reported as it is not a runner.
+func (r *Runner) sample(node foundation.Node, depth, limit int) {
+	if depth > limit {
+		return
+	}
+
+	if depth > 0 {
+		r.gauge(node.Name).Set(float64(node.Goroutines))
+	}
+
+	for child := range slices.Values(node.Children) {
+		r.sample(child, depth+1, limit)
+	}
+}
+
+// gauge returns the gauge of the runner.
+func (r *Runner) gauge(name string) metrics.Gauge {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	g, ok := r.gauges[name]
+	if !ok {
+		g = metrics.NewGauge("foundation_runner_goroutines", metrics.Labels{"runner": name})
+		r.gauges[name] = g
+	}
+
+	return g
+}
@@ -0,0 +1,176 @@
+// Package adminserver serves the operational endpoints of a foundation service on a single loopback port.
+package adminserver
+
+import (
+	"context"
+	"net/http"
+	"runtime"
+	"slices"
+
+	"go.krak3n.io/foundation"
+	"go.krak3n.io/foundation/buildinfo"
+	"go.krak3n.io/foundation/health"
+	"go.krak3n.io/foundation/logging"
+	"go.krak3n.io/foundation/metrics/prometheus"
+	transporthttp "go.krak3n.io/foundation/transport/http"
+)
+
+// An Option configures the admin server Runner.
+type Option interface {
+	apply(*config)
+}
+
+// Options is one or more Option.
+type Options []Option
+
+func (opts Options) apply(cfg *config) {
+	for opt := range slices.Values(opts) {
+		if opt != nil {
+			opt.apply(cfg)
+		}
+	}
+}
+
+type optionFunc func(*config)
+
+func (f optionFunc) apply(cfg *config) {
+	f(cfg)
+}
+
+// config holds the admin server Runner configuration.
+type config struct {
+	addr     string
+	registry *prometheus.Registry
+	config   func() any
+	redact   []string
+	errors   bool
+	rate     int
+}
+
+// WithAddress sets the address the admin server listens on, defaults to transporthttp.DefaultAdminAddress.
+// The address must be a loopback address.
+func WithAddress(addr string) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.addr = addr
+	})
+}
+
+// WithRegistry sets the Prometheus registry served on /metrics, defaults to the registry in the value
+// store, see prometheus.Get, or a new registry.
+func WithRegistry(r *prometheus.Registry) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.registry = r
+	})
+}
+
+// WithConfig serves the configuration returned by the function on /debug/config, for example the Config
+// method of a config.Runner or config.Watcher. Values of secret looking fields are redacted, see WithRedact.
+func WithConfig(fn func() any) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.config = fn
+	})
+}
+
+// WithRedact adds field names whose values are redacted from /debug/config, in addition to any field
+// whose name contains password, secret, token, key, credential or dsn. Names are case insensitive.
+func WithRedact(names ...string) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.redact = append(cfg.redact, names...)
+	})
+}
+
+// WithHealthErrors reports the error each failed sensor failed with on /_health, see health.WithErrors.
+func WithHealthErrors() Option {
+	return optionFunc(func(cfg *config) {
+		cfg.errors = true
+	})
+}
+
+// WithContentionProfiling enables the block and mutex profiles served on /debug/pprof/, sampling on
+// average one blocking event per rate nanoseconds blocked and one in rate mutex contention events, see
+// runtime.SetBlockProfileRate and runtime.SetMutexProfileFraction. Both are disabled again on stop.
+func WithContentionProfiling(rate int) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.rate = rate
+	})
+}
+
+// Run returns a foundation.Runner which serves the admin endpoints:
+//
+//   - /debug/pprof/ the net/http/pprof profiles
+//   - /debug/vars the expvar variables
+//   - /debug/tree a JSON snapshot of the runner tree
+//   - /debug/ticks the history of named tickers, see tick.WithName
+//   - /debug/config the redacted configuration, if configured with WithConfig
+//   - /debug/loglevel the log level, if set by logging.Run, see logging.LevelHandler
+//   - /metrics the Prometheus metrics
+//   - /_health the health probe sensor reports, with the build information if set by buildinfo.Run
+//   - /version the build information, if set by buildinfo.Run
+//
+// Run it after logging.Run, buildinfo.Run and prometheus.Run so their values are in the value store.
+func Run(opts ...Option) foundation.Runner {
+	return foundation.RunFunc(func(ctx context.Context, f foundation.F) {
+		cfg := config{
+			addr: transporthttp.DefaultAdminAddress,
+		}
+
+		Options(opts).apply(&cfg)
+
+		if err := transporthttp.CheckLoopback(cfg.addr); err != nil {
+			f.Error(err)
+		}
+
+		if cfg.registry == nil {
+			if r, ok := prometheus.Get(f); ok {
+				cfg.registry = r
+			} else {
+				cfg.registry = prometheus.NewRegistry()
+			}
+		}
+
+		var healthOpts []health.HandlerOption
+
+		if cfg.errors {
+			healthOpts = append(healthOpts, health.WithErrors())
+		}
+
+		healthHandler := health.JSONHandler(healthOpts...)
+
+		if info, ok := buildinfo.Get(f); ok {
+			healthHandler = health.NewHandler(health.JSONEnvelopeReportMarshaler(info.Fields()), healthOpts...)
+		}
+
+		if cfg.rate > 0 {
+			runtime.SetBlockProfileRate(cfg.rate)
+			runtime.SetMutexProfileFraction(cfg.rate)
+
+			f.On().Stop(func() {
+				runtime.SetBlockProfileRate(0)
+				runtime.SetMutexProfileFraction(0)
+			})
+		}
+
+		healthMux := health.ServeMux("/_health", healthHandler)
+
+		mux := http.NewServeMux()
+		mux.Handle("/debug/", transporthttp.AdminHandler(f))
+		mux.Handle("GET /debug/ticks", ticksHandler())
+		mux.Handle("GET /metrics", cfg.registry)
+		mux.Handle("/_health", healthMux)
+		mux.Handle("/_health/", healthMux)
+
+		if info, ok := buildinfo.Get(f); ok {
+			mux.Handle("GET /version", buildinfo.Handler(info))
+		}
+
+		if cfg.config != nil {
+			mux.Handle("GET /debug/config", configHandler(cfg.config, cfg.redact))
+		}
+
+		if level, ok := logging.Level(f); ok {
+			mux.Handle("/debug/loglevel", logging.LevelHandler(level))
+		}
+
+		f.Run(ctx, transporthttp.Run(mux, transporthttp.WtihServerAddress(cfg.addr)))
+	})
+}
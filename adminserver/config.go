@@ -0,0 +1,76 @@
+package adminserver
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// redacted replaces the value of redacted fields.
+const redacted = "REDACTED"
+
+// sensitive are the substrings of field names which are always redacted.
+var sensitive = []string{"password", "secret", "token", "key", "credential", "dsn"}
+
+// configHandler returns a http.Handler writing the configuration returned by fn as JSON with the values
+// of sensitive fields redacted.
+func configHandler(fn func() any, names []string) http.Handler {
+	names = append(slices.Clone(sensitive), names...)
+
+	for i, name := range names {
+		names[i] = strings.ToLower(name)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Round trip through JSON so field names are those the configuration is marshaled with.
+		b, err := json.Marshal(fn())
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			slog.ErrorContext(r.Context(), "failed to marshal config", slog.String("err", err.Error()))
+
+			return
+		}
+
+		var v any
+
+		if err := json.Unmarshal(b, &v); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			slog.ErrorContext(r.Context(), "failed to unmarshal config", slog.String("err", err.Error()))
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+
+		if err := json.NewEncoder(w).Encode(redact(v, names)); err != nil {
+			slog.ErrorContext(r.Context(), "failed to write config", slog.String("err", err.Error()))
+		}
+	})
+}
+
+// redact replaces the values of object fields whose name contains any of the names.
+func redact(v any, names []string) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, value := range v {
+			if slices.ContainsFunc(names, func(name string) bool {
+				return strings.Contains(strings.ToLower(k), name)
+			}) {
+				v[k] = redacted
+
+				continue
+			}
+
+			v[k] = redact(value, names)
+		}
+	case []any:
+		for i, value := range v {
+			v[i] = redact(value, names)
+		}
+	}
+
+	return v
+}
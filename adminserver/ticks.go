@@ -0,0 +1,35 @@
+package adminserver
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"go.krak3n.io/foundation/tick"
+)
+
+// ticksHandler returns a http.Handler writing the history of every named ticker as JSON, or of the
+// ticker named by the name query parameter, see tick.WithName.
+func ticksHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var v any = tick.Histories()
+
+		if name := r.URL.Query().Get("name"); name != "" {
+			executions := tick.History(name)
+			if executions == nil {
+				http.NotFound(w, r)
+
+				return
+			}
+
+			v = executions
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+
+		if err := json.NewEncoder(w).Encode(v); err != nil {
+			slog.ErrorContext(r.Context(), "failed to write ticker history", slog.String("err", err.Error()))
+		}
+	})
+}
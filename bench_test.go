@@ -0,0 +1,24 @@
+package foundation_test
+
+import (
+	"slices"
+	"testing"
+
+	"go.krak3n.io/foundation/foundationtest/bench"
+)
+
+func BenchmarkRun(b *testing.B) {
+	bench.Run(b)
+}
+
+func BenchmarkStop(b *testing.B) {
+	for shape := range slices.Values(bench.Shapes) {
+		b.Run(shape.Name, func(b *testing.B) {
+			bench.Stop(b, shape)
+		})
+	}
+}
+
+func TestBudget(t *testing.T) {
+	bench.Assert(t, bench.DefaultBudget)
+}
@@ -0,0 +1,181 @@
+// Package blob manages the lifecycle of an object storage client, such as S3 or GCS, bound to a bucket.
+package blob
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"go.krak3n.io/foundation"
+	"go.krak3n.io/foundation/health/probe"
+)
+
+// A Bucket is an object storage bucket. Foundation does not depend on any cloud SDK, instead a Bucket is
+// a thin adapter over a client which checks the bucket can be accessed, for example with S3 from
+// github.com/aws/aws-sdk-go-v2/service/s3:
+//
+//	type bucket struct {
+//		*s3.Client
+//		name string
+//	}
+//
+//	func (b bucket) Head(ctx context.Context) error {
+//		_, err := b.HeadBucket(ctx, &s3.HeadBucketInput{Bucket: aws.String(b.name)})
+//		return err
+//	}
+//
+// Or with GCS from cloud.google.com/go/storage, whose client also has a Close method:
+//
+//	type bucket struct {
+//		*storage.Client
+//		name string
+//	}
+//
+//	func (b bucket) Head(ctx context.Context) error {
+//		_, err := b.Bucket(b.name).Attrs(ctx)
+//		return err
+//	}
+type Bucket interface {
+	// Head verifies the bucket exists and the credentials in use can access it.
+	Head(ctx context.Context) error
+}
+
+// A ConnectFunc constructs a Bucket, the context carries the timeout.
+type ConnectFunc[B Bucket] func(ctx context.Context) (B, error)
+
+// An Option configures the blob Runner.
+type Option interface {
+	apply(*config)
+}
+
+// Options is one or more Option.
+type Options []Option
+
+func (opts Options) apply(cfg *config) {
+	for opt := range slices.Values(opts) {
+		if opt != nil {
+			opt.apply(cfg)
+		}
+	}
+}
+
+type optionFunc func(*config)
+
+func (f optionFunc) apply(cfg *config) {
+	f(cfg)
+}
+
+// config holds the blob Runner configuration.
+type config struct {
+	name       string
+	timeout    time.Duration
+	sensorMode probe.Mode
+}
+
+// WithName sets the name of the bucket, used to name the sensor and as the value store key, see Get.
+// Defaults to "blob".
+func WithName(name string) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.name = name
+	})
+}
+
+// WithTimeout bounds constructing the bucket and each head request, defaults to 5 seconds.
+func WithTimeout(d time.Duration) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.timeout = d
+	})
+}
+
+// WithSensorMode sets the mode of the buckets sensor, defaults to probe.ReadinessMode.
+func WithSensorMode(mode probe.Mode) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.sensorMode = mode
+	})
+}
+
+// A Runner is a foundation.Runner which constructs a Bucket and verifies it can be accessed. Access is
+// checked once rather than retried, misconfigured credentials or permissions will not fix themselves, so
+// the service fails fast at startup.
+type Runner[B Bucket] struct {
+	connect ConnectFunc[B]
+	opts    []Option
+	mtx     sync.RWMutex
+	bucket  B
+}
+
+// Run returns a Runner which constructs a bucket using the connect function.
+func Run[B Bucket](connect ConnectFunc[B], opts ...Option) *Runner[B] {
+	return &Runner[B]{
+		connect: connect,
+		opts:    opts,
+	}
+}
+
+// Bucket returns the bucket, the zero value until the runner has verified access which is guaranteed
+// once F.Run has returned for the Runner.
+func (r *Runner[B]) Bucket() B {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	return r.bucket
+}
+
+// Run constructs the bucket, verifies it can be accessed and publishes it to the value store. If the
+// bucket has a Close method it is called on stop.
+func (r *Runner[B]) Run(ctx context.Context, f foundation.F) {
+	cfg := config{
+		name:       "blob",
+		timeout:    5 * time.Second,
+		sensorMode: probe.ReadinessMode,
+	}
+
+	Options(r.opts).apply(&cfg)
+
+	connectCtx, cancel := context.WithTimeout(ctx, cfg.timeout)
+	bucket, err := r.connect(connectCtx)
+	cancel()
+
+	if err != nil {
+		f.Error(fmt.Errorf("connect %s: %w", cfg.name, err))
+	}
+
+	if closer, ok := any(bucket).(interface{ Close() error }); ok {
+		f.On().Stop(func() {
+			if err := closer.Close(); err != nil {
+				slog.Warn("failed to close blob bucket", slog.String("name", cfg.name), slog.String("err", err.Error()))
+			}
+		})
+	}
+
+	head := func(ctx context.Context) error {
+		ctx, cancel := context.WithTimeout(ctx, cfg.timeout)
+		defer cancel()
+
+		return bucket.Head(ctx)
+	}
+
+	if err := head(ctx); err != nil {
+		f.Error(fmt.Errorf("access %s: %w", cfg.name, err))
+	}
+
+	r.mtx.Lock()
+	r.bucket = bucket
+	r.mtx.Unlock()
+
+	f.Values().Store(bucketKey(cfg.name), bucket)
+
+	probe.RegisterContext(ctx, probe.NewSensor(fmt.Sprintf("blob[%s]", cfg.name), cfg.sensorMode, head))
+}
+
+// bucketKey is the value store key a bucket is stored under, keyed by the buckets name.
+type bucketKey string
+
+// Get returns the bucket with the given name from the F's value store, for example
+// Get[bucket](f, "blob").
+func Get[B Bucket](f foundation.F, name string) (B, bool) {
+	return foundation.Value[B](f, bucketKey(name))
+}
@@ -0,0 +1,166 @@
+package blueprint
+
+import (
+	"slices"
+
+	"go.krak3n.io/foundation"
+	"go.krak3n.io/foundation/adminserver"
+	"go.krak3n.io/foundation/buildinfo"
+	foundationconfig "go.krak3n.io/foundation/config"
+	"go.krak3n.io/foundation/logging"
+	"go.krak3n.io/foundation/metrics/prometheus"
+	"go.krak3n.io/foundation/otel"
+	transportgrpc "go.krak3n.io/foundation/transport/grpc"
+	transporthttp "go.krak3n.io/foundation/transport/http"
+)
+
+// An Option configures a blueprint.
+type Option interface {
+	apply(*config)
+}
+
+// Options is one or more Option.
+type Options []Option
+
+func (opts Options) apply(cfg *config) {
+	for opt := range slices.Values(opts) {
+		if opt != nil {
+			opt.apply(cfg)
+		}
+	}
+}
+
+type optionFunc func(*config)
+
+func (f optionFunc) apply(cfg *config) {
+	f(cfg)
+}
+
+// config holds the blueprint configuration.
+type config struct {
+	addr        string
+	profile     Profile
+	build       buildinfo.Info
+	setup       []foundation.Runner
+	runners     []foundation.Runner
+	runOpts     []foundation.RunOption
+	noLogging   bool
+	logOpts     []logging.Option
+	otelSetup   otel.SetupFunc
+	otelOpts    []otel.Option
+	noMetrics   bool
+	metricsOpts []prometheus.Option
+	adminOpts   []adminserver.Option
+	httpOpts    []transporthttp.RunnerOption
+	grpcOpts    []transportgrpc.RunnerOption
+}
+
+// WithAddress sets the address the HTTP or gRPC server listens on, defaults to ":8080" for HTTP and
+// ":9090" for gRPC. Ignored by Run and Worker.
+func WithAddress(addr string) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.addr = addr
+	})
+}
+
+// WithConfig loads configuration of type T before any other runner but logging, failing startup if it
+// cannot be loaded, see config.Run. The configuration is read with config.Get and served redacted by the
+// admin server on /debug/config.
+func WithConfig[T any](opts ...foundationconfig.Option) Option {
+	return optionFunc(func(cfg *config) {
+		r := foundationconfig.Run[T](opts...)
+
+		cfg.setup = append(cfg.setup, r)
+		cfg.adminOpts = append(cfg.adminOpts, adminserver.WithConfig(func() any {
+			return r.Config()
+		}))
+	})
+}
+
+// WithLogging configures the default slog logger, see logging.Run.
+func WithLogging(opts ...logging.Option) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.logOpts = append(cfg.logOpts, opts...)
+	})
+}
+
+// WithoutLogging leaves the default slog logger as it is.
+func WithoutLogging() Option {
+	return optionFunc(func(cfg *config) {
+		cfg.noLogging = true
+	})
+}
+
+// WithOTel initialises the OpenTelemetry providers with the SetupFunc, see otel.Run. Foundation does
+// not depend on the OpenTelemetry SDK so without a SetupFunc no providers are initialised.
+func WithOTel(setup otel.SetupFunc, opts ...otel.Option) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.otelSetup = setup
+		cfg.otelOpts = append(cfg.otelOpts, opts...)
+	})
+}
+
+// WithMetrics configures the Prometheus registry, see prometheus.Run. The registry is served by the admin
+// server on /metrics.
+func WithMetrics(opts ...prometheus.Option) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.metricsOpts = append(cfg.metricsOpts, opts...)
+	})
+}
+
+// WithoutMetrics does not store a Prometheus registry, the admin server serves its own on /metrics.
+func WithoutMetrics() Option {
+	return optionFunc(func(cfg *config) {
+		cfg.noMetrics = true
+	})
+}
+
+// WithBuildInfo sets the build information, typically injected at build time with -ldflags, overriding
+// that read from the binary, see buildinfo.Read. Empty values are read from the binary. The version and
+// commit are added to every log and the version to the OpenTelemetry resource, the build information is
+// added to the health reports and served by the admin server on /version.
+func WithBuildInfo(version, commit, date string) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.build = buildinfo.Info{
+			Version: version,
+			Commit:  commit,
+			Date:    date,
+		}
+	})
+}
+
+// WithRunners runs the given runners, in order, before the service's own runner, for example to connect
+// to databases the service depends on.
+func WithRunners(runners ...foundation.Runner) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.runners = append(cfg.runners, runners...)
+	})
+}
+
+// WithRunOptions configures the root foundation.F.
+func WithRunOptions(opts ...foundation.RunOption) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.runOpts = append(cfg.runOpts, opts...)
+	})
+}
+
+// WithAdminOptions configures the admin server.
+func WithAdminOptions(opts ...adminserver.Option) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.adminOpts = append(cfg.adminOpts, opts...)
+	})
+}
+
+// WithHTTPOptions configures the HTTP server of a HTTP service, applied after the blueprint's defaults.
+func WithHTTPOptions(opts ...transporthttp.RunnerOption) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.httpOpts = append(cfg.httpOpts, opts...)
+	})
+}
+
+// WithGRPCOptions configures the gRPC server runner of a gRPC service.
+func WithGRPCOptions(opts ...transportgrpc.RunnerOption) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.grpcOpts = append(cfg.grpcOpts, opts...)
+	})
+}
@@ -0,0 +1,87 @@
+package blueprint
+
+import (
+	"log/slog"
+	"os"
+	"strings"
+
+	"go.krak3n.io/foundation/adminserver"
+	"go.krak3n.io/foundation/logging"
+	"go.krak3n.io/foundation/otel"
+)
+
+// A Profile is a set of defaults suited to the environment a service runs in.
+type Profile string
+
+// Profiles.
+const (
+	// Dev logs text at debug level, reports sensor errors in the admin server's health reports, enables
+	// the block and mutex profiles and records every trace.
+	Dev Profile = "dev"
+	// Staging logs JSON at info level, reports sensor errors in the admin server's health reports and
+	// records every trace.
+	Staging Profile = "staging"
+	// Prod logs JSON at info level, reports only the status of sensors and records 10% of traces which
+	// do not have a sampled parent.
+	Prod Profile = "prod"
+)
+
+// ProfileFromEnv returns the profile named by FOUNDATION_ENV, defaulting to Prod. Unknown profiles
+// behave as Prod.
+func ProfileFromEnv() Profile {
+	if env := os.Getenv("FOUNDATION_ENV"); env != "" {
+		return Profile(strings.ToLower(env))
+	}
+
+	return Prod
+}
+
+// WithProfile sets the profile, overriding FOUNDATION_ENV. The profile only sets defaults, LOG_FORMAT,
+// LOG_LEVEL, OTEL_TRACES_SAMPLER and options given with WithLogging, WithOTel and WithAdminOptions take
+// precedence.
+func WithProfile(p Profile) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.profile = p
+	})
+}
+
+// apply prepends the profile's defaults to the configured options so those given take precedence, any
+// default configured by the environment is skipped.
+func (p Profile) apply(cfg *config) {
+	var (
+		logOpts   []logging.Option
+		otelOpts  []otel.Option
+		adminOpts []adminserver.Option
+	)
+
+	unset := func(key string) bool {
+		return os.Getenv(key) == ""
+	}
+
+	sampler, ratio := "parentbased_traceidratio", 0.1
+
+	switch p {
+	case Dev:
+		if unset("LOG_FORMAT") {
+			logOpts = append(logOpts, logging.WithFormat(logging.Text))
+		}
+
+		if unset("LOG_LEVEL") {
+			logOpts = append(logOpts, logging.WithLevel(slog.LevelDebug))
+		}
+
+		sampler, ratio = "parentbased_always_on", 1
+		adminOpts = append(adminOpts, adminserver.WithHealthErrors(), adminserver.WithContentionProfiling(1))
+	case Staging:
+		sampler, ratio = "parentbased_always_on", 1
+		adminOpts = append(adminOpts, adminserver.WithHealthErrors())
+	}
+
+	if unset("OTEL_TRACES_SAMPLER") {
+		otelOpts = append(otelOpts, otel.WithSampler(sampler, ratio))
+	}
+
+	cfg.logOpts = append(logOpts, cfg.logOpts...)
+	cfg.otelOpts = append(otelOpts, cfg.otelOpts...)
+	cfg.adminOpts = append(adminOpts, cfg.adminOpts...)
+}
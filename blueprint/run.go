@@ -1,12 +1,89 @@
+// Package blueprint runs services within a standard opinionated set of runners, so a new service needs
+// only its own handler or runner.
 package blueprint
 
 import (
+	"context"
+	"log/slog"
+
 	"go.krak3n.io/foundation"
+	"go.krak3n.io/foundation/adminserver"
+	"go.krak3n.io/foundation/buildinfo"
 	"go.krak3n.io/foundation/health"
+	"go.krak3n.io/foundation/logging"
+	"go.krak3n.io/foundation/metrics/prometheus"
+	"go.krak3n.io/foundation/otel"
 )
 
 // Run runs the given runner with in a standard opinionated set of other runners which provides
-// telemetry, logging, healthchecks etc.
-func Run(name string, r foundation.Runner) {
-	foundation.Run(name, health.Run(r))
+// telemetry, logging, healthchecks, an admin server etc, with defaults for the environment set by the
+// Profile, see WithProfile. Before the runner is run:
+//
+//   - the default slog logger is configured, see logging.Run and WithLogging
+//   - the build information is stored, see buildinfo.Run and WithBuildInfo
+//   - the OpenTelemetry providers are initialised, if configured with WithOTel
+//   - configuration is loaded, if configured with WithConfig
+//   - a Prometheus registry is stored for the metrics, see prometheus.Run and WithMetrics
+//   - the admin server is started, see adminserver.Run
+//
+// As runners are stopped newest first the runner is stopped before the providers are flushed and shut
+// down, then the logs flushed.
+func Run(name string, r foundation.Runner, opts ...Option) {
+	run(name, func(config) foundation.Runner { return r }, opts)
+}
+
+// run applies the options and runs the runner built from the configuration within the standard set of
+// runners, any runners given with WithRunners are run
+// first.
+func run(name string, build func(config) foundation.Runner, opts []Option) {
+	cfg := config{
+		profile: ProfileFromEnv(),
+	}
+
+	Options(opts).apply(&cfg)
+
+	cfg.profile.apply(&cfg)
+
+	info := cfg.build.Merge(buildinfo.Read())
+
+	var (
+		runners  []foundation.Runner
+		logOpts  []logging.Option
+		otelOpts []otel.Option
+	)
+
+	if info.Version != "" {
+		logOpts = append(logOpts, logging.WithVersion(info.Version))
+		otelOpts = append(otelOpts, otel.WithResourceAttributes(map[string]string{
+			"service.version": info.Version,
+		}))
+	}
+
+	if info.Commit != "" {
+		logOpts = append(logOpts, logging.WithAttrs(slog.String("commit", info.Commit)))
+	}
+
+	if !cfg.noLogging {
+		runners = append(runners, logging.Run(append(logOpts, cfg.logOpts...)...))
+	}
+
+	runners = append(runners, buildinfo.Run(info))
+
+	if cfg.otelSetup != nil {
+		runners = append(runners, otel.Run(cfg.otelSetup, append(otelOpts, cfg.otelOpts...)...))
+	}
+
+	runners = append(runners, cfg.setup...)
+
+	if !cfg.noMetrics {
+		runners = append(runners, prometheus.Run(cfg.metricsOpts...))
+	}
+
+	runners = append(runners,
+		adminserver.Run(cfg.adminOpts...),
+		health.Run(append(cfg.runners, build(cfg))...))
+
+	foundation.Run(name, foundation.RunFunc(func(ctx context.Context, f foundation.F) {
+		f.Run(ctx, runners...)
+	}), cfg.runOpts...)
 }
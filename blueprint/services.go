@@ -0,0 +1,62 @@
+package blueprint
+
+import (
+	"net/http"
+	"time"
+
+	"go.krak3n.io/foundation"
+	transportgrpc "go.krak3n.io/foundation/transport/grpc"
+	transporthttp "go.krak3n.io/foundation/transport/http"
+)
+
+// Default addresses the service archetypes listen on.
+const (
+	DefaultHTTPAddress = ":8080"
+	DefaultGRPCAddress = ":9090"
+)
+
+// HTTP runs a HTTP service serving the handler, draining in flight requests for up to 10 seconds on
+// stop. Request bodies are limited to 10 MiB and headers to 100 values of up to 8 KiB each, options
+// given with WithHTTPOptions are applied after so may change them.
+//
+//	blueprint.HTTP("orders", mux)
+func HTTP(name string, handler http.Handler, opts ...Option) {
+	run(name, func(cfg config) foundation.Runner {
+		addr := cfg.addr
+		if addr == "" {
+			addr = DefaultHTTPAddress
+		}
+
+		return transporthttp.Run(handler, append(transporthttp.RunnerOptions{
+			transporthttp.WtihServerAddress(addr),
+			transporthttp.WithDrain(10 * time.Second),
+			transporthttp.WithMaxBodySize(10 << 20),
+			transporthttp.WithHeaderLimits(100, 8<<10),
+		}, cfg.httpOpts...)...)
+	}, opts)
+}
+
+// GRPC runs a gRPC service serving the server, typically a *grpc.Server with its services registered.
+//
+//	srv := grpc.NewServer()
+//	pb.RegisterOrdersServer(srv, orders)
+//
+//	blueprint.GRPC("orders", srv)
+func GRPC(name string, server transportgrpc.Server, opts ...Option) {
+	run(name, func(cfg config) foundation.Runner {
+		addr := cfg.addr
+		if addr == "" {
+			addr = DefaultGRPCAddress
+		}
+
+		return transportgrpc.Run(addr, server, cfg.grpcOpts...)
+	}, opts)
+}
+
+// Worker runs a background worker service, for example a consumer.Runner processing messages, which
+// serves no traffic of its own beyond the admin server and health checks.
+//
+//	blueprint.Worker("order-events", consumer.Run("order-events", source, handle))
+func Worker(name string, r foundation.Runner, opts ...Option) {
+	Run(name, r, opts...)
+}
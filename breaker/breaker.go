@@ -0,0 +1,289 @@
+// Package breaker provides a circuit breaker, failing calls to an unhealthy dependency fast rather than
+// waiting on it, until it has had time to recover.
+package breaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"go.krak3n.io/foundation/health/probe"
+	"go.krak3n.io/foundation/metrics"
+)
+
+// ErrOpen is returned when a call is rejected because the breaker is open.
+var ErrOpen = errors.New("circuit breaker open")
+
+// A State is the state of a Breaker.
+type State int
+
+// Breaker states.
+const (
+	// Closed allows calls, counting consecutive failures.
+	Closed State = iota
+	// HalfOpen allows a limited number of trial calls once the open timeout has passed.
+	HalfOpen
+	// Open rejects calls until the open timeout has passed.
+	Open
+)
+
+// String returns the name of the state.
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case HalfOpen:
+		return "half-open"
+	case Open:
+		return "open"
+	default:
+		return fmt.Sprintf("State(%d)", int(s))
+	}
+}
+
+// A StateChangeFunc is called when a Breaker changes state.
+type StateChangeFunc func(name string, from, to State)
+
+// An Option configures a Breaker.
+type Option interface {
+	apply(*config)
+}
+
+// Options is one or more Option.
+type Options []Option
+
+func (opts Options) apply(cfg *config) {
+	for opt := range slices.Values(opts) {
+		if opt != nil {
+			opt.apply(cfg)
+		}
+	}
+}
+
+type optionFunc func(*config)
+
+func (f optionFunc) apply(cfg *config) {
+	f(cfg)
+}
+
+// config holds the Breaker configuration.
+type config struct {
+	threshold   int
+	openTimeout time.Duration
+	trials      int
+	hooks       []StateChangeFunc
+	sensor      *probe.Mode
+}
+
+// WithFailureThreshold sets the number of consecutive failures which open the breaker, defaults to 5.
+func WithFailureThreshold(n int) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.threshold = n
+	})
+}
+
+// WithOpenTimeout sets how long the breaker stays open before allowing trial calls, defaults to 30
+// seconds.
+func WithOpenTimeout(d time.Duration) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.openTimeout = d
+	})
+}
+
+// WithTrials sets the number of trial calls allowed whilst half open, all of which must succeed to close
+// the breaker, defaults to 1.
+func WithTrials(n int) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.trials = n
+	})
+}
+
+// WithStateChange calls the function whenever the breaker changes state.
+func WithStateChange(fn StateChangeFunc) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.hooks = append(cfg.hooks, fn)
+	})
+}
+
+// WithSensor registers a health probe sensor which fails whilst the breaker is open, for breakers guarding
+// a dependency the service cannot function without.
+func WithSensor(mode probe.Mode) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.sensor = &mode
+	})
+}
+
+// A Breaker is a circuit breaker. Calls are allowed whilst closed, after a number of consecutive failures
+// it opens rejecting calls with ErrOpen, once the open timeout has passed it half opens allowing trial
+// calls which close it again if they succeed or reopen it if any fail.
+type Breaker struct {
+	name string
+	cfg  config
+
+	mtx       sync.Mutex
+	state     State
+	failures  int
+	opened    time.Time
+	trials    int
+	successes int
+
+	gauge       metrics.Gauge
+	transitions map[State]metrics.Counter
+	rejected    metrics.Counter
+}
+
+// New returns a closed Breaker, the name is used to label metrics and name the sensor.
+func New(name string, opts ...Option) *Breaker {
+	cfg := config{
+		threshold:   5,
+		openTimeout: 30 * time.Second,
+		trials:      1,
+	}
+
+	Options(opts).apply(&cfg)
+
+	transition := func(to State) metrics.Counter {
+		return metrics.NewCounter("breaker_transitions_total", metrics.Labels{"breaker": name, "to": to.String()})
+	}
+
+	b := &Breaker{
+		name:  name,
+		cfg:   cfg,
+		gauge: metrics.NewGauge("breaker_state", metrics.Labels{"breaker": name}),
+		transitions: map[State]metrics.Counter{
+			Closed:   transition(Closed),
+			HalfOpen: transition(HalfOpen),
+			Open:     transition(Open),
+		},
+		rejected: metrics.NewCounter("breaker_rejected_total", metrics.Labels{"breaker": name}),
+	}
+
+	if cfg.sensor != nil {
+		probe.Register(probe.NewSensor(fmt.Sprintf("breaker[%s]", name), *cfg.sensor, func(context.Context) error {
+			if b.State() == Open {
+				return fmt.Errorf("%s: %w", name, ErrOpen)
+			}
+
+			return nil
+		}))
+	}
+
+	return b
+}
+
+// Name returns the name of the breaker.
+func (b *Breaker) Name() string {
+	return b.name
+}
+
+// State returns the current state, an open breaker whose timeout has passed is reported as half open.
+func (b *Breaker) State() State {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	if b.state == Open && time.Since(b.opened) >= b.cfg.openTimeout {
+		return HalfOpen
+	}
+
+	return b.state
+}
+
+// Allow reports whether a call may be made, returning ErrOpen if not. Every allowed call must be followed
+// by a call to Done with its outcome.
+func (b *Breaker) Allow() error {
+	b.mtx.Lock()
+
+	if b.state == Open && time.Since(b.opened) >= b.cfg.openTimeout {
+		defer b.transition(Open, HalfOpen)()
+
+		b.state = HalfOpen
+		b.trials = 0
+		b.successes = 0
+	}
+
+	var err error
+
+	switch b.state {
+	case Open:
+		err = ErrOpen
+	case HalfOpen:
+		if b.trials >= b.cfg.trials {
+			err = ErrOpen
+		} else {
+			b.trials++
+		}
+	}
+
+	b.mtx.Unlock()
+
+	if err != nil {
+		b.rejected.Add(1)
+	}
+
+	return err
+}
+
+// Done records the outcome of an allowed call, a nil error is a success.
+func (b *Breaker) Done(err error) {
+	b.mtx.Lock()
+
+	from := b.state
+
+	switch {
+	case err == nil && b.state == HalfOpen:
+		if b.successes++; b.successes >= b.cfg.trials {
+			b.state = Closed
+			b.failures = 0
+		}
+	case err == nil:
+		b.failures = 0
+	case b.state == HalfOpen:
+		b.state = Open
+		b.opened = time.Now()
+	case b.state == Closed:
+		if b.failures++; b.failures >= b.cfg.threshold {
+			b.state = Open
+			b.opened = time.Now()
+		}
+	}
+
+	to := b.state
+
+	b.mtx.Unlock()
+
+	if from != to {
+		b.transition(from, to)()
+	}
+}
+
+// Do calls fn if the breaker allows it, recording its outcome, otherwise returns ErrOpen.
+func (b *Breaker) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	if err := b.Allow(); err != nil {
+		return err
+	}
+
+	err := fn(ctx)
+
+	b.Done(err)
+
+	return err
+}
+
+// transition returns a function which records the state change and calls the hooks, to be called once
+// the lock is released.
+func (b *Breaker) transition(from, to State) func() {
+	return func() {
+		b.gauge.Set(float64(to))
+		b.transitions[to].Add(1)
+
+		slog.Info("circuit breaker state changed", slog.String("breaker", b.name), slog.String("from", from.String()), slog.String("to", to.String()))
+
+		for fn := range slices.Values(b.cfg.hooks) {
+			fn(b.name, from, to)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+var errFailed = errors.New("failed")
+
+// transitions returns a breaker recording its state changes.
+func transitions(t *testing.T, opts ...Option) (*Breaker, *[]State) {
+	t.Helper()
+
+	var states []State
+
+	b := New(t.Name(), append(opts, WithStateChange(func(_ string, _, to State) {
+		states = append(states, to)
+	}))...)
+
+	return b, &states
+}
+
+func TestBreakerOpens(t *testing.T) {
+	b, states := transitions(t, WithFailureThreshold(3), WithOpenTimeout(time.Hour))
+
+	fail := func(context.Context) error { return errFailed }
+
+	for range 2 {
+		if err := b.Do(context.Background(), fail); !errors.Is(err, errFailed) {
+			t.Fatalf("want call made whilst closed, got %v", err)
+		}
+	}
+
+	// A success resets the consecutive failures.
+	_ = b.Do(context.Background(), func(context.Context) error { return nil })
+
+	for range 3 {
+		_ = b.Do(context.Background(), fail)
+	}
+
+	if s := b.State(); s != Open {
+		t.Fatalf("want open, got %s", s)
+	}
+
+	called := false
+
+	err := b.Do(context.Background(), func(context.Context) error {
+		called = true
+
+		return nil
+	})
+
+	if !errors.Is(err, ErrOpen) || called {
+		t.Errorf("want call rejected whilst open, got %v, called %t", err, called)
+	}
+
+	if want := []State{Open}; !slices.Equal(*states, want) {
+		t.Errorf("want transitions %v, got %v", want, *states)
+	}
+}
+
+func TestBreakerHalfOpen(t *testing.T) {
+	tests := []struct {
+		name        string
+		outcomes    []error
+		state       State
+		transitions []State
+	}{
+		{
+			name:        "trials succeed",
+			outcomes:    []error{nil, nil},
+			state:       Closed,
+			transitions: []State{Open, HalfOpen, Closed},
+		},
+		{
+			name:        "trial fails",
+			outcomes:    []error{nil, errFailed},
+			state:       Open,
+			transitions: []State{Open, HalfOpen, Open},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, states := transitions(t, WithFailureThreshold(1), WithOpenTimeout(10*time.Millisecond), WithTrials(2))
+
+			if err := b.Allow(); err != nil {
+				t.Fatalf("want call allowed whilst closed, got %v", err)
+			}
+
+			b.Done(errFailed)
+
+			time.Sleep(20 * time.Millisecond)
+
+			if s := b.State(); s != HalfOpen {
+				t.Fatalf("want half-open once the timeout has passed, got %s", s)
+			}
+
+			for range tt.outcomes {
+				if err := b.Allow(); err != nil {
+					t.Fatalf("want trial allowed, got %v", err)
+				}
+			}
+
+			if err := b.Allow(); !errors.Is(err, ErrOpen) {
+				t.Errorf("want calls over the trials rejected, got %v", err)
+			}
+
+			for err := range slices.Values(tt.outcomes) {
+				b.Done(err)
+			}
+
+			if s := b.State(); s != tt.state {
+				t.Errorf("want %s, got %s", tt.state, s)
+			}
+
+			if !slices.Equal(*states, tt.transitions) {
+				t.Errorf("want transitions %v, got %v", tt.transitions, *states)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package foundation
+
+import (
+	"context"
+	"maps"
+	"sync"
+	"time"
+)
+
+// A StopCtxFunc is a stop hook given a context whose deadline is the runner's share of the shutdown
+// budget, see EventHook.StopCtx and WithShutdownBudget.
+type StopCtxFunc func(ctx context.Context)
+
+// WithShutdownBudget bounds how long stopping may take once the foundation starts to stop, for example
+// 25 seconds to finish before the orchestrator sends SIGKILL. The budget is divided between runners with
+// StopCtx hooks as each starts to stop, in proportion to their weight, see ShutdownWeight. Each is given
+// its share of the budget remaining at that point, so time left over by runners which stop quickly is
+// given to those stopping after them and the first slow runner can not consume the whole grace period.
+// The share is the deadline of the context given to the runner's StopCtx hooks, runners must respect it.
+func WithShutdownBudget(d time.Duration) RunOption {
+	return runConfigFunc(func(cfg *runConfig) {
+		cfg.budget = d
+	})
+}
+
+// ShutdownWeight sets the weight of the runner of the given F in the division of the shutdown budget,
+// defaults to 1. A runner with a weight of 2 is given twice the share of one with a weight of 1.
+func ShutdownWeight(v F, weight float64) {
+	f, ok := v.(*f)
+	if !ok || weight <= 0 {
+		return
+	}
+
+	f.budget.mtx.Lock()
+	f.budget.share(f).weight = weight
+	f.budget.mtx.Unlock()
+}
+
+// budget divides the shutdown budget between runners with StopCtx hooks, shared by every F in a tree.
+type budget struct {
+	mtx      sync.Mutex
+	total    time.Duration
+	deadline time.Time
+	shares   map[*f]*share
+}
+
+// share is the share of the budget of a runner.
+type share struct {
+	weight float64
+	// Whether the runner has StopCtx hooks so is given a share of the budget.
+	hooked bool
+	// The deadline of the share once the runner has started to stop.
+	deadline time.Time
+	taken    bool
+}
+
+func newBudget() *budget {
+	return &budget{
+		shares: make(map[*f]*share),
+	}
+}
+
+// share returns the share of the runner, called with the lock held.
+func (b *budget) share(f *f) *share {
+	s, ok := b.shares[f]
+	if !ok {
+		s = &share{weight: 1}
+		b.shares[f] = s
+	}
+
+	return s
+}
+
+// hook records the runner has StopCtx hooks so is given a share of the budget.
+func (b *budget) hook(f *f) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	b.share(f).hooked = true
+}
+
+// release forgets the share of the runner once it has stopped.
+func (b *budget) release(f *f) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	delete(b.shares, f)
+}
+
+// start starts the budget, if configured, as the foundation starts to stop.
+func (b *budget) start(now time.Time) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	if b.total > 0 && b.deadline.IsZero() {
+		b.deadline = now.Add(b.total)
+	}
+}
+
+// context returns the context given to the StopCtx hooks of the runner, with the deadline of its share
+// of the budget. The share is taken when the first hook is called, subsequent hooks share its deadline.
+// Runners stopped before the budget has started are given a context without a deadline.
+func (b *budget) context(f *f) (context.Context, context.CancelFunc) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	s, ok := b.shares[f]
+	if !ok {
+		return context.WithCancel(context.Background())
+	}
+
+	if !s.taken {
+		s.deadline = b.divide(s, time.Now())
+		s.taken = true
+	}
+
+	if s.deadline.IsZero() {
+		return context.WithCancel(context.Background())
+	}
+
+	return context.WithDeadline(context.Background(), s.deadline)
+}
+
+// divide returns the deadline of the share, its weight's proportion of the remaining budget between the
+// runners yet to stop, called with the lock held.
+func (b *budget) divide(s *share, now time.Time) time.Time {
+	if b.deadline.IsZero() {
+		return time.Time{}
+	}
+
+	remaining := b.deadline.Sub(now)
+	if remaining <= 0 {
+		return b.deadline
+	}
+
+	var weights float64
+
+	for other := range maps.Values(b.shares) {
+		if other.hooked && !other.taken {
+			weights += other.weight
+		}
+	}
+
+	return now.Add(time.Duration(float64(remaining) * s.weight / weights))
+}
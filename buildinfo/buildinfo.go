@@ -0,0 +1,123 @@
+// Package buildinfo describes the build of the running binary, its version, commit and build date, so
+// logs, health reports, telemetry and the admin server identify it consistently.
+package buildinfo
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"maps"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"slices"
+
+	"go.krak3n.io/foundation"
+)
+
+// Info describes the build of the binary.
+type Info struct {
+	Version   string `json:"version,omitempty"`
+	Commit    string `json:"commit,omitempty"`
+	Date      string `json:"date,omitempty"`
+	GoVersion string `json:"go_version,omitempty"`
+}
+
+// Read returns the build information embedded by the Go toolchain, the module version and the VCS
+// revision and commit time, if the binary was built with them.
+func Read() Info {
+	info := Info{
+		GoVersion: runtime.Version(),
+	}
+
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+
+	if v := bi.Main.Version; v != "(devel)" {
+		info.Version = v
+	}
+
+	for s := range slices.Values(bi.Settings) {
+		switch s.Key {
+		case "vcs.revision":
+			info.Commit = s.Value
+		case "vcs.time":
+			info.Date = s.Value
+		}
+	}
+
+	return info
+}
+
+// Merge returns the Info with its empty fields set from other.
+func (i Info) Merge(other Info) Info {
+	return Info{
+		Version:   cmp.Or(i.Version, other.Version),
+		Commit:    cmp.Or(i.Commit, other.Commit),
+		Date:      cmp.Or(i.Date, other.Date),
+		GoVersion: cmp.Or(i.GoVersion, other.GoVersion),
+	}
+}
+
+// Fields returns the non empty fields keyed by their JSON names.
+func (i Info) Fields() map[string]string {
+	fields := make(map[string]string)
+
+	for k, v := range map[string]string{
+		"version":    i.Version,
+		"commit":     i.Commit,
+		"date":       i.Date,
+		"go_version": i.GoVersion,
+	} {
+		if v != "" {
+			fields[k] = v
+		}
+	}
+
+	return fields
+}
+
+// LogValue returns the Info as a slog group.
+func (i Info) LogValue() slog.Value {
+	fields := i.Fields()
+
+	attrs := make([]slog.Attr, 0, len(fields))
+
+	for _, k := range slices.Sorted(maps.Keys(fields)) {
+		attrs = append(attrs, slog.String(k, fields[k]))
+	}
+
+	return slog.GroupValue(attrs...)
+}
+
+// Handler returns a http.Handler which serves the Info as JSON.
+func Handler(info Info) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if err := json.NewEncoder(w).Encode(info); err != nil {
+			slog.ErrorContext(r.Context(), "failed to write build info", slog.String("err", err.Error()))
+		}
+	})
+}
+
+// Run returns a foundation.Runner which stores the Info in the value store, see Get. Run it first so
+// later runners can describe the build.
+func Run(info Info) foundation.Runner {
+	return foundation.RunFunc(func(ctx context.Context, f foundation.F) {
+		slog.InfoContext(ctx, "build info", slog.Any("build", info))
+
+		f.Values().Store(infoKey{}, info)
+	})
+}
+
+// infoKey is the value store key the Info is stored under.
+type infoKey struct{}
+
+// Get returns the Info from the F's value store.
+func Get(f foundation.F) (Info, bool) {
+	return foundation.Value[Info](f, infoKey{})
+}
@@ -0,0 +1,315 @@
+// Package bus provides a typed publish and subscribe bus for communication between runners, for example a
+// configuration watcher telling an HTTP server to reload its certificates, without ad hoc shared channels.
+//
+// The Bus is run first by the runner given to foundation.Run, so it is owned by the root of the tree and
+// stopped last, and is shared through the value store.
+//
+//	var CertsRotated = bus.NewTopic[tls.Certificate]("certs.rotated")
+//
+//	foundation.Run("service", foundation.RunFunc(func(ctx context.Context, f foundation.F) {
+//		f.Run(ctx, bus.Run(), watcher, server)
+//	}))
+//
+//	// In the server.
+//	bus.Subscribe(ctx, f, CertsRotated, func(ctx context.Context, cert tls.Certificate) {
+//		...
+//	})
+//
+//	// In the watcher.
+//	bus.Publish(ctx, f, CertsRotated, cert)
+//
+// Each subscription receives messages in the order they were published on its own go routine. Messages
+// queued for a subscription are delivered before the subscribing runner stops.
+package bus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"runtime/debug"
+	"slices"
+	"sync"
+	"time"
+
+	"go.krak3n.io/foundation"
+)
+
+var (
+	// ErrNoBus is returned when publishing or subscribing if no Bus is running, see Run.
+	ErrNoBus = errors.New("no bus running")
+	// ErrClosed is returned when publishing or subscribing once the Bus has stopped.
+	ErrClosed = errors.New("bus closed")
+)
+
+// A Topic names a stream of messages of type T. Topics of different types with the same name are
+// distinct.
+type Topic[T any] struct {
+	name string
+}
+
+// NewTopic returns the topic with the given name.
+func NewTopic[T any](name string) Topic[T] {
+	return Topic[T]{
+		name: name,
+	}
+}
+
+// Name returns the name of the topic.
+func (t Topic[T]) Name() string {
+	return t.name
+}
+
+// An Option configures the Bus.
+type Option interface {
+	apply(*config)
+}
+
+// Options is one or more Option.
+type Options []Option
+
+func (opts Options) apply(cfg *config) {
+	for opt := range slices.Values(opts) {
+		if opt != nil {
+			opt.apply(cfg)
+		}
+	}
+}
+
+type optionFunc func(*config)
+
+func (f optionFunc) apply(cfg *config) {
+	f(cfg)
+}
+
+// config holds the Bus configuration.
+type config struct {
+	buffer  int
+	timeout time.Duration
+}
+
+// WithBuffer sets how many messages are queued for each subscription before publishing blocks, defaults
+// to 64.
+func WithBuffer(n int) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.buffer = n
+	})
+}
+
+// WithDrainTimeout sets how long queued messages are given to be delivered when a subscription ends,
+// defaults to 30 seconds. Messages still queued after the timeout are dropped.
+func WithDrainTimeout(d time.Duration) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.timeout = d
+	})
+}
+
+// A Bus is a foundation.Runner which delivers messages published on topics to their subscribers.
+type Bus struct {
+	cfg config
+
+	mtx    sync.RWMutex
+	subs   map[any][]*subscription
+	closed bool
+}
+
+// Run returns a Bus, which is a foundation.Runner.
+func Run(opts ...Option) *Bus {
+	cfg := config{
+		buffer:  64,
+		timeout: 30 * time.Second,
+	}
+
+	Options(opts).apply(&cfg)
+
+	return &Bus{
+		cfg:  cfg,
+		subs: make(map[any][]*subscription),
+	}
+}
+
+// Run stores the Bus in the value store, see Get. Once stopped further messages are rejected with
+// ErrClosed and the subscriptions which remain are drained.
+func (b *Bus) Run(ctx context.Context, f foundation.F) {
+	f.Values().Store(busKey{}, b)
+
+	f.On().Stop(func() {
+		b.mtx.Lock()
+
+		b.closed = true
+
+		var subs []*subscription
+
+		for v := range maps.Values(b.subs) {
+			subs = append(subs, v...)
+		}
+
+		clear(b.subs)
+
+		b.mtx.Unlock()
+
+		for sub := range slices.Values(subs) {
+			sub.close(b.cfg.timeout)
+		}
+	})
+
+	f.Parallel()
+}
+
+// busKey is the value store key the Bus is stored under.
+type busKey struct{}
+
+// Get returns the Bus from the F's value store.
+func Get(f foundation.F) (*Bus, bool) {
+	return foundation.Value[*Bus](f, busKey{})
+}
+
+// Publish publishes the message on the topic of the Bus in the F's value store, queuing it for every
+// subscription. If a subscription's queue is full Publish blocks until there is room or the context is
+// done, returning the context's error.
+func Publish[T any](ctx context.Context, f foundation.F, topic Topic[T], msg T) error {
+	b, ok := Get(f)
+	if !ok {
+		return ErrNoBus
+	}
+
+	b.mtx.RLock()
+
+	if b.closed {
+		b.mtx.RUnlock()
+
+		return ErrClosed
+	}
+
+	subs := b.subs[topic]
+
+	b.mtx.RUnlock()
+
+	for sub := range slices.Values(subs) {
+		if err := sub.send(ctx, msg); err != nil {
+			return fmt.Errorf("publish %s: %w", topic.name, err)
+		}
+	}
+
+	return nil
+}
+
+// Subscribe calls fn with each message published on the topic of the Bus in the F's value store until
+// the F stops, delivering queued messages before the F's Stop hooks return. A panic in fn is reported
+// as an error through the F. The context is passed to fn.
+func Subscribe[T any](ctx context.Context, f foundation.F, topic Topic[T], fn func(ctx context.Context, msg T)) error {
+	b, ok := Get(f)
+	if !ok {
+		return ErrNoBus
+	}
+
+	sub := &subscription{
+		ch:   make(chan any, b.cfg.buffer),
+		quit: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+
+	b.mtx.Lock()
+
+	if b.closed {
+		b.mtx.Unlock()
+
+		return ErrClosed
+	}
+
+	b.subs[topic] = append(b.subs[topic], sub)
+
+	b.mtx.Unlock()
+
+	go func() {
+		defer close(sub.done)
+
+		for msg := range sub.ch {
+			deliver(ctx, f, topic, fn, msg.(T))
+		}
+	}()
+
+	f.On().Stop(func() {
+		b.mtx.Lock()
+
+		// Copied as publishers may be sending to a snapshot of the subscriptions.
+		b.subs[topic] = slices.DeleteFunc(slices.Clone(b.subs[topic]), func(v *subscription) bool {
+			return v == sub
+		})
+
+		if len(b.subs[topic]) == 0 {
+			delete(b.subs, topic)
+		}
+
+		b.mtx.Unlock()
+
+		sub.close(b.cfg.timeout)
+	})
+
+	return nil
+}
+
+// deliver calls fn with the message, reporting a panic as an error through the F.
+func deliver[T any](ctx context.Context, f foundation.F, topic Topic[T], fn func(context.Context, T), msg T) {
+	defer func() {
+		if r := recover(); r != nil {
+			foundation.Report(f, foundation.RuntimeError{
+				Cause:  fmt.Errorf("bus subscriber of %s panicked: %v", topic.name, r),
+				Stack:  debug.Stack(),
+				Runner: f.Name(),
+			})
+		}
+	}()
+
+	fn(ctx, msg)
+}
+
+// subscription queues messages for a subscriber.
+type subscription struct {
+	mtx    sync.RWMutex
+	ch     chan any
+	closed bool
+	// quit is closed when the subscription ends, unblocking publishers waiting for room.
+	quit chan struct{}
+	// done is closed once queued messages have been delivered.
+	done chan struct{}
+	once sync.Once
+}
+
+// send queues the message, it is dropped if the subscription has ended.
+func (s *subscription) send(ctx context.Context, msg any) error {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	if s.closed {
+		return nil
+	}
+
+	select {
+	case s.ch <- msg:
+		return nil
+	case <-s.quit:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// close ends the subscription, waiting up to the timeout for queued messages to be delivered.
+func (s *subscription) close(timeout time.Duration) {
+	s.once.Do(func() {
+		close(s.quit)
+
+		s.mtx.Lock()
+		s.closed = true
+		close(s.ch)
+		s.mtx.Unlock()
+	})
+
+	select {
+	case <-s.done:
+	case <-time.After(timeout):
+		slog.Warn("timed out delivering queued bus messages", slog.Int("dropped", len(s.ch)))
+	}
+}
@@ -0,0 +1,119 @@
+// Package cache provides a typed in-memory cache whose entries expire after a time to live, and a Runner
+// which keeps a cache of reference data refreshed on a schedule.
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+// entry is a cached value and when it expires, the zero time if never.
+type entry[V any] struct {
+	value   V
+	expires time.Time
+}
+
+// A Cache is a typed in-memory cache safe for concurrent use. Expired entries are not returned, they are
+// held until deleted, overwritten or the cache is replaced.
+type Cache[K comparable, V any] struct {
+	ttl     time.Duration
+	mtx     sync.RWMutex
+	entries map[K]entry[V]
+}
+
+// New returns a Cache whose entries expire after the TTL, entries never expire if it is 0.
+func New[K comparable, V any](ttl time.Duration) *Cache[K, V] {
+	return &Cache[K, V]{
+		ttl:     ttl,
+		entries: make(map[K]entry[V]),
+	}
+}
+
+// Get returns the value for the key and whether it was present and not expired.
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	c.mtx.RLock()
+	e, ok := c.entries[key]
+	c.mtx.RUnlock()
+
+	if !ok || c.expired(e, time.Now()) {
+		var zero V
+
+		return zero, false
+	}
+
+	return e.value, true
+}
+
+// Set sets the value for the key, expiring after the caches TTL.
+func (c *Cache[K, V]) Set(key K, value V) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.entries[key] = c.entry(value, time.Now())
+}
+
+// Delete deletes the key.
+func (c *Cache[K, V]) Delete(key K) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	delete(c.entries, key)
+}
+
+// Replace replaces every entry with the given values, each expiring after the caches TTL.
+func (c *Cache[K, V]) Replace(values map[K]V) {
+	now := time.Now()
+
+	entries := make(map[K]entry[V], len(values))
+
+	for k, v := range values {
+		entries[k] = c.entry(v, now)
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.entries = entries
+}
+
+// All returns a copy of the entries which have not expired.
+func (c *Cache[K, V]) All() map[K]V {
+	now := time.Now()
+
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+
+	values := make(map[K]V, len(c.entries))
+
+	for k, e := range c.entries {
+		if !c.expired(e, now) {
+			values[k] = e.value
+		}
+	}
+
+	return values
+}
+
+// Len returns the number of entries, including any which have expired but not yet been removed.
+func (c *Cache[K, V]) Len() int {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+
+	return len(c.entries)
+}
+
+// entry returns the entry for the value set at the given time.
+func (c *Cache[K, V]) entry(value V, now time.Time) entry[V] {
+	e := entry[V]{value: value}
+
+	if c.ttl > 0 {
+		e.expires = now.Add(c.ttl)
+	}
+
+	return e
+}
+
+// expired reports whether the entry has expired at the given time.
+func (c *Cache[K, V]) expired(e entry[V], now time.Time) bool {
+	return !e.expires.IsZero() && now.After(e.expires)
+}
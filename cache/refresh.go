@@ -0,0 +1,226 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"go.krak3n.io/foundation"
+	"go.krak3n.io/foundation/health/probe"
+	"go.krak3n.io/foundation/metrics"
+	"go.krak3n.io/foundation/tick"
+)
+
+// A LoadFunc loads every entry of the cache, for example reference data from a database or API.
+type LoadFunc[K comparable, V any] func(ctx context.Context) (map[K]V, error)
+
+// An Option configures the Refresher.
+type Option interface {
+	apply(*config)
+}
+
+// Options is one or more Option.
+type Options []Option
+
+func (opts Options) apply(cfg *config) {
+	for opt := range slices.Values(opts) {
+		if opt != nil {
+			opt.apply(cfg)
+		}
+	}
+}
+
+type optionFunc func(*config)
+
+func (f optionFunc) apply(cfg *config) {
+	f(cfg)
+}
+
+// config holds the Refresher configuration.
+type config struct {
+	interval     time.Duration
+	jitter       float64
+	backoff      tick.Backoff
+	timeout      time.Duration
+	maxStaleness time.Duration
+	sensorMode   probe.Mode
+}
+
+// WithInterval sets how often the cache is refreshed, defaults to 1 minute.
+func WithInterval(d time.Duration) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.interval = d
+	})
+}
+
+// WithJitter applies random jitter to the interval so replicas do not refresh in lockstep, a fraction of
+// the interval, defaults to 0.1.
+func WithJitter(jitter float64) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.jitter = jitter
+	})
+}
+
+// WithBackoff sets the backoff between attempts once a refresh has failed, defaults to an exponential
+// backoff with a scalar of 1 second and 20% jitter, capped at the interval.
+func WithBackoff(backoff tick.Backoff) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.backoff = backoff
+	})
+}
+
+// WithTimeout bounds each load, defaults to 30 seconds.
+func WithTimeout(d time.Duration) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.timeout = d
+	})
+}
+
+// WithMaxStaleness sets how long since the last successful refresh before the sensor fails, defaults to
+// 3 intervals.
+func WithMaxStaleness(d time.Duration) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.maxStaleness = d
+	})
+}
+
+// WithSensorMode sets the mode of the staleness sensor, defaults to probe.ReadinessMode.
+func WithSensorMode(mode probe.Mode) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.sensorMode = mode
+	})
+}
+
+// A Refresher is a foundation.Runner which keeps a Cache refreshed, replacing its entries with those
+// loaded on each refresh. A failed refresh keeps the current entries, subject to the caches TTL, and is
+// retried with backoff.
+type Refresher[K comparable, V any] struct {
+	name  string
+	cache *Cache[K, V]
+	load  LoadFunc[K, V]
+	opts  []Option
+
+	mtx       sync.RWMutex
+	refreshed time.Time
+	err       error
+
+	succeeded metrics.Counter
+	failed    metrics.Counter
+	staleness metrics.Gauge
+}
+
+// Refresh returns a Refresher named name, used to label metrics and name the sensor, which loads the
+// caches entries with the load function.
+func Refresh[K comparable, V any](name string, c *Cache[K, V], load LoadFunc[K, V], opts ...Option) *Refresher[K, V] {
+	return &Refresher[K, V]{
+		name:      name,
+		cache:     c,
+		load:      load,
+		opts:      opts,
+		succeeded: metrics.NewCounter("cache_refreshes_total", metrics.Labels{"cache": name, "status": "success"}),
+		failed:    metrics.NewCounter("cache_refreshes_total", metrics.Labels{"cache": name, "status": "error"}),
+		staleness: metrics.NewGauge("cache_staleness_seconds", metrics.Labels{"cache": name}),
+	}
+}
+
+// Refreshed returns when the cache was last refreshed successfully and the error of the last refresh if
+// it failed.
+func (r *Refresher[K, V]) Refreshed() (time.Time, error) {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	return r.refreshed, r.err
+}
+
+// Run loads the cache, erroring if the first load fails so the service never runs with an empty cache,
+// then refreshes it in parallel until stopped.
+func (r *Refresher[K, V]) Run(ctx context.Context, f foundation.F) {
+	cfg := config{
+		interval:   time.Minute,
+		jitter:     0.1,
+		backoff:    tick.ExponentialBackoff(time.Second, tick.WithJitter(0.2)),
+		timeout:    30 * time.Second,
+		sensorMode: probe.ReadinessMode,
+	}
+
+	Options(r.opts).apply(&cfg)
+
+	if cfg.maxStaleness == 0 {
+		cfg.maxStaleness = 3 * cfg.interval
+	}
+
+	if err := r.refresh(ctx, cfg); err != nil {
+		f.Error(fmt.Errorf("load cache %s: %w", r.name, err))
+	}
+
+	probe.RegisterContext(ctx, probe.NewSensor(fmt.Sprintf("cache[%s]", r.name), cfg.sensorMode, func(context.Context) error {
+		refreshed, err := r.Refreshed()
+
+		stale := time.Since(refreshed)
+
+		r.staleness.Set(stale.Seconds())
+
+		if stale > cfg.maxStaleness {
+			if err != nil {
+				return fmt.Errorf("cache %s stale for %s: %w", r.name, stale.Round(time.Second), err)
+			}
+
+			return fmt.Errorf("cache %s stale for %s", r.name, stale.Round(time.Second))
+		}
+
+		return nil
+	}))
+
+	var failures uint8
+
+	interval := tick.LinearBackoff(cfg.interval, tick.WithJitter(cfg.jitter))
+
+	// Wait the interval with jitter between refreshes, backing off instead whilst refreshes are failing.
+	backoff := tick.BackoffFunc(func(ctx context.Context, attempt uint8) time.Duration {
+		if failures > 0 {
+			return min(cfg.backoff.Wait(ctx, failures), cfg.interval)
+		}
+
+		return interval.Wait(ctx, attempt)
+	})
+
+	f.Run(ctx, tick.NewRunner(func(ctx context.Context, _ tick.Ticker) {
+		if err := r.refresh(ctx, cfg); err != nil {
+			failures = min(failures+1, 10)
+
+			slog.WarnContext(ctx, "failed to refresh cache", slog.String("cache", r.name), slog.String("err", err.Error()))
+
+			return
+		}
+
+		failures = 0
+	}, backoff))
+}
+
+// refresh loads the cache replacing its entries.
+func (r *Refresher[K, V]) refresh(ctx context.Context, cfg config) error {
+	ctx, cancel := context.WithTimeout(ctx, cfg.timeout)
+	defer cancel()
+
+	values, err := r.load(ctx)
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	r.err = err
+
+	if err != nil {
+		r.failed.Add(1)
+
+		return err
+	}
+
+	r.cache.Replace(values)
+	r.refreshed = time.Now()
+	r.succeeded.Add(1)
+
+	return nil
+}
@@ -0,0 +1,273 @@
+// Package cli runs one of several subcommands, such as serve, migrate or worker, each bound to a Runner
+// and its own flags, with the same lifecycle and signal handling as foundation.Run.
+//
+//	serve := cli.NewCommand("serve", "serve the API", foundation.RunFunc(func(ctx context.Context, f foundation.F) {
+//		f.Run(ctx, config.Run[Config](config.WithArgs(cli.Args(f))), api)
+//	}))
+//
+//	cli.Run("api", []*cli.Command{serve, migrate, cli.HealthCheck()})
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"go.krak3n.io/foundation"
+)
+
+// A CommandOption configures a Command.
+type CommandOption interface {
+	applyCommand(*Command)
+}
+
+// CommandOptions is one or more CommandOption.
+type CommandOptions []CommandOption
+
+func (opts CommandOptions) applyCommand(cmd *Command) {
+	for opt := range slices.Values(opts) {
+		if opt != nil {
+			opt.applyCommand(cmd)
+		}
+	}
+}
+
+type commandOptionFunc func(*Command)
+
+func (f commandOptionFunc) applyCommand(cmd *Command) {
+	f(cmd)
+}
+
+// WithFlags registers the commands flags on its flag set. Flags are parsed before the Runner runs, so
+// values bound with for example fs.StringVar are set by the time it does. Arguments after the flags are
+// available to the Runner through Args.
+func WithFlags(fn func(fs *flag.FlagSet)) CommandOption {
+	return commandOptionFunc(func(cmd *Command) {
+		cmd.flags = fn
+	})
+}
+
+// WithRunOptions sets the options the command is run with, see foundation.Run.
+func WithRunOptions(opts ...foundation.RunOption) CommandOption {
+	return commandOptionFunc(func(cmd *Command) {
+		cmd.runOpts = append(cmd.runOpts, opts...)
+	})
+}
+
+// A Command is a subcommand bound to a Runner.
+type Command struct {
+	name    string
+	usage   string
+	runner  foundation.Runner
+	flags   func(fs *flag.FlagSet)
+	runOpts []foundation.RunOption
+}
+
+// NewCommand returns a Command with the given name and one line usage description which runs the Runner.
+func NewCommand(name, usage string, r foundation.Runner, opts ...CommandOption) *Command {
+	cmd := &Command{
+		name:   name,
+		usage:  usage,
+		runner: r,
+	}
+
+	CommandOptions(opts).applyCommand(cmd)
+
+	return cmd
+}
+
+// An Option configures Run.
+type Option interface {
+	apply(*config)
+}
+
+// Options is one or more Option.
+type Options []Option
+
+func (opts Options) apply(cfg *config) {
+	for opt := range slices.Values(opts) {
+		if opt != nil {
+			opt.apply(cfg)
+		}
+	}
+}
+
+type optionFunc func(*config)
+
+func (f optionFunc) apply(cfg *config) {
+	f(cfg)
+}
+
+// config holds the Run configuration.
+type config struct {
+	args   []string
+	output io.Writer
+	def    string
+}
+
+// WithArgs sets the arguments, the first of which is the command name, defaults to os.Args[1:].
+func WithArgs(args []string) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.args = args
+	})
+}
+
+// WithOutput sets where usage and errors are written, defaults to os.Stderr.
+func WithOutput(w io.Writer) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.output = w
+	})
+}
+
+// WithDefault sets the command run when no command is given, otherwise usage is written and the process
+// exits.
+func WithDefault(name string) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.def = name
+	})
+}
+
+// Run runs the command named by the first argument with foundation.Run, under the given service name. The
+// help command, or -h or --help, writes usage for all commands or, followed by a command name, for that
+// command. Usage errors exit with code 2.
+func Run(name string, cmds []*Command, opts ...Option) {
+	cfg := config{
+		args:   os.Args[1:],
+		output: os.Stderr,
+	}
+
+	Options(opts).apply(&cfg)
+
+	cmd, args, code := resolve(name, cmds, cfg)
+	if cmd == nil {
+		os.Exit(code)
+	}
+
+	foundation.Run(name, foundation.RunFunc(func(ctx context.Context, f foundation.F) {
+		f.Values().Store(argsKey{}, args)
+
+		cmd.runner.Run(ctx, f)
+	}), cmd.runOpts...)
+}
+
+// resolve returns the command to run and its arguments once its flags have been parsed, or the exit code
+// if there is no command to run.
+func resolve(name string, cmds []*Command, cfg config) (*Command, []string, int) {
+	args := cfg.args
+
+	if len(args) == 0 {
+		if cfg.def == "" {
+			usage(cfg.output, name, cmds)
+
+			return nil, nil, 2
+		}
+
+		args = []string{cfg.def}
+	}
+
+	switch args[0] {
+	case "help", "-h", "-help", "--help":
+		if len(args) > 1 {
+			if cmd := find(cmds, args[1]); cmd != nil {
+				cmd.flagSet(name, cfg.output).Usage()
+
+				return nil, nil, 0
+			}
+
+			fmt.Fprintf(cfg.output, "unknown command %q\n\n", args[1])
+		}
+
+		usage(cfg.output, name, cmds)
+
+		return nil, nil, 0
+	}
+
+	cmd := find(cmds, args[0])
+	if cmd == nil {
+		fmt.Fprintf(cfg.output, "unknown command %q\n\n", args[0])
+		usage(cfg.output, name, cmds)
+
+		return nil, nil, 2
+	}
+
+	fs := cmd.flagSet(name, cfg.output)
+
+	if err := fs.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil, nil, 0
+		}
+
+		return nil, nil, 2
+	}
+
+	return cmd, fs.Args(), 0
+}
+
+// find returns the command with the given name or nil.
+func find(cmds []*Command, name string) *Command {
+	i := slices.IndexFunc(cmds, func(cmd *Command) bool {
+		return cmd.name == name
+	})
+	if i < 0 {
+		return nil
+	}
+
+	return cmds[i]
+}
+
+// flagSet returns the commands flag set with its flags registered.
+func (cmd *Command) flagSet(name string, w io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	fs.SetOutput(w)
+	fs.Usage = func() {
+		fmt.Fprintf(w, "Usage: %s %s [flags] [args]\n\n%s\n", name, cmd.name, cmd.usage)
+
+		var flags bool
+
+		fs.VisitAll(func(*flag.Flag) {
+			flags = true
+		})
+
+		if flags {
+			fmt.Fprintf(w, "\nFlags:\n")
+			fs.PrintDefaults()
+		}
+	}
+
+	if cmd.flags != nil {
+		cmd.flags(fs)
+	}
+
+	return fs
+}
+
+// usage writes the usage for all commands.
+func usage(w io.Writer, name string, cmds []*Command) {
+	fmt.Fprintf(w, "Usage: %s <command> [flags] [args]\n\nCommands:\n", name)
+
+	width := 4 // help
+
+	for cmd := range slices.Values(cmds) {
+		width = max(width, len(cmd.name))
+	}
+
+	for cmd := range slices.Values(cmds) {
+		fmt.Fprintf(w, "  %-*s  %s\n", width, cmd.name, cmd.usage)
+	}
+
+	fmt.Fprintf(w, "  %-*s  %s\n", width, "help", "show usage for a command")
+}
+
+// argsKey is the value store key the command arguments are stored under.
+type argsKey struct{}
+
+// Args returns the arguments remaining after the commands flags were parsed, nil if not run by Run.
+func Args(f foundation.F) []string {
+	args, _ := foundation.Value[[]string](f, argsKey{})
+
+	return args
+}
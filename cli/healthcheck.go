@@ -0,0 +1,65 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.krak3n.io/foundation"
+	"go.krak3n.io/foundation/health/heartbeat"
+)
+
+// HealthCheck returns a healthcheck Command which requests the readiness endpoint of the health server
+// run by health.Run, exiting non-zero unless it responds 200 OK. It suits container health checks where
+// the image has no HTTP client, for example HEALTHCHECK CMD ["/api", "healthcheck"].
+func HealthCheck() *Command {
+	var (
+		url     string
+		timeout time.Duration
+	)
+
+	return NewCommand("healthcheck", "check the health of a running instance", foundation.RunFunc(func(ctx context.Context, f foundation.F) {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			f.Error(err)
+		}
+
+		rsp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			f.Error(err)
+		}
+
+		defer rsp.Body.Close()
+
+		if rsp.StatusCode != http.StatusOK {
+			f.Error(fmt.Errorf("%s: %s", url, rsp.Status))
+		}
+	}), WithFlags(func(fs *flag.FlagSet) {
+		fs.StringVar(&url, "url", "http://127.0.0.1:3417/_health/readiness", "health endpoint to request")
+		fs.DurationVar(&timeout, "timeout", 5*time.Second, "time to wait for a response")
+	}))
+}
+
+// HeartbeatCheck returns a heartbeatcheck Command which checks the heartbeat file written by
+// heartbeat.Run was touched recently, exiting non-zero if it is missing or stale. It suits exec checks
+// on platforms which check files rather than HTTP, for example a Nomad script check.
+func HeartbeatCheck() *Command {
+	var (
+		path   string
+		maxAge time.Duration
+	)
+
+	return NewCommand("heartbeatcheck", "check the heartbeat file of a running instance", foundation.RunFunc(func(ctx context.Context, f foundation.F) {
+		if err := heartbeat.Check(path, maxAge); err != nil {
+			f.Error(err)
+		}
+	}), WithFlags(func(fs *flag.FlagSet) {
+		fs.StringVar(&path, "path", "", "heartbeat file to check")
+		fs.DurationVar(&maxAge, "max-age", 30*time.Second, "maximum age of the heartbeat file")
+	}))
+}
@@ -0,0 +1,321 @@
+// Package config loads typed configuration into a struct from defaults, files, environment variables and
+// command line flags, validating the result.
+//
+// Sources are applied in order of precedence, lowest first: the default struct tag, files in the order
+// given, environment variables and finally flags. Fields are mapped with struct tags:
+//
+//	type Config struct {
+//		Addr     string        `json:"addr" env:"ADDR" flag:"addr" default:":8080" usage:"listen address"`
+//		Timeout  time.Duration `json:"timeout" env:"TIMEOUT" default:"5s"`
+//		Database struct {
+//			DSN string `json:"dsn" env:"DATABASE_DSN" required:"true"`
+//		} `json:"database"`
+//	}
+//
+// Supported field types are strings, bools, integers, floats, time.Duration, string slices (comma
+// separated) and types implementing encoding.TextUnmarshaler. Nested structs are walked.
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+)
+
+// A Decoder decodes a configuration file into v.
+type Decoder func(data []byte, v any) error
+
+// A Validator is a configuration which validates itself once loaded, for checks which cannot be expressed
+// with the required struct tag.
+type Validator interface {
+	Validate() error
+}
+
+// A Resolver resolves references in string values once loaded, for example secret references, see the
+// secrets package. Values which are not references are returned unchanged.
+type Resolver interface {
+	Resolve(ctx context.Context, value string) (string, error)
+}
+
+// An Option configures loading.
+type Option interface {
+	apply(*config)
+}
+
+// Options is one or more Option.
+type Options []Option
+
+func (opts Options) apply(cfg *config) {
+	for opt := range slices.Values(opts) {
+		if opt != nil {
+			opt.apply(cfg)
+		}
+	}
+}
+
+type optionFunc func(*config)
+
+func (f optionFunc) apply(cfg *config) {
+	f(cfg)
+}
+
+// file is a configuration file to load.
+type file struct {
+	path     string
+	optional bool
+}
+
+// config holds the loading configuration.
+type config struct {
+	files    []file
+	decoders map[string]Decoder
+	prefix   string
+	args     []string
+	output   io.Writer
+	resolver Resolver
+	// pollInterval is how often a Watcher polls the files.
+	pollInterval time.Duration
+}
+
+// WithFile loads the given file, decoded by the Decoder for its extension, see WithDecoder. The file must
+// exist.
+func WithFile(path string) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.files = append(cfg.files, file{path: path})
+	})
+}
+
+// WithOptionalFile loads the given file if it exists, for example local overrides.
+func WithOptionalFile(path string) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.files = append(cfg.files, file{path: path, optional: true})
+	})
+}
+
+// WithDecoder sets the Decoder for files with the given extension. JSON is supported out of the box,
+// other formats are supported by registering their Unmarshal function, for example with
+// gopkg.in/yaml.v3 and github.com/BurntSushi/toml:
+//
+//	config.WithDecoder(".yaml", yaml.Unmarshal)
+//	config.WithDecoder(".toml", toml.Unmarshal)
+func WithDecoder(ext string, dec Decoder) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.decoders[strings.ToLower(ext)] = dec
+	})
+}
+
+// WithEnvPrefix prefixes the env struct tag names, so with the prefix API_ the tag env:"ADDR" reads
+// API_ADDR.
+func WithEnvPrefix(prefix string) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.prefix = prefix
+	})
+}
+
+// WithArgs parses the given command line arguments, usually os.Args[1:], setting fields with a flag
+// struct tag. Flags are not parsed unless WithArgs is given.
+func WithArgs(args []string) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.args = args
+	})
+}
+
+// WithResolver resolves every string field with the Resolver once all sources have been applied, before
+// validation.
+func WithResolver(r Resolver) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.resolver = r
+	})
+}
+
+// WithUsageOutput sets where flag usage and parse errors are written, defaults to os.Stderr.
+func WithUsageOutput(w io.Writer) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.output = w
+	})
+}
+
+// newConfig returns the loading configuration with the options applied.
+func newConfig(opts []Option) config {
+	cfg := config{
+		decoders: map[string]Decoder{
+			".json": json.Unmarshal,
+		},
+		output: os.Stderr,
+	}
+
+	Options(opts).apply(&cfg)
+
+	return cfg
+}
+
+// Load loads configuration of type T, which must be a struct, returning all errors found joined
+// together rather than just the first.
+func Load[T any](opts ...Option) (T, error) {
+	var v T
+
+	cfg := newConfig(opts)
+
+	err := cfg.load(&v)
+
+	return v, err
+}
+
+// load loads the configuration into the struct pointed to by ptr.
+func (cfg config) load(ptr any) error {
+	rv := reflect.ValueOf(ptr)
+	if rv.Kind() != reflect.Pointer || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("config: %T is not a pointer to a struct", ptr)
+	}
+
+	fs := fields(rv.Elem(), "")
+
+	var errs []error
+
+	// Defaults
+	for f := range slices.Values(fs) {
+		if def, ok := f.tag.Lookup("default"); ok {
+			if err := f.set(def); err != nil {
+				errs = append(errs, FieldError{Field: f.path, Source: "default", Err: err})
+			}
+		}
+	}
+
+	// Files
+	for file := range slices.Values(cfg.files) {
+		if err := cfg.decode(file, ptr); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	// Environment
+	for f := range slices.Values(fs) {
+		name, ok := f.tag.Lookup("env")
+		if !ok || name == "" {
+			continue
+		}
+
+		name = cfg.prefix + name
+
+		if value, ok := os.LookupEnv(name); ok {
+			if err := f.set(value); err != nil {
+				errs = append(errs, FieldError{Field: f.path, Source: "env " + name, Err: err})
+			}
+		}
+	}
+
+	// Flags
+	if cfg.args != nil {
+		if err := cfg.parseFlags(fs); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	// References
+	if cfg.resolver != nil && len(errs) == 0 {
+		for f := range slices.Values(fs) {
+			if f.value.Kind() != reflect.String || f.value.String() == "" {
+				continue
+			}
+
+			value, err := cfg.resolver.Resolve(context.Background(), f.value.String())
+			if err != nil {
+				errs = append(errs, FieldError{Field: f.path, Source: "resolver", Err: err})
+
+				continue
+			}
+
+			f.value.SetString(value)
+		}
+	}
+
+	// Bail before validating if any value could not be set, validation errors would only be noise.
+	if len(errs) > 0 {
+		return fmt.Errorf("config: %w", errors.Join(errs...))
+	}
+
+	for f := range slices.Values(fs) {
+		if f.tag.Get("required") == "true" && f.value.IsZero() {
+			errs = append(errs, FieldError{Field: f.path, Err: errRequired})
+		}
+	}
+
+	if v, ok := ptr.(Validator); ok {
+		if err := v.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("config: %w", errors.Join(errs...))
+	}
+
+	return nil
+}
+
+// decode decodes the file into ptr.
+func (cfg config) decode(file file, ptr any) error {
+	dec, ok := cfg.decoders[strings.ToLower(filepath.Ext(file.path))]
+	if !ok {
+		return fmt.Errorf("%s: no decoder for %q files", file.path, filepath.Ext(file.path))
+	}
+
+	b, err := os.ReadFile(file.path)
+	if file.optional && errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if err := dec(b, ptr); err != nil {
+		return fmt.Errorf("%s: %w", file.path, err)
+	}
+
+	return nil
+}
+
+// parseFlags parses the arguments setting fields with a flag struct tag.
+func (cfg config) parseFlags(fs []field) error {
+	set := flag.NewFlagSet(filepath.Base(os.Args[0]), flag.ContinueOnError)
+	set.SetOutput(cfg.output)
+
+	var errs []error
+
+	for f := range slices.Values(fs) {
+		name, ok := f.tag.Lookup("flag")
+		if !ok || name == "" {
+			continue
+		}
+
+		fn := func(value string) error {
+			if err := f.set(value); err != nil {
+				errs = append(errs, FieldError{Field: f.path, Source: "flag -" + name, Err: err})
+			}
+
+			return nil
+		}
+
+		if f.isBool() {
+			set.BoolFunc(name, f.tag.Get("usage"), fn)
+		} else {
+			set.Func(name, f.tag.Get("usage"), fn)
+		}
+	}
+
+	if err := set.Parse(cfg.args); err != nil {
+		return err
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,156 @@
+package config
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// field is a settable leaf field of a configuration struct.
+type field struct {
+	// path is the dotted Go path of the field, used in errors.
+	path  string
+	value reflect.Value
+	tag   reflect.StructTag
+}
+
+// fields returns the leaf fields of the struct pointed to by v, walking nested and embedded structs.
+func fields(v reflect.Value, prefix string) []field {
+	var out []field
+
+	t := v.Type()
+
+	for i := range t.NumField() {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+
+		fv := v.Field(i)
+		path := prefix + sf.Name
+
+		if sf.Type.Kind() == reflect.Struct && !isLeaf(fv) {
+			out = append(out, fields(fv, path+".")...)
+
+			continue
+		}
+
+		out = append(out, field{path: path, value: fv, tag: sf.Tag})
+	}
+
+	return out
+}
+
+var (
+	durationType        = reflect.TypeFor[time.Duration]()
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+)
+
+// isLeaf reports whether a struct value is set as a single value rather than walked.
+func isLeaf(v reflect.Value) bool {
+	return v.Addr().Type().Implements(textUnmarshalerType)
+}
+
+// set parses s into the field.
+func (f field) set(s string) error {
+	v := f.value
+
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(s))
+	}
+
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+
+		v.SetInt(int64(d))
+
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		v.SetFloat(n)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported type %s", v.Type())
+		}
+
+		var values []string
+
+		for value := range strings.SplitSeq(s, ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				values = append(values, value)
+			}
+		}
+
+		v.Set(reflect.ValueOf(values).Convert(v.Type()))
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+
+	return nil
+}
+
+// isBool reports whether the field is a bool, bool flags do not require a value.
+func (f field) isBool() bool {
+	return f.value.Kind() == reflect.Bool
+}
+
+// errRequired is returned for a required field which is not set.
+var errRequired = errors.New("required")
+
+// A FieldError is an error setting or validating a configuration field.
+type FieldError struct {
+	// Field is the dotted path of the field, for example Database.Host.
+	Field string
+	// Source is where the value came from, for example env FOO, empty for validation errors.
+	Source string
+	Err    error
+}
+
+func (err FieldError) Error() string {
+	if err.Source != "" {
+		return fmt.Sprintf("%s (%s): %s", err.Field, err.Source, err.Err)
+	}
+
+	return fmt.Sprintf("%s: %s", err.Field, err.Err)
+}
+
+func (err FieldError) Unwrap() error {
+	return err.Err
+}
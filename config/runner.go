@@ -0,0 +1,53 @@
+package config
+
+import (
+	"context"
+
+	"go.krak3n.io/foundation"
+)
+
+// A Runner is a foundation.Runner which loads configuration of type T, failing startup with every
+// error found if it cannot be loaded or is invalid. The configuration is added to the value store, see
+// Get. Run it first so later runners can read the configuration.
+type Runner[T any] struct {
+	opts []Option
+	cfg  T
+}
+
+// Run returns a Runner which loads configuration of type T.
+//
+//	foundation.Run("api", foundation.RunFunc(func(ctx context.Context, f foundation.F) {
+//		f.Run(ctx, config.Run[Config](config.WithFile("config.json"), config.WithEnvPrefix("API_")))
+//
+//		cfg, _ := config.Get[Config](f)
+//	}))
+func Run[T any](opts ...Option) *Runner[T] {
+	return &Runner[T]{
+		opts: opts,
+	}
+}
+
+// Config returns the loaded configuration.
+func (r *Runner[T]) Config() T {
+	return r.cfg
+}
+
+// Run loads the configuration.
+func (r *Runner[T]) Run(ctx context.Context, f foundation.F) {
+	cfg, err := Load[T](r.opts...)
+	if err != nil {
+		f.Error(err)
+	}
+
+	r.cfg = cfg
+
+	f.Values().Store(configKey[T]{}, cfg)
+}
+
+// configKey is the value store key the configuration of type T is stored under.
+type configKey[T any] struct{}
+
+// Get returns the configuration of type T from the F's value store.
+func Get[T any](f foundation.F) (T, bool) {
+	return foundation.Value[T](f, configKey[T]{})
+}
@@ -0,0 +1,160 @@
+package config
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"os"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.krak3n.io/foundation"
+	"go.krak3n.io/foundation/metrics"
+	"go.krak3n.io/foundation/tick"
+)
+
+// WithPollInterval sets how often a Watcher checks its files for changes, defaults to 5 seconds.
+func WithPollInterval(d time.Duration) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.pollInterval = d
+	})
+}
+
+// A Watcher is a foundation.Runner which loads configuration of type T like Runner and then polls its
+// files, loading the configuration again when their contents change. Polling rather than file system
+// notifications copes with files swapped by symlink, as Kubernetes does when a ConfigMap is updated.
+//
+// New configuration is delivered to subscribers, see Subscribe, stored in the value store, see Get, and
+// the Reload hooks are called. Should the changed configuration fail to load or validate it is
+// discarded, keeping the last good configuration, until the files change again.
+type Watcher[T any] struct {
+	opts []Option
+	cfg  config
+
+	current atomic.Pointer[T]
+	hash    []byte
+
+	mtx         sync.Mutex
+	subscribers []func(T)
+
+	reloaded metrics.Counter
+	failed   metrics.Counter
+}
+
+// Watch returns a Watcher which loads configuration of type T and watches its files for changes.
+func Watch[T any](opts ...Option) *Watcher[T] {
+	return &Watcher[T]{
+		opts:     opts,
+		reloaded: metrics.NewCounter("config_reloads_total", metrics.Labels{"status": "ok"}),
+		failed:   metrics.NewCounter("config_reloads_total", metrics.Labels{"status": "failed"}),
+	}
+}
+
+// Config returns the current configuration.
+func (w *Watcher[T]) Config() T {
+	if v := w.current.Load(); v != nil {
+		return *v
+	}
+
+	var zero T
+
+	return zero
+}
+
+// Subscribe calls fn with the new configuration whenever it changes. Subscribers are called in the order
+// they subscribed, before the Reload hooks.
+func (w *Watcher[T]) Subscribe(fn func(T)) {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	w.subscribers = append(w.subscribers, fn)
+}
+
+// Run loads the configuration, failing startup if it cannot be loaded, then polls for changes until
+// stopped.
+func (w *Watcher[T]) Run(ctx context.Context, f foundation.F) {
+	w.cfg = newConfig(w.opts)
+
+	if w.cfg.pollInterval <= 0 {
+		w.cfg.pollInterval = 5 * time.Second
+	}
+
+	w.hash = w.checksum()
+
+	var v T
+
+	if err := w.cfg.load(&v); err != nil {
+		f.Error(err)
+	}
+
+	w.current.Store(&v)
+	f.Values().Store(configKey[T]{}, v)
+
+	tick.Run(ctx, f, w.cfg.pollInterval, func(ctx context.Context, _ tick.Ticker) {
+		w.check(ctx, f)
+	})
+}
+
+// check loads the configuration again if the files have changed.
+func (w *Watcher[T]) check(ctx context.Context, f foundation.F) {
+	hash := w.checksum()
+	if slices.Equal(hash, w.hash) {
+		return
+	}
+
+	// Record the hash regardless of the outcome so invalid configuration is only reported once.
+	w.hash = hash
+
+	var v T
+
+	if err := w.cfg.load(&v); err != nil {
+		w.failed.Add(1)
+		slog.ErrorContext(ctx, "config reload failed, keeping last good config", slog.String("err", err.Error()))
+
+		return
+	}
+
+	w.reloaded.Add(1)
+	slog.InfoContext(ctx, "config reloaded")
+
+	w.current.Store(&v)
+	f.Values().Store(configKey[T]{}, v)
+
+	w.mtx.Lock()
+	subscribers := slices.Clone(w.subscribers)
+	w.mtx.Unlock()
+
+	for fn := range slices.Values(subscribers) {
+		fn(v)
+	}
+
+	foundation.Reload(f)
+}
+
+// checksum returns a hash of the contents of the files, a missing file hashes differently to an empty
+// one so optional files appearing or disappearing are picked up.
+func (w *Watcher[T]) checksum() []byte {
+	h := sha256.New()
+
+	for file := range slices.Values(w.cfg.files) {
+		b, err := os.ReadFile(file.path)
+
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			h.Write([]byte{0})
+		case err != nil:
+			h.Write([]byte{1})
+		default:
+			h.Write([]byte{2})
+			h.Write(b)
+		}
+
+		h.Write([]byte(file.path))
+	}
+
+	return h.Sum(nil)
+}
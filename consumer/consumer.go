@@ -0,0 +1,51 @@
+// Package consumer provides a broker agnostic message processing pipeline. Broker adapters implement
+// Source and Message, user code implements Handler, and the Runner takes care of concurrency, retries,
+// dead lettering, metrics and draining in flight messages on stop.
+package consumer
+
+import (
+	"context"
+)
+
+// A Message is a message received from a broker.
+type Message interface {
+	// ID returns the broker assigned message id.
+	ID() string
+	// Body returns the message payload.
+	Body() []byte
+	// Metadata returns the message headers or attributes.
+	Metadata() map[string]string
+	// Ack acknowledges the message, it will not be redelivered.
+	Ack(ctx context.Context) error
+	// Nack negatively acknowledges the message, it will be redelivered.
+	Nack(ctx context.Context) error
+}
+
+// A Handler handles a message. Returning an error causes the message to be retried.
+type Handler interface {
+	Handle(ctx context.Context, msg Message) error
+}
+
+// HandlerFunc is an adapter to allow the use of ordinary functions as a Handler.
+type HandlerFunc func(ctx context.Context, msg Message) error
+
+// Handle calls f(ctx, msg).
+func (f HandlerFunc) Handle(ctx context.Context, msg Message) error {
+	return f(ctx, msg)
+}
+
+// A Middleware wraps a Handler, for example to add tracing or decode message bodies.
+type Middleware func(Handler) Handler
+
+// A Source receives messages from a broker, for example an SQS queue, AMQP channel or NATS subscription.
+type Source interface {
+	// Receive blocks until messages are available or the context is done.
+	Receive(ctx context.Context) ([]Message, error)
+	// Close closes the source once all received messages have been acked or nacked.
+	Close() error
+}
+
+// A DeadLetterFunc is called with a message which could not be handled once retries are exhausted,
+// typically publishing it to a dead letter queue. The message is acked if it returns nil and nacked
+// otherwise.
+type DeadLetterFunc func(ctx context.Context, msg Message, err error) error
@@ -0,0 +1,482 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log/slog"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.krak3n.io/foundation"
+	"go.krak3n.io/foundation/breaker"
+	"go.krak3n.io/foundation/health/probe"
+	"go.krak3n.io/foundation/metrics"
+	"go.krak3n.io/foundation/rate"
+	"go.krak3n.io/foundation/tick"
+)
+
+// A RunnerOption configures the consumer Runner.
+type RunnerOption interface {
+	applyRunnerConfig(*runnerConfig)
+}
+
+// RunnerOptions is one or more RunnerOption.
+type RunnerOptions []RunnerOption
+
+func (o RunnerOptions) applyRunnerConfig(cfg *runnerConfig) {
+	for opt := range slices.Values(o) {
+		if opt != nil {
+			opt.applyRunnerConfig(cfg)
+		}
+	}
+}
+
+type runnerConfigFunc func(*runnerConfig)
+
+func (f runnerConfigFunc) applyRunnerConfig(cfg *runnerConfig) {
+	f(cfg)
+}
+
+// runnerConfig holds the configuration for the consumer Runner.
+type runnerConfig struct {
+	concurrency     int
+	retries         uint8
+	backoff         tick.Backoff
+	deadLetter      DeadLetterFunc
+	middleware      []Middleware
+	shutdownTimeout time.Duration
+	sensorMode      probe.Mode
+	breaker         *breaker.Breaker
+	limiter         rate.Limiter
+	queueSize       int
+	ordering        func(Message) string
+	sensorName      string
+}
+
+// WithConcurrency sets the number of messages handled concurrently, defaults to 1.
+func WithConcurrency(n int) RunnerOption {
+	return runnerConfigFunc(func(cfg *runnerConfig) {
+		cfg.concurrency = n
+	})
+}
+
+// WithRetries sets the number of times a message is retried when the handler returns an error, defaults
+// to 3.
+func WithRetries(n uint8) RunnerOption {
+	return runnerConfigFunc(func(cfg *runnerConfig) {
+		cfg.retries = n
+	})
+}
+
+// WithBackoff sets the backoff used between handler retries and receive attempts on error, defaults to
+// an exponential backoff with a scalar of 100ms and 20% jitter.
+func WithBackoff(backoff tick.Backoff) RunnerOption {
+	return runnerConfigFunc(func(cfg *runnerConfig) {
+		cfg.backoff = backoff
+	})
+}
+
+// WithDeadLetter sets the function called with messages which could not be handled once retries are
+// exhausted. Without a dead letter function such messages are nacked.
+func WithDeadLetter(fn DeadLetterFunc) RunnerOption {
+	return runnerConfigFunc(func(cfg *runnerConfig) {
+		cfg.deadLetter = fn
+	})
+}
+
+// WithMiddleware wraps the handler in the given middleware, the first middleware is the outermost.
+func WithMiddleware(mw ...Middleware) RunnerOption {
+	return runnerConfigFunc(func(cfg *runnerConfig) {
+		cfg.middleware = append(cfg.middleware, mw...)
+	})
+}
+
+// WithShutdownTimeout sets how long to wait on stop for in flight messages to be handled before the
+// handler context is cancelled, defaults to 30 seconds.
+func WithShutdownTimeout(d time.Duration) RunnerOption {
+	return runnerConfigFunc(func(cfg *runnerConfig) {
+		cfg.shutdownTimeout = d
+	})
+}
+
+// WithSensorMode sets the mode of the consumers sensor, defaults to probe.ReadinessMode.
+func WithSensorMode(mode probe.Mode) RunnerOption {
+	return runnerConfigFunc(func(cfg *runnerConfig) {
+		cfg.sensorMode = mode
+	})
+}
+
+// WithSensorName sets the name of the consumers sensor, defaults to consumer[name] where name is the
+// name given to Run.
+func WithSensorName(name string) RunnerOption {
+	return runnerConfigFunc(func(cfg *runnerConfig) {
+		cfg.sensorName = name
+	})
+}
+
+// WithQueueSize sets how many received messages can be queued per worker before receiving blocks,
+// defaults to 1.
+func WithQueueSize(n int) RunnerOption {
+	return runnerConfigFunc(func(cfg *runnerConfig) {
+		cfg.queueSize = n
+	})
+}
+
+// WithOrdering handles messages with the same key in the order they were received, each key is always
+// handled by the same worker, for example keyed by the partition of a Kafka record. Messages with
+// different keys are handled concurrently, see WithConcurrency.
+func WithOrdering(key func(Message) string) RunnerOption {
+	return runnerConfigFunc(func(cfg *runnerConfig) {
+		cfg.ordering = key
+	})
+}
+
+// WithBreaker guards the handler with the circuit breaker, messages are counted as a single call once
+// retries are exhausted. Whilst the breaker is open messages are nacked without calling the handler, so
+// they are redelivered rather than dead lettered whilst a dependency is unhealthy.
+func WithBreaker(b *breaker.Breaker) RunnerOption {
+	return runnerConfigFunc(func(cfg *runnerConfig) {
+		cfg.breaker = b
+	})
+}
+
+// WithRateLimit caps the rate messages are handled at with the limiter, waiting for it before each
+// message is handled. Retries are not limited.
+func WithRateLimit(l rate.Limiter) RunnerOption {
+	return runnerConfigFunc(func(cfg *runnerConfig) {
+		cfg.limiter = l
+	})
+}
+
+// A Runner is a foundation.Runner which receives messages from a Source and handles them.
+type Runner struct {
+	name    string
+	source  Source
+	handler Handler
+	opts    []RunnerOption
+
+	err      atomic.Pointer[error]
+	acked    metrics.Counter
+	nacked   metrics.Counter
+	dead     metrics.Counter
+	retried  metrics.Counter
+	inflight metrics.Gauge
+	duration metrics.Histogram
+}
+
+// Run returns a Runner named name, used to label metrics and the sensor, which receives messages from
+// the source calling the handler for each.
+func Run(name string, source Source, handler Handler, opts ...RunnerOption) *Runner {
+	labels := func(status string) metrics.Labels {
+		return metrics.Labels{"consumer": name, "status": status}
+	}
+
+	return &Runner{
+		name:     name,
+		source:   source,
+		handler:  handler,
+		opts:     opts,
+		acked:    metrics.NewCounter("consumer_messages_total", labels("acked")),
+		nacked:   metrics.NewCounter("consumer_messages_total", labels("nacked")),
+		dead:     metrics.NewCounter("consumer_messages_total", labels("dead_lettered")),
+		retried:  metrics.NewCounter("consumer_retries_total", metrics.Labels{"consumer": name}),
+		inflight: metrics.NewGauge("consumer_inflight_messages", metrics.Labels{"consumer": name}),
+		duration: metrics.NewHistogram("consumer_handle_duration_seconds", metrics.Labels{"consumer": name}),
+	}
+}
+
+// Run receives messages until told to stop. On stop receiving ends, in flight messages are handled and
+// the source closed before the runner exits.
+func (r *Runner) Run(ctx context.Context, f foundation.F) {
+	cfg := runnerConfig{
+		concurrency:     1,
+		retries:         3,
+		backoff:         tick.ExponentialBackoff(100*time.Millisecond, tick.WithJitter(0.2)),
+		shutdownTimeout: 30 * time.Second,
+		sensorMode:      probe.ReadinessMode,
+		queueSize:       1,
+		sensorName:      fmt.Sprintf("consumer[%s]", r.name),
+	}
+
+	RunnerOptions(r.opts).applyRunnerConfig(&cfg)
+
+	cfg.concurrency = max(cfg.concurrency, 1)
+	cfg.queueSize = max(cfg.queueSize, 1)
+
+	handler := r.handler
+
+	for _, mw := range slices.Backward(cfg.middleware) {
+		handler = mw(handler)
+	}
+
+	probe.RegisterContext(ctx, probe.NewSensor(cfg.sensorName, cfg.sensorMode, func(context.Context) error {
+		if err := r.err.Load(); err != nil {
+			return *err
+		}
+
+		return nil
+	}))
+
+	queues := newQueues(cfg)
+
+	// Handlers get their own context which is only cancelled if the shutdown timeout is exceeded.
+	handlerCtx, cancelHandlers := context.WithCancel(context.WithoutCancel(ctx))
+	receiveCtx, cancelReceive := context.WithCancel(ctx)
+	receiving := make(chan struct{})
+
+	var wg sync.WaitGroup
+
+	for i := range cfg.concurrency {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for msg := range queues.workers[i] {
+				r.process(handlerCtx, cfg, handler, msg)
+			}
+		}()
+	}
+
+	f.On().Stop(func() {
+		defer cancelHandlers()
+
+		cancelReceive()
+		<-receiving
+
+		queues.close()
+
+		done := make(chan struct{})
+
+		go func() {
+			wg.Wait()
+			close(done)
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(cfg.shutdownTimeout):
+			slog.Warn("consumer shutdown timeout exceeded, cancelling handlers", slog.String("consumer", r.name))
+			cancelHandlers()
+
+			<-done
+		}
+
+		if err := r.source.Close(); err != nil {
+			f.Error(fmt.Errorf("close source: %w", err))
+		}
+	})
+
+	f.Parallel() // Mark the Runner as parallel now we are going start blocking
+
+	defer close(receiving)
+
+	r.receive(receiveCtx, cfg, queues)
+}
+
+// queues are the queues of received messages handled by each worker.
+type queues struct {
+	workers  []chan Message
+	ordering func(Message) string
+}
+
+// newQueues returns the worker queues, a queue per worker if ordered otherwise a queue shared by every
+// worker.
+func newQueues(cfg runnerConfig) *queues {
+	q := &queues{
+		workers:  make([]chan Message, cfg.concurrency),
+		ordering: cfg.ordering,
+	}
+
+	if q.ordering == nil {
+		shared := make(chan Message, cfg.concurrency*cfg.queueSize)
+
+		for i := range q.workers {
+			q.workers[i] = shared
+		}
+
+		return q
+	}
+
+	for i := range q.workers {
+		q.workers[i] = make(chan Message, cfg.queueSize)
+	}
+
+	return q
+}
+
+// queue returns the queue of the worker to handle the message.
+func (q *queues) queue(msg Message) chan<- Message {
+	if q.ordering == nil {
+		return q.workers[0]
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(q.ordering(msg)))
+
+	return q.workers[h.Sum32()%uint32(len(q.workers))]
+}
+
+// close closes the queues once receiving has ended.
+func (q *queues) close() {
+	if q.ordering == nil {
+		close(q.workers[0])
+
+		return
+	}
+
+	for queue := range slices.Values(q.workers) {
+		close(queue)
+	}
+}
+
+// receive receives messages from the source until the context is done, retrying with backoff on error.
+func (r *Runner) receive(ctx context.Context, cfg runnerConfig, queues *queues) {
+	var attempt uint8
+
+	for {
+		batch, err := r.source.Receive(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+
+		if err != nil {
+			r.err.Store(&err)
+			attempt = min(attempt+1, 10)
+
+			slog.WarnContext(ctx, "consumer receive failed", slog.String("consumer", r.name), slog.String("err", err.Error()))
+
+			if err := sleep(ctx, cfg.backoff.Wait(ctx, attempt)); err != nil {
+				return
+			}
+
+			continue
+		}
+
+		r.err.Store(nil)
+		attempt = 0
+
+		for _, msg := range batch {
+			select {
+			case queues.queue(msg) <- msg:
+			case <-ctx.Done():
+				// Messages received but not handled are nacked so they are redelivered promptly.
+				r.settle(context.WithoutCancel(ctx), msg, msg.Nack, r.nacked)
+			}
+		}
+	}
+}
+
+// process handles the message with retries, acking it on success and otherwise dead lettering or
+// nacking it.
+func (r *Runner) process(ctx context.Context, cfg runnerConfig, handler Handler, msg Message) {
+	r.inflight.Add(1)
+	defer r.inflight.Add(-1)
+
+	if cfg.limiter != nil {
+		if err := cfg.limiter.Wait(ctx); err != nil {
+			r.settle(context.WithoutCancel(ctx), msg, msg.Nack, r.nacked)
+
+			return
+		}
+	}
+
+	if cfg.breaker != nil {
+		if err := cfg.breaker.Allow(); err != nil {
+			r.settle(ctx, msg, msg.Nack, r.nacked)
+
+			return
+		}
+	}
+
+	start := time.Now()
+	err := r.handle(ctx, cfg, handler, msg)
+	r.duration.Observe(time.Since(start).Seconds())
+
+	if cfg.breaker != nil {
+		cfg.breaker.Done(err)
+	}
+
+	if err == nil {
+		r.settle(ctx, msg, msg.Ack, r.acked)
+
+		return
+	}
+
+	log := slog.With(slog.String("consumer", r.name), slog.String("message", msg.ID()), slog.String("err", err.Error()))
+
+	if cfg.deadLetter == nil {
+		log.ErrorContext(ctx, "consumer handler failed, nacking message")
+		r.settle(ctx, msg, msg.Nack, r.nacked)
+
+		return
+	}
+
+	if dlErr := cfg.deadLetter(ctx, msg, err); dlErr != nil {
+		log.ErrorContext(ctx, "failed to dead letter message, nacking message", slog.String("dead_letter_err", dlErr.Error()))
+		r.settle(ctx, msg, msg.Nack, r.nacked)
+
+		return
+	}
+
+	log.WarnContext(ctx, "consumer handler failed, message dead lettered")
+	r.settle(ctx, msg, msg.Ack, r.dead)
+}
+
+// handle calls the handler retrying with backoff on error.
+func (r *Runner) handle(ctx context.Context, cfg runnerConfig, handler Handler, msg Message) error {
+	var attempt uint8
+
+	for {
+		err := call(ctx, handler, msg)
+		if err == nil || attempt >= cfg.retries {
+			return err
+		}
+
+		attempt++
+		r.retried.Add(1)
+
+		if sleepErr := sleep(ctx, cfg.backoff.Wait(ctx, attempt)); sleepErr != nil {
+			return errors.Join(err, sleepErr)
+		}
+	}
+}
+
+// settle acks or nacks the message incrementing the counter on success.
+func (r *Runner) settle(ctx context.Context, msg Message, fn func(context.Context) error, counter metrics.Counter) {
+	if err := fn(ctx); err != nil {
+		slog.ErrorContext(ctx, "failed to settle message", slog.String("consumer", r.name), slog.String("message", msg.ID()), slog.String("err", err.Error()))
+
+		return
+	}
+
+	counter.Add(1)
+}
+
+// call calls the handler converting a panic into an error.
+func call(ctx context.Context, handler Handler, msg Message) (err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = foundation.PanicError{Cause: rec}
+		}
+	}()
+
+	return handler.Handle(ctx, msg)
+}
+
+// sleep waits for the given duration or until the context is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,177 @@
+package foundation
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"runtime/pprof"
+	"slices"
+	"sync"
+	"time"
+)
+
+// defaultCrashDumpEvents is the number of lifecycle events a crash dump records if not configured.
+const defaultCrashDumpEvents = 100
+
+// WithCrashDump writes a crash dump file to the directory when an error stops the foundation, for post
+// mortem context stderr logs lack. The dump is JSON holding the error, the stacks of every go routine,
+// the runner tree, the last events lifecycle events and the build info, and is written before the
+// runners are stopped so captures the state they failed in. If events is 0 the last 100 events are
+// recorded.
+func WithCrashDump(dir string, events int) RunOption {
+	return runConfigFunc(func(cfg *runConfig) {
+		if events <= 0 {
+			events = defaultCrashDumpEvents
+		}
+
+		cfg.crashDump = &crashDump{
+			dir:    dir,
+			events: make([]Event, 0, events),
+			size:   events,
+		}
+	})
+}
+
+// crashDump records lifecycle events and writes crash dump files, see WithCrashDump.
+type crashDump struct {
+	dir string
+
+	mtx    sync.Mutex
+	events []Event
+	size   int
+	next   int
+}
+
+// record is an Interceptor recording the last events.
+func (c *crashDump) record(e Event) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if len(c.events) < c.size {
+		c.events = append(c.events, e)
+
+		return
+	}
+
+	c.events[c.next] = e
+	c.next = (c.next + 1) % c.size
+}
+
+// recorded returns the recorded events, oldest first.
+func (c *crashDump) recorded() []Event {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	events := make([]Event, 0, len(c.events))
+	events = append(events, c.events[c.next:]...)
+	events = append(events, c.events[:c.next]...)
+
+	return events
+}
+
+// crashFile is the content of a crash dump file.
+type crashFile struct {
+	Service    string           `json:"service"`
+	Time       time.Time        `json:"time"`
+	PID        int              `json:"pid"`
+	Error      string           `json:"error"`
+	Build      *crashBuild      `json:"build,omitempty"`
+	Tree       Node             `json:"tree"`
+	Events     []crashFileEvent `json:"events"`
+	Goroutines string           `json:"goroutines"`
+}
+
+// crashBuild is the build info of a crash dump.
+type crashBuild struct {
+	Path      string            `json:"path"`
+	Version   string            `json:"version"`
+	GoVersion string            `json:"go_version"`
+	Settings  map[string]string `json:"settings,omitempty"`
+}
+
+// crashFileEvent is a lifecycle event of a crash dump.
+type crashFileEvent struct {
+	Kind   string    `json:"kind"`
+	Runner string    `json:"runner"`
+	Time   time.Time `json:"time"`
+	Err    string    `json:"err,omitempty"`
+}
+
+// write writes a crash dump file for the error which stopped the foundation, logging where it was
+// written or why it could not be.
+func (c *crashDump) write(service string, f *f, cause error) {
+	path, err := c.dump(service, f, cause, time.Now())
+	if err != nil {
+		slog.Error("failed to write crash dump", slog.String("err", err.Error()))
+
+		return
+	}
+
+	slog.Error("wrote crash dump", slog.String("path", path))
+}
+
+// dump writes the crash dump file returning its path.
+func (c *crashDump) dump(service string, f *f, cause error, now time.Time) (string, error) {
+	var stacks bytes.Buffer
+
+	if err := pprof.Lookup("goroutine").WriteTo(&stacks, 2); err != nil {
+		return "", fmt.Errorf("write go routine stacks: %w", err)
+	}
+
+	file := crashFile{
+		Service:    service,
+		Time:       now,
+		PID:        os.Getpid(),
+		Error:      cause.Error(),
+		Tree:       Tree(f),
+		Goroutines: stacks.String(),
+	}
+
+	if info, ok := debug.ReadBuildInfo(); ok {
+		file.Build = &crashBuild{
+			Path:      info.Main.Path,
+			Version:   info.Main.Version,
+			GoVersion: info.GoVersion,
+			Settings:  make(map[string]string),
+		}
+
+		for s := range slices.Values(info.Settings) {
+			file.Build.Settings[s.Key] = s.Value
+		}
+	}
+
+	for e := range slices.Values(c.recorded()) {
+		event := crashFileEvent{
+			Kind:   e.Kind.String(),
+			Runner: e.Runner,
+			Time:   e.Time,
+		}
+
+		if e.Err != nil {
+			event.Err = e.Err.Error()
+		}
+
+		file.Events = append(file.Events, event)
+	}
+
+	b, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("marshal crash dump: %w", err)
+	}
+
+	if err := os.MkdirAll(c.dir, 0o755); err != nil {
+		return "", fmt.Errorf("create crash dump directory: %w", err)
+	}
+
+	path := filepath.Join(c.dir, fmt.Sprintf("%s-crash-%s-%d.json", service, now.UTC().Format("20060102T150405Z"), file.PID))
+
+	if err := os.WriteFile(path, b, 0o600); err != nil {
+		return "", fmt.Errorf("write crash dump: %w", err)
+	}
+
+	return path, nil
+}
@@ -0,0 +1,254 @@
+// Package db manages the lifecycle of a database/sql connection pool.
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"go.krak3n.io/foundation"
+	"go.krak3n.io/foundation/health/probe"
+	"go.krak3n.io/foundation/tick"
+)
+
+// An Option configures the database Runner.
+type Option interface {
+	apply(*config)
+}
+
+// Options is one or more Option.
+type Options []Option
+
+func (opts Options) apply(cfg *config) {
+	for opt := range slices.Values(opts) {
+		if opt != nil {
+			opt.apply(cfg)
+		}
+	}
+}
+
+type optionFunc func(*config)
+
+func (f optionFunc) apply(cfg *config) {
+	f(cfg)
+}
+
+// config holds the database Runner configuration.
+type config struct {
+	name            string
+	maxOpenConns    int
+	maxIdleConns    int
+	connMaxLifetime time.Duration
+	connMaxIdleTime time.Duration
+	pingAttempts    uint8
+	pingTimeout     time.Duration
+	backoff         tick.Backoff
+	sensorMode      probe.Mode
+}
+
+// WithName sets the name of the pool, used to name the sensor and as the value store key, see DB.
+// Defaults to the driver name, or "sql" when constructed with New.
+func WithName(name string) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.name = name
+	})
+}
+
+// WithMaxOpenConns sets the maximum number of open connections, see sql.DB.SetMaxOpenConns.
+func WithMaxOpenConns(n int) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.maxOpenConns = n
+	})
+}
+
+// WithMaxIdleConns sets the maximum number of idle connections, see sql.DB.SetMaxIdleConns.
+func WithMaxIdleConns(n int) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.maxIdleConns = n
+	})
+}
+
+// WithConnMaxLifetime sets the maximum time a connection may be reused, see sql.DB.SetConnMaxLifetime.
+func WithConnMaxLifetime(d time.Duration) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.connMaxLifetime = d
+	})
+}
+
+// WithConnMaxIdleTime sets the maximum time a connection may be idle, see sql.DB.SetConnMaxIdleTime.
+func WithConnMaxIdleTime(d time.Duration) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.connMaxIdleTime = d
+	})
+}
+
+// WithPing sets the number of attempts made to ping the database at startup and the backoff between
+// attempts, defaults to 5 attempts with an exponential backoff with a scalar of 100ms and 20% jitter.
+func WithPing(attempts uint8, backoff tick.Backoff) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.pingAttempts = attempts
+		cfg.backoff = backoff
+	})
+}
+
+// WithPingTimeout sets the timeout of each ping, defaults to 5 seconds.
+func WithPingTimeout(d time.Duration) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.pingTimeout = d
+	})
+}
+
+// WithSensorMode sets the mode of the databases sensor, defaults to probe.ReadinessMode.
+func WithSensorMode(mode probe.Mode) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.sensorMode = mode
+	})
+}
+
+// A Runner is a foundation.Runner which opens a database connection pool and verifies connectivity. The
+// runner returns once the database is reachable, runners started after it can rely on the pool being
+// usable. The pool is closed on stop, because runners are stopped newest first this is after the
+// runners started after it, which may be using the pool, have stopped.
+type Runner struct {
+	driver string
+	dsn    string
+	opts   []Option
+	mtx    sync.RWMutex
+	db     *sql.DB
+}
+
+// Run returns a Runner which opens a connection pool for the driver and data source name.
+func Run(driver, dsn string, opts ...Option) *Runner {
+	return &Runner{
+		driver: driver,
+		dsn:    dsn,
+		opts:   opts,
+	}
+}
+
+// New returns a Runner which manages an already opened connection pool.
+func New(db *sql.DB, opts ...Option) *Runner {
+	return &Runner{
+		db:   db,
+		opts: opts,
+	}
+}
+
+// DB returns the connection pool, nil until the runner has opened it which is guaranteed once F.Run has
+// returned for the Runner.
+func (r *Runner) DB() *sql.DB {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	return r.db
+}
+
+// Run opens the pool, pings the database until it is reachable and publishes the pool to the value
+// store. The runner errors if the database cannot be reached.
+func (r *Runner) Run(ctx context.Context, f foundation.F) {
+	cfg := config{
+		name:         r.driver,
+		pingAttempts: 5,
+		pingTimeout:  5 * time.Second,
+		backoff:      tick.ExponentialBackoff(100*time.Millisecond, tick.WithJitter(0.2)),
+		sensorMode:   probe.ReadinessMode,
+	}
+
+	if cfg.name == "" {
+		cfg.name = "sql"
+	}
+
+	Options(r.opts).apply(&cfg)
+
+	db := r.DB()
+
+	if db == nil {
+		var err error
+
+		if db, err = sql.Open(r.driver, r.dsn); err != nil {
+			f.Error(fmt.Errorf("open %s database: %w", cfg.name, err))
+		}
+	}
+
+	if cfg.maxOpenConns > 0 {
+		db.SetMaxOpenConns(cfg.maxOpenConns)
+	}
+
+	if cfg.maxIdleConns > 0 {
+		db.SetMaxIdleConns(cfg.maxIdleConns)
+	}
+
+	if cfg.connMaxLifetime > 0 {
+		db.SetConnMaxLifetime(cfg.connMaxLifetime)
+	}
+
+	if cfg.connMaxIdleTime > 0 {
+		db.SetConnMaxIdleTime(cfg.connMaxIdleTime)
+	}
+
+	f.On().Stop(func() {
+		if err := db.Close(); err != nil {
+			slog.Warn("failed to close database", slog.String("name", cfg.name), slog.String("err", err.Error()))
+		}
+	})
+
+	if err := ping(ctx, db, cfg); err != nil {
+		f.Error(fmt.Errorf("ping %s database: %w", cfg.name, err))
+	}
+
+	r.mtx.Lock()
+	r.db = db
+	r.mtx.Unlock()
+
+	f.Values().Store(dbKey(cfg.name), db)
+
+	probe.RegisterContext(ctx, probe.NewSensor(fmt.Sprintf("db[%s]", cfg.name), cfg.sensorMode, func(ctx context.Context) error {
+		ctx, cancel := context.WithTimeout(ctx, cfg.pingTimeout)
+		defer cancel()
+
+		return db.PingContext(ctx)
+	}))
+}
+
+// ping pings the database retrying with backoff until it succeeds or the attempts are exhausted.
+func ping(ctx context.Context, db *sql.DB, cfg config) error {
+	var err error
+
+	for attempt := range max(cfg.pingAttempts, 1) {
+		if attempt > 0 {
+			slog.WarnContext(ctx, "database unreachable, retrying", slog.String("name", cfg.name), slog.String("err", err.Error()))
+
+			timer := time.NewTimer(cfg.backoff.Wait(ctx, attempt))
+
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			case <-timer.C:
+			}
+		}
+
+		pingCtx, cancel := context.WithTimeout(ctx, cfg.pingTimeout)
+		err = db.PingContext(pingCtx)
+		cancel()
+
+		if err == nil {
+			return nil
+		}
+	}
+
+	return err
+}
+
+// dbKey is the value store key a connection pool is stored under, keyed by the pools name.
+type dbKey string
+
+// DB returns the connection pool with the given name from the F's value store, for example
+// DB(f, "postgres").
+func DB(f foundation.F, name string) (*sql.DB, bool) {
+	return foundation.Value[*sql.DB](f, dbKey(name))
+}
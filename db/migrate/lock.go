@@ -0,0 +1,72 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+)
+
+// A Locker serialises migrations across replicas.
+type Locker interface {
+	// Lock blocks until the lock is acquired or the context is done.
+	Lock(ctx context.Context) error
+	// Unlock releases the lock.
+	Unlock(ctx context.Context) error
+}
+
+// PostgresLock returns a Locker using a PostgreSQL session level advisory lock with the given key. The
+// lock is held on a dedicated connection so it is released if the process dies.
+func PostgresLock(db *sql.DB, key int64) Locker {
+	return &postgresLock{
+		db:  db,
+		key: key,
+	}
+}
+
+type postgresLock struct {
+	db   *sql.DB
+	key  int64
+	mtx  sync.Mutex
+	conn *sql.Conn
+}
+
+func (l *postgresLock) Lock(ctx context.Context) error {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	conn, err := l.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("get connection: %w", err)
+	}
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", l.key); err != nil {
+		conn.Close()
+
+		return fmt.Errorf("acquire advisory lock: %w", err)
+	}
+
+	l.conn = conn
+
+	return nil
+}
+
+func (l *postgresLock) Unlock(ctx context.Context) error {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	if l.conn == nil {
+		return nil
+	}
+
+	defer func() {
+		l.conn.Close()
+		l.conn = nil
+	}()
+
+	if _, err := l.conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", l.key); err != nil {
+		return fmt.Errorf("release advisory lock: %w", err)
+	}
+
+	return nil
+}
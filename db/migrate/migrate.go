@@ -0,0 +1,206 @@
+// Package migrate applies SQL migrations at startup.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"go.krak3n.io/foundation"
+)
+
+// An Option configures the migration Runner.
+type Option interface {
+	apply(*config)
+}
+
+// Options is one or more Option.
+type Options []Option
+
+func (opts Options) apply(cfg *config) {
+	for opt := range slices.Values(opts) {
+		if opt != nil {
+			opt.apply(cfg)
+		}
+	}
+}
+
+type optionFunc func(*config)
+
+func (f optionFunc) apply(cfg *config) {
+	f(cfg)
+}
+
+// config holds the migration Runner configuration.
+type config struct {
+	table  string
+	locker Locker
+	dryRun bool
+}
+
+// WithTable sets the table applied migration versions are recorded in, defaults to "schema_migrations".
+func WithTable(table string) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.table = table
+	})
+}
+
+// WithLocker holds the lock whilst migrating so replicas starting at the same time do not apply
+// migrations concurrently, see PostgresLock.
+func WithLocker(locker Locker) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.locker = locker
+	})
+}
+
+// WithDryRun logs the migrations which would be applied without applying them.
+func WithDryRun() Option {
+	return optionFunc(func(cfg *config) {
+		cfg.dryRun = true
+	})
+}
+
+// A Runner is a foundation.Runner which applies pending migrations and returns, so runners started after
+// it only start once the schema is up to date. A failed migration halts startup.
+type Runner struct {
+	db     *sql.DB
+	source Source
+	opts   []Option
+}
+
+// Run returns a Runner which applies migrations from the source to the database.
+func Run(db *sql.DB, source Source, opts ...Option) *Runner {
+	return &Runner{
+		db:     db,
+		source: source,
+		opts:   opts,
+	}
+}
+
+// Run applies pending migrations, each in its own transaction, in version order.
+func (r *Runner) Run(ctx context.Context, f foundation.F) {
+	cfg := config{
+		table: "schema_migrations",
+	}
+
+	Options(r.opts).apply(&cfg)
+
+	migrations, err := r.source.Migrations()
+	if err != nil {
+		f.Error(fmt.Errorf("load migrations: %w", err))
+	}
+
+	if cfg.locker != nil {
+		if err := cfg.locker.Lock(ctx); err != nil {
+			f.Error(fmt.Errorf("acquire migration lock: %w", err))
+		}
+
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+			defer cancel()
+
+			if err := cfg.locker.Unlock(ctx); err != nil {
+				slog.Warn("failed to release migration lock", slog.String("err", err.Error()))
+			}
+		}()
+	}
+
+	applied, err := r.applied(ctx, cfg.table)
+	if err != nil {
+		f.Error(err)
+	}
+
+	var pending []Migration
+
+	for _, m := range migrations {
+		if _, ok := applied[m.Version]; !ok {
+			pending = append(pending, m)
+		}
+	}
+
+	if len(pending) == 0 {
+		slog.InfoContext(ctx, "database schema up to date")
+
+		return
+	}
+
+	for i, m := range pending {
+		log := slog.With(slog.Int64("version", m.Version), slog.String("name", m.Name))
+
+		if cfg.dryRun {
+			log.InfoContext(ctx, "pending migration (dry run)")
+
+			continue
+		}
+
+		start := time.Now()
+
+		if err := r.apply(ctx, cfg.table, m); err != nil {
+			f.Error(fmt.Errorf("migration %d_%s failed, %d of %d pending migrations not applied: %w",
+				m.Version, m.Name, len(pending)-i, len(pending), err))
+		}
+
+		log.InfoContext(ctx, "applied migration", slog.Duration("duration", time.Since(start)))
+	}
+}
+
+// applied creates the migrations table if required and returns the applied versions.
+func (r *Runner) applied(ctx context.Context, table string) (map[int64]struct{}, error) {
+	query := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (version BIGINT PRIMARY KEY, applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP)", table)
+
+	if _, err := r.db.ExecContext(ctx, query); err != nil {
+		return nil, fmt.Errorf("create migrations table: %w", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx, fmt.Sprintf("SELECT version FROM %s", table))
+	if err != nil {
+		return nil, fmt.Errorf("query applied migrations: %w", err)
+	}
+
+	defer rows.Close()
+
+	applied := make(map[int64]struct{})
+
+	for rows.Next() {
+		var v int64
+
+		if err := rows.Scan(&v); err != nil {
+			return nil, fmt.Errorf("scan applied migration: %w", err)
+		}
+
+		applied[v] = struct{}{}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("read applied migrations: %w", err)
+	}
+
+	return applied, nil
+}
+
+// apply applies the migration and records its version in a single transaction.
+func (r *Runner) apply(ctx context.Context, table string, m Migration) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+
+	defer tx.Rollback() // Rollback is a no-op once committed.
+
+	if _, err := tx.ExecContext(ctx, m.SQL); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf("INSERT INTO %s (version) VALUES (%d)", table, m.Version)); err != nil {
+		return fmt.Errorf("record version: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+
+	return nil
+}
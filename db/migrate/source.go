@@ -0,0 +1,92 @@
+package migrate
+
+import (
+	"cmp"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// A Migration is a versioned SQL script.
+type Migration struct {
+	Version int64
+	Name    string
+	SQL     string
+}
+
+// A Source provides migrations.
+type Source interface {
+	Migrations() ([]Migration, error)
+}
+
+// SourceFunc is an adapter to allow the use of ordinary functions as a Source.
+type SourceFunc func() ([]Migration, error)
+
+// Migrations calls f().
+func (f SourceFunc) Migrations() ([]Migration, error) {
+	return f()
+}
+
+// FS returns a Source which reads migrations from the root of fsys, typically an embed.FS. Migration
+// files are named <version>_<name>.sql or <version>_<name>.up.sql, for example 0001_create_users.sql.
+// Files ending in .down.sql and files not ending in .sql are ignored.
+func FS(fsys fs.FS) Source {
+	return SourceFunc(func() ([]Migration, error) {
+		entries, err := fs.ReadDir(fsys, ".")
+		if err != nil {
+			return nil, fmt.Errorf("read migrations: %w", err)
+		}
+
+		var migrations []Migration
+
+		for _, entry := range entries {
+			name := entry.Name()
+
+			if entry.IsDir() || path.Ext(name) != ".sql" || strings.HasSuffix(name, ".down.sql") {
+				continue
+			}
+
+			version, desc, ok := strings.Cut(strings.TrimSuffix(strings.TrimSuffix(name, ".sql"), ".up"), "_")
+			if !ok {
+				return nil, fmt.Errorf("migration %s: name must be <version>_<name>.sql", name)
+			}
+
+			v, err := strconv.ParseInt(version, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("migration %s: invalid version: %w", name, err)
+			}
+
+			b, err := fs.ReadFile(fsys, name)
+			if err != nil {
+				return nil, fmt.Errorf("migration %s: %w", name, err)
+			}
+
+			migrations = append(migrations, Migration{
+				Version: v,
+				Name:    desc,
+				SQL:     string(b),
+			})
+		}
+
+		slices.SortFunc(migrations, func(a, b Migration) int {
+			return cmp.Compare(a.Version, b.Version)
+		})
+
+		for i := 1; i < len(migrations); i++ {
+			if migrations[i].Version == migrations[i-1].Version {
+				return nil, fmt.Errorf("duplicate migration version %d", migrations[i].Version)
+			}
+		}
+
+		return migrations, nil
+	})
+}
+
+// Dir returns a Source which reads migrations from a directory, see FS.
+func Dir(dir string) Source {
+	return FS(os.DirFS(dir))
+}
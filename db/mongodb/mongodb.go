@@ -0,0 +1,218 @@
+// Package mongodb manages the lifecycle of a MongoDB client.
+package mongodb
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"go.krak3n.io/foundation"
+	"go.krak3n.io/foundation/health/probe"
+	"go.krak3n.io/foundation/tick"
+)
+
+// A Client is a MongoDB client. Foundation does not depend on the MongoDB driver, instead a Client is
+// a thin adapter over *mongo.Client from go.mongodb.org/mongo-driver which selects a read preference:
+//
+//	type client struct{ *mongo.Client }
+//
+//	func (c client) Ping(ctx context.Context) error {
+//		return c.Client.Ping(ctx, readpref.Primary())
+//	}
+type Client interface {
+	// Ping verifies the client can reach a server.
+	Ping(ctx context.Context) error
+	// Disconnect closes the clients connections.
+	Disconnect(ctx context.Context) error
+}
+
+// A ConnectFunc connects a Client, the context carries the server selection timeout.
+type ConnectFunc[C Client] func(ctx context.Context) (C, error)
+
+// An Option configures the MongoDB Runner.
+type Option interface {
+	apply(*config)
+}
+
+// Options is one or more Option.
+type Options []Option
+
+func (opts Options) apply(cfg *config) {
+	for opt := range slices.Values(opts) {
+		if opt != nil {
+			opt.apply(cfg)
+		}
+	}
+}
+
+type optionFunc func(*config)
+
+func (f optionFunc) apply(cfg *config) {
+	f(cfg)
+}
+
+// config holds the MongoDB Runner configuration.
+type config struct {
+	name                   string
+	serverSelectionTimeout time.Duration
+	disconnectTimeout      time.Duration
+	pingAttempts           uint8
+	backoff                tick.Backoff
+	sensorMode             probe.Mode
+}
+
+// WithName sets the name of the client, used to name the sensor and as the value store key, see Get.
+// Defaults to "mongodb".
+func WithName(name string) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.name = name
+	})
+}
+
+// WithServerSelectionTimeout bounds connecting and each ping, defaults to 5 seconds.
+func WithServerSelectionTimeout(d time.Duration) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.serverSelectionTimeout = d
+	})
+}
+
+// WithDisconnectTimeout bounds disconnecting on stop, defaults to 10 seconds.
+func WithDisconnectTimeout(d time.Duration) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.disconnectTimeout = d
+	})
+}
+
+// WithPing sets the number of attempts made to ping the server at startup and the backoff between
+// attempts, defaults to 5 attempts with an exponential backoff with a scalar of 100ms and 20% jitter.
+func WithPing(attempts uint8, backoff tick.Backoff) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.pingAttempts = attempts
+		cfg.backoff = backoff
+	})
+}
+
+// WithSensorMode sets the mode of the clients sensor, defaults to probe.ReadinessMode.
+func WithSensorMode(mode probe.Mode) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.sensorMode = mode
+	})
+}
+
+// A Runner is a foundation.Runner which connects a MongoDB client and verifies connectivity. The runner
+// returns once the server is reachable and disconnects the client on stop, after runners started after
+// it have stopped.
+type Runner[C Client] struct {
+	connect ConnectFunc[C]
+	opts    []Option
+	mtx     sync.RWMutex
+	client  C
+}
+
+// Run returns a Runner which connects a client using the connect function.
+func Run[C Client](connect ConnectFunc[C], opts ...Option) *Runner[C] {
+	return &Runner[C]{
+		connect: connect,
+		opts:    opts,
+	}
+}
+
+// Client returns the connected client, the zero value until the runner has connected which is
+// guaranteed once F.Run has returned for the Runner.
+func (r *Runner[C]) Client() C {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	return r.client
+}
+
+// Run connects the client, pings the server until it is reachable and publishes the client to the value
+// store. The runner errors if the server cannot be reached.
+func (r *Runner[C]) Run(ctx context.Context, f foundation.F) {
+	cfg := config{
+		name:                   "mongodb",
+		serverSelectionTimeout: 5 * time.Second,
+		disconnectTimeout:      10 * time.Second,
+		pingAttempts:           5,
+		backoff:                tick.ExponentialBackoff(100*time.Millisecond, tick.WithJitter(0.2)),
+		sensorMode:             probe.ReadinessMode,
+	}
+
+	Options(r.opts).apply(&cfg)
+
+	connectCtx, cancel := context.WithTimeout(ctx, cfg.serverSelectionTimeout)
+	client, err := r.connect(connectCtx)
+	cancel()
+
+	if err != nil {
+		f.Error(fmt.Errorf("connect %s: %w", cfg.name, err))
+	}
+
+	f.On().Stop(func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), cfg.disconnectTimeout)
+		defer cancel()
+
+		if err := client.Disconnect(ctx); err != nil {
+			slog.Warn("failed to disconnect mongodb client", slog.String("name", cfg.name), slog.String("err", err.Error()))
+		}
+	})
+
+	if err := ping(ctx, client, cfg); err != nil {
+		f.Error(fmt.Errorf("ping %s: %w", cfg.name, err))
+	}
+
+	r.mtx.Lock()
+	r.client = client
+	r.mtx.Unlock()
+
+	f.Values().Store(clientKey(cfg.name), client)
+
+	probe.RegisterContext(ctx, probe.NewSensor(fmt.Sprintf("mongodb[%s]", cfg.name), cfg.sensorMode, func(ctx context.Context) error {
+		ctx, cancel := context.WithTimeout(ctx, cfg.serverSelectionTimeout)
+		defer cancel()
+
+		return client.Ping(ctx)
+	}))
+}
+
+// ping pings the server retrying with backoff until it succeeds or the attempts are exhausted.
+func ping(ctx context.Context, client Client, cfg config) error {
+	var err error
+
+	for attempt := range max(cfg.pingAttempts, 1) {
+		if attempt > 0 {
+			slog.WarnContext(ctx, "mongodb unreachable, retrying", slog.String("name", cfg.name), slog.String("err", err.Error()))
+
+			timer := time.NewTimer(cfg.backoff.Wait(ctx, attempt))
+
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			case <-timer.C:
+			}
+		}
+
+		pingCtx, cancel := context.WithTimeout(ctx, cfg.serverSelectionTimeout)
+		err = client.Ping(pingCtx)
+		cancel()
+
+		if err == nil {
+			return nil
+		}
+	}
+
+	return err
+}
+
+// clientKey is the value store key a client is stored under, keyed by the clients name.
+type clientKey string
+
+// Get returns the client with the given name from the F's value store, for example
+// Get[client](f, "mongodb").
+func Get[C Client](f foundation.F, name string) (C, bool) {
+	return foundation.Value[C](f, clientKey(name))
+}
@@ -0,0 +1,326 @@
+// Package di is a typed dependency injection container. Constructors are provided for each type and
+// resolve the types they depend on, so the construction order follows from the dependencies rather than
+// the order of closures and globals wired by hand.
+//
+//	c := di.New()
+//
+//	di.Provide(c, func(ctx context.Context, c *di.Container) (*sql.DB, error) {
+//		cfg, err := di.Resolve[Config](ctx, c)
+//		if err != nil {
+//			return nil, err
+//		}
+//
+//		return sql.Open("postgres", cfg.DSN)
+//	})
+//
+//	di.Provide(c, func(ctx context.Context, c *di.Container) (*Server, error) {
+//		db, err := di.Resolve[*sql.DB](ctx, c)
+//		if err != nil {
+//			return nil, err
+//		}
+//
+//		return NewServer(db), nil
+//	})
+//
+//	foundation.Run("api", c)
+//
+// Run as a foundation.Runner the Container constructs every provided type, runs those which are
+// foundation.Runners in the order they were constructed and, once stopped, disposes of them in reverse.
+package di
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+
+	"go.krak3n.io/foundation"
+)
+
+var (
+	// ErrNotProvided is returned when resolving a type which has not been provided.
+	ErrNotProvided = errors.New("not provided")
+	// ErrCycle is returned when resolving a type which depends on itself.
+	ErrCycle = errors.New("dependency cycle")
+)
+
+// A Constructor constructs a value of type T, resolving its dependencies from the Container with the
+// given context, see Resolve.
+type Constructor[T any] func(ctx context.Context, c *Container) (T, error)
+
+// A ProvideOption configures how a value of type T is provided.
+type ProvideOption[T any] interface {
+	applyProvide(*provideConfig[T])
+}
+
+// ProvideOptions is one or more ProvideOption.
+type ProvideOptions[T any] []ProvideOption[T]
+
+func (opts ProvideOptions[T]) applyProvide(cfg *provideConfig[T]) {
+	for opt := range slices.Values(opts) {
+		if opt != nil {
+			opt.applyProvide(cfg)
+		}
+	}
+}
+
+type provideFunc[T any] func(*provideConfig[T])
+
+func (fn provideFunc[T]) applyProvide(cfg *provideConfig[T]) {
+	fn(cfg)
+}
+
+// provideConfig holds the configuration of a provided type.
+type provideConfig[T any] struct {
+	dispose func(ctx context.Context, v T) error
+}
+
+// WithDispose sets the function which disposes of the value once the Container stops. By default values
+// which are an io.Closer are closed.
+func WithDispose[T any](fn func(ctx context.Context, v T) error) ProvideOption[T] {
+	return provideFunc[T](func(cfg *provideConfig[T]) {
+		cfg.dispose = fn
+	})
+}
+
+// provider constructs and holds the value of a type.
+type provider struct {
+	typ       reflect.Type
+	construct func(ctx context.Context, c *Container) (any, error)
+	dispose   func(ctx context.Context, v any) error
+	value     any
+	built     bool
+}
+
+// A Container holds the constructors of provided types and the values constructed by them.
+type Container struct {
+	// resolving is held by the outermost Resolve so values are constructed once, one at a time.
+	resolving sync.Mutex
+
+	mtx       sync.Mutex
+	providers map[reflect.Type]*provider
+	provided  []*provider
+	built     []*provider
+}
+
+// New returns an empty Container.
+func New() *Container {
+	return &Container{
+		providers: make(map[reflect.Type]*provider),
+	}
+}
+
+// Provide provides values of type T with the constructor, replacing any constructor previously provided
+// for T. The value is constructed once, when first resolved.
+func Provide[T any](c *Container, ctor Constructor[T], opts ...ProvideOption[T]) {
+	var cfg provideConfig[T]
+
+	ProvideOptions[T](opts).applyProvide(&cfg)
+
+	p := &provider{
+		typ: reflect.TypeFor[T](),
+		construct: func(ctx context.Context, c *Container) (any, error) {
+			return ctor(ctx, c)
+		},
+		dispose: func(ctx context.Context, v any) error {
+			if cfg.dispose != nil {
+				return cfg.dispose(ctx, v.(T))
+			}
+
+			if closer, ok := v.(io.Closer); ok {
+				return closer.Close()
+			}
+
+			return nil
+		},
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if _, ok := c.providers[p.typ]; ok {
+		c.provided = slices.DeleteFunc(c.provided, func(v *provider) bool {
+			return v.typ == p.typ
+		})
+	}
+
+	c.providers[p.typ] = p
+	c.provided = append(c.provided, p)
+}
+
+// Value provides the value of type T as is, for example configuration loaded before the Container runs.
+// It is not disposed of.
+func Value[T any](c *Container, v T) {
+	Provide(c, func(context.Context, *Container) (T, error) {
+		return v, nil
+	}, WithDispose(func(context.Context, T) error {
+		return nil
+	}))
+}
+
+// chainKey is the context key of the types being resolved, from the outermost.
+type chainKey struct{}
+
+// Resolve returns the value of type T, constructing it and its dependencies if they have not been
+// constructed. Constructors must resolve their dependencies with the context they are given, which is
+// how dependency cycles are detected.
+func Resolve[T any](ctx context.Context, c *Container) (T, error) {
+	v, err := c.resolve(ctx, reflect.TypeFor[T]())
+	if err != nil {
+		var zero T
+
+		return zero, err
+	}
+
+	return v.(T), nil
+}
+
+// MustResolve returns the value of type T as Resolve does, panicking if it can not be resolved. Within a
+// Runner the panic is reported as a runtime error, stopping the foundation.
+func MustResolve[T any](ctx context.Context, c *Container) T {
+	v, err := Resolve[T](ctx, c)
+	if err != nil {
+		panic(err)
+	}
+
+	return v
+}
+
+// resolve returns the value of the type, constructing it if it has not been constructed.
+func (c *Container) resolve(ctx context.Context, typ reflect.Type) (any, error) {
+	chain, nested := ctx.Value(chainKey{}).([]reflect.Type)
+	if !nested {
+		c.resolving.Lock()
+		defer c.resolving.Unlock()
+	}
+
+	if slices.Contains(chain, typ) {
+		return nil, fmt.Errorf("%w: %s", ErrCycle, path(append(chain, typ)))
+	}
+
+	c.mtx.Lock()
+	p, ok := c.providers[typ]
+	c.mtx.Unlock()
+
+	if !ok {
+		if len(chain) > 0 {
+			return nil, fmt.Errorf("resolve %s: %s %w", path(chain), typ, ErrNotProvided)
+		}
+
+		return nil, fmt.Errorf("resolve %s: %w", typ, ErrNotProvided)
+	}
+
+	if p.built {
+		return p.value, nil
+	}
+
+	v, err := p.construct(context.WithValue(ctx, chainKey{}, append(slices.Clone(chain), typ)), c)
+	if err != nil {
+		return nil, fmt.Errorf("construct %s: %w", typ, err)
+	}
+
+	p.value = v
+	p.built = true
+
+	c.mtx.Lock()
+	c.built = append(c.built, p)
+	c.mtx.Unlock()
+
+	slog.DebugContext(ctx, "constructed dependency", slog.String("type", typ.String()))
+
+	return v, nil
+}
+
+// path formats the types as a dependency path.
+func path(types []reflect.Type) string {
+	names := make([]string, len(types))
+
+	for i, typ := range types {
+		names[i] = typ.String()
+	}
+
+	return strings.Join(names, " -> ")
+}
+
+// Run constructs every provided type, failing with every error encountered, stores the Container in the
+// value store, see Get, then runs the constructed values which are foundation.Runners in the order they
+// were constructed. Once stopped every constructed value is disposed of in reverse order.
+func (c *Container) Run(ctx context.Context, f foundation.F) {
+	f.Values().Store(containerKey{}, c)
+
+	f.On().Stop(func() {
+		if err := c.Dispose(context.WithoutCancel(ctx)); err != nil {
+			f.Error(err)
+		}
+	})
+
+	c.mtx.Lock()
+	provided := slices.Clone(c.provided)
+	c.mtx.Unlock()
+
+	var errs []error
+
+	for p := range slices.Values(provided) {
+		if _, err := c.resolve(ctx, p.typ); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		f.Error(err)
+	}
+
+	c.mtx.Lock()
+	built := slices.Clone(c.built)
+	c.mtx.Unlock()
+
+	var runners []foundation.Runner
+
+	for p := range slices.Values(built) {
+		if runner, ok := p.value.(foundation.Runner); ok {
+			runners = append(runners, runner)
+		}
+	}
+
+	f.Run(ctx, runners...)
+}
+
+// Dispose disposes of every constructed value in the reverse order they were constructed, returning
+// every error encountered. Disposed values are constructed again if resolved.
+func (c *Container) Dispose(ctx context.Context) error {
+	c.resolving.Lock()
+	defer c.resolving.Unlock()
+
+	c.mtx.Lock()
+	built := c.built
+	c.built = nil
+	c.mtx.Unlock()
+
+	var errs []error
+
+	for _, p := range slices.Backward(built) {
+		v := p.value
+
+		p.value = nil
+		p.built = false
+
+		if err := p.dispose(ctx, v); err != nil {
+			errs = append(errs, fmt.Errorf("dispose %s: %w", p.typ, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// containerKey is the value store key the Container is stored under.
+type containerKey struct{}
+
+// Get returns the Container from the F's value store.
+func Get(f foundation.F) (*Container, bool) {
+	return foundation.Value[*Container](f, containerKey{})
+}
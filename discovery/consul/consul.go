@@ -0,0 +1,328 @@
+// Package consul registers a service with the local Consul agent using the agent HTTP API.
+package consul
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"go.krak3n.io/foundation"
+	"go.krak3n.io/foundation/health/probe"
+	"go.krak3n.io/foundation/tick"
+)
+
+// DefaultHealthURL is the readiness endpoint of the foundation health server, see health.Run.
+const DefaultHealthURL = "http://127.0.0.1:3417/_health/readiness"
+
+// A Service describes the service instance to register.
+type Service struct {
+	// ID uniquely identifies the instance, defaults to Name.
+	ID      string
+	Name    string
+	Address string
+	Port    int
+	Tags    []string
+	Meta    map[string]string
+}
+
+// An Option configures the Consul Runner.
+type Option interface {
+	apply(*config)
+}
+
+// Options is one or more Option.
+type Options []Option
+
+func (opts Options) apply(cfg *config) {
+	for opt := range slices.Values(opts) {
+		if opt != nil {
+			opt.apply(cfg)
+		}
+	}
+}
+
+type optionFunc func(*config)
+
+func (f optionFunc) apply(cfg *config) {
+	f(cfg)
+}
+
+// config holds the Consul Runner configuration.
+type config struct {
+	agent          string
+	token          string
+	client         *http.Client
+	healthURL      string
+	interval       time.Duration
+	ttl            time.Duration
+	deregisterTime time.Duration
+}
+
+// WithAgent sets the address of the Consul agent, defaults to CONSUL_HTTP_ADDR or http://127.0.0.1:8500.
+func WithAgent(addr string) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.agent = addr
+	})
+}
+
+// WithToken sets the ACL token, defaults to CONSUL_HTTP_TOKEN.
+func WithToken(token string) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.token = token
+	})
+}
+
+// WithHTTPClient sets the HTTP client used to call the agent, defaults to a client with a 10 second timeout.
+func WithHTTPClient(client *http.Client) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.client = client
+	})
+}
+
+// WithHTTPCheck registers a check which Consul polls at the given interval, defaults to polling
+// DefaultHealthURL every 10 seconds.
+func WithHTTPCheck(url string, interval time.Duration) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.healthURL = url
+		cfg.interval = interval
+		cfg.ttl = 0
+	})
+}
+
+// WithTTLCheck registers a TTL check instead of a HTTP check, the runner runs the registered readiness
+// sensors every half TTL reporting the result to Consul. Useful when Consul cannot reach the instance.
+func WithTTLCheck(ttl time.Duration) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.ttl = ttl
+	})
+}
+
+// WithDeregisterCriticalAfter sets how long the check may be critical before Consul deregisters the
+// service, cleaning up after instances which die without deregistering. Defaults to 1 minute.
+func WithDeregisterCriticalAfter(d time.Duration) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.deregisterTime = d
+	})
+}
+
+// A Runner is a foundation.Runner which registers a service with Consul and deregisters it on stop.
+// Run it after the runners serving traffic so it stops first, removing the instance from discovery
+// before they drain.
+type Runner struct {
+	service Service
+	opts    []Option
+	cfg     config
+
+	registered atomic.Bool
+	err        atomic.Pointer[error]
+}
+
+// Run returns a Runner which registers the service.
+func Run(service Service, opts ...Option) *Runner {
+	if service.ID == "" {
+		service.ID = service.Name
+	}
+
+	return &Runner{
+		service: service,
+		opts:    opts,
+	}
+}
+
+// Run registers the service, registration failures are retried and surfaced through the runners sensor
+// rather than failing startup.
+func (r *Runner) Run(ctx context.Context, f foundation.F) {
+	r.cfg = config{
+		agent:          "http://127.0.0.1:8500",
+		token:          os.Getenv("CONSUL_HTTP_TOKEN"),
+		client:         &http.Client{Timeout: 10 * time.Second},
+		healthURL:      DefaultHealthURL,
+		interval:       10 * time.Second,
+		deregisterTime: time.Minute,
+	}
+
+	if addr := os.Getenv("CONSUL_HTTP_ADDR"); addr != "" {
+		r.cfg.agent = addr
+	}
+
+	Options(r.opts).apply(&r.cfg)
+
+	if !strings.Contains(r.cfg.agent, "://") {
+		r.cfg.agent = "http://" + r.cfg.agent
+	}
+
+	probe.RegisterContext(ctx, probe.NewSensor(fmt.Sprintf("consul[%s]", r.service.ID), probe.ReadinessMode, func(context.Context) error {
+		if err := r.err.Load(); err != nil {
+			return *err
+		}
+
+		return nil
+	}))
+
+	f.On().Stop(func() {
+		if !r.registered.Load() {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		defer cancel()
+
+		if err := r.call(ctx, "/v1/agent/service/deregister/"+url.PathEscape(r.service.ID), nil); err != nil {
+			slog.Warn("failed to deregister consul service", slog.String("service", r.service.ID), slog.String("err", err.Error()))
+		}
+	})
+
+	r.sync(ctx)
+
+	interval := r.cfg.interval
+	if r.cfg.ttl > 0 {
+		interval = r.cfg.ttl / 2
+	}
+
+	tick.Run(ctx, f, interval, func(ctx context.Context, _ tick.Ticker) {
+		r.sync(ctx)
+	})
+}
+
+// sync registers the service if required and updates the TTL check, recording any failure.
+func (r *Runner) sync(ctx context.Context) {
+	err := r.register(ctx)
+	if err == nil && r.cfg.ttl > 0 {
+		err = r.updateTTL(ctx)
+	}
+
+	if err != nil {
+		slog.WarnContext(ctx, "consul registration failed", slog.String("service", r.service.ID), slog.String("err", err.Error()))
+		r.err.Store(&err)
+
+		return
+	}
+
+	r.err.Store(nil)
+}
+
+// register registers the service with the agent if it is not already registered.
+func (r *Runner) register(ctx context.Context) error {
+	if r.registered.Load() {
+		return nil
+	}
+
+	check := map[string]string{
+		"DeregisterCriticalServiceAfter": r.cfg.deregisterTime.String(),
+	}
+
+	if r.cfg.ttl > 0 {
+		check["TTL"] = r.cfg.ttl.String()
+	} else {
+		check["HTTP"] = r.cfg.healthURL
+		check["Interval"] = r.cfg.interval.String()
+	}
+
+	body := map[string]any{
+		"ID":      r.service.ID,
+		"Name":    r.service.Name,
+		"Address": r.service.Address,
+		"Port":    r.service.Port,
+		"Tags":    r.service.Tags,
+		"Meta":    r.service.Meta,
+		"Check":   check,
+	}
+
+	if err := r.call(ctx, "/v1/agent/service/register", body); err != nil {
+		return fmt.Errorf("register: %w", err)
+	}
+
+	r.registered.Store(true)
+
+	slog.InfoContext(ctx, "registered consul service", slog.String("service", r.service.ID))
+
+	return nil
+}
+
+// updateTTL runs the readiness sensors reporting the result to the services TTL check.
+func (r *Runner) updateTTL(ctx context.Context) error {
+	sensors := slices.DeleteFunc(slices.Clone(probe.RegistryFromContext(ctx).SensorsFor(probe.ReadinessMode)), func(s probe.Sensor) bool {
+		return s.Name() == fmt.Sprintf("consul[%s]", r.service.ID)
+	})
+
+	var failed []string
+
+	for s := range probe.Run(probe.WithMode(ctx, probe.ReadinessMode), sensors...) {
+		if s.Status == probe.StatusFailed {
+			failed = append(failed, s.Name)
+		}
+	}
+
+	status, output := "passing", "all readiness sensors passing"
+
+	if len(failed) > 0 {
+		slices.Sort(failed)
+
+		status, output = "critical", "failing sensors: "+strings.Join(failed, ", ")
+	}
+
+	body := map[string]string{
+		"Status": status,
+		"Output": output,
+	}
+
+	if err := r.call(ctx, "/v1/agent/check/update/service:"+url.PathEscape(r.service.ID), body); err != nil {
+		// The agent may have lost the registration, for example after an agent restart.
+		r.registered.Store(false)
+
+		return fmt.Errorf("update ttl check: %w", err)
+	}
+
+	return nil
+}
+
+// call makes a PUT request to the agent API.
+func (r *Runner) call(ctx context.Context, path string, body any) error {
+	var rd io.Reader = http.NoBody
+
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+
+		rd = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, r.cfg.agent+path, rd)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	if r.cfg.token != "" {
+		req.Header.Set("X-Consul-Token", r.cfg.token)
+	}
+
+	rsp, err := r.cfg.client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer rsp.Body.Close()
+
+	if rsp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(rsp.Body, 1024))
+
+		return errors.New(strings.TrimSpace(fmt.Sprintf("%s: %s", rsp.Status, msg)))
+	}
+
+	return nil
+}
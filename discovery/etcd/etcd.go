@@ -0,0 +1,315 @@
+// Package etcd registers a service instance in etcd under a lease using the etcd v3 JSON gateway.
+package etcd
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.krak3n.io/foundation"
+	"go.krak3n.io/foundation/health/probe"
+	"go.krak3n.io/foundation/tick"
+)
+
+// An Instance is the service instance registered under the service prefix, stored as JSON.
+type Instance struct {
+	ID      string            `json:"id"`
+	Address string            `json:"address"`
+	Meta    map[string]string `json:"meta,omitempty"`
+}
+
+// An Option configures the etcd Runner.
+type Option interface {
+	apply(*config)
+}
+
+// Options is one or more Option.
+type Options []Option
+
+func (opts Options) apply(cfg *config) {
+	for opt := range slices.Values(opts) {
+		if opt != nil {
+			opt.apply(cfg)
+		}
+	}
+}
+
+type optionFunc func(*config)
+
+func (f optionFunc) apply(cfg *config) {
+	f(cfg)
+}
+
+// config holds the etcd Runner configuration.
+type config struct {
+	endpoint string
+	username string
+	password string
+	client   *http.Client
+	ttl      time.Duration
+}
+
+// WithEndpoint sets the etcd endpoint, defaults to the first of ETCD_ENDPOINTS or http://127.0.0.1:2379.
+func WithEndpoint(endpoint string) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.endpoint = endpoint
+	})
+}
+
+// WithAuth authenticates with etcd using the given credentials.
+func WithAuth(username, password string) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.username = username
+		cfg.password = password
+	})
+}
+
+// WithHTTPClient sets the HTTP client used to call etcd, defaults to a client with a 10 second timeout.
+func WithHTTPClient(client *http.Client) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.client = client
+	})
+}
+
+// WithTTL sets the lease TTL, rounded up to whole seconds, the lease is kept alive every third of the
+// TTL. Defaults to 10 seconds.
+func WithTTL(ttl time.Duration) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.ttl = ttl
+	})
+}
+
+// A Runner is a foundation.Runner which registers an instance under a service prefix attached to a lease,
+// keeping the lease alive until stopped when the lease is revoked removing the registration. Should the
+// process die the registration is removed once the lease expires. Run it after the runners serving
+// traffic so it stops first.
+type Runner struct {
+	prefix   string
+	instance Instance
+	opts     []Option
+	cfg      config
+
+	mtx   sync.Mutex
+	lease string
+	token string
+	err   atomic.Pointer[error]
+}
+
+// Run returns a Runner which registers the instance under the prefix, for example /services/api.
+func Run(prefix string, instance Instance, opts ...Option) *Runner {
+	return &Runner{
+		prefix:   prefix,
+		instance: instance,
+		opts:     opts,
+	}
+}
+
+// Key returns the key the instance is registered under.
+func (r *Runner) Key() string {
+	return path.Join(r.prefix, r.instance.ID)
+}
+
+// Run grants a lease and registers the instance, failures are retried on the next keepalive and
+// surfaced through the runners sensor rather than failing startup.
+func (r *Runner) Run(ctx context.Context, f foundation.F) {
+	r.cfg = config{
+		endpoint: "http://127.0.0.1:2379",
+		client:   &http.Client{Timeout: 10 * time.Second},
+		ttl:      10 * time.Second,
+	}
+
+	if endpoints := os.Getenv("ETCD_ENDPOINTS"); endpoints != "" {
+		r.cfg.endpoint, _, _ = strings.Cut(endpoints, ",")
+	}
+
+	Options(r.opts).apply(&r.cfg)
+
+	if !strings.Contains(r.cfg.endpoint, "://") {
+		r.cfg.endpoint = "http://" + r.cfg.endpoint
+	}
+
+	probe.RegisterContext(ctx, probe.NewSensor(fmt.Sprintf("etcd[%s]", r.Key()), probe.ReadinessMode, func(context.Context) error {
+		if err := r.err.Load(); err != nil {
+			return *err
+		}
+
+		return nil
+	}))
+
+	f.On().Stop(func() {
+		r.mtx.Lock()
+		defer r.mtx.Unlock()
+
+		if r.lease == "" {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		defer cancel()
+
+		if err := r.call(ctx, "/v3/lease/revoke", map[string]string{"ID": r.lease}, nil); err != nil {
+			slog.Warn("failed to revoke etcd lease", slog.String("key", r.Key()), slog.String("err", err.Error()))
+		}
+	})
+
+	r.sync(ctx)
+
+	tick.Run(ctx, f, r.cfg.ttl/3, func(ctx context.Context, _ tick.Ticker) {
+		r.sync(ctx)
+	})
+}
+
+// sync keeps the lease alive, registering the instance under a new lease if the lease has expired.
+func (r *Runner) sync(ctx context.Context) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	err := r.keepAlive(ctx)
+	if err != nil {
+		slog.WarnContext(ctx, "etcd registration failed", slog.String("key", r.Key()), slog.String("err", err.Error()))
+		r.err.Store(&err)
+
+		return
+	}
+
+	r.err.Store(nil)
+}
+
+// keepAlive refreshes the lease, or grants a new lease and registers the instance if there is no lease.
+func (r *Runner) keepAlive(ctx context.Context) error {
+	if r.lease != "" {
+		var rsp struct {
+			Result struct {
+				TTL string `json:"TTL"`
+			} `json:"result"`
+		}
+
+		if err := r.call(ctx, "/v3/lease/keepalive", map[string]string{"ID": r.lease}, &rsp); err != nil {
+			return fmt.Errorf("keepalive lease: %w", err)
+		}
+
+		if ttl, _ := strconv.ParseInt(rsp.Result.TTL, 10, 64); ttl > 0 {
+			return nil
+		}
+
+		// The lease expired, for example after a network partition, register again under a new lease.
+		slog.WarnContext(ctx, "etcd lease expired, registering again", slog.String("key", r.Key()))
+		r.lease = ""
+	}
+
+	var grant struct {
+		ID string `json:"ID"`
+	}
+
+	if err := r.call(ctx, "/v3/lease/grant", map[string]int64{"TTL": max(int64(math.Ceil(r.cfg.ttl.Seconds())), 1)}, &grant); err != nil {
+		return fmt.Errorf("grant lease: %w", err)
+	}
+
+	value, err := json.Marshal(r.instance)
+	if err != nil {
+		return fmt.Errorf("marshal instance: %w", err)
+	}
+
+	put := map[string]string{
+		"key":   base64.StdEncoding.EncodeToString([]byte(r.Key())),
+		"value": base64.StdEncoding.EncodeToString(value),
+		"lease": grant.ID,
+	}
+
+	if err := r.call(ctx, "/v3/kv/put", put, nil); err != nil {
+		return fmt.Errorf("put %s: %w", r.Key(), err)
+	}
+
+	r.lease = grant.ID
+
+	slog.InfoContext(ctx, "registered etcd instance", slog.String("key", r.Key()), slog.String("lease", grant.ID))
+
+	return nil
+}
+
+// call makes a POST request to the etcd JSON gateway, authenticating first if credentials are set.
+func (r *Runner) call(ctx context.Context, path string, body, out any) error {
+	if r.cfg.username != "" && r.token == "" {
+		var auth struct {
+			Token string `json:"token"`
+		}
+
+		credentials := map[string]string{"name": r.cfg.username, "password": r.cfg.password}
+
+		if err := r.post(ctx, "/v3/auth/authenticate", credentials, &auth); err != nil {
+			return fmt.Errorf("authenticate: %w", err)
+		}
+
+		r.token = auth.Token
+	}
+
+	err := r.post(ctx, path, body, out)
+
+	var status statusError
+	if errors.As(err, &status) && status == http.StatusUnauthorized {
+		// The token expired, authenticate again on the next call.
+		r.token = ""
+	}
+
+	return err
+}
+
+// statusError is an unexpected HTTP response status.
+type statusError int
+
+func (err statusError) Error() string {
+	return http.StatusText(int(err))
+}
+
+// post makes a POST request decoding the response into out if not nil.
+func (r *Runner) post(ctx context.Context, path string, body, out any) error {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.cfg.endpoint+path, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	if r.token != "" {
+		req.Header.Set("Authorization", r.token)
+	}
+
+	rsp, err := r.cfg.client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer rsp.Body.Close()
+
+	if rsp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(rsp.Body, 1024))
+
+		return fmt.Errorf("%w: %s", statusError(rsp.StatusCode), strings.TrimSpace(string(msg)))
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	return json.NewDecoder(rsp.Body).Decode(out)
+}
@@ -1,13 +1,27 @@
 package foundation
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
 )
 
 type RuntimeError struct {
 	Cause error
 	Stack []byte
+	// Runner is the name of the F the error occurred in, for example api.1.2.
+	Runner string
+	// Attrs are additional attributes logged with the error, for example request metadata.
+	Attrs []slog.Attr
+}
+
+// Unwrap returns the cause of the error.
+func (err RuntimeError) Unwrap() error {
+	return err.Cause
 }
 
 func (err RuntimeError) Error() string {
@@ -23,6 +37,13 @@ func (err RuntimeError) Error() string {
 type CleanupError struct {
 	Cause error
 	Stack []byte
+	// Runner is the name of the F the error occurred in, for example api.1.2.
+	Runner string
+}
+
+// Unwrap returns the cause of the error.
+func (err CleanupError) Unwrap() error {
+	return err.Cause
 }
 
 func (err CleanupError) Error() string {
@@ -49,6 +70,59 @@ func (err PanicError) Error() string {
 	return s
 }
 
+// A Frame is a function call within a stack trace.
+type Frame struct {
+	Function string `json:"function"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+}
+
+// ParseStack parses a stack trace as formatted by runtime/debug.Stack into frames, most recent call
+// first. Frames of the panic machinery and of debug.Stack itself are dropped.
+func ParseStack(stack []byte) []Frame {
+	var frames []Frame
+
+	scanner := bufio.NewScanner(bytes.NewReader(stack))
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "goroutine ") || strings.HasPrefix(line, "\t") {
+			continue
+		}
+
+		fn := line
+
+		if created, ok := strings.CutPrefix(fn, "created by "); ok {
+			fn, _, _ = strings.Cut(created, " in goroutine")
+		} else if i := strings.LastIndex(fn, "("); i > 0 {
+			fn = fn[:i]
+		}
+
+		if !scanner.Scan() {
+			break
+		}
+
+		location := strings.TrimSpace(scanner.Text())
+		if i := strings.LastIndex(location, " +0x"); i > 0 {
+			location = location[:i]
+		}
+
+		file, lineno, _ := strings.Cut(location, ":")
+		n, _ := strconv.Atoi(lineno)
+
+		frames = append(frames, Frame{Function: fn, File: file, Line: n})
+	}
+
+	// Drop everything up to and including the panic call, or failing that debug.Stack.
+	for i := len(frames) - 1; i >= 0; i-- {
+		if frames[i].Function == "panic" || frames[i].Function == "runtime/debug.Stack" {
+			return frames[i+1:]
+		}
+	}
+
+	return frames
+}
+
 // Error is a placeholder for common error handling patterns
 func Error(error) {}
 
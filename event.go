@@ -1,6 +1,7 @@
 package foundation
 
 import (
+	"fmt"
 	"slices"
 	"sync"
 )
@@ -10,6 +11,11 @@ type EventHookFunc func()
 type EventHook interface {
 	Done(fns ...EventHookFunc)
 	Stop(fns ...EventHookFunc)
+	// StopCtx adds stop hooks given a context whose deadline is the runner's share of the shutdown
+	// budget, see WithShutdownBudget. Without a budget the context has no deadline.
+	StopCtx(fns ...StopCtxFunc)
+	// Reload adds functions called when a reload is requested, see Reload.
+	Reload(fns ...EventHookFunc)
 }
 
 type eventHook uint8
@@ -17,17 +23,31 @@ type eventHook uint8
 const (
 	doneEvent eventHook = iota + 1
 	stopEvent
+	reloadEvent
 )
 
+// String returns the name of the event.
+func (e eventHook) String() string {
+	switch e {
+	case doneEvent:
+		return "done"
+	case stopEvent:
+		return "stop"
+	case reloadEvent:
+		return "reload"
+	default:
+		return fmt.Sprintf("eventHook(%d)", e)
+	}
+}
+
 type eventHooks struct {
 	mtx   sync.RWMutex
 	hooks map[eventHook][]EventHookFunc
-}
 
-func newEventHooks() *eventHooks {
-	return &eventHooks{
-		hooks: make(map[eventHook][]EventHookFunc),
-	}
+	// Whether the stop hooks have been called, stop hooks added afterwards are called as they are added.
+	stopped bool
+	// The F the hooks belong to, which calls hooks added late.
+	owner *f
 }
 
 func (e *eventHooks) Done(fns ...EventHookFunc) {
@@ -38,19 +58,76 @@ func (e *eventHooks) Stop(fns ...EventHookFunc) {
 	e.add(stopEvent, fns...)
 }
 
+func (e *eventHooks) StopCtx(fns ...StopCtxFunc) {
+	owner := e.owner
+	owner.budget.hook(owner)
+
+	hooks := make([]EventHookFunc, 0, len(fns))
+
+	for fn := range slices.Values(fns) {
+		hooks = append(hooks, func() {
+			ctx, cancel := owner.budget.context(owner)
+			defer cancel()
+
+			fn(ctx)
+		})
+	}
+
+	e.add(stopEvent, hooks...)
+}
+
+func (e *eventHooks) Reload(fns ...EventHookFunc) {
+	e.add(reloadEvent, fns...)
+}
+
 func (e *eventHooks) add(event eventHook, fns ...EventHookFunc) {
 	e.mtx.Lock()
-	defer e.mtx.Unlock()
+
+	if e.hooks == nil {
+		e.hooks = make(map[eventHook][]EventHookFunc)
+	}
 
 	e.hooks[event] = append(e.hooks[event], fns...)
+	late := event == stopEvent && e.stopped
+	e.mtx.Unlock()
+
+	// The F is already stopping, a runner started as it stopped must still be told to stop.
+	if late {
+		hooks := slices.Clone(fns)
+		slices.Reverse(hooks)
+
+		e.owner.callEventHooks(event, hooks)
+	}
 }
 
 func (e *eventHooks) get(event eventHook) []EventHookFunc {
 	e.mtx.RLock()
 	defer e.mtx.RUnlock()
 
+	return e.list(event)
+}
+
+// fire returns the hooks to call for the event, once the stop event has fired stop hooks are called as
+// they are added.
+func (e *eventHooks) fire(event eventHook) []EventHookFunc {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+
+	if event == stopEvent {
+		e.stopped = true
+	}
+
+	return e.list(event)
+}
+
+// list returns the hooks of the event in the order they are called, called with the lock held.
+func (e *eventHooks) list(event eventHook) []EventHookFunc {
 	hooks := slices.Clone(e.hooks[event])
-	slices.Reverse(hooks)
+
+	// Reload hooks run in the order they were added, so dependencies reload before their dependents.
+	if event != reloadEvent {
+		slices.Reverse(hooks)
+	}
 
 	return hooks
 }
@@ -0,0 +1,107 @@
+package foundation
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// A Describer is a Runner with a description, written in the --help output of Run when configured with
+// WithFlags, see Describe.
+type Describer interface {
+	Runner
+	Description() string
+}
+
+// describer is a Runner with a description.
+type describer struct {
+	Runner
+	description string
+}
+
+// Description returns the description of the Runner.
+func (d describer) Description() string {
+	return d.description
+}
+
+// Describe returns the Runner with a description, for example what the service does.
+func Describe(r Runner, description string) Describer {
+	return describer{
+		Runner:      r,
+		description: description,
+	}
+}
+
+// WithFlags parses the flag set from os.Args[1:] before the Runner runs. Flags not given on the command
+// line fall back to the environment variable of the same name upper cased with dashes and dots replaced
+// by underscores, for example -db-host falls back to DB_HOST. The flag set is stored in the value store,
+// see Flags.
+//
+// On -h or --help the usage, including the Runner's description if it is a Describer, is written to the
+// flag set's output and the process exits. Invalid flags exit with code 2.
+func WithFlags(fs *flag.FlagSet) RunOption {
+	return runConfigFunc(func(cfg *runConfig) {
+		cfg.flags = fs
+	})
+}
+
+// parseFlags parses the flag set from the arguments, falling back to the environment for flags not given.
+// Errors are written to the flag set's output followed by the usage, as the flag package does.
+func parseFlags(fs *flag.FlagSet, name string, runner Runner, args []string) error {
+	fs.Usage = func() {
+		w := fs.Output()
+
+		fmt.Fprintf(w, "Usage of %s:\n", name)
+
+		if d, ok := runner.(Describer); ok {
+			fmt.Fprintf(w, "\n%s\n\nFlags:\n", d.Description())
+		}
+
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	set := make(map[string]bool)
+
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+
+	var errs []error
+
+	fs.VisitAll(func(f *flag.Flag) {
+		if set[f.Name] {
+			return
+		}
+
+		env := strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(f.Name))
+
+		if v, ok := os.LookupEnv(env); ok {
+			if err := fs.Set(f.Name, v); err != nil {
+				errs = append(errs, fmt.Errorf("invalid value %q for %s: %w", v, env, err))
+			}
+		}
+	})
+
+	if err := errors.Join(errs...); err != nil {
+		fmt.Fprintln(fs.Output(), err)
+		fs.Usage()
+
+		return err
+	}
+
+	return nil
+}
+
+// flagsKey is the value store key the flag set is stored under.
+type flagsKey struct{}
+
+// Flags returns the flag set given to WithFlags from the F's value store.
+func Flags(f F) (*flag.FlagSet, bool) {
+	return Value[*flag.FlagSet](f, flagsKey{})
+}
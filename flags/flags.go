@@ -0,0 +1,154 @@
+// Package flags provides a feature flag store populated from one or more providers, such as a file,
+// the environment or a remote service, and kept up to date by a Runner so flags can be flipped without
+// restarting the process.
+package flags
+
+import (
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A Change is a change to a flag value. Old is empty if the flag was added and New is empty if it was
+// removed.
+type Change struct {
+	Name string
+	Old  string
+	New  string
+}
+
+// A ChangeFunc is called when flags change.
+type ChangeFunc func(Change)
+
+// watcher is a ChangeFunc watching the given flags, all flags if empty.
+type watcher struct {
+	names []string
+	fn    ChangeFunc
+}
+
+// A Store holds flag values. Values are stored as strings and converted on read, a flag which is not
+// set or cannot be converted returns the given default.
+type Store struct {
+	mtx      sync.RWMutex
+	values   map[string]string
+	watchers []watcher
+}
+
+// NewStore returns an empty Store.
+func NewStore() *Store {
+	return &Store{
+		values: make(map[string]string),
+	}
+}
+
+// Lookup returns the raw value of the flag and whether it is set.
+func (s *Store) Lookup(name string) (string, bool) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	v, ok := s.values[name]
+
+	return v, ok
+}
+
+// String returns the value of the flag.
+func (s *Store) String(name, def string) string {
+	if v, ok := s.Lookup(name); ok {
+		return v
+	}
+
+	return def
+}
+
+// Bool returns the value of the flag as a bool, see strconv.ParseBool.
+func (s *Store) Bool(name string, def bool) bool {
+	return lookup(s, name, def, strconv.ParseBool)
+}
+
+// Int returns the value of the flag as an int.
+func (s *Store) Int(name string, def int) int {
+	return lookup(s, name, def, strconv.Atoi)
+}
+
+// Float returns the value of the flag as a float64.
+func (s *Store) Float(name string, def float64) float64 {
+	return lookup(s, name, def, func(v string) (float64, error) {
+		return strconv.ParseFloat(v, 64)
+	})
+}
+
+// Duration returns the value of the flag as a time.Duration, see time.ParseDuration.
+func (s *Store) Duration(name string, def time.Duration) time.Duration {
+	return lookup(s, name, def, time.ParseDuration)
+}
+
+// All returns a copy of all the flag values.
+func (s *Store) All() map[string]string {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	return maps.Clone(s.values)
+}
+
+// OnChange calls fn whenever one of the named flags changes, or any flag if no names are given. fn is
+// called synchronously as the flags are updated so should not block.
+func (s *Store) OnChange(fn ChangeFunc, names ...string) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.watchers = append(s.watchers, watcher{names: names, fn: fn})
+}
+
+// Set replaces all flag values, calling the change functions for any flags which changed.
+func (s *Store) Set(values map[string]string) {
+	s.mtx.Lock()
+
+	var changes []Change
+
+	for name, v := range values {
+		if old, ok := s.values[name]; !ok || old != v {
+			changes = append(changes, Change{Name: name, Old: old, New: v})
+		}
+	}
+
+	for name, old := range s.values {
+		if _, ok := values[name]; !ok {
+			changes = append(changes, Change{Name: name, Old: old})
+		}
+	}
+
+	s.values = maps.Clone(values)
+	watchers := slices.Clone(s.watchers)
+
+	s.mtx.Unlock()
+
+	slices.SortFunc(changes, func(a, b Change) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	for change := range slices.Values(changes) {
+		for w := range slices.Values(watchers) {
+			if len(w.names) == 0 || slices.Contains(w.names, change.Name) {
+				w.fn(change)
+			}
+		}
+	}
+}
+
+// lookup returns the flag value converted with parse, or def if not set or invalid.
+func lookup[T any](s *Store, name string, def T, parse func(string) (T, error)) T {
+	v, ok := s.Lookup(name)
+	if !ok {
+		return def
+	}
+
+	t, err := parse(v)
+	if err != nil {
+		return def
+	}
+
+	return t
+}
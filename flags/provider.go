@@ -0,0 +1,116 @@
+package flags
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// A Provider loads flag values.
+type Provider interface {
+	Load(ctx context.Context) (map[string]string, error)
+}
+
+// The ProviderFunc type is an adapter to allow the use of ordinary functions as Providers.
+type ProviderFunc func(ctx context.Context) (map[string]string, error)
+
+// Load calls fn(ctx).
+func (fn ProviderFunc) Load(ctx context.Context) (map[string]string, error) {
+	return fn(ctx)
+}
+
+// File returns a Provider which reads flags from a JSON object of flag names to values, for example
+// {"new_checkout": true, "max_items": 10}. The file is read on every load so it can be updated in place,
+// for example a mounted ConfigMap.
+func File(path string) Provider {
+	return ProviderFunc(func(context.Context) (map[string]string, error) {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		return decode(b)
+	})
+}
+
+// Env returns a Provider which reads flags from environment variables with the given prefix. The flag
+// name is the remainder of the variable name in lower case, so with the prefix FLAG_ the variable
+// FLAG_NEW_CHECKOUT sets the flag new_checkout.
+func Env(prefix string) Provider {
+	return ProviderFunc(func(context.Context) (map[string]string, error) {
+		values := make(map[string]string)
+
+		for _, kv := range os.Environ() {
+			key, value, _ := strings.Cut(kv, "=")
+
+			if name, ok := strings.CutPrefix(key, prefix); ok && name != "" {
+				values[strings.ToLower(name)] = value
+			}
+		}
+
+		return values, nil
+	})
+}
+
+// HTTP returns a Provider which fetches flags from a remote service responding with a JSON object of
+// flag names to values, as for File. A nil client uses http.DefaultClient.
+func HTTP(url string, client *http.Client) Provider {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return ProviderFunc(func(ctx context.Context) (map[string]string, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, http.NoBody)
+		if err != nil {
+			return nil, err
+		}
+
+		req.Header.Set("Accept", "application/json")
+
+		rsp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		defer rsp.Body.Close()
+
+		if rsp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("unexpected status: %s", rsp.Status)
+		}
+
+		b, err := io.ReadAll(rsp.Body)
+		if err != nil {
+			return nil, err
+		}
+
+		return decode(b)
+	})
+}
+
+// decode decodes a JSON object of flag values, non string values are stored as their JSON encoding.
+func decode(b []byte) (map[string]string, error) {
+	var raw map[string]json.RawMessage
+
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string, len(raw))
+
+	for name, v := range raw {
+		var s string
+		if err := json.Unmarshal(v, &s); err == nil {
+			values[name] = s
+
+			continue
+		}
+
+		values[name] = string(v)
+	}
+
+	return values, nil
+}
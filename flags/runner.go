@@ -0,0 +1,136 @@
+package flags
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"time"
+
+	"go.krak3n.io/foundation"
+	"go.krak3n.io/foundation/tick"
+)
+
+// An Option configures the flags Runner.
+type Option interface {
+	apply(*config)
+}
+
+// Options is one or more Option.
+type Options []Option
+
+func (opts Options) apply(cfg *config) {
+	for opt := range slices.Values(opts) {
+		if opt != nil {
+			opt.apply(cfg)
+		}
+	}
+}
+
+type optionFunc func(*config)
+
+func (f optionFunc) apply(cfg *config) {
+	f(cfg)
+}
+
+// config holds the flags Runner configuration.
+type config struct {
+	providers []Provider
+	interval  time.Duration
+}
+
+// WithProvider adds a provider, flags from later providers override those from earlier providers.
+func WithProvider(p Provider) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.providers = append(cfg.providers, p)
+	})
+}
+
+// WithInterval sets how often the flags are refreshed, defaults to 30 seconds.
+func WithInterval(d time.Duration) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.interval = d
+	})
+}
+
+// A Runner is a foundation.Runner which loads flags from its providers into a Store, refreshing them on
+// a tick so flags can be flipped at runtime, see Store.OnChange. The Store is added to the value store,
+// see Get.
+type Runner struct {
+	opts  []Option
+	cfg   config
+	store *Store
+}
+
+// Run returns a Runner loading flags from the configured providers.
+//
+//	f.Run(ctx, flags.Run(
+//		flags.WithProvider(flags.File("/etc/flags/flags.json")),
+//		flags.WithProvider(flags.Env("FLAG_")),
+//	))
+func Run(opts ...Option) *Runner {
+	return &Runner{
+		opts:  opts,
+		store: NewStore(),
+	}
+}
+
+// Store returns the Runners flag Store.
+func (r *Runner) Store() *Store {
+	return r.store
+}
+
+// Run loads the flags, failing startup if they cannot be loaded, then refreshes them until stopped. Failed
+// refreshes are logged keeping the last loaded flags.
+func (r *Runner) Run(ctx context.Context, f foundation.F) {
+	r.cfg = config{
+		interval: 30 * time.Second,
+	}
+
+	Options(r.opts).apply(&r.cfg)
+
+	values, err := r.load(ctx)
+	if err != nil {
+		f.Error(err)
+	}
+
+	r.store.Set(values)
+
+	f.Values().Store(storeKey{}, r.store)
+
+	tick.Run(ctx, f, r.cfg.interval, func(ctx context.Context, _ tick.Ticker) {
+		values, err := r.load(ctx)
+		if err != nil {
+			slog.WarnContext(ctx, "failed to refresh flags", slog.String("err", err.Error()))
+
+			return
+		}
+
+		r.store.Set(values)
+	})
+}
+
+// load loads and merges the flags from all providers.
+func (r *Runner) load(ctx context.Context) (map[string]string, error) {
+	values := make(map[string]string)
+
+	for i, p := range r.cfg.providers {
+		v, err := p.Load(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("load flags from provider %d: %w", i, err)
+		}
+
+		maps.Copy(values, v)
+	}
+
+	return values, nil
+}
+
+// storeKey is the value store key the flag Store is stored under.
+type storeKey struct{}
+
+// Get returns the flag Store from the F's value store.
+func Get(f foundation.F) (*Store, bool) {
+	return foundation.Value[*Store](f, storeKey{})
+}
@@ -2,11 +2,14 @@ package foundation
 
 import (
 	"context"
-	"fmt"
+	"log/slog"
 	"runtime/debug"
+	"runtime/pprof"
 	"slices"
+	"strconv"
 	"sync"
 	"sync/atomic"
+	"time"
 )
 
 // F is the core interface to Foundation. It builds a linked list of functions to be run
@@ -18,6 +21,10 @@ type F interface {
 	// Run runs the given Runners in order. These will block until they have completed running.
 	Run(context.Context, ...Runner)
 
+	// Go runs the given Runners in order as parallel routines, as if each called Parallel as soon as it
+	// started, so they do not block.
+	Go(context.Context, ...Runner)
+
 	// Parallel narks the current runner as an asynchronous routine.
 	Parallel()
 
@@ -27,6 +34,9 @@ type F interface {
 	// Error causes execution to exit immediately unless called from within a clean up function in which case the error
 	// will just be logged.
 	Error(error)
+
+	// Values returns the value store shared by all F instances within a Run.
+	Values() ValueStore
 }
 
 // A Runner runs something.
@@ -44,53 +54,137 @@ func (fn RunFunc) Run(ctx context.Context, f F) {
 	fn(ctx, f)
 }
 
+// A Namer is a Runner with a name, used in place of its position in the name of its F, see Named.
+type Namer interface {
+	Runner
+	RunnerName() string
+}
+
+// named is a Runner with a name.
+type named struct {
+	Runner
+	name string
+}
+
+// RunnerName returns the name of the Runner.
+func (n named) RunnerName() string {
+	return n.name
+}
+
+// Named returns the Runner with a name, naming its F after it rather than its position, for example
+// api.kafka-consumer rather than api.1.2, so the runner can be told apart in logs and events. A runner
+// given the name of a sibling already run has its position appended, for example api.worker-3.
+//
+//	f.Run(ctx, foundation.Named("kafka-consumer", consumer))
+func Named(name string, r Runner) Namer {
+	return named{
+		Runner: r,
+		name:   name,
+	}
+}
+
 // f is an implementation of foundation.F.
 type f struct {
 	// If this is a sub function this is the parent.
 	parent *f
 	// Indicates the function has completed execution.
 	signalC chan struct{}
-	// Explicitly stop the function and call cleanups which should cause the function to complete.
-	stopC chan struct{}
-	// Errors that occur during execution of this f are pushed onto this channel
+	// Errors that occur during execution of the tree are pushed onto this channel, only the root has one,
+	// sub functions forward their errors up the tree to it.
 	errC chan error
+	// Guards errC against sends once it has been closed.
+	errMtx sync.RWMutex
+	// Indicates errC has been closed, or for a sub function that it has stopped forwarding errors.
+	errClosed bool
 	// Name of the F
 	name string
-	// Sub functions that are children of this F.
+	// Sub functions that are children of this F, less any dropped once complete, see drop.
 	subs []*f
+	// The number of sub functions run, used to name them and index the subs.
+	runs int
 	// Guards the fields to prevent race conditions.
 	mtx sync.RWMutex
 	// Indicates the function has run and has finished execution.
 	done atomic.Bool
 	// Indicates an explicit stop has been called.
 	stopped atomic.Bool
+	// Indicates stopping the f has been claimed, so it is stopped once by either its parent or a
+	// supervisor restarting it, see Supervise.
+	claimed atomic.Bool
 	// Indicates if an error has been encountered.
 	erred atomic.Bool
-	// Wait group for any go routines we want to wait for before Stop() can exit.
-	wg sync.WaitGroup
 	// parallelC is a channel closed by Parallal() if the f should be non blocking
 	parallelC chan struct{}
 	// parallel marks the f as non blocking.
 	parallel bool
 	// Event hooks to be called when certain events happen.
-	hooks *eventHooks
+	hooks eventHooks
+	// Value store shared with all sub functions.
+	values *values
+	// Divides the shutdown budget between runners, shared with all sub functions.
+	budget *budget
+	// Interceptors called with lifecycle events, shared with all sub functions.
+	interceptors []Interceptor
+	// Validate invariants, see WithStrict.
+	strict bool
+	// Label go routines with the runner name, see WithAccounting.
+	accounting bool
+	// Handles errors from sub functions if supervising, see Supervise. Returns the error to push up the
+	// tree and whether it was handled, in which case it is not.
+	supervise func(err error) (error, bool)
+	// The number of hooks called for each event, counted in strict mode.
+	called [reloadEvent + 1]int
 }
 
-// newf constructs a new F.
+// newf constructs a new root F.
 func newf(name string) *f {
 	f := &f{
 		signalC:   make(chan struct{}),
 		parallelC: make(chan struct{}),
-		stopC:     make(chan struct{}),
 		errC:      make(chan error),
-		subs:      make([]*f, 0),
 		name:      name,
-		hooks:     newEventHooks(),
+		values:    newValues(),
+		budget:    newBudget(),
 	}
 
+	f.hooks.owner = f
+
 	return f
 }
 
+// newSub constructs a new sub function of the parent, sharing its value store, interceptors, strictness
+// and accounting, named after the runner if it is a Namer otherwise its position. Called with the parents
+// lock held.
+func newSub(parent *f, runner Runner) *f {
+	parent.runs++
+
+	name := parent.name + "." + strconv.Itoa(parent.runs)
+
+	if v, ok := runner.(Namer); ok && v.RunnerName() != "" {
+		name = parent.name + "." + v.RunnerName()
+
+		if slices.ContainsFunc(parent.subs, func(sub *f) bool { return sub.name == name }) {
+			name += "-" + strconv.Itoa(parent.runs)
+		}
+	}
+
+	sub := &f{
+		parent:       parent,
+		signalC:      make(chan struct{}),
+		parallelC:    make(chan struct{}),
+		name:         name,
+		values:       parent.values,
+		budget:       parent.budget,
+		interceptors: parent.interceptors,
+		strict:       parent.strict,
+		accounting:   parent.accounting,
+	}
+
+	sub.hooks.owner = sub
+
+	return sub
+}
+
 // Name returns the Name of F.
 func (f *f) Name() string {
 	return f.name
@@ -99,32 +193,37 @@ func (f *f) Name() string {
 // Run executes the given run function.
 func (f *f) Run(ctx context.Context, runners ...Runner) {
 	for _, runner := range runners {
-		f.run(ctx, runner)
+		f.run(ctx, runner, false)
 	}
 }
 
 // Go executes the given run function in a go routine. This is useful for running asynchronous processes
-// which need to block, for example servers / message consumers.
+// which need to block, for example servers / message consumers, without the runner calling Parallel.
 // Foundation will not exit until all go routines have gracefully exited either naturally or via an explicit
 // stop call.
-// func (f *f) Go(ctx context.Context, runners ...Runner) {
-// 	for _, runner := range runners {
-// 		f.run(ctx, runner, true)
-// 	}
-// }
+func (f *f) Go(ctx context.Context, runners ...Runner) {
+	for _, runner := range runners {
+		f.run(ctx, runner, true)
+	}
+}
 
 // Parallel marks this f as a parallel routine. If already marked as parallel this is no-op.
 func (f *f) Parallel() {
 	f.mtx.Lock()
-	defer f.mtx.Unlock()
 
 	// If we are already maked as parallel then do nothing.
 	if f.parallel {
+		f.mtx.Unlock()
+
 		return
 	}
 
 	close(f.parallelC)
 	f.parallel = true
+
+	f.mtx.Unlock()
+
+	f.emit(EventParallel, nil)
 }
 
 // Error records an error. If being called from a Run function this will stop execution preventing any
@@ -140,22 +239,10 @@ func (f *f) Error(err error) {
 		return
 	}
 
-	// Set error state.
+	// Set error state, the parents error state is set as the error is pushed up the tree unless a
+	// supervisor handles it, see Supervise.
 	f.erred.Store(true)
 
-	parent := f.parent
-
-	for {
-		// No parent so we are at the root, error state would have already been set.
-		if parent == nil {
-			break
-		}
-
-		// Set the parent error state
-		parent.erred.Store(true)
-		parent = parent.parent
-	}
-
 	// Throw a panic
 	//
 	// This ensures execution of the current function will stop.
@@ -165,189 +252,385 @@ func (f *f) Error(err error) {
 	panic(err)
 }
 
+// Report reports an error through the error pipeline of the given F without interrupting the caller,
+// unlike F.Error which panics to stop the calling Runner. This is useful for go routines owned by a Runner,
+// such as HTTP handlers, which are not the Runner itself. Errors reported once the F has stopped are
+// logged.
+func Report(v F, err error) {
+	if err == nil {
+		return
+	}
+
+	f, ok := v.(*f)
+	if !ok {
+		slog.Error(err.Error())
+
+		return
+	}
+
+	f.report(err)
+}
+
+// ServiceName returns the name given to Run, the name of the root F of the tree of the given F.
+func ServiceName(v F) string {
+	f, ok := v.(*f)
+	if !ok {
+		return v.Name()
+	}
+
+	for f.parent != nil {
+		f = f.parent
+	}
+
+	return f.name
+}
+
+// Reload calls the Reload event hooks of every F in the tree of the given F, starting at the root, in
+// the order the hooks were added. Run calls Reload when the process receives SIGHUP.
+func Reload(v F) {
+	f, ok := v.(*f)
+	if !ok {
+		return
+	}
+
+	for f.parent != nil {
+		f = f.parent
+	}
+
+	f.reload()
+}
+
+// reload runs the reload event hooks of the f and its subs, unless stopping.
+func (f *f) reload() {
+	if f.stopped.Load() {
+		return
+	}
+
+	f.runEventHooks(reloadEvent)
+
+	f.mtx.RLock()
+	subs := slices.Clone(f.subs)
+	f.mtx.RUnlock()
+
+	for sub := range slices.Values(subs) {
+		sub.reload()
+	}
+}
+
+// report pushes the error onto the error channel setting the error state if it escalates.
+func (f *f) report(err error) {
+	f.errMtx.RLock()
+	defer f.errMtx.RUnlock()
+
+	if f.errClosed {
+		slog.Error(err.Error())
+
+		return
+	}
+
+	if escalates(err) {
+		f.erred.Store(true)
+	}
+
+	f.emit(EventError, err)
+
+	f.push(err)
+}
+
+// push pushes the error onto the root error channel, sub functions forward it to their parent.
+func (f *f) push(err error) {
+	if f.parent == nil {
+		f.errC <- err
+
+		return
+	}
+
+	f.parent.forward(err)
+}
+
+// forward pushes an error from a sub function up the tree setting the error state if it escalates,
+// logging it if stopped or handing it to the supervisor if supervised. The read lock is held until the
+// error is pushed so the root error channel is not closed beneath it.
+func (f *f) forward(err error) {
+	f.errMtx.RLock()
+	defer f.errMtx.RUnlock()
+
+	if f.errClosed {
+		slog.Error(err.Error())
+
+		return
+	}
+
+	if f.supervise != nil {
+		var handled bool
+
+		if err, handled = f.supervise(err); handled {
+			return
+		}
+	}
+
+	if escalates(err) {
+		f.erred.Store(true)
+	}
+
+	f.push(err)
+}
+
 // On returns an event hook to add functions which will be called when specific events occur.
 func (f *f) On() EventHook {
-	return f.hooks
+	return &f.hooks
+}
+
+// Values returns the value store shared by all F instances within a Run.
+func (f *f) Values() ValueStore {
+	return f.values
 }
 
 func (f *f) stop() {
+	if f.strict {
+		f.checkStop()
+	}
+
 	// Set stopping state to true, used to prevent further Run functions from being executed.
 	f.stopped.Store(true)
 
-	// Call Stop() on sub functions in reverse order so we stop the newest first and the oldest last.
-	f.mtx.RLock()
-	for i := len(f.subs) - 1; i >= 0; i-- {
-		f.subs[i].stop()
+	// The shutdown budget starts as the root stops.
+	if f.parent == nil {
+		f.budget.start(time.Now())
 	}
+
+	f.emit(EventStop, nil)
+
+	// Take the subs to stop, no more can be added now stopping. The lock is not held whilst stopping them
+	// as their runners may need it to finish.
+	f.mtx.RLock()
+	subs := slices.Clone(f.subs)
+	runs := f.runs
 	f.mtx.RUnlock()
 
+	// Call Stop() on sub functions in reverse order so we stop the newest first and the oldest last.
+	for i := len(subs) - 1; i >= 0; i-- {
+		if f.strict {
+			checkStopOrder(subs, i)
+		}
+
+		if subs[i].claimed.CompareAndSwap(false, true) {
+			subs[i].stop()
+		}
+	}
+
 	// Call stop event hooks
 	f.runEventHooks(stopEvent)
+	f.budget.release(f)
 
 	// Wait for signal channel to be closed indicating execution has finished
 	// and thereofre we can close error channels.
 	<-f.signalC
 
-	// Close error channel causing any go routines listening on it to exit.
-	close(f.errC)
+	// Stop hooks are called once, including any added whilst stopping, and no sub functions are run once
+	// the subs have been stopped.
+	if f.strict {
+		f.checkHooks(stopEvent)
+		f.checkSubs(runs)
+	}
+
+	// Stop forwarding errors, closing the root error channel causing any go routines listening on it to
+	// exit.
+	f.errMtx.Lock()
+	f.errClosed = true
+
+	if f.parent == nil {
+		close(f.errC)
+	}
 
-	// Wait for routines to exit
-	f.wg.Wait()
+	f.errMtx.Unlock()
 
 	// Store done state.
 	f.done.Store(true)
 }
 
-func (f *f) wait() <-chan struct{} {
-	// Create a channel to close once all sub functions are complete.
-	ch := make(chan struct{})
+// wait blocks until the function and all its sub functions are complete.
+func (f *f) wait() {
+	// Wait for the sub functions, including any run whilst waiting.
+	n := f.waitSubs(0)
 
-	// Create a defer function which closes the returned channel
-	// when all sub functions a completed. If there are no sub functions
-	// this will close the channel.
-	defer func() {
-		// We always close the channel when exiting this defer.
-		defer close(ch)
+	// If this is the root function and so do not have a parent we can close our signal channel
+	// now as all sub functions are complete.
+	if f.parent == nil {
+		close(f.signalC)
+	}
 
-		// Obtain read lock
+	// Wait for our function to finish executing
+	<-f.signalC
+
+	// Wait for any sub functions run before our function finished.
+	f.waitSubs(n)
+}
+
+// waitSubs waits for the sub functions from the given index to complete, returning the number waited for.
+// The lock is not held whilst waiting so the function can run more sub functions, which are also waited for.
+func (f *f) waitSubs(from int) int {
+	for i := from; ; i++ {
 		f.mtx.RLock()
 
-		// Loop over the sub functions.
-		for i := range f.subs {
-			// Wait for the sub to be done
-			<-f.subs[i].wait()
+		// Indexes count the subs dropped once complete, which need not be waited for.
+		dropped := f.runs - len(f.subs)
+		i = max(i, dropped)
+
+		if i-dropped >= len(f.subs) {
+			f.mtx.RUnlock()
+
+			return i
 		}
 
-		// Release read lock
+		sub := f.subs[i-dropped]
+
 		f.mtx.RUnlock()
 
-		// If this is the root function and so do not have a parent we can close our signal channel
-		// now as all sub functions are complete.
-		if f.parent == nil {
-			close(f.signalC)
-		}
+		sub.wait()
+	}
+}
 
-		// Wait for our function to finish executing
-		<-f.signalC
-	}()
+// drop removes the sub function once it has completed, so a runner which is run again in its place, for
+// example restarted by a supervisor, does not grow the subs without bound. Only the oldest sub can be
+// dropped so the indexes of those remaining are unchanged, see waitSubs.
+func (f *f) drop(sub *f) {
+	sub.wait()
 
-	return ch
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	if len(f.subs) > 0 && f.subs[0] == sub {
+		f.subs = slices.Delete(f.subs, 0, 1)
+	}
 }
 
+// run runs the runner in a new sub function returning it, or nil if not run. If parallel the sub function
+// is marked as parallel once started, see Parallel.
+//
 // TODO: there is a lot of optimisation to do here and better separation of concerns.
 // Will tackle that at a later date.
-func (f *f) run(ctx context.Context, runner Runner) {
+func (f *f) run(ctx context.Context, runner Runner, parallel bool) *f {
 	// If erred prevent the function from being run.
 	if f.erred.Load() || f.done.Load() {
-		return
+		return nil
 	}
 
-	// Build the name of the new sub f
-	f.mtx.RLock()
-	name := fmt.Sprintf("%s.%d", f.name, len(f.subs)+1)
-	f.mtx.RUnlock()
+	f.mtx.Lock()
 
-	// Create a new sub function
-	sub := newf(name)
-	sub.parent = f
+	// Prevent the function from being run once stopping, checked whilst holding the lock so the sub is
+	// either added before stop stops the subs or not at all.
+	if f.stopped.Load() {
+		f.mtx.Unlock()
 
-	// Add the below go routine to the wg.
-	sub.wg.Add(1)
+		return nil
+	}
 
-	// Start a go routine to push errors up to the parent. This will run until the sub error channel is closed
-	// explicitly on Stop().
-	go func() {
-		defer sub.wg.Done()
+	// Create a new sub function and add it to the list of subs.
+	sub := newSub(f, runner)
+	f.subs = append(f.subs, sub)
+	f.mtx.Unlock()
 
-		for {
-			err, ok := <-sub.errC
-			if !ok {
-				return
-			}
+	// Run the sub function.
+	go sub.exec(ctx, runner, parallel)
 
-			f.errC <- err
-		}
-	}()
+	// Wait for the function to either complete or gets marked as a
+	// parallel function in which case we do not wait.
+	select {
+	case <-sub.signalC:
+	case <-sub.parallelC:
+	}
 
-	// Add the new sub function to the list of subs.
-	f.mtx.Lock()
-	f.subs = append(f.subs, sub)
-	f.mtx.Unlock()
+	return sub
+}
 
-	waitC := make(chan struct{})
-
-	// Wrap the function so we can add a defer to know when the functio has completed.
-	wrapped := func() {
-		defer func() {
-			if r := recover(); r != nil {
-				stack := debug.Stack()
-
-				if err, ok := r.(error); ok {
-					sub.errC <- RuntimeError{
-						Stack: stack,
-						Cause: err,
-					}
-				} else {
-					sub.errC <- RuntimeError{
-						Stack: stack,
-						Cause: PanicError{
-							Cause: r,
-						},
-					}
-				}
+// exec runs the runner with the sub function, closing its signal channel once the runner has returned.
+// If parallel the sub function is marked as parallel before the runner runs.
+func (f *f) exec(ctx context.Context, runner Runner, parallel bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			stack := debug.Stack()
+
+			err := RuntimeError{
+				Stack:  stack,
+				Runner: f.name,
 			}
 
-			// Once the function has completed execution close the signal channel and mark as done.
-			sub.mtx.Lock()
-			if !sub.done.Load() {
-				close(sub.signalC)
+			if cause, ok := r.(error); ok {
+				err.Cause = cause
+			} else {
+				err.Cause = PanicError{
+					Cause: r,
+				}
 			}
 
-			sub.mtx.Unlock()
+			f.report(err)
+		}
+
+		// Once the function has completed execution close the signal channel.
+		close(f.signalC)
+
+		f.emit(EventDone, nil)
 
-			close(waitC)
+		f.runEventHooks(doneEvent)
+
+		if f.strict {
+			f.checkHooks(doneEvent)
+		}
+	}()
 
-			sub.runEventHooks(doneEvent)
-		}()
+	f.emit(EventStart, nil)
 
-		runner.Run(ctx, sub)
+	if parallel {
+		f.Parallel()
 	}
 
-	// Run the wrapped sub f.
-	go wrapped()
+	if !f.accounting {
+		runner.Run(ctx, f)
 
-	// Wait for the function to either complete or gets marked as a
-	// parallel function in which case we do not wait.
-	select {
-	case <-waitC:
-	case <-sub.parallelC:
+		return
 	}
+
+	pprof.Do(ctx, pprof.Labels(RunnerLabel, f.name), func(ctx context.Context) {
+		runner.Run(ctx, f)
+	})
 }
 
 func (f *f) runEventHooks(event eventHook) {
-	for hook := range slices.Values(f.hooks.get(event)) {
+	f.callEventHooks(event, f.hooks.fire(event))
+}
+
+func (f *f) callEventHooks(event eventHook, hooks []EventHookFunc) {
+	if f.strict {
+		f.mtx.Lock()
+		f.called[event] += len(hooks)
+		f.mtx.Unlock()
+	}
+
+	for hook := range slices.Values(hooks) {
 		f.runEventHook(hook)
 	}
 }
 
 func (f *f) runEventHook(hook EventHookFunc) {
 	defer func() {
-		stack := debug.Stack()
-
 		if r := recover(); r != nil {
-			if err, ok := r.(error); ok {
-				f.errC <- CleanupError{
-					Stack: stack,
-					Cause: err,
-				}
+			stack := debug.Stack()
+
+			err := CleanupError{
+				Stack:  stack,
+				Runner: f.name,
+			}
+
+			if cause, ok := r.(error); ok {
+				err.Cause = cause
 			} else {
-				f.errC <- CleanupError{
-					Stack: stack,
-					Cause: PanicError{
-						Cause: r,
-					},
+				err.Cause = PanicError{
+					Cause: r,
 				}
 			}
+
+			f.report(err)
 		}
 	}()
 
@@ -0,0 +1,310 @@
+// Package bench benchmarks the runner tree engine, running, waiting for and stopping trees of various
+// shapes, and publishes the budgets the engine is held to so its overhead stays negligible for services
+// running thousands of short lived runners.
+//
+//	func BenchmarkEngine(b *testing.B) {
+//		bench.Run(b)
+//	}
+//
+//	func BenchmarkStop(b *testing.B) {
+//		for shape := range slices.Values(bench.Shapes) {
+//			b.Run(shape.Name, func(b *testing.B) {
+//				bench.Stop(b, shape)
+//			})
+//		}
+//	}
+//
+//	func TestBudget(t *testing.T) {
+//		bench.Assert(t, bench.DefaultBudget)
+//	}
+package bench
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"slices"
+	"testing"
+	"time"
+
+	"go.krak3n.io/foundation"
+)
+
+// A Shape is the shape of a tree of runners.
+type Shape struct {
+	// Name of the shape, used to name the benchmark.
+	Name string
+	// Depth of the tree.
+	Depth int
+	// Width is the number of runners run by each runner.
+	Width int
+	// Parallel marks every runner as parallel, otherwise runners run sequentially.
+	Parallel bool
+}
+
+// Runners returns the number of runners in the tree, including the root.
+func (s Shape) Runners() int {
+	n, level := 1, 1
+
+	for range s.Depth {
+		level *= s.Width
+		n += level
+	}
+
+	return n
+}
+
+// Shapes are the shapes of trees benchmarked by Run.
+var Shapes = []Shape{
+	{Name: "flat", Depth: 1, Width: 100},
+	{Name: "flat-parallel", Depth: 1, Width: 100, Parallel: true},
+	{Name: "deep", Depth: 100, Width: 1},
+	{Name: "deep-parallel", Depth: 100, Width: 1, Parallel: true},
+	{Name: "tree", Depth: 4, Width: 4},
+	{Name: "tree-parallel", Depth: 4, Width: 4, Parallel: true},
+}
+
+// A Cost is the cost of the engine.
+type Cost struct {
+	// AllocsPerRun is the number of allocations made running a short lived runner.
+	AllocsPerRun float64
+	// GoroutinesPerRun is the number of go routines held by a short lived runner once it has returned,
+	// until its F is stopped.
+	GoroutinesPerRun float64
+	// GoroutinesPerParallel is the number of go routines held by a parallel runner, including its own,
+	// until its F is stopped.
+	GoroutinesPerParallel float64
+}
+
+// DefaultBudget is the budget the engine is held to.
+var DefaultBudget = Cost{
+	AllocsPerRun:          8,
+	GoroutinesPerRun:      0,
+	GoroutinesPerParallel: 1,
+}
+
+// Run runs the benchmarks, reporting the allocations and go routines per runner. The run benchmark runs
+// a short lived runner per operation within a running tree, the tree benchmarks run, wait for and stop a
+// whole tree of each of the Shapes per operation.
+func Run(b *testing.B) {
+	b.Run("run", func(b *testing.B) {
+		ctx := context.Background()
+		f, stop := start()
+
+		defer stop()
+
+		b.ReportAllocs()
+		b.ResetTimer()
+
+		for range b.N {
+			f.Run(ctx, noop)
+		}
+	})
+
+	for shape := range slices.Values(Shapes) {
+		b.Run("tree/"+shape.Name, func(b *testing.B) {
+			Tree(b, shape)
+		})
+	}
+}
+
+// Tree benchmarks running, waiting for and stopping a tree of the given shape, reporting the allocations
+// and go routines per runner.
+func Tree(b *testing.B, shape Shape) {
+	runner := tree(shape, shape.Depth)
+	runners := float64(shape.Runners())
+
+	var peak int
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for range b.N {
+		before := runtime.NumGoroutine()
+
+		i := foundation.Start("bench", runner, foundation.WithoutSignals())
+
+		peak = max(peak, runtime.NumGoroutine()-before)
+
+		i.Stop()
+
+		if err := i.Wait(); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	b.StopTimer()
+
+	b.ReportMetric(float64(peak)/runners, "goroutines/runner")
+	b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N)/runners, "ns/runner")
+}
+
+// Stop benchmarks stopping and waiting for a running tree of the given shape, excluding the time taken
+// to start it, reporting the time per runner.
+func Stop(b *testing.B, shape Shape) {
+	runner := tree(shape, shape.Depth)
+	runners := float64(shape.Runners())
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for range b.N {
+		b.StopTimer()
+
+		i := foundation.Start("bench", runner, foundation.WithoutSignals())
+
+		b.StartTimer()
+
+		i.Stop()
+
+		if err := i.Wait(); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	b.StopTimer()
+
+	b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N)/runners, "ns/runner")
+}
+
+// Measure measures the cost of the engine.
+func Measure() Cost {
+	const n = 1000
+
+	ctx := context.Background()
+
+	var cost Cost
+
+	// Short lived runners run by a running F.
+	f, stop := start()
+
+	cost.AllocsPerRun = testing.AllocsPerRun(n, func() {
+		f.Run(ctx, noop)
+	})
+
+	before := runtime.NumGoroutine()
+
+	for range n {
+		f.Run(ctx, noop)
+	}
+
+	cost.GoroutinesPerRun = settle(before, n)
+
+	stop()
+
+	// Parallel runners run by a running F.
+	f, stop = start()
+
+	before = runtime.NumGoroutine()
+
+	for range n {
+		f.Run(ctx, parallel)
+	}
+
+	cost.GoroutinesPerParallel = settle(before, n)
+
+	stop()
+
+	return cost
+}
+
+// Check returns an error if the cost exceeds the budget.
+func Check(cost, budget Cost) error {
+	var errs []error
+
+	if cost.AllocsPerRun > budget.AllocsPerRun {
+		errs = append(errs, fmt.Errorf("%.1f allocations per run exceeds budget of %.1f", cost.AllocsPerRun, budget.AllocsPerRun))
+	}
+
+	if cost.GoroutinesPerRun > budget.GoroutinesPerRun {
+		errs = append(errs, fmt.Errorf("%.2f go routines per run exceeds budget of %.2f", cost.GoroutinesPerRun, budget.GoroutinesPerRun))
+	}
+
+	if cost.GoroutinesPerParallel > budget.GoroutinesPerParallel {
+		errs = append(errs, fmt.Errorf("%.2f go routines per parallel runner exceeds budget of %.2f", cost.GoroutinesPerParallel, budget.GoroutinesPerParallel))
+	}
+
+	return errors.Join(errs...)
+}
+
+// Assert measures the cost of the engine failing the test if it exceeds the budget.
+func Assert(t testing.TB, budget Cost) {
+	t.Helper()
+
+	if err := Check(Measure(), budget); err != nil {
+		t.Error(err)
+	}
+}
+
+// noop is a short lived runner.
+var noop = foundation.RunFunc(func(context.Context, foundation.F) {})
+
+// parallel is a parallel runner which blocks until stopped.
+var parallel = foundation.RunFunc(func(_ context.Context, f foundation.F) {
+	done := make(chan struct{})
+
+	f.On().Stop(func() {
+		close(done)
+	})
+
+	f.Parallel()
+
+	<-done
+})
+
+// start starts an instance returning its root F and a function which stops it.
+func start() (foundation.F, func()) {
+	i := foundation.Start("bench", parallel, foundation.WithoutSignals())
+
+	return i.F(), func() {
+		i.Stop()
+		_ = i.Wait()
+	}
+}
+
+// tree returns a runner which runs the runners of the tree of the given shape below it, depth levels deep.
+func tree(shape Shape, depth int) foundation.Runner {
+	var children []foundation.Runner
+
+	if depth > 0 {
+		child := tree(shape, depth-1)
+
+		for range shape.Width {
+			children = append(children, child)
+		}
+	}
+
+	if !shape.Parallel {
+		return foundation.RunFunc(func(ctx context.Context, f foundation.F) {
+			f.Run(ctx, children...)
+		})
+	}
+
+	return foundation.RunFunc(func(ctx context.Context, f foundation.F) {
+		done := make(chan struct{})
+
+		f.On().Stop(func() {
+			close(done)
+		})
+
+		// Run the children before marking as parallel so the whole tree is running once Start returns.
+		f.Run(ctx, children...)
+
+		f.Parallel()
+
+		<-done
+	})
+}
+
+// settle returns the go routines held per runner of the n runners run since before, giving go routines
+// which exit asynchronously time to do so.
+func settle(before, n int) float64 {
+	deadline := time.Now().Add(100 * time.Millisecond)
+
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	return float64(max(runtime.NumGoroutine()-before, 0)) / float64(n)
+}
@@ -0,0 +1,40 @@
+// Package foundationtest runs foundation runners in tests.
+package foundationtest
+
+import (
+	"testing"
+	"time"
+
+	"go.krak3n.io/foundation"
+)
+
+// StopTimeout is how long an instance started with Start is given to stop when the test finishes.
+var StopTimeout = 10 * time.Second
+
+// Start starts the runner, see foundation.Start, in strict mode without handling os signals, see
+// foundation.WithStrict. Start returns once the runner has returned or marked itself as parallel. When the test finishes the instance is stopped and
+// the test fails if it does not stop within StopTimeout or stopped with an error. Tests expecting an
+// error should use foundation.Start directly.
+func Start(t testing.TB, runner foundation.Runner, opts ...foundation.RunOption) *foundation.Instance {
+	t.Helper()
+
+	i := foundation.Start(t.Name(), runner, append([]foundation.RunOption{foundation.WithoutSignals(), foundation.WithStrict()}, opts...)...)
+
+	t.Cleanup(func() {
+		i.Stop()
+
+		select {
+		case <-i.Done():
+		case <-time.After(StopTimeout):
+			t.Errorf("foundation did not stop within %s", StopTimeout)
+
+			return
+		}
+
+		if err := i.Wait(); err != nil {
+			t.Errorf("foundation stopped with error: %v", err)
+		}
+	})
+
+	return i
+}
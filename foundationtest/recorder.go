@@ -0,0 +1,89 @@
+package foundationtest
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.krak3n.io/foundation"
+)
+
+// A Recorder records the lifecycle events of a foundation, so tests can assert the order runners start
+// and stop in.
+//
+//	rec := foundationtest.NewRecorder()
+//
+//	i := foundationtest.Start(t, runner, rec.Option())
+//	i.Stop()
+//	i.Wait()
+//
+//	rec.AssertOrder(t,
+//		foundation.Event{Kind: foundation.EventStop, Runner: "TestShutdown.1.2"}, // consumer
+//		foundation.Event{Kind: foundation.EventStop, Runner: "TestShutdown.1.1"}, // database pool
+//	)
+type Recorder struct {
+	mtx    sync.Mutex
+	events []foundation.Event
+}
+
+// NewRecorder returns an empty Recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// Record records the event, it is a foundation.Interceptor.
+func (r *Recorder) Record(e foundation.Event) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	r.events = append(r.events, e)
+}
+
+// Option returns the RunOption which records events with the Recorder.
+func (r *Recorder) Option() foundation.RunOption {
+	return foundation.WithInterceptor(r.Record)
+}
+
+// Events returns the recorded events in the order they occurred.
+func (r *Recorder) Events() []foundation.Event {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	return slices.Clone(r.events)
+}
+
+// Index returns the index of the first recorded event matching the kind and runner, or -1 if none match.
+func (r *Recorder) Index(kind foundation.EventKind, runner string) int {
+	return slices.IndexFunc(r.Events(), func(e foundation.Event) bool {
+		return e.Kind == kind && e.Runner == runner
+	})
+}
+
+// AssertOrder fails the test unless events matching the kind and runner of each of the given events were
+// recorded in the given order, other events may occur between them.
+func (r *Recorder) AssertOrder(t testing.TB, events ...foundation.Event) {
+	t.Helper()
+
+	recorded := r.Events()
+
+	var next int
+
+	for e := range slices.Values(recorded) {
+		if next < len(events) && e.Kind == events[next].Kind && e.Runner == events[next].Runner {
+			next++
+		}
+	}
+
+	if next == len(events) {
+		return
+	}
+
+	var b strings.Builder
+
+	for e := range slices.Values(recorded) {
+		b.WriteString("\n\t" + e.Kind.String() + " " + e.Runner)
+	}
+
+	t.Errorf("no %s event for %s in order after the preceding events, recorded:%s", events[next].Kind, events[next].Runner, b.String())
+}
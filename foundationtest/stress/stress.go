@@ -0,0 +1,216 @@
+// Package stress stress tests the runner tree engine with randomly generated trees of sequential and
+// parallel runners which error, panic and stop at random times, checking that every tree stops without
+// deadlocking or leaking goroutines and that errors propagate to the root.
+//
+//	func TestEngine(t *testing.T) {
+//		stress.Run(t, 1000, stress.DefaultConfig())
+//	}
+//
+// Failures report the seed of the tree so it can be reproduced with Check.
+package stress
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.krak3n.io/foundation"
+)
+
+// Config configures the generated trees.
+type Config struct {
+	// MaxDepth is the maximum depth of the tree.
+	MaxDepth int
+	// MaxWidth is the maximum number of runners run by each runner.
+	MaxWidth int
+	// ParallelRate is the probability a runner is parallel.
+	ParallelRate float64
+	// ErrorRate is the probability a runner errors.
+	ErrorRate float64
+	// PanicRate is the probability a runner panics.
+	PanicRate float64
+	// MaxDelay is the maximum time a runner runs for before returning, erroring or panicking.
+	MaxDelay time.Duration
+	// MaxStop is the maximum time after starting the tree is stopped.
+	MaxStop time.Duration
+	// Timeout is how long the tree is given to stop before it is considered deadlocked.
+	Timeout time.Duration
+}
+
+// DefaultConfig returns the default configuration.
+func DefaultConfig() Config {
+	return Config{
+		MaxDepth:     4,
+		MaxWidth:     4,
+		ParallelRate: 0.5,
+		ErrorRate:    0.05,
+		PanicRate:    0.02,
+		MaxDelay:     2 * time.Millisecond,
+		MaxStop:      5 * time.Millisecond,
+		Timeout:      5 * time.Second,
+	}
+}
+
+// Run checks n trees generated from the seeds 1 to n, failing the test with the seed of every tree which
+// fails its checks.
+func Run(t testing.TB, n int, cfg Config) {
+	t.Helper()
+
+	for seed := range uint64(n) {
+		if err := Check(seed+1, cfg); err != nil {
+			t.Errorf("seed %d: %v", seed+1, err)
+		}
+	}
+}
+
+// errInjected is the error injected by runners.
+var errInjected = errors.New("injected error")
+
+// tree is the state shared by the runners of a generated tree.
+type tree struct {
+	cfg     Config
+	rand    *rand.Rand
+	stopped atomic.Bool
+	erred   atomic.Bool
+	failed  atomic.Bool
+}
+
+// Check generates a tree from the seed, runs it in strict mode, see foundation.WithStrict, and stops it
+// after a random delay. It returns an error if the tree does not stop within the timeout, leaks
+// goroutines, stops with an error when no runner failed, or stops without an error when a runner failed
+// before the tree was stopped.
+func Check(seed uint64, cfg Config) error {
+	before := runtime.NumGoroutine()
+
+	t := &tree{
+		cfg:  cfg,
+		rand: rand.New(rand.NewPCG(seed, seed)),
+	}
+
+	// Generate the tree up front so the random source is not shared between go routines.
+	root := t.node(0, true)
+	stop := t.duration(cfg.MaxStop)
+
+	i := foundation.Start("stress", root, foundation.WithoutSignals(), foundation.WithStrict())
+
+	time.Sleep(stop)
+
+	t.stopped.Store(true)
+	i.Stop()
+
+	select {
+	case <-i.Done():
+	case <-time.After(cfg.Timeout):
+		return fmt.Errorf("deadlock: tree did not stop within %s", cfg.Timeout)
+	}
+
+	err := i.Wait()
+
+	switch {
+	case err != nil && !t.erred.Load():
+		return fmt.Errorf("stopped with an error when no runner failed: %w", err)
+	case err == nil && t.failed.Load():
+		return errors.New("stopped without an error when a runner failed before stopping")
+	}
+
+	// Go routines exit asynchronously once the tree has stopped, give them time to do so.
+	deadline := time.Now().Add(time.Second)
+
+	for runtime.NumGoroutine() > before {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("leaked %d go routines", runtime.NumGoroutine()-before)
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	return nil
+}
+
+// node generates a runner at the given depth, the root is always parallel so Start returns.
+func (t *tree) node(depth int, root bool) foundation.Runner {
+	parallel := root || t.rand.Float64() < t.cfg.ParallelRate
+	delay := t.duration(t.cfg.MaxDelay)
+
+	var children []foundation.Runner
+
+	if depth < t.cfg.MaxDepth {
+		for range t.rand.IntN(t.cfg.MaxWidth + 1) {
+			children = append(children, t.node(depth+1, false))
+		}
+	}
+
+	var fail func(f foundation.F)
+
+	switch p := t.rand.Float64(); {
+	case root:
+	case p < t.cfg.ErrorRate:
+		fail = func(f foundation.F) {
+			t.fail()
+			f.Error(errInjected)
+		}
+	case p < t.cfg.ErrorRate+t.cfg.PanicRate:
+		fail = func(foundation.F) {
+			t.fail()
+			panic("injected panic")
+		}
+	}
+
+	return foundation.RunFunc(func(ctx context.Context, f foundation.F) {
+		if !parallel {
+			f.Run(ctx, children...)
+
+			time.Sleep(delay)
+
+			if fail != nil {
+				fail(f)
+			}
+
+			return
+		}
+
+		done := make(chan struct{})
+
+		f.On().Stop(func() {
+			close(done)
+		})
+
+		f.Parallel()
+
+		f.Run(ctx, children...)
+
+		if fail != nil {
+			select {
+			case <-done:
+				return
+			case <-time.After(delay):
+				fail(f)
+			}
+		}
+
+		<-done
+	})
+}
+
+// fail records a runner failing, and whether it failed before the tree was stopped.
+func (t *tree) fail() {
+	t.erred.Store(true)
+
+	if !t.stopped.Load() {
+		t.failed.Store(true)
+	}
+}
+
+// duration returns a random duration up to max.
+func (t *tree) duration(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+
+	return time.Duration(t.rand.Int64N(int64(max)))
+}
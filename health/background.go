@@ -0,0 +1,266 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.krak3n.io/foundation"
+	"go.krak3n.io/foundation/health/probe"
+	"go.krak3n.io/foundation/tick"
+)
+
+// ErrNotProbed is the error a sensor fails with when probing in the background until it has first run.
+var ErrNotProbed = errors.New("not yet probed")
+
+// An Option configures the health Runner, see RunWith.
+type Option interface {
+	apply(*config)
+}
+
+// Options is one or more Option.
+type Options []Option
+
+func (opts Options) apply(cfg *config) {
+	for opt := range slices.Values(opts) {
+		if opt != nil {
+			opt.apply(cfg)
+		}
+	}
+}
+
+type optionFunc func(*config)
+
+func (f optionFunc) apply(cfg *config) {
+	f(cfg)
+}
+
+// config holds the health Runner configuration.
+type config struct {
+	background bool
+	intervals  map[probe.Mode]time.Duration
+}
+
+// WithBackgroundProbing runs the sensors of each mode in the background on an interval, serving their
+// last results rather than running them on every request. Liveness sensors are run every 5 seconds,
+// readiness sensors every 10 seconds and startup sensors every second until they all pass, see
+// WithProbeInterval.
+func WithBackgroundProbing() Option {
+	return optionFunc(func(cfg *config) {
+		cfg.background = true
+	})
+}
+
+// WithProbeInterval sets how often the sensors of the mode are run when probing in the background,
+// enabling it, see WithBackgroundProbing. The mode may combine several modes.
+func WithProbeInterval(mode probe.Mode, d time.Duration) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.background = true
+
+		for m := range slices.Values(modes) {
+			if mode&m != 0 {
+				cfg.intervals[m] = d
+			}
+		}
+	})
+}
+
+// modes are the individual sensor modes.
+var modes = []probe.Mode{probe.StartupMode, probe.LivenessMode, probe.ReadinessMode}
+
+// A prober runs sensors in the background, it is a ModeSensorRegistry of sensors which return their last
+// result.
+type prober struct {
+	intervals map[probe.Mode]time.Duration
+	registry  *probe.Registry
+	// Set once every runner has run and so registered its sensors, startup sensors are probed until then.
+	registered atomic.Bool
+
+	mtx     sync.RWMutex
+	results map[probed]probe.SensorStatus
+	// The number of times each sensor has not passed in the mode since it last passed.
+	failures map[probed]int
+}
+
+// probed is a sensor, by name as the registry keeps them unique, probed in a mode. A sensor of several
+// modes is probed in each on its interval. Sensors are not keys themselves as they need not be comparable.
+type probed struct {
+	mode   probe.Mode
+	sensor string
+}
+
+// newProber returns a prober running the sensors of each mode on its interval.
+func newProber(intervals map[probe.Mode]time.Duration, registry *probe.Registry) *prober {
+	return &prober{
+		intervals: intervals,
+		registry:  registry,
+		results:   make(map[probed]probe.SensorStatus),
+		failures:  make(map[probed]int),
+	}
+}
+
+// Run runs the sensors of each mode on its interval until stopped, startup sensors only until they all
+// pass once every sensor has been registered.
+func (p *prober) Run(ctx context.Context, f foundation.F) {
+	for m := range slices.Values(modes) {
+		d, ok := p.intervals[m]
+		if !ok || d <= 0 {
+			continue
+		}
+
+		// Probe once now so results are available before the first tick.
+		p.probe(ctx, m, d)
+
+		tick.Run(ctx, f, d, func(ctx context.Context, t tick.Ticker) {
+			if registered := p.registered.Load(); p.probe(ctx, m, d) && registered && m == probe.StartupMode {
+				t.Stop()
+			}
+		})
+	}
+}
+
+// probe runs the sensors of the mode, bounded by the interval, recording their results. The sensors are
+// given the mode and their attempt since they last passed through the context, see
+// probe.ModeFromContext. Returns true if they all passed.
+func (p *prober) probe(ctx context.Context, mode probe.Mode, d time.Duration) bool {
+	ctx, cancel := context.WithTimeout(ctx, d)
+	defer cancel()
+
+	sensors := p.registry.SensorsFor(mode)
+	attempts := make([]probe.Sensor, len(sensors))
+
+	p.mtx.RLock()
+
+	for i, s := range sensors {
+		if s != nil {
+			attempts[i] = attempted{Sensor: s, attempt: p.failures[probed{mode, s.Name()}] + 1}
+		}
+	}
+
+	p.mtx.RUnlock()
+
+	statuses := probe.RunAll(probe.WithMode(ctx, mode), attempts...)
+
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	passed := true
+
+	for i, s := range sensors {
+		if s == nil {
+			continue
+		}
+
+		key := probed{mode, s.Name()}
+
+		if statuses[i].Status != probe.StatusSuccess {
+			passed = false
+			p.failures[key]++
+		} else {
+			delete(p.failures, key)
+		}
+
+		p.results[key] = statuses[i]
+	}
+
+	return passed
+}
+
+// attempted is a sensor run with the attempt of running it since it last passed, see
+// probe.AttemptFromContext.
+type attempted struct {
+	probe.Sensor
+
+	attempt int
+}
+
+func (s attempted) Run(ctx context.Context) error {
+	return s.Sensor.Run(probe.WithAttempt(ctx, s.attempt))
+}
+
+func (s attempted) DependsOn() []string {
+	if d, ok := s.Sensor.(probe.Dependent); ok {
+		return d.DependsOn()
+	}
+
+	return nil
+}
+
+// Sensors returns the registered sensors, each returning its last result.
+func (p *prober) Sensors() []probe.Sensor {
+	return p.cached(p.registry.Sensors())
+}
+
+// SensorsFor returns the registered sensors which run in any of the given modes, each returning its last
+// result.
+func (p *prober) SensorsFor(mode probe.Mode) []probe.Sensor {
+	return p.cached(p.registry.SensorsFor(mode))
+}
+
+// cached returns sensors which return the last result of each of the sensors in the mode they are run in,
+// recording its details, see last.
+func (p *prober) cached(sensors []probe.Sensor) []probe.Sensor {
+	cached := make([]probe.Sensor, 0, len(sensors))
+
+	for s := range slices.Values(sensors) {
+		if s == nil {
+			continue
+		}
+
+		cached = append(cached, probe.NewSensor(s.Name(), s.Mode(), func(ctx context.Context) error {
+			mode, ok := probe.ModeFromContext(ctx)
+			if !ok {
+				mode = probe.AllModes
+			}
+
+			p.mtx.RLock()
+			defer p.mtx.RUnlock()
+
+			status, ok := p.last(s, mode)
+			if !ok {
+				return ErrNotProbed
+			}
+
+			for k, v := range status.Details {
+				probe.Detail(ctx, k, v)
+			}
+
+			return status.Err
+		}))
+	}
+
+	return cached
+}
+
+// last returns the last result of the sensor in the mode. If the mode combines several modes the first
+// result which did not pass is returned, otherwise the first which did. Called with the lock held.
+func (p *prober) last(s probe.Sensor, mode probe.Mode) (probe.SensorStatus, bool) {
+	var (
+		last  probe.SensorStatus
+		found bool
+	)
+
+	for m := range slices.Values(modes) {
+		if mode&m == 0 {
+			continue
+		}
+
+		status, ok := p.results[probed{m, s.Name()}]
+		if !ok {
+			continue
+		}
+
+		if status.Status != probe.StatusSuccess {
+			return status, true
+		}
+
+		if !found {
+			last, found = status, true
+		}
+	}
+
+	return last, found
+}
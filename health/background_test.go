@@ -0,0 +1,138 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.krak3n.io/foundation/health/probe"
+)
+
+// sliceSensor is a sensor which is not comparable, so can not be a map key.
+type sliceSensor struct {
+	name  string
+	mode  probe.Mode
+	fails []probe.Mode
+}
+
+func (s sliceSensor) Name() string     { return s.name }
+func (s sliceSensor) Mode() probe.Mode { return s.mode }
+
+func (s sliceSensor) Run(ctx context.Context) error {
+	mode, _ := probe.ModeFromContext(ctx)
+
+	for _, m := range s.fails {
+		if m == mode {
+			return errNotReady
+		}
+	}
+
+	return nil
+}
+
+var errNotReady = errors.New("not ready")
+
+// last runs the cached sensor of the prober with the name in the mode, returning its error.
+func last(t *testing.T, p *prober, name string, mode probe.Mode) error {
+	t.Helper()
+
+	for _, s := range p.SensorsFor(mode) {
+		if s.Name() == name {
+			return s.Run(probe.WithMode(context.Background(), mode))
+		}
+	}
+
+	t.Fatalf("sensor %s not found for mode %s", name, mode)
+
+	return nil
+}
+
+func TestProberModes(t *testing.T) {
+	registry := probe.NewRegistry()
+	registry.Register(sliceSensor{
+		name:  "db",
+		mode:  probe.LivenessMode | probe.ReadinessMode,
+		fails: []probe.Mode{probe.ReadinessMode},
+	})
+
+	p := newProber(nil, registry)
+
+	if err := last(t, p, "db", probe.LivenessMode); !errors.Is(err, ErrNotProbed) {
+		t.Errorf("want ErrNotProbed before probing, got %v", err)
+	}
+
+	if !p.probe(context.Background(), probe.LivenessMode, time.Second) {
+		t.Error("want liveness to pass")
+	}
+
+	if p.probe(context.Background(), probe.ReadinessMode, time.Second) {
+		t.Error("want readiness to fail")
+	}
+
+	tests := []struct {
+		mode probe.Mode
+		err  error
+	}{
+		{mode: probe.LivenessMode},
+		{mode: probe.ReadinessMode, err: errNotReady},
+		// A combined mode reports the failure of any of its modes.
+		{mode: probe.LivenessMode | probe.ReadinessMode, err: errNotReady},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode.String(), func(t *testing.T) {
+			if err := last(t, p, "db", tt.mode); !errors.Is(err, tt.err) {
+				t.Errorf("want %v, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestProberAttempts(t *testing.T) {
+	var attempts []int
+
+	fail := true
+
+	registry := probe.NewRegistry()
+	registry.Register(probe.NewSensor("flaky", probe.ReadinessMode|probe.LivenessMode, func(ctx context.Context) error {
+		if mode, _ := probe.ModeFromContext(ctx); mode != probe.ReadinessMode {
+			return nil
+		}
+
+		attempt, _ := probe.AttemptFromContext(ctx)
+		attempts = append(attempts, attempt)
+
+		if fail {
+			return errNotReady
+		}
+
+		return nil
+	}))
+
+	p := newProber(nil, registry)
+
+	for range 3 {
+		p.probe(context.Background(), probe.ReadinessMode, time.Second)
+
+		// Passing liveness probes do not reset the readiness attempts.
+		p.probe(context.Background(), probe.LivenessMode, time.Second)
+	}
+
+	fail = false
+
+	p.probe(context.Background(), probe.ReadinessMode, time.Second)
+	p.probe(context.Background(), probe.ReadinessMode, time.Second)
+
+	want := []int{1, 2, 3, 4, 1}
+
+	if len(attempts) != len(want) {
+		t.Fatalf("want attempts %v, got %v", want, attempts)
+	}
+
+	for i := range want {
+		if attempts[i] != want[i] {
+			t.Fatalf("want attempts %v, got %v", want, attempts)
+		}
+	}
+}
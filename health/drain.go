@@ -0,0 +1,79 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+
+	"go.krak3n.io/foundation"
+	"go.krak3n.io/foundation/health/probe"
+)
+
+// ErrDraining is the error the drain sensor fails with whilst draining, see Drain.
+var ErrDraining = errors.New("draining")
+
+var (
+	draining atomic.Bool
+	drained  sync.Map // *probe.Registry the drain sensor is registered with.
+)
+
+// Drain fails readiness, so load balancers stop sending traffic, whilst the process otherwise keeps
+// running, for example before maintenance. The drain sensor is registered with the global registry on
+// first use, see DrainContext.
+func Drain() {
+	DrainContext(context.Background())
+}
+
+// DrainContext is Drain registering the drain sensor with the registry of the context on first use, see
+// probe.RegistryFromContext, so an instance given its own registry fails its own readiness. Draining
+// is shared by the process, Undrain restores readiness for every registry.
+func DrainContext(ctx context.Context) {
+	if r := probe.RegistryFromContext(ctx); !loaded(r) {
+		r.Register(probe.NewSensor("drain", probe.ReadinessMode, func(context.Context) error {
+			if draining.Load() {
+				return ErrDraining
+			}
+
+			return nil
+		}))
+	}
+
+	draining.Store(true)
+}
+
+// loaded reports whether the drain sensor has already been registered with the registry.
+func loaded(r *probe.Registry) bool {
+	_, ok := drained.LoadOrStore(r, struct{}{})
+
+	return ok
+}
+
+// Undrain restores readiness failed by Drain.
+func Undrain() {
+	draining.Store(false)
+}
+
+// Draining reports whether readiness has been failed by Drain.
+func Draining() bool {
+	return draining.Load()
+}
+
+// DrainAction returns a foundation.SignalAction which toggles draining, see DrainContext, so an operator
+// can drain and restore a process by signalling it.
+//
+//	foundation.Run("api", runner, foundation.WithSignalAction(syscall.SIGUSR1, health.DrainAction()))
+func DrainAction() foundation.SignalAction {
+	return func(ctx context.Context, _ foundation.F) {
+		if Draining() {
+			Undrain()
+			slog.InfoContext(ctx, "readiness restored")
+
+			return
+		}
+
+		DrainContext(ctx)
+		slog.InfoContext(ctx, "draining readiness")
+	}
+}
@@ -1,15 +1,24 @@
 package health
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
 	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"go.krak3n.io/foundation/health/probe"
 )
 
-// ServeMux returns a *http.ServeMux for routing http requests to the HTTP health check handler.
+// ServeMux returns a *http.ServeMux for routing http requests to the HTTP health check handler. The GET
+// patterns also match HEAD requests, as issued by some load balancers.
 func ServeMux(prefix string, handler http.Handler) *http.ServeMux {
 	mux := http.NewServeMux()
 
@@ -23,24 +32,83 @@ func ServeMux(prefix string, handler http.Handler) *http.ServeMux {
 	return mux
 }
 
+// A HandlerOption configures a Handler.
+type HandlerOption interface {
+	applyHandler(*Handler)
+}
+
+type handlerOptionFunc func(*Handler)
+
+func (f handlerOptionFunc) applyHandler(h *Handler) {
+	f(h)
+}
+
+// WithErrors reports the error each failed sensor failed with. Errors may reveal details of the
+// service's dependencies so should only be enabled for endpoints which are not publicly exposed.
+func WithErrors() HandlerOption {
+	return handlerOptionFunc(func(h *Handler) {
+		h.errors = true
+	})
+}
+
+// WithRegistry sets the registry of the sensors the handler runs, defaults to DefaultSensorRegistry.
+func WithRegistry(registry SensorRegistry) HandlerOption {
+	return handlerOptionFunc(func(h *Handler) {
+		h.registry = registry
+	})
+}
+
+// WithTimeout bounds how long the sensors may run for each request, sensors still running once it has
+// passed are reported failed without waiting for them, see probe.Run. Defaults to no bound other than the
+// request's.
+func WithTimeout(d time.Duration) HandlerOption {
+	return handlerOptionFunc(func(h *Handler) {
+		h.timeout = d
+	})
+}
+
 // A Handler is a HTTP handler for serving the HTTP health check endpoint.
 type Handler struct {
 	registry  SensorRegistry
 	marshaler ReportsMarshaler
+	errors    bool
+	timeout   time.Duration
 }
 
 // JSONHandler returns a JSON HTTP health check endpoint handler.
-func JSONHandler() http.Handler {
-	return &Handler{
+func JSONHandler(opts ...HandlerOption) http.Handler {
+	return NewHandler(JSONReportMarshaler(), opts...)
+}
+
+// NewHandler returns a HTTP health check endpoint handler which runs the registered sensors, marshaling
+// the reports with the given marshaler.
+func NewHandler(marshaler ReportsMarshaler, opts ...HandlerOption) http.Handler {
+	h := &Handler{
 		registry:  DefaultSensorRegistry(),
-		marshaler: JSONReportMarshaler(),
+		marshaler: marshaler,
 	}
+
+	for opt := range slices.Values(opts) {
+		if opt != nil {
+			opt.applyHandler(h)
+		}
+	}
+
+	return h
 }
 
-// ServeHTTP runs the sensors capturing the status and writing the report back on the response.
+// ServeHTTP runs the sensors capturing the status and writing the report back on the response, gzip
+// compressed if the client accepts it. HEAD requests are answered with the status and headers only.
 func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+
+		return
+	}
+
 	mode := probe.AllModes
 
 	if v := r.PathValue("mode"); v != "" {
@@ -53,24 +121,38 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	sensors := slices.DeleteFunc(slices.Clone(h.registry.Sensors()), func(s probe.Sensor) bool {
-		return s.Mode()&mode == 0
-	})
+	sensors := sensorsFor(h.registry, mode)
 
 	status := http.StatusOK
 
 	reports := make([]Report, 0)
 
-	for s := range probe.Run(ctx, sensors...) {
+	runCtx := probe.WithMode(ctx, mode)
+
+	if h.timeout > 0 {
+		var cancel context.CancelFunc
+
+		runCtx, cancel = context.WithTimeout(runCtx, h.timeout)
+		defer cancel()
+	}
+
+	for s := range probe.Run(runCtx, sensors...) {
 		if s.Status == probe.StatusFailed {
 			status = http.StatusServiceUnavailable
 		}
 
-		reports = append(reports, Report{
-			Name:   s.Name,
-			Mode:   s.Mode,
-			Status: s.Status,
-		})
+		report := Report{
+			Name:    s.Name,
+			Mode:    s.Mode,
+			Status:  s.Status,
+			Details: s.Details,
+		}
+
+		if h.errors && s.Err != nil {
+			report.Error = s.Err.Error()
+		}
+
+		reports = append(reports, report)
 	}
 
 	b, err := h.marshaler.MarshalReports(reports...)
@@ -82,9 +164,74 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", h.marshaler.ContentType())
+	w.Header().Add("Vary", "Accept-Encoding")
+
+	if acceptsGzip(r.Header.Get("Accept-Encoding")) {
+		if gz, err := compress(b); err != nil {
+			slog.ErrorContext(ctx, "failed to compress health probe sensor reports", slog.String("err", err.Error()))
+		} else {
+			w.Header().Set("Content-Encoding", "gzip")
+
+			b = gz
+		}
+	}
+
+	w.Header().Set("Content-Length", strconv.Itoa(len(b)))
 	w.WriteHeader(status)
 
+	// HEAD requests only need the status, the headers describe the body a GET request would return.
+	if r.Method == http.MethodHead {
+		return
+	}
+
 	if _, err := w.Write(b); err != nil {
 		slog.ErrorContext(ctx, "failed to write health probe sensor reports", slog.String("err", err.Error()))
 	}
 }
+
+// acceptsGzip reports whether the Accept-Encoding header accepts gzip.
+func acceptsGzip(header string) bool {
+	for part := range strings.SplitSeq(header, ",") {
+		encoding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+
+		if !strings.EqualFold(encoding, "gzip") && encoding != "*" {
+			continue
+		}
+
+		// q=0 means not acceptable.
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok && strings.Trim(q, "0.") == "" {
+			return false
+		}
+
+		return true
+	}
+
+	return false
+}
+
+// gzipWriters pools gzip writers as health endpoints are requested frequently.
+var gzipWriters = sync.Pool{
+	New: func() any {
+		return gzip.NewWriter(io.Discard)
+	},
+}
+
+// compress returns the gzip compressed bytes.
+func compress(b []byte) ([]byte, error) {
+	var buf bytes.Buffer
+
+	gz := gzipWriters.Get().(*gzip.Writer)
+	defer gzipWriters.Put(gz)
+
+	gz.Reset(&buf)
+
+	if _, err := gz.Write(b); err != nil {
+		return nil, err
+	}
+
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
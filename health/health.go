@@ -3,8 +3,11 @@ package health
 import (
 	"context"
 	stdhttp "net/http"
+	"time"
 
 	"go.krak3n.io/foundation"
+	"go.krak3n.io/foundation/buildinfo"
+	"go.krak3n.io/foundation/health/probe"
 	"go.krak3n.io/foundation/transport/http"
 )
 
@@ -14,6 +17,26 @@ import (
 // As soon as a stop signal is received the server will respond with a 503.
 // The server is the last thing to stop.
 func Run(runners ...foundation.Runner) foundation.Runner {
+	return RunWith(nil, runners...)
+}
+
+// RunWith returns a foundation.Runner which runs the health check server as Run does, configured with
+// the options.
+//
+//	health.RunWith([]health.Option{
+//		health.WithProbeInterval(probe.ReadinessMode, 30*time.Second),
+//	}, api)
+func RunWith(opts []Option, runners ...foundation.Runner) foundation.Runner {
+	cfg := config{
+		intervals: map[probe.Mode]time.Duration{
+			probe.StartupMode:   time.Second,
+			probe.LivenessMode:  5 * time.Second,
+			probe.ReadinessMode: 10 * time.Second,
+		},
+	}
+
+	Options(opts).apply(&cfg)
+
 	return foundation.RunFunc(func(ctx context.Context, f foundation.F) {
 		// Track the state of whether we want the health check server to response available or not.
 		// We want the server to the first thing we start but to only allow sensors to be checked
@@ -22,6 +45,25 @@ func Run(runners ...foundation.Runner) foundation.Runner {
 		// before the runners have been told to stop.
 		var available bool
 
+		// Sensors are read from the registry of the context, the global registry unless the instance has
+		// been given its own, see probe.ContextWithRegistry.
+		registry := probe.RegistryFromContext(ctx)
+
+		var p *prober
+
+		hopts := []HandlerOption{WithRegistry(registry)}
+
+		// Serve the results of the sensors run in the background, probing until the server stops.
+		if cfg.background {
+			p = newProber(cfg.intervals, registry)
+
+			f.Run(ctx, p)
+
+			hopts = append(hopts, WithRegistry(p))
+		}
+
+		h := handler(f, hopts...)
+
 		// Start a standard HTTP server serving on 3417 by default
 		f.Run(ctx, http.Run(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
 			if !available {
@@ -30,7 +72,7 @@ func Run(runners ...foundation.Runner) foundation.Runner {
 				return
 			}
 
-			ServeMux("/_health", JSONHandler()).ServeHTTP(w, r)
+			ServeMux("/_health", h).ServeHTTP(w, r)
 		}), http.WtihServerAddress("127.0.0.1:3417")))
 
 		// Add a new runner that is the first to stop which sets the HTTP health check server as unavailable
@@ -43,9 +85,23 @@ func Run(runners ...foundation.Runner) foundation.Runner {
 		// Now all probes should be registered we can mark the server as generally available
 		f.On().Done(func() {
 			available = true
+
+			if p != nil {
+				p.registered.Store(true)
+			}
 		})
 
 		// Run the runners
 		f.Run(ctx, runners...)
 	})
 }
+
+// handler returns the JSON health check handler, enveloping the reports with the build information if
+// it is in the value store, see buildinfo.Run.
+func handler(f foundation.F, opts ...HandlerOption) stdhttp.Handler {
+	if info, ok := buildinfo.Get(f); ok {
+		return NewHandler(JSONEnvelopeReportMarshaler(info.Fields()), opts...)
+	}
+
+	return JSONHandler(opts...)
+}
@@ -0,0 +1,118 @@
+// Package healthtest provides helpers for golden file testing health reports, so changes to the format
+// of the health payload are caught in review.
+//
+//	reports := healthtest.Collect(sensors...)
+//
+//	healthtest.Golden(t, "testdata/health.json", healthtest.Render(t, health.JSONReportMarshaler(), reports))
+//
+// Run the tests with UPDATE_GOLDEN=1 to write the golden files.
+package healthtest
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"go.krak3n.io/foundation/health"
+	"go.krak3n.io/foundation/health/probe"
+)
+
+// A RedactFunc redacts values of a report which change between runs, for example addresses or errors.
+type RedactFunc func(*health.Report)
+
+// RedactErrors replaces the error of every failed report with "REDACTED".
+func RedactErrors() RedactFunc {
+	return func(r *health.Report) {
+		if r.Error != "" {
+			r.Error = "REDACTED"
+		}
+	}
+}
+
+// RedactName replaces the name of every report with the given name with the replacement, for example a
+// sensor named after a random port.
+func RedactName(name, replacement string) RedactFunc {
+	return func(r *health.Report) {
+		if r.Name == name {
+			r.Name = replacement
+		}
+	}
+}
+
+// Collect runs the sensors returning their reports, including the errors of failed sensors.
+func Collect(sensors ...probe.Sensor) []health.Report {
+	var reports []health.Report
+
+	for s := range probe.Run(context.Background(), sensors...) {
+		report := health.Report{
+			Name:    s.Name,
+			Mode:    s.Mode,
+			Status:  s.Status,
+			Details: s.Details,
+		}
+
+		if s.Err != nil {
+			report.Error = s.Err.Error()
+		}
+
+		reports = append(reports, report)
+	}
+
+	return reports
+}
+
+// Render marshals the reports with the marshaler, sorted by name then mode so the output is stable
+// regardless of the order sensors finished in. Each report is redacted with the redact functions.
+func Render(t testing.TB, marshaler health.ReportsMarshaler, reports []health.Report, redact ...RedactFunc) []byte {
+	t.Helper()
+
+	reports = slices.Clone(reports)
+
+	for i := range reports {
+		for fn := range slices.Values(redact) {
+			fn(&reports[i])
+		}
+	}
+
+	slices.SortStableFunc(reports, func(a, b health.Report) int {
+		return cmp.Or(cmp.Compare(a.Name, b.Name), cmp.Compare(a.Mode, b.Mode))
+	})
+
+	b, err := marshaler.MarshalReports(reports...)
+	if err != nil {
+		t.Fatalf("marshal health reports: %v", err)
+	}
+
+	return b
+}
+
+// Golden compares got with the golden file at path, failing the test if they differ. When UPDATE_GOLDEN
+// is set the golden file is written with got instead.
+func Golden(t testing.TB, path string, got []byte) {
+	t.Helper()
+
+	if os.Getenv("UPDATE_GOLDEN") != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("create golden file directory: %v", err)
+		}
+
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatalf("write golden file: %v", err)
+		}
+
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read golden file, run with UPDATE_GOLDEN=1 to create it: %v", err)
+	}
+
+	if !bytes.Equal(got, want) {
+		t.Errorf("health reports differ from golden file %s, run with UPDATE_GOLDEN=1 to update it\n got: %s\nwant: %s", path, got, want)
+	}
+}
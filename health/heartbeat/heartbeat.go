@@ -0,0 +1,177 @@
+// Package heartbeat touches a heartbeat file whilst the service is healthy, for platforms such as Nomad
+// raw_exec or cron monitors on bare metal which check file modification times rather than HTTP. Check,
+// or the cli.HeartbeatCheck command, verifies the file is fresh.
+//
+//	foundation.Run("worker", foundation.RunFunc(func(ctx context.Context, f foundation.F) {
+//		f.Run(ctx, worker, heartbeat.Run("/run/worker.heartbeat"))
+//	}))
+package heartbeat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"time"
+
+	"go.krak3n.io/foundation"
+	"go.krak3n.io/foundation/health/probe"
+	"go.krak3n.io/foundation/tick"
+)
+
+// ErrStale is returned by Check if the heartbeat file has not been touched within the maximum age.
+var ErrStale = errors.New("heartbeat stale")
+
+// An Option configures the heartbeat Runner.
+type Option interface {
+	apply(*config)
+}
+
+// Options is one or more Option.
+type Options []Option
+
+func (opts Options) apply(cfg *config) {
+	for opt := range slices.Values(opts) {
+		if opt != nil {
+			opt.apply(cfg)
+		}
+	}
+}
+
+type optionFunc func(*config)
+
+func (f optionFunc) apply(cfg *config) {
+	f(cfg)
+}
+
+// config holds the heartbeat Runner configuration.
+type config struct {
+	interval time.Duration
+	timeout  time.Duration
+	mode     probe.Mode
+}
+
+// WithInterval sets how often the sensors are run and the file touched, defaults to 10 seconds. Checks
+// should allow a maximum age of a few intervals.
+func WithInterval(d time.Duration) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.interval = d
+	})
+}
+
+// WithTimeout sets how long the sensors are given to run each interval, defaults to 5 seconds.
+func WithTimeout(d time.Duration) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.timeout = d
+	})
+}
+
+// WithSensorMode sets the mode of the sensors which must pass for the file to be touched, defaults to
+// probe.LivenessMode.
+func WithSensorMode(mode probe.Mode) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.mode = mode
+	})
+}
+
+// A Runner is a foundation.Runner which touches a heartbeat file each interval whilst the registered
+// sensors pass, removing it once stopped.
+type Runner struct {
+	path string
+	opts []Option
+}
+
+// Run returns a Runner which touches the heartbeat file at the path.
+func Run(path string, opts ...Option) *Runner {
+	return &Runner{
+		path: path,
+		opts: opts,
+	}
+}
+
+// Run touches the heartbeat file each interval whilst the sensors pass until stopped.
+func (r *Runner) Run(ctx context.Context, f foundation.F) {
+	cfg := config{
+		interval: 10 * time.Second,
+		timeout:  5 * time.Second,
+		mode:     probe.LivenessMode,
+	}
+
+	Options(r.opts).apply(&cfg)
+
+	f.On().Stop(func() {
+		if err := os.Remove(r.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			slog.Warn("failed to remove heartbeat file", slog.String("path", r.path), slog.String("err", err.Error()))
+		}
+	})
+
+	tick.Run(ctx, f, cfg.interval, func(ctx context.Context, _ tick.Ticker) {
+		if err := healthy(ctx, cfg.mode, cfg.timeout); err != nil {
+			slog.WarnContext(ctx, "skipping heartbeat", slog.String("err", err.Error()))
+
+			return
+		}
+
+		if err := touch(r.path); err != nil {
+			slog.WarnContext(ctx, "failed to write heartbeat file", slog.String("path", r.path), slog.String("err", err.Error()))
+		}
+	})
+}
+
+// healthy runs the sensors of the mode returning an error for each which failed.
+func healthy(ctx context.Context, mode probe.Mode, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var errs []error
+
+	for status := range probe.Run(probe.WithMode(ctx, mode), probe.RegistryFromContext(ctx).SensorsFor(mode)...) {
+		if status.Status != probe.StatusSuccess {
+			errs = append(errs, fmt.Errorf("sensor %s: %w", status.Name, status.Err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// touch atomically replaces the file with the current time, writing a temporary file in the same
+// directory and renaming it so readers never see a partial file.
+func touch(path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.WriteString(strconv.FormatInt(time.Now().Unix(), 10) + "\n"); err != nil {
+		tmp.Close()
+
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// Check returns an error unless the heartbeat file at the path was touched within the maximum age,
+// wrapping ErrStale if it is too old.
+func Check(path string, maxAge time.Duration) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("heartbeat: %w", err)
+	}
+
+	if age := time.Since(info.ModTime()); age > maxAge {
+		return fmt.Errorf("%w: %s last touched %s ago", ErrStale, path, age.Round(time.Millisecond))
+	}
+
+	return nil
+}
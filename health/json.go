@@ -11,8 +11,18 @@ func JSONReportMarshaler() ReportsMarshaler {
 	}
 }
 
+// JSONEnvelopeReportMarshaler returns a ReportsMarshaler which marshals the reports under "reports" in a
+// JSON object with the given fields, for example the service's build information.
+func JSONEnvelopeReportMarshaler(fields map[string]string) ReportsMarshaler {
+	return &jsonReportMarshaler{
+		marshaler: json.Marshal,
+		envelope:  fields,
+	}
+}
+
 type jsonReportMarshaler struct {
 	marshaler func(v any) ([]byte, error)
+	envelope  map[string]string
 }
 
 func (m *jsonReportMarshaler) LogValue() slog.Value {
@@ -24,5 +34,17 @@ func (m *jsonReportMarshaler) ContentType() string {
 }
 
 func (m *jsonReportMarshaler) MarshalReports(reports ...Report) ([]byte, error) {
-	return m.marshaler(reports)
+	if m.envelope == nil {
+		return m.marshaler(reports)
+	}
+
+	v := make(map[string]any, len(m.envelope)+1)
+
+	for k, field := range m.envelope {
+		v[k] = field
+	}
+
+	v["reports"] = reports
+
+	return m.marshaler(v)
 }
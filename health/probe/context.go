@@ -0,0 +1,104 @@
+package probe
+
+import (
+	"context"
+	"maps"
+	"sync"
+)
+
+// Context keys of the evaluation metadata passed to sensors.
+type (
+	modeKey    struct{}
+	attemptKey struct{}
+	detailsKey struct{}
+)
+
+// WithMode returns a context carrying the mode sensors are being run in, see ModeFromContext.
+func WithMode(ctx context.Context, mode Mode) context.Context {
+	return context.WithValue(ctx, modeKey{}, mode)
+}
+
+// ModeFromContext returns the mode the sensor is being run in, so a sensor in several modes can perform
+// a cheaper check for liveness than for readiness without being registered twice. The mode may combine
+// several modes, for example when every sensor is run regardless of mode. Reports false if the sensor is
+// not being run for a mode. The deadline of the check, if any, is the context's deadline.
+//
+//	probe.NewSensor("db", probe.LivenessMode|probe.ReadinessMode, func(ctx context.Context) error {
+//		if mode, _ := probe.ModeFromContext(ctx); mode == probe.LivenessMode {
+//			return db.PingContext(ctx)
+//		}
+//
+//		return db.QueryRowContext(ctx, "SELECT 1").Err()
+//	})
+func ModeFromContext(ctx context.Context) (Mode, bool) {
+	mode, ok := ctx.Value(modeKey{}).(Mode)
+
+	return mode, ok
+}
+
+// WithAttempt returns a context carrying the attempt of running a sensor, see AttemptFromContext.
+func WithAttempt(ctx context.Context, attempt int) context.Context {
+	return context.WithValue(ctx, attemptKey{}, attempt)
+}
+
+// AttemptFromContext returns the attempt, from 1, of running the sensor since it last passed, for
+// example to log only once a sensor has failed several times. Reports false if the attempt is not
+// tracked, it is when sensors are probed in the background.
+func AttemptFromContext(ctx context.Context) (int, bool) {
+	attempt, ok := ctx.Value(attemptKey{}).(int)
+
+	return attempt, ok
+}
+
+// details are the details recorded by a sensor whilst it is run, see Detail.
+type details struct {
+	mtx sync.Mutex
+	m   map[string]any
+}
+
+// withDetails returns a context the sensor records its details in.
+func withDetails(ctx context.Context) (context.Context, *details) {
+	d := new(details)
+
+	return context.WithValue(ctx, detailsKey{}, d), d
+}
+
+// values returns a copy of the recorded details, or nil if none were recorded.
+func (d *details) values() map[string]any {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	return maps.Clone(d.m)
+}
+
+// Detail records structured detail alongside the result of the sensor being run, for example
+// replication lag in seconds or pool utilisation, so dashboards can graph the underlying value rather
+// than just whether the sensor passed, see SensorStatus. The value should marshal to JSON. Recording a
+// key again replaces its value. Does nothing if the sensor is not being run by Run or RunAll.
+//
+//	probe.NewSensor("replica", probe.ReadinessMode, func(ctx context.Context) error {
+//		lag := replica.Lag()
+//
+//		probe.Detail(ctx, "lag_seconds", lag.Seconds())
+//
+//		if lag > time.Minute {
+//			return ErrLagging
+//		}
+//
+//		return nil
+//	})
+func Detail(ctx context.Context, key string, value any) {
+	d, ok := ctx.Value(detailsKey{}).(*details)
+	if !ok {
+		return
+	}
+
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	if d.m == nil {
+		d.m = make(map[string]any)
+	}
+
+	d.m[key] = value
+}
@@ -1,6 +1,13 @@
 package probe
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrSkipped is the error of a sensor which was skipped as a sensor it depends on failed, see
+// WithDependencies. Sensors which fail with it are reported as StatusSkipped.
+var ErrSkipped = errors.New("skipped")
 
 // ErrInvalidMode is an error returned for invalid sensor mode.
 type ErrInvalidMode struct {
@@ -0,0 +1,131 @@
+// Package probetest provides helpers for testing health probe sensors.
+package probetest
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"go.krak3n.io/foundation/health/probe"
+)
+
+// Timeout bounds each sensor run by the assertions.
+var Timeout = 5 * time.Second
+
+// AssertHealthy runs the sensor failing the test if it errors.
+func AssertHealthy(t testing.TB, sensor probe.Sensor) {
+	t.Helper()
+
+	if err := run(sensor); err != nil {
+		t.Errorf("sensor %s unhealthy: %v", sensor.Name(), err)
+	}
+}
+
+// AssertUnhealthy runs the sensor failing the test if it does not error.
+func AssertUnhealthy(t testing.TB, sensor probe.Sensor) {
+	t.Helper()
+
+	if err := run(sensor); err == nil {
+		t.Errorf("sensor %s healthy, expected unhealthy", sensor.Name())
+	}
+}
+
+// AssertRegistered fails the test if no sensor with the name is registered, returning the sensor if it is.
+func AssertRegistered(t testing.TB, name string) probe.Sensor {
+	t.Helper()
+
+	sensors := probe.Sensors()
+
+	i := slices.IndexFunc(sensors, func(s probe.Sensor) bool {
+		return s.Name() == name
+	})
+
+	if i < 0 {
+		t.Errorf("sensor %s not registered", name)
+
+		return nil
+	}
+
+	return sensors[i]
+}
+
+// run runs the sensor bounded by the timeout.
+func run(sensor probe.Sensor) error {
+	ctx, cancel := context.WithTimeout(context.Background(), Timeout)
+	defer cancel()
+
+	return sensor.Run(ctx)
+}
+
+// Isolate gives the test an empty sensor registry, restoring the previously registered sensors when the
+// test finishes. The registry is global so tests using Isolate must not run in parallel.
+func Isolate(t testing.TB) {
+	t.Helper()
+
+	previous := probe.Replace()
+
+	t.Cleanup(func() {
+		probe.Replace(previous...)
+	})
+}
+
+// A FakeSensor is a sensor whose result is controlled by the test, it is healthy until failed.
+type FakeSensor struct {
+	name string
+	mode probe.Mode
+
+	mtx  sync.Mutex
+	err  error
+	runs int
+}
+
+// NewFakeSensor returns a healthy FakeSensor.
+func NewFakeSensor(name string, mode probe.Mode) *FakeSensor {
+	return &FakeSensor{
+		name: name,
+		mode: mode,
+	}
+}
+
+// Name returns the name of the sensor.
+func (s *FakeSensor) Name() string {
+	return s.name
+}
+
+// Mode returns the mode of the sensor.
+func (s *FakeSensor) Mode() probe.Mode {
+	return s.mode
+}
+
+// Run returns the error the sensor was failed with, nil if healthy.
+func (s *FakeSensor) Run(context.Context) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.runs++
+
+	return s.err
+}
+
+// Fail makes the sensor unhealthy, erroring with err.
+func (s *FakeSensor) Fail(err error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.err = err
+}
+
+// Heal makes the sensor healthy.
+func (s *FakeSensor) Heal() {
+	s.Fail(nil)
+}
+
+// Runs returns the number of times the sensor has run.
+func (s *FakeSensor) Runs() int {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	return s.runs
+}
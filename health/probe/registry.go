@@ -1,38 +1,131 @@
 package probe
 
 import (
+	"context"
+	"slices"
 	"sync"
+	"sync/atomic"
 )
 
-var globalRegistry = &registry{sensors: make([]Sensor, 0)}
+var globalRegistry = NewRegistry()
 
-// Register registers one or more sensors.
+// Register registers one or more sensors with the global registry.
 func Register(sensors ...Sensor) {
 	globalRegistry.Register(sensors...)
 }
 
-// Sensors returns the registered sensors.
+// RegisterContext registers one or more sensors with the registry of the context, see
+// RegistryFromContext, so runners of an instance given its own registry register with it.
+func RegisterContext(ctx context.Context, sensors ...Sensor) {
+	RegistryFromContext(ctx).Register(sensors...)
+}
+
+// Sensors returns the registered sensors. The returned slice is shared and must not be modified.
 func Sensors() []Sensor {
 	return globalRegistry.Sensors()
 }
 
-type registry struct {
-	mtx     sync.RWMutex
+// SensorsFor returns the registered sensors which run in any of the given modes, without locking or
+// filtering so it is cheap enough to call on every probe. The returned slice is shared and must not be
+// modified.
+func SensorsFor(mode Mode) []Sensor {
+	return globalRegistry.SensorsFor(mode)
+}
+
+// Replace replaces the registered sensors, returning those previously registered. It is intended for
+// tests which need an isolated registry, see probetest.Isolate.
+func Replace(sensors ...Sensor) []Sensor {
+	return globalRegistry.Replace(sensors...)
+}
+
+// index is an immutable snapshot of the registered sensors, bucketed by every combination of modes.
+type index struct {
 	sensors []Sensor
+	modes   [AllModes + 1][]Sensor
+}
+
+// newIndex returns an index of the sensors. A sensor's mode is read once when it is indexed.
+func newIndex(sensors []Sensor) *index {
+	idx := &index{
+		sensors: sensors,
+	}
+
+	for sensor := range slices.Values(sensors) {
+		if sensor == nil {
+			continue
+		}
+
+		mode := sensor.Mode()
+
+		for m := range idx.modes {
+			if Mode(m)&mode != 0 {
+				idx.modes[m] = append(idx.modes[m], sensor)
+			}
+		}
+	}
+
+	return idx
+}
+
+// A Registry is a copy on write registry of sensors. Reads load the current index without locking,
+// writes are serialised and replace the index.
+type Registry struct {
+	mtx   sync.Mutex
+	index atomic.Pointer[index]
+}
+
+// NewRegistry returns an empty Registry, for example to give each of several foundation instances in a
+// process their own sensors, see ContextWithRegistry.
+func NewRegistry() *Registry {
+	r := &Registry{}
+	r.index.Store(newIndex(nil))
+
+	return r
+}
+
+// Register registers one or more sensors.
+func (r *Registry) Register(sensors ...Sensor) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	r.index.Store(newIndex(slices.Concat(r.index.Load().sensors, sensors)))
 }
 
-// Register registers a sensor.
-func (r *registry) Register(sensors ...Sensor) {
+// Sensors returns the sensors.
+func (r *Registry) Sensors() []Sensor {
+	return r.index.Load().sensors
+}
+
+// SensorsFor returns the sensors which run in any of the given modes.
+func (r *Registry) SensorsFor(mode Mode) []Sensor {
+	return r.index.Load().modes[mode&AllModes]
+}
+
+// Replace replaces the sensors returning the previous sensors.
+func (r *Registry) Replace(sensors ...Sensor) []Sensor {
 	r.mtx.Lock()
 	defer r.mtx.Unlock()
 
-	r.sensors = append(r.sensors, sensors...)
+	previous := r.index.Swap(newIndex(slices.Clone(sensors)))
+
+	return previous.sensors
+}
+
+// registryKey is the context key a Registry is stored under.
+type registryKey struct{}
+
+// ContextWithRegistry returns a context carrying the registry sensors are registered with and read from
+// by runners given the context, see RegisterContext. Give it as the base context of a foundation
+// instance, see foundation.WithBaseContext, to isolate its sensors from other instances in the process.
+func ContextWithRegistry(ctx context.Context, r *Registry) context.Context {
+	return context.WithValue(ctx, registryKey{}, r)
 }
 
-// Sensors returns the sensors filtered by mode.
-func (r *registry) Sensors() []Sensor {
-	r.mtx.RLock()
-	defer r.mtx.RUnlock()
+// RegistryFromContext returns the registry of the context, the global registry if it has none.
+func RegistryFromContext(ctx context.Context) *Registry {
+	if r, ok := ctx.Value(registryKey{}).(*Registry); ok {
+		return r
+	}
 
-	return r.sensors
+	return globalRegistry
 }
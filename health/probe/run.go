@@ -2,8 +2,11 @@ package probe
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"slices"
-	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // A SensorStatus is the status of a Sensor.
@@ -11,43 +14,268 @@ type SensorStatus struct {
 	Name   string
 	Mode   Mode
 	Status Status
+	// Err is the error the sensor failed with, an ErrSkipped error if it was skipped or the cause of the
+	// context being done if it was done before the sensor finished.
+	Err error
+	// Details are the details the sensor recorded, see Detail, or nil if it recorded none.
+	Details map[string]any
+	// Started is when the sensor started running, zero if it did not run as it was skipped or the
+	// context was done first.
+	Started time.Time
+	// Finished is when the status of the sensor was determined.
+	Finished time.Time
 }
 
-// Run executes the given sensors in go routines returning a channel of sensor reports describing
-// the result of the sensor.
+// Took returns how long the sensor ran for, zero if it did not run.
+func (s SensorStatus) Took() time.Duration {
+	if s.Started.IsZero() {
+		return 0
+	}
+
+	return s.Finished.Sub(s.Started)
+}
+
+// Run executes the given sensors in go routines returning a channel streaming the status of each sensor
+// as it finishes, closed once every sensor has a status. Sensors which depend on others are run once
+// they have passed, and skipped if any failed, see WithDependencies.
+//
+// Once the context is done sensors yet to run are not run and every sensor without a status, including
+// those still running, is reported failed with the cause without waiting for it to return, so deadlines
+// are enforced even on sensors which ignore their context. The channel holds every status so the
+// consumer may stop receiving at any time, cancelling the context to stop the sensors still running.
 func Run(ctx context.Context, sensors ...Sensor) <-chan SensorStatus {
-	ch := make(chan SensorStatus)
+	ch := make(chan SensorStatus, len(sensors))
 
 	go func() {
 		defer close(ch)
 
-		var wg sync.WaitGroup
-		wg.Add(len(sensors))
+		run(ctx, sensors, func(_ int, status SensorStatus) {
+			ch <- status
+		})
+	}()
+
+	return ch
+}
 
-		for sensor := range slices.Values(sensors) {
-			go func(sensor Sensor) {
-				defer wg.Done()
+// RunAll executes the given sensors as Run does, returning the status of each in the order of the
+// sensors once they all have a status. Nil sensors have a zero status.
+func RunAll(ctx context.Context, sensors ...Sensor) []SensorStatus {
+	statuses := make([]SensorStatus, len(sensors))
 
-				if sensor == nil {
-					return
-				}
+	run(ctx, sensors, func(i int, status SensorStatus) {
+		statuses[i] = status
+	})
+
+	return statuses
+}
+
+// result is the status of the sensor at an index.
+type result struct {
+	i      int
+	status SensorStatus
+}
+
+// run runs the sensors concurrently, each once the sensors it depends on have run, calling report with
+// the index and status of each sensor as it finishes, or as the context is done if it has not.
+func run(ctx context.Context, sensors []Sensor, report func(i int, status SensorStatus)) {
+	deps := dependencies(sensors)
+	done := make([]chan struct{}, len(sensors))
+	statuses := make([]Status, len(sensors))
+	started := make([]atomic.Pointer[time.Time], len(sensors))
+	recorded := make([]*details, len(sensors))
+
+	// Buffered so sensors finishing once the context is done do not block.
+	results := make(chan result, len(sensors))
+
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
+
+	var pending int
 
-				status := StatusSuccess
+	for i, sensor := range sensors {
+		if sensor == nil {
+			close(done[i])
 
-				if err := sensor.Run(ctx); err != nil {
-					status = StatusFailed
+			continue
+		}
+
+		pending++
+
+		ctx, details := withDetails(ctx)
+		recorded[i] = details
+
+		go func() {
+			err := skipped(ctx, sensors, deps[i], done, statuses)
+			if err == nil {
+				now := time.Now()
+				started[i].Store(&now)
+
+				err = sensor.Run(ctx)
+			}
+
+			status := StatusSuccess
+
+			switch {
+			case errors.Is(err, ErrSkipped):
+				status = StatusSkipped
+			case err != nil:
+				status = StatusFailed
+			}
+
+			// Written before closing so dependents waiting on done read it.
+			statuses[i] = status
+			close(done[i])
+
+			results <- result{i: i, status: SensorStatus{
+				Name:     sensor.Name(),
+				Mode:     sensor.Mode(),
+				Status:   status,
+				Err:      err,
+				Details:  details.values(),
+				Started:  startedAt(&started[i]),
+				Finished: time.Now(),
+			}}
+		}()
+	}
+
+	reported := make([]bool, len(sensors))
+
+	for ; pending > 0; pending-- {
+		select {
+		case r := <-results:
+			reported[r.i] = true
+			report(r.i, r.status)
+		case <-ctx.Done():
+			finished := time.Now()
+
+			// Report the sensors which finished before the context was done, then fail the rest.
+			for len(results) > 0 {
+				r := <-results
+				reported[r.i] = true
+				report(r.i, r.status)
+			}
+
+			for i, sensor := range sensors {
+				if sensor == nil || reported[i] {
+					continue
 				}
 
-				ch <- SensorStatus{
-					Name:   sensor.Name(),
-					Mode:   sensor.Mode(),
-					Status: status,
+				report(i, SensorStatus{
+					Name:     sensor.Name(),
+					Mode:     sensor.Mode(),
+					Status:   StatusFailed,
+					Err:      context.Cause(ctx),
+					Details:  recorded[i].values(),
+					Started:  startedAt(&started[i]),
+					Finished: finished,
+				})
+			}
+
+			return
+		}
+	}
+}
+
+// startedAt returns when the sensor started running, zero if it did not.
+func startedAt(started *atomic.Pointer[time.Time]) time.Time {
+	if t := started.Load(); t != nil {
+		return *t
+	}
+
+	return time.Time{}
+}
+
+// skipped waits for the dependencies to run returning an ErrSkipped error if any did not pass, or the
+// cause of the context being done whilst waiting.
+func skipped(ctx context.Context, sensors []Sensor, deps []int, done []chan struct{}, statuses []Status) error {
+	for d := range slices.Values(deps) {
+		select {
+		case <-done[d]:
+		case <-ctx.Done():
+			return context.Cause(ctx)
+		}
+
+		if statuses[d] != StatusSuccess {
+			return fmt.Errorf("%w: depends on %s which %s", ErrSkipped, sensors[d].Name(), statuses[d])
+		}
+	}
+
+	return nil
+}
+
+// dependencies returns the indexes of the sensors each sensor depends on. Dependencies on sensors which
+// are not present are ignored, as are the dependencies of sensors in or depending on a cycle so every
+// sensor runs.
+func dependencies(sensors []Sensor) [][]int {
+	deps := make([][]int, len(sensors))
+
+	byName := make(map[string][]int, len(sensors))
+
+	for i, s := range sensors {
+		if s != nil {
+			byName[s.Name()] = append(byName[s.Name()], i)
+		}
+	}
+
+	var found bool
+
+	for i, s := range sensors {
+		d, ok := s.(Dependent)
+		if !ok {
+			continue
+		}
+
+		for name := range slices.Values(d.DependsOn()) {
+			for j := range slices.Values(byName[name]) {
+				if j != i {
+					deps[i] = append(deps[i], j)
+					found = true
 				}
-			}(sensor)
+			}
 		}
+	}
 
-		wg.Wait()
-	}()
+	if !found {
+		return deps
+	}
 
-	return ch
+	// Order the sensors topologically, any left unordered are in or depend on a cycle.
+	pending := make([]int, len(sensors))
+	dependents := make([][]int, len(sensors))
+
+	for i := range deps {
+		pending[i] = len(deps[i])
+
+		for j := range slices.Values(deps[i]) {
+			dependents[j] = append(dependents[j], i)
+		}
+	}
+
+	var ready []int
+
+	for i := range pending {
+		if pending[i] == 0 {
+			ready = append(ready, i)
+		}
+	}
+
+	for len(ready) > 0 {
+		i := ready[len(ready)-1]
+		ready = ready[:len(ready)-1]
+
+		for j := range slices.Values(dependents[i]) {
+			if pending[j]--; pending[j] == 0 {
+				ready = append(ready, j)
+			}
+		}
+	}
+
+	for i := range pending {
+		if pending[i] > 0 {
+			deps[i] = nil
+		}
+	}
+
+	return deps
 }
@@ -1,6 +1,9 @@
 package probe
 
-import "context"
+import (
+	"context"
+	"slices"
+)
 
 // A Sensor is a health check probe sensor which determines if an something
 // is healthy.
@@ -13,21 +16,68 @@ type Sensor interface {
 // A SensorFunc is a functiontion called by a sensor to determine the health of the sensor.
 type SensorFunc func(ctx context.Context) error
 
+// A Dependent is a Sensor which depends on other sensors, it is skipped rather than run if any of them
+// fail, so the failure of a shared dependency does not cascade into a failure of every sensor which
+// relies on it.
+type Dependent interface {
+	Sensor
+	// DependsOn returns the names of the sensors the sensor depends on.
+	DependsOn() []string
+}
+
+// A SensorOption configures a Sensor constructed with NewSensor.
+type SensorOption interface {
+	applySensor(*sensor)
+}
+
+// SensorOptions is one or more SensorOption.
+type SensorOptions []SensorOption
+
+func (opts SensorOptions) applySensor(s *sensor) {
+	for opt := range slices.Values(opts) {
+		if opt != nil {
+			opt.applySensor(s)
+		}
+	}
+}
+
+type sensorOptionFunc func(*sensor)
+
+func (fn sensorOptionFunc) applySensor(s *sensor) {
+	fn(s)
+}
+
+// WithDependencies declares the names of the sensors the sensor depends on, for example queue lag
+// depending on the broker being reachable. When run together, see Run, the sensor is run once they have
+// passed and is skipped if any failed. Dependencies which are not run together, for example as they are
+// in another mode, are ignored.
+func WithDependencies(names ...string) SensorOption {
+	return sensorOptionFunc(func(s *sensor) {
+		s.deps = append(s.deps, names...)
+	})
+}
+
 // NewSensor constructs a new Sensor.
-func NewSensor(name string, mode Mode, f SensorFunc) Sensor {
-	return &sensor{
+func NewSensor(name string, mode Mode, f SensorFunc, opts ...SensorOption) Sensor {
+	s := &sensor{
 		name: name,
 		mode: mode,
 		f:    f,
 	}
+
+	SensorOptions(opts).applySensor(s)
+
+	return s
 }
 
 type sensor struct {
 	name string
 	mode Mode
 	f    SensorFunc
+	deps []string
 }
 
 func (s *sensor) Name() string                  { return s.name }
 func (s *sensor) Mode() Mode                    { return s.mode }
 func (s *sensor) Run(ctx context.Context) error { return s.f(ctx) }
+func (s *sensor) DependsOn() []string           { return s.deps }
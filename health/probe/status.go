@@ -9,9 +9,12 @@ import (
 const (
 	StatusFailed Status = iota + 1
 	StatusSuccess
+	// StatusSkipped is the status of a sensor which was not run as a sensor it depends on failed, see
+	// WithDependencies.
+	StatusSkipped
 )
 
-// A Status is returned by a sensor indicating whether the sensor succeeded or failed.
+// A Status is returned by a sensor indicating whether the sensor succeeded, failed or was skipped.
 type Status int8
 
 func (s Status) String() string {
@@ -22,6 +25,8 @@ func (s Status) String() string {
 		v = "failed"
 	case StatusSuccess:
 		v = "success"
+	case StatusSkipped:
+		v = "skipped"
 	default:
 		v = "unknown"
 	}
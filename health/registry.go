@@ -1,19 +1,53 @@
 package health
 
-import "go.krak3n.io/foundation/health/probe"
+import (
+	"slices"
+
+	"go.krak3n.io/foundation/health/probe"
+)
 
 type SensorRegistry interface {
 	Sensors() []probe.Sensor
 }
 
+// A ModeSensorRegistry is a SensorRegistry which returns the sensors of a mode itself, for example from
+// an index, rather than the handler filtering every sensor on each request.
+type ModeSensorRegistry interface {
+	SensorRegistry
+	// SensorsFor returns the sensors which run in any of the given modes, the returned slice is not
+	// modified.
+	SensorsFor(mode probe.Mode) []probe.Sensor
+}
+
 type SensorRegistryFunc func() []probe.Sensor
 
 func (f SensorRegistryFunc) Sensors() []probe.Sensor {
 	return f()
 }
 
+// DefaultSensorRegistry returns the registry of the sensors registered with probe.Register.
 func DefaultSensorRegistry() SensorRegistry {
-	return SensorRegistryFunc(func() []probe.Sensor {
-		return probe.Sensors()
+	return defaultRegistry{}
+}
+
+// defaultRegistry is the global probe registry, indexed by mode.
+type defaultRegistry struct{}
+
+func (defaultRegistry) Sensors() []probe.Sensor {
+	return probe.Sensors()
+}
+
+func (defaultRegistry) SensorsFor(mode probe.Mode) []probe.Sensor {
+	return probe.SensorsFor(mode)
+}
+
+// sensorsFor returns the sensors of the registry which run in any of the given modes.
+func sensorsFor(registry SensorRegistry, mode probe.Mode) []probe.Sensor {
+	if r, ok := registry.(ModeSensorRegistry); ok {
+		return r.SensorsFor(mode)
+	}
+
+	return slices.DeleteFunc(slices.Clone(registry.Sensors()), func(s probe.Sensor) bool {
+		return s.Mode()&mode == 0
 	})
 }
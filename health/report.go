@@ -11,6 +11,11 @@ type Report struct {
 	Name   string       `json:"name"`
 	Mode   probe.Mode   `json:"mode"`
 	Status probe.Status `json:"status"`
+	// Error is the error a failed sensor failed with, only reported by handlers configured with
+	// WithErrors.
+	Error string `json:"error,omitempty"`
+	// Details are the details the sensor recorded, see probe.Detail.
+	Details map[string]any `json:"details,omitempty"`
 }
 
 // A ReportsMarshaler can marshal Report's for the HTTP server.
@@ -0,0 +1,90 @@
+package foundation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+)
+
+// ErrAlreadyRunning is returned when Run is called with the name of an instance already running in the
+// process, usually an accidental double Run.
+var ErrAlreadyRunning = errors.New("already running")
+
+// WithBaseContext sets the context every runner's context is derived from, defaults to
+// context.Background. Running several instances in one process, each may be given a context carrying
+// its own registries, for example probe.ContextWithRegistry so their sensors are isolated.
+func WithBaseContext(ctx context.Context) RunOption {
+	return runConfigFunc(func(cfg *runConfig) {
+		cfg.ctx = ctx
+	})
+}
+
+// instances are the instances running in the process.
+var instances = struct {
+	mtx     sync.Mutex
+	running map[*Instance]string
+}{
+	running: make(map[*Instance]string),
+}
+
+// claim records the instance as running under its name. If exclusive, as instances run with Run are,
+// an error is returned if an instance with the name is already running.
+func claim(name string, i *Instance, exclusive bool) error {
+	instances.mtx.Lock()
+	defer instances.mtx.Unlock()
+
+	if exclusive {
+		for running := range maps.Values(instances.running) {
+			if running == name {
+				return fmt.Errorf("instance %q: %w", name, ErrAlreadyRunning)
+			}
+		}
+	}
+
+	instances.running[i] = name
+
+	return nil
+}
+
+// release forgets the running instance.
+func release(i *Instance) {
+	instances.mtx.Lock()
+	defer instances.mtx.Unlock()
+
+	delete(instances.running, i)
+}
+
+// waitSignalled waits for the running instances handling signals, which stop on the same signals, so
+// exiting the process does not cut their shutdown short. Returns true if any stopped with an error.
+func waitSignalled() bool {
+	instances.mtx.Lock()
+	running := slices.Collect(maps.Keys(instances.running))
+	instances.mtx.Unlock()
+
+	var erred bool
+
+	for i := range slices.Values(running) {
+		if i.signals && i.Wait() != nil {
+			erred = true
+		}
+	}
+
+	return erred
+}
+
+// failed returns an instance which failed to start with the error.
+func failed(name string, err error) *Instance {
+	i := &Instance{
+		f:    newf(name),
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+		err:  err,
+	}
+
+	close(i.done)
+
+	return i
+}
@@ -0,0 +1,83 @@
+package foundation
+
+import (
+	"fmt"
+	"time"
+)
+
+// An EventKind is the kind of a lifecycle Event.
+type EventKind uint8
+
+// Lifecycle event kinds.
+const (
+	// EventStart is emitted when a Runner starts running.
+	EventStart EventKind = iota + 1
+	// EventStop is emitted when an F is told to stop, before its Stop hooks are called.
+	EventStop
+	// EventDone is emitted when a Runner returns.
+	EventDone
+	// EventError is emitted for every error encountered, including panics and errors in hooks.
+	EventError
+	// EventParallel is emitted when a Runner marks itself as parallel, see F.Parallel.
+	EventParallel
+)
+
+// String returns the name of the event kind.
+func (k EventKind) String() string {
+	switch k {
+	case EventStart:
+		return "start"
+	case EventStop:
+		return "stop"
+	case EventDone:
+		return "done"
+	case EventError:
+		return "error"
+	case EventParallel:
+		return "parallel"
+	default:
+		return fmt.Sprintf("EventKind(%d)", k)
+	}
+}
+
+// An Event is a lifecycle event of an F in the tree, see WithInterceptor.
+type Event struct {
+	// Kind is the kind of event.
+	Kind EventKind
+	// Runner is the name of the F, for example api.1.2.
+	Runner string
+	// Time is when the event occurred.
+	Time time.Time
+	// Err is the error of an EventError.
+	Err error
+}
+
+// An Interceptor is called with every lifecycle event of every F in the tree. Interceptors are called
+// synchronously from the engine so must return quickly and must not call back into the F.
+type Interceptor func(Event)
+
+// WithInterceptor calls the interceptor with every lifecycle event, for example to record the order
+// runners start and stop in tests, see foundationtest.Recorder.
+func WithInterceptor(fn Interceptor) RunOption {
+	return runConfigFunc(func(cfg *runConfig) {
+		cfg.interceptors = append(cfg.interceptors, fn)
+	})
+}
+
+// emit calls the interceptors with the event.
+func (f *f) emit(kind EventKind, err error) {
+	if len(f.interceptors) == 0 {
+		return
+	}
+
+	e := Event{
+		Kind:   kind,
+		Runner: f.name,
+		Time:   time.Now(),
+		Err:    err,
+	}
+
+	for _, fn := range f.interceptors {
+		fn(e)
+	}
+}
@@ -0,0 +1,236 @@
+// Package kube provides helpers for running foundation services on Kubernetes.
+package kube
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.krak3n.io/foundation"
+)
+
+// DefaultTerminationLogPath is the default path of the container termination message.
+const DefaultTerminationLogPath = "/dev/termination-log"
+
+// maxTerminationLogSize is the maximum size of a termination message read by the kubelet.
+const maxTerminationLogSize = 4096
+
+// TerminationLog writes the error which caused the process to exit to the termination message path, so
+// it is visible in the pod status, for example with kubectl describe pod. Nothing is written on a clean
+// exit. An empty path uses DefaultTerminationLogPath.
+//
+//	foundation.Run("api", runner, kube.TerminationLog(""))
+func TerminationLog(path string) foundation.RunOption {
+	if path == "" {
+		path = DefaultTerminationLogPath
+	}
+
+	return foundation.WithExitHook(func(err error) {
+		if err == nil {
+			return
+		}
+
+		msg := err.Error()
+		if len(msg) > maxTerminationLogSize {
+			msg = msg[:maxTerminationLogSize]
+		}
+
+		if err := os.WriteFile(path, []byte(msg), 0o644); err != nil {
+			slog.Warn("failed to write termination log", slog.String("path", path), slog.String("err", err.Error()))
+		}
+	})
+}
+
+// PreStop returns a foundation.Runner which delays stopping by the given duration. Kubernetes removes a
+// terminating pod from service endpoints concurrently with signalling it, so traffic can still arrive
+// for a short time after the signal. Run it after the runners serving traffic so, as runners are
+// stopped newest first, they keep serving during the delay before draining.
+func PreStop(delay time.Duration) foundation.Runner {
+	return foundation.RunFunc(func(ctx context.Context, f foundation.F) {
+		f.On().Stop(func() {
+			slog.Info("pre-stop delay", slog.Duration("delay", delay))
+			time.Sleep(delay)
+		})
+	})
+}
+
+// Metadata is pod metadata exposed through the downward API.
+type Metadata struct {
+	PodName        string            `json:"pod_name,omitempty"`
+	Namespace      string            `json:"namespace,omitempty"`
+	NodeName       string            `json:"node_name,omitempty"`
+	PodIP          string            `json:"pod_ip,omitempty"`
+	ServiceAccount string            `json:"service_account,omitempty"`
+	Labels         map[string]string `json:"labels,omitempty"`
+	Annotations    map[string]string `json:"annotations,omitempty"`
+}
+
+// LogAttrs returns the metadata as log attributes, empty fields are omitted.
+func (m Metadata) LogAttrs() []any {
+	var attrs []any
+
+	for key, value := range map[string]string{
+		"k8s.pod.name":       m.PodName,
+		"k8s.namespace.name": m.Namespace,
+		"k8s.node.name":      m.NodeName,
+	} {
+		if value != "" {
+			attrs = append(attrs, slog.String(key, value))
+		}
+	}
+
+	slices.SortFunc(attrs, func(a, b any) int {
+		return strings.Compare(a.(slog.Attr).Key, b.(slog.Attr).Key)
+	})
+
+	return attrs
+}
+
+// An Option configures metadata loading.
+type Option interface {
+	apply(*config)
+}
+
+// Options is one or more Option.
+type Options []Option
+
+func (opts Options) apply(cfg *config) {
+	for opt := range slices.Values(opts) {
+		if opt != nil {
+			opt.apply(cfg)
+		}
+	}
+}
+
+type optionFunc func(*config)
+
+func (f optionFunc) apply(cfg *config) {
+	f(cfg)
+}
+
+// config holds the metadata configuration.
+type config struct {
+	podInfo  string
+	logAttrs bool
+}
+
+// WithPodInfo sets the directory of the downward API volume containing the labels and annotations
+// files, defaults to /etc/podinfo.
+func WithPodInfo(dir string) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.podInfo = dir
+	})
+}
+
+// WithoutLogAttrs stops the metadata being added to the default logger.
+func WithoutLogAttrs() Option {
+	return optionFunc(func(cfg *config) {
+		cfg.logAttrs = false
+	})
+}
+
+// LoadMetadata loads pod metadata from the POD_NAME, POD_NAMESPACE, NODE_NAME, POD_IP and
+// SERVICE_ACCOUNT environment variables and the labels and annotations files of the downward API volume.
+func LoadMetadata(opts ...Option) (Metadata, error) {
+	cfg := config{
+		podInfo: "/etc/podinfo",
+	}
+
+	Options(opts).apply(&cfg)
+
+	return load(cfg)
+}
+
+func load(cfg config) (Metadata, error) {
+	md := Metadata{
+		PodName:        os.Getenv("POD_NAME"),
+		Namespace:      os.Getenv("POD_NAMESPACE"),
+		NodeName:       os.Getenv("NODE_NAME"),
+		PodIP:          os.Getenv("POD_IP"),
+		ServiceAccount: os.Getenv("SERVICE_ACCOUNT"),
+	}
+
+	var err error
+
+	if md.Labels, err = readPodInfo(filepath.Join(cfg.podInfo, "labels")); err != nil {
+		return md, err
+	}
+
+	if md.Annotations, err = readPodInfo(filepath.Join(cfg.podInfo, "annotations")); err != nil {
+		return md, err
+	}
+
+	return md, nil
+}
+
+// readPodInfo reads a downward API file of key="value" lines, a missing file is not an error.
+func readPodInfo(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), "=")
+		if !ok {
+			continue
+		}
+
+		if v, err := strconv.Unquote(value); err == nil {
+			value = v
+		}
+
+		values[key] = value
+	}
+
+	return values, scanner.Err()
+}
+
+// Run returns a foundation.Runner which loads pod metadata, stores it in the value store, see
+// GetMetadata, and adds it to the default logger. Run it first so later runners log with the metadata.
+func Run(opts ...Option) foundation.Runner {
+	return foundation.RunFunc(func(ctx context.Context, f foundation.F) {
+		cfg := config{
+			podInfo:  "/etc/podinfo",
+			logAttrs: true,
+		}
+
+		Options(opts).apply(&cfg)
+
+		md, err := load(cfg)
+		if err != nil {
+			slog.WarnContext(ctx, "failed to load pod metadata", slog.String("err", err.Error()))
+		}
+
+		f.Values().Store(metadataKey{}, md)
+
+		if attrs := md.LogAttrs(); cfg.logAttrs && len(attrs) > 0 {
+			slog.SetDefault(slog.Default().With(attrs...))
+		}
+	})
+}
+
+// metadataKey is the value store key the pod metadata is stored under.
+type metadataKey struct{}
+
+// GetMetadata returns the pod metadata from the F's value store.
+func GetMetadata(f foundation.F) (Metadata, bool) {
+	return foundation.Value[Metadata](f, metadataKey{})
+}
@@ -0,0 +1,108 @@
+// Package lock provides a distributed lock with a time to live, so a lock held by a process which dies
+// is released once its TTL expires.
+package lock
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrNotHeld is returned when renewing or releasing a lock which is not held, for example because its
+// TTL expired and another owner acquired it.
+var ErrNotHeld = errors.New("lock not held")
+
+// A Lock is a distributed lock. Each Lock has a unique owner token so a lock can only be renewed or
+// released by the Lock which acquired it.
+type Lock interface {
+	// Acquire attempts to acquire the lock for the TTL without blocking, returning true if acquired.
+	// Acquiring a lock already held by this Lock extends its TTL.
+	Acquire(ctx context.Context, ttl time.Duration) (bool, error)
+	// Renew extends the TTL of a held lock, returning ErrNotHeld if the lock is not held.
+	Renew(ctx context.Context, ttl time.Duration) error
+	// Release releases a held lock, returning ErrNotHeld if the lock is not held.
+	Release(ctx context.Context) error
+}
+
+// A Lease holds a Lock across repeated attempts, renewing it whilst held and acquiring it otherwise.
+// It satisfies outbox.Locker.
+type Lease struct {
+	lock Lock
+	ttl  time.Duration
+	mtx  sync.Mutex
+	held bool
+}
+
+// NewLease returns a Lease over the lock with the given TTL. The lease must be retried more often than
+// the TTL to keep holding the lock.
+func NewLease(lock Lock, ttl time.Duration) *Lease {
+	return &Lease{
+		lock: lock,
+		ttl:  ttl,
+	}
+}
+
+// TryLock renews the lock if held or attempts to acquire it, returning true if the lock is held.
+func (l *Lease) TryLock(ctx context.Context) (bool, error) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	if l.held {
+		err := l.lock.Renew(ctx, l.ttl)
+		if err == nil {
+			return true, nil
+		}
+
+		l.held = false
+
+		if !errors.Is(err, ErrNotHeld) {
+			return false, err
+		}
+	}
+
+	ok, err := l.lock.Acquire(ctx, l.ttl)
+	if err != nil {
+		return false, err
+	}
+
+	l.held = ok
+
+	return ok, nil
+}
+
+// Unlock releases the lock if held.
+func (l *Lease) Unlock(ctx context.Context) error {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	if !l.held {
+		return nil
+	}
+
+	l.held = false
+
+	if err := l.lock.Release(ctx); err != nil && !errors.Is(err, ErrNotHeld) {
+		return err
+	}
+
+	return nil
+}
+
+// Held reports whether the lease believes it holds the lock, as of the last call to TryLock.
+func (l *Lease) Held() bool {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	return l.held
+}
+
+// token returns a random owner token.
+func token() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,89 @@
+package lock
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// Postgres returns a Lock stored as a row in the given table, which is created if it does not exist.
+// Unlike advisory locks a table lock has a TTL so it survives connection resets and can be shared
+// through connection poolers.
+func Postgres(db *sql.DB, table, name string) Lock {
+	return &postgresLock{
+		db:    db,
+		table: table,
+		name:  name,
+		token: token(),
+	}
+}
+
+type postgresLock struct {
+	db      *sql.DB
+	table   string
+	name    string
+	token   string
+	created atomic.Bool
+}
+
+func (l *postgresLock) Acquire(ctx context.Context, ttl time.Duration) (bool, error) {
+	if !l.created.Load() {
+		create := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (name TEXT PRIMARY KEY, owner TEXT NOT NULL, expires_at TIMESTAMPTZ NOT NULL)", l.table)
+
+		if _, err := l.db.ExecContext(ctx, create); err != nil {
+			return false, fmt.Errorf("create lock table: %w", err)
+		}
+
+		l.created.Store(true)
+	}
+
+	// Insert the lock or take it over if it has expired or we already own it.
+	query := fmt.Sprintf(`INSERT INTO %[1]s (name, owner, expires_at) VALUES ($1, $2, now() + $3::bigint * interval '1 millisecond')
+		ON CONFLICT (name) DO UPDATE SET owner = EXCLUDED.owner, expires_at = EXCLUDED.expires_at
+		WHERE %[1]s.expires_at < now() OR %[1]s.owner = EXCLUDED.owner`, l.table)
+
+	res, err := l.db.ExecContext(ctx, query, l.name, l.token, ttl.Milliseconds())
+	if err != nil {
+		return false, fmt.Errorf("acquire lock %s: %w", l.name, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("acquire lock %s: %w", l.name, err)
+	}
+
+	return n == 1, nil
+}
+
+func (l *postgresLock) Renew(ctx context.Context, ttl time.Duration) error {
+	query := fmt.Sprintf("UPDATE %s SET expires_at = now() + $3::bigint * interval '1 millisecond' WHERE name = $1 AND owner = $2 AND expires_at >= now()", l.table)
+
+	return l.exec(ctx, "renew", query, l.name, l.token, ttl.Milliseconds())
+}
+
+func (l *postgresLock) Release(ctx context.Context) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE name = $1 AND owner = $2", l.table)
+
+	return l.exec(ctx, "release", query, l.name, l.token)
+}
+
+// exec executes a query which affects the lock row if held.
+func (l *postgresLock) exec(ctx context.Context, op, query string, args ...any) error {
+	res, err := l.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("%s lock %s: %w", op, l.name, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s lock %s: %w", op, l.name, err)
+	}
+
+	if n != 1 {
+		return ErrNotHeld
+	}
+
+	return nil
+}
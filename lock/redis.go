@@ -0,0 +1,83 @@
+package lock
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// A RedisClient issues the commands required by a Redis lock. Foundation does not depend on a Redis
+// library, instead a RedisClient is a thin adapter over a library such as go-redis.
+type RedisClient interface {
+	// SetNX sets the key to value with the TTL if it does not exist (SET key value NX PX ttl).
+	SetNX(ctx context.Context, key, value string, ttl time.Duration) (bool, error)
+	// Eval evaluates a Lua script returning its integer result (EVAL script keys args).
+	Eval(ctx context.Context, script string, keys []string, args ...any) (int64, error)
+}
+
+// Scripts compare the owner token before modifying the key so only the owner can renew or release.
+const (
+	redisAcquireScript = `if redis.call("GET", KEYS[1]) == ARGV[1] then return redis.call("PEXPIRE", KEYS[1], ARGV[2]) end return 0`
+	redisRenewScript   = redisAcquireScript
+	redisReleaseScript = `if redis.call("GET", KEYS[1]) == ARGV[1] then return redis.call("DEL", KEYS[1]) end return 0`
+)
+
+// Redis returns a Lock stored in Redis under the given key.
+func Redis(client RedisClient, key string) Lock {
+	return &redisLock{
+		client: client,
+		key:    key,
+		token:  token(),
+	}
+}
+
+type redisLock struct {
+	client RedisClient
+	key    string
+	token  string
+}
+
+func (l *redisLock) Acquire(ctx context.Context, ttl time.Duration) (bool, error) {
+	ok, err := l.client.SetNX(ctx, l.key, l.token, ttl)
+	if err != nil {
+		return false, fmt.Errorf("acquire lock %s: %w", l.key, err)
+	}
+
+	if ok {
+		return true, nil
+	}
+
+	// The key exists, extend it if we already own it.
+	n, err := l.client.Eval(ctx, redisAcquireScript, []string{l.key}, l.token, ttl.Milliseconds())
+	if err != nil {
+		return false, fmt.Errorf("acquire lock %s: %w", l.key, err)
+	}
+
+	return n == 1, nil
+}
+
+func (l *redisLock) Renew(ctx context.Context, ttl time.Duration) error {
+	n, err := l.client.Eval(ctx, redisRenewScript, []string{l.key}, l.token, ttl.Milliseconds())
+	if err != nil {
+		return fmt.Errorf("renew lock %s: %w", l.key, err)
+	}
+
+	if n != 1 {
+		return ErrNotHeld
+	}
+
+	return nil
+}
+
+func (l *redisLock) Release(ctx context.Context) error {
+	n, err := l.client.Eval(ctx, redisReleaseScript, []string{l.key}, l.token)
+	if err != nil {
+		return fmt.Errorf("release lock %s: %w", l.key, err)
+	}
+
+	if n != 1 {
+		return ErrNotHeld
+	}
+
+	return nil
+}
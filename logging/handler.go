@@ -0,0 +1,148 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+)
+
+// WithHandler logs with the given handler rather than a JSON or text handler, for example one backed by
+// zap or zerolog, see NewFuncHandler. Format, output and source options are ignored. The level, sampling,
+// context and service attributes still apply.
+func WithHandler(h slog.Handler) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.handler = h
+	})
+}
+
+// contextKey is the context key attributes are stored under.
+type contextKey struct{}
+
+// NewContext returns a context carrying attributes added to every log written with it, for example a
+// request ID, by the logger set by Run.
+func NewContext(ctx context.Context, attrs ...slog.Attr) context.Context {
+	existing, _ := ctx.Value(contextKey{}).([]slog.Attr)
+
+	return context.WithValue(ctx, contextKey{}, append(slices.Clip(existing), attrs...))
+}
+
+// contextHandler adds the attributes carried by the context to each record.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if attrs, ok := ctx.Value(contextKey{}).([]slog.Attr); ok {
+		r = r.Clone()
+		r.AddAttrs(attrs...)
+	}
+
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+// levelHandler filters records below the level, wrapping handlers which do not filter by the level
+// themselves, see WithHandler.
+type levelHandler struct {
+	slog.Handler
+	level slog.Leveler
+}
+
+func (h levelHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.level.Level() && h.Handler.Enabled(ctx, level)
+}
+
+func (h levelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return levelHandler{h.Handler.WithAttrs(attrs), h.level}
+}
+
+func (h levelHandler) WithGroup(name string) slog.Handler {
+	return levelHandler{h.Handler.WithGroup(name), h.level}
+}
+
+// A LogFunc writes a log to another logging library. Attributes are resolved and flattened, the keys of
+// attributes within groups are prefixed with the group names separated by dots.
+type LogFunc func(ctx context.Context, level slog.Level, msg string, attrs []slog.Attr)
+
+// NewFuncHandler returns a slog.Handler which writes logs with the LogFunc, for adapting loggers such as
+// zap and zerolog. Filtering by level is left to the logger.
+func NewFuncHandler(fn LogFunc) slog.Handler {
+	return &funcHandler{fn: fn}
+}
+
+// funcHandler is a slog.Handler which writes with a LogFunc.
+type funcHandler struct {
+	fn     LogFunc
+	attrs  []slog.Attr
+	prefix string
+}
+
+func (h *funcHandler) Enabled(context.Context, slog.Level) bool {
+	return true
+}
+
+func (h *funcHandler) Handle(ctx context.Context, r slog.Record) error {
+	attrs := slices.Clone(h.attrs)
+
+	r.Attrs(func(attr slog.Attr) bool {
+		attrs = flatten(attrs, h.prefix, attr)
+
+		return true
+	})
+
+	h.fn(ctx, r.Level, r.Message, attrs)
+
+	return nil
+}
+
+func (h *funcHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := *h
+	clone.attrs = slices.Clone(h.attrs)
+
+	for attr := range slices.Values(attrs) {
+		clone.attrs = flatten(clone.attrs, h.prefix, attr)
+	}
+
+	return &clone
+}
+
+func (h *funcHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	clone := *h
+	clone.prefix = h.prefix + name + "."
+
+	return &clone
+}
+
+// flatten appends the resolved attribute, flattening groups.
+func flatten(attrs []slog.Attr, prefix string, attr slog.Attr) []slog.Attr {
+	attr.Value = attr.Value.Resolve()
+
+	if attr.Equal(slog.Attr{}) {
+		return attrs
+	}
+
+	if attr.Value.Kind() != slog.KindGroup {
+		return append(attrs, slog.Attr{Key: prefix + attr.Key, Value: attr.Value})
+	}
+
+	if attr.Key != "" {
+		prefix += attr.Key + "."
+	}
+
+	for a := range slices.Values(attr.Value.Group()) {
+		attrs = flatten(attrs, prefix, a)
+	}
+
+	return attrs
+}
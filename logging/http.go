@@ -0,0 +1,67 @@
+package logging
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// levelBody is the request and response body of the LevelHandler.
+type levelBody struct {
+	Level    string `json:"level"`
+	Duration string `json:"duration,omitempty"`
+}
+
+// LevelHandler returns a http.Handler which reads the level on GET and changes it on PUT, for example
+// with a body of {"level":"debug"}. An optional duration, for example {"level":"debug","duration":"15m"},
+// reverts the change after it, see SetLevel. Both respond with the current level.
+func LevelHandler(level *slog.LevelVar) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead:
+		case http.MethodPut:
+			var body levelBody
+
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+
+				return
+			}
+
+			var l slog.Level
+
+			if err := l.UnmarshalText([]byte(body.Level)); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+
+				return
+			}
+
+			var d time.Duration
+
+			if body.Duration != "" {
+				var err error
+
+				if d, err = time.ParseDuration(body.Duration); err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+
+					return
+				}
+			}
+
+			SetLevel(level, l, d)
+		default:
+			w.Header().Set("Allow", "GET, HEAD, PUT")
+			w.WriteHeader(http.StatusMethodNotAllowed)
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+
+		if err := json.NewEncoder(w).Encode(levelBody{Level: level.Level().String()}); err != nil {
+			slog.ErrorContext(r.Context(), "failed to write log level", slog.String("err", err.Error()))
+		}
+	})
+}
@@ -0,0 +1,129 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+)
+
+// revert is a pending revert of a temporary level change.
+type revert struct {
+	timer *time.Timer
+	level slog.Level
+}
+
+// reverts holds the pending revert of each *slog.LevelVar.
+var (
+	revertsMtx sync.Mutex
+	reverts    = make(map[*slog.LevelVar]*revert)
+)
+
+// SetLevel changes the level, reverting it after the duration if greater than zero. Further temporary
+// changes before the revert extend it and revert to the level from before the first, a permanent change
+// cancels it.
+func SetLevel(level *slog.LevelVar, l slog.Level, d time.Duration) {
+	revertsMtx.Lock()
+	defer revertsMtx.Unlock()
+
+	previous := level.Level()
+
+	pending, ok := reverts[level]
+	if ok {
+		pending.timer.Stop()
+		delete(reverts, level)
+	}
+
+	level.Set(l)
+
+	attrs := []any{slog.String("from", previous.String()), slog.String("to", l.String())}
+
+	if d > 0 {
+		base := previous
+		if ok {
+			base = pending.level
+		}
+
+		r := &revert{level: base}
+		r.timer = time.AfterFunc(d, func() {
+			revertsMtx.Lock()
+			defer revertsMtx.Unlock()
+
+			if reverts[level] != r {
+				return
+			}
+
+			delete(reverts, level)
+
+			level.Set(base)
+
+			slog.Info("log level reverted", slog.String("to", base.String()))
+		})
+
+		reverts[level] = r
+
+		attrs = append(attrs, slog.Duration("revert", d))
+	}
+
+	slog.Info("log level changed", attrs...)
+}
+
+// WithLevelSignals lowers the level a step on SIGUSR1, for example from info to debug, and raises it a
+// step on SIGUSR2, reverting after the duration if greater than zero. The steps are debug, info, warn and
+// error. Not supported on platforms without these signals.
+func WithLevelSignals(d time.Duration) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.signals = true
+		cfg.signalsRevert = d
+	})
+}
+
+// handleLevelSignals steps the level on the level signals until the context is done.
+func handleLevelSignals(ctx context.Context, level *slog.LevelVar, d time.Duration) {
+	if lower == nil || raise == nil {
+		slog.WarnContext(ctx, "log level signals are not supported on this platform")
+
+		return
+	}
+
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, lower, raise)
+
+	go func() {
+		defer signal.Stop(ch)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case sig := <-ch:
+				l := level.Level()
+
+				switch {
+				case sig == lower && l > slog.LevelDebug:
+					SetLevel(level, step(l, -1), d)
+				case sig == raise && l < slog.LevelError:
+					SetLevel(level, step(l, 1), d)
+				}
+			}
+		}
+	}()
+}
+
+// step returns the level n steps above or below the given level, snapping custom levels to the nearest
+// step below.
+func step(l slog.Level, n int) slog.Level {
+	steps := []slog.Level{slog.LevelDebug, slog.LevelInfo, slog.LevelWarn, slog.LevelError}
+
+	i := 0
+
+	for j, s := range steps {
+		if l >= s {
+			i = j
+		}
+	}
+
+	return steps[max(0, min(len(steps)-1, i+n))]
+}
@@ -0,0 +1,235 @@
+// Package logging configures the default slog logger for a foundation service from the environment.
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"go.krak3n.io/foundation"
+)
+
+// A Format is the log output format.
+type Format string
+
+// Supported formats.
+const (
+	JSON Format = "json"
+	Text Format = "text"
+)
+
+// An Option configures the logging Runner.
+type Option interface {
+	apply(*config)
+}
+
+// Options is one or more Option.
+type Options []Option
+
+func (opts Options) apply(cfg *config) {
+	for opt := range slices.Values(opts) {
+		if opt != nil {
+			opt.apply(cfg)
+		}
+	}
+}
+
+type optionFunc func(*config)
+
+func (f optionFunc) apply(cfg *config) {
+	f(cfg)
+}
+
+// config holds the logging Runner configuration.
+type config struct {
+	format  Format
+	level   slog.Level
+	output  io.Writer
+	service string
+	version string
+	attrs   []slog.Attr
+	source  bool
+	sampler *sampler
+	handler slog.Handler
+
+	signals       bool
+	signalsRevert time.Duration
+}
+
+// WithFormat sets the format, defaults to LOG_FORMAT or JSON.
+func WithFormat(format Format) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.format = format
+	})
+}
+
+// WithLevel sets the minimum level, defaults to LOG_LEVEL, for example debug or warn, or info.
+func WithLevel(level slog.Level) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.level = level
+	})
+}
+
+// WithOutput sets where logs are written, defaults to os.Stderr. If the output has a Flush or Sync
+// method, such as a *bufio.Writer or *os.File, it is called when stopped so buffered logs are not lost,
+// as is the handler given to WithHandler.
+func WithOutput(w io.Writer) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.output = w
+	})
+}
+
+// WithService sets the service attribute, defaults to the name given to foundation.Run.
+func WithService(name string) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.service = name
+	})
+}
+
+// WithVersion sets the version attribute, defaults to SERVICE_VERSION. Omitted if empty.
+func WithVersion(version string) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.version = version
+	})
+}
+
+// WithAttrs adds attributes to every log.
+func WithAttrs(attrs ...slog.Attr) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.attrs = append(cfg.attrs, attrs...)
+	})
+}
+
+// WithSource adds the source file and line to every log.
+func WithSource() Option {
+	return optionFunc(func(cfg *config) {
+		cfg.source = true
+	})
+}
+
+// WithSampling samples repetitive logs. Within each interval the first logs with the same level and
+// message are logged, after which only every thereafter-th is. Errors are never sampled.
+func WithSampling(interval time.Duration, first, thereafter uint64) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.sampler = newSampler(interval, first, thereafter)
+	})
+}
+
+// Run returns a foundation.Runner which sets the default slog logger. Run it first so every later runner
+// logs with it; blueprint.Run does so. The level can be changed at runtime through the *slog.LevelVar in
+// the value store, see Level, SetLevel, LevelHandler and WithLevelSignals. Attributes carried by the
+// context, see NewContext, are added to each log.
+//
+// When stopped the output is flushed and the previous default logger restored. As runners are stopped
+// newest first this happens once everything else has stopped.
+func Run(opts ...Option) foundation.Runner {
+	return foundation.RunFunc(func(ctx context.Context, f foundation.F) {
+		cfg := config{
+			format:  JSON,
+			level:   slog.LevelInfo,
+			output:  os.Stderr,
+			service: foundation.ServiceName(f),
+			version: os.Getenv("SERVICE_VERSION"),
+		}
+
+		if format := os.Getenv("LOG_FORMAT"); format != "" {
+			cfg.format = Format(strings.ToLower(format))
+		}
+
+		if level := os.Getenv("LOG_LEVEL"); level != "" {
+			if err := cfg.level.UnmarshalText([]byte(level)); err != nil {
+				slog.WarnContext(ctx, "invalid LOG_LEVEL", slog.String("level", level))
+			}
+		}
+
+		Options(opts).apply(&cfg)
+
+		level := new(slog.LevelVar)
+		level.Set(cfg.level)
+
+		hopts := &slog.HandlerOptions{
+			Level:     level,
+			AddSource: cfg.source,
+		}
+
+		handler := cfg.handler
+
+		switch {
+		case handler != nil:
+			handler = levelHandler{Handler: handler, level: level}
+		case cfg.format == Text:
+			handler = slog.NewTextHandler(cfg.output, hopts)
+		default:
+			handler = slog.NewJSONHandler(cfg.output, hopts)
+		}
+
+		handler = contextHandler{handler}
+
+		if cfg.sampler != nil {
+			handler = &samplingHandler{Handler: handler, sampler: cfg.sampler}
+		}
+
+		attrs := []slog.Attr{slog.String("service", cfg.service)}
+
+		if cfg.version != "" {
+			attrs = append(attrs, slog.String("version", cfg.version))
+		}
+
+		handler = handler.WithAttrs(append(attrs, cfg.attrs...))
+
+		previous := slog.Default()
+
+		slog.SetDefault(slog.New(handler))
+
+		f.Values().Store(levelKey{}, level)
+
+		ctx, cancel := context.WithCancel(ctx)
+
+		if cfg.signals {
+			handleLevelSignals(ctx, level, cfg.signalsRevert)
+		}
+
+		f.On().Stop(func() {
+			cancel()
+
+			if cfg.handler != nil {
+				flush(cfg.handler)
+			} else {
+				flush(cfg.output)
+			}
+
+			slog.SetDefault(previous)
+		})
+	})
+}
+
+// flush flushes the output or handler if it supports it.
+func flush(v any) {
+	var err error
+
+	switch w := v.(type) {
+	case interface{ Flush() error }:
+		err = w.Flush()
+	case interface{ Sync() error }:
+		// Syncing stderr or stdout fails when they are not files, for example a terminal, which is fine.
+		if w != os.Stderr && w != os.Stdout {
+			err = w.Sync()
+		}
+	}
+
+	if err != nil {
+		slog.Warn("failed to flush logs", slog.String("err", err.Error()))
+	}
+}
+
+// levelKey is the value store key the level is stored under.
+type levelKey struct{}
+
+// Level returns the level of the default logger from the F's value store.
+func Level(f foundation.F) (*slog.LevelVar, bool) {
+	return foundation.Value[*slog.LevelVar](f, levelKey{})
+}
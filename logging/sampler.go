@@ -0,0 +1,81 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// sampler counts logs by level and message within fixed intervals.
+type sampler struct {
+	interval   time.Duration
+	first      uint64
+	thereafter uint64
+
+	mtx    sync.Mutex
+	window time.Time
+	counts map[samplerKey]uint64
+}
+
+type samplerKey struct {
+	level   slog.Level
+	message string
+}
+
+func newSampler(interval time.Duration, first, thereafter uint64) *sampler {
+	return &sampler{
+		interval:   interval,
+		first:      first,
+		thereafter: thereafter,
+		counts:     make(map[samplerKey]uint64),
+	}
+}
+
+// sample reports whether the record should be logged.
+func (s *sampler) sample(r slog.Record) bool {
+	if r.Level >= slog.LevelError {
+		return true
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if window := r.Time.Truncate(s.interval); !window.Equal(s.window) {
+		s.window = window
+		clear(s.counts)
+	}
+
+	key := samplerKey{level: r.Level, message: r.Message}
+
+	s.counts[key]++
+	n := s.counts[key]
+
+	if n <= s.first {
+		return true
+	}
+
+	return s.thereafter > 0 && (n-s.first)%s.thereafter == 0
+}
+
+// samplingHandler drops records not sampled by its sampler.
+type samplingHandler struct {
+	slog.Handler
+	sampler *sampler
+}
+
+func (h *samplingHandler) Handle(ctx context.Context, r slog.Record) error {
+	if !h.sampler.sample(r) {
+		return nil
+	}
+
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *samplingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &samplingHandler{Handler: h.Handler.WithAttrs(attrs), sampler: h.sampler}
+}
+
+func (h *samplingHandler) WithGroup(name string) slog.Handler {
+	return &samplingHandler{Handler: h.Handler.WithGroup(name), sampler: h.sampler}
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package logging
+
+import "os"
+
+// lower and raise are nil as there are no user signals on this platform, see WithLevelSignals.
+var (
+	lower os.Signal
+	raise os.Signal
+)
@@ -0,0 +1,14 @@
+//go:build unix
+
+package logging
+
+import (
+	"os"
+	"syscall"
+)
+
+// lower and raise are the signals which lower and raise the level, see WithLevelSignals.
+var (
+	lower os.Signal = syscall.SIGUSR1
+	raise os.Signal = syscall.SIGUSR2
+)
@@ -0,0 +1,59 @@
+// Package slogzap adapts a zap logger to a slog.Handler, so services standardised on zap can plug it into
+// foundation logging with logging.WithHandler.
+package slogzap
+
+import (
+	"context"
+	"log/slog"
+
+	"go.krak3n.io/foundation/logging"
+)
+
+// A Logger is a zap logger, satisfied by *zap.SugaredLogger from go.uber.org/zap, for example
+// zap.Must(zap.NewProduction()).Sugar().
+type Logger interface {
+	Debugw(msg string, keysAndValues ...any)
+	Infow(msg string, keysAndValues ...any)
+	Warnw(msg string, keysAndValues ...any)
+	Errorw(msg string, keysAndValues ...any)
+	Sync() error
+}
+
+// A Handler is a slog.Handler writing to a zap Logger. Levels below info are written at debug and above
+// warn at error, filtering by level is left to the Logger.
+//
+//	f.Run(ctx, logging.Run(logging.WithHandler(slogzap.NewHandler(logger.Sugar()))))
+type Handler struct {
+	slog.Handler
+	logger Logger
+}
+
+// NewHandler returns a Handler writing to the Logger.
+func NewHandler(l Logger) *Handler {
+	return &Handler{
+		Handler: logging.NewFuncHandler(func(_ context.Context, level slog.Level, msg string, attrs []slog.Attr) {
+			kv := make([]any, 0, len(attrs)*2)
+
+			for _, attr := range attrs {
+				kv = append(kv, attr.Key, attr.Value.Any())
+			}
+
+			switch {
+			case level < slog.LevelInfo:
+				l.Debugw(msg, kv...)
+			case level < slog.LevelWarn:
+				l.Infow(msg, kv...)
+			case level < slog.LevelError:
+				l.Warnw(msg, kv...)
+			default:
+				l.Errorw(msg, kv...)
+			}
+		}),
+		logger: l,
+	}
+}
+
+// Sync flushes any buffered logs, called by logging.Run when stopped.
+func (h *Handler) Sync() error {
+	return h.logger.Sync()
+}
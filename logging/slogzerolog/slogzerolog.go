@@ -0,0 +1,61 @@
+// Package slogzerolog adapts a zerolog logger to a slog.Handler, so services standardised on zerolog can
+// plug it into foundation logging with logging.WithHandler.
+package slogzerolog
+
+import (
+	"context"
+	"log/slog"
+
+	"go.krak3n.io/foundation/logging"
+)
+
+// A Logger writes a log with the given fields. Foundation does not depend on zerolog, whose events are
+// built with concrete types, so a Logger is a thin adapter over zerolog.Logger from
+// github.com/rs/zerolog, for example with LoggerFunc:
+//
+//	logger := zerolog.New(os.Stderr).With().Timestamp().Logger()
+//
+//	slogzerolog.LoggerFunc(func(level slog.Level, msg string, fields map[string]any) {
+//		logger.WithLevel(slogzerolog.Level(level)).Fields(fields).Msg(msg)
+//	})
+type Logger interface {
+	Log(level slog.Level, msg string, fields map[string]any)
+}
+
+// The LoggerFunc type is an adapter to allow the use of ordinary functions as Loggers.
+type LoggerFunc func(level slog.Level, msg string, fields map[string]any)
+
+// Log calls fn(level, msg, fields).
+func (fn LoggerFunc) Log(level slog.Level, msg string, fields map[string]any) {
+	fn(level, msg, fields)
+}
+
+// Level returns the zerolog level for the slog level, to convert with zerolog.Level(Level(level)). Levels
+// below info map to debug (0), info to info (1), warn to warn (2) and error and above to error (3).
+func Level(level slog.Level) int8 {
+	switch {
+	case level < slog.LevelInfo:
+		return 0
+	case level < slog.LevelWarn:
+		return 1
+	case level < slog.LevelError:
+		return 2
+	default:
+		return 3
+	}
+}
+
+// NewHandler returns a slog.Handler writing to the Logger, filtering by level is left to the Logger.
+//
+//	f.Run(ctx, logging.Run(logging.WithHandler(slogzerolog.NewHandler(logger))))
+func NewHandler(l Logger) slog.Handler {
+	return logging.NewFuncHandler(func(_ context.Context, level slog.Level, msg string, attrs []slog.Attr) {
+		fields := make(map[string]any, len(attrs))
+
+		for _, attr := range attrs {
+			fields[attr.Key] = attr.Value.Any()
+		}
+
+		l.Log(level, msg, fields)
+	})
+}
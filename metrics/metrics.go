@@ -0,0 +1,89 @@
+package metrics
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// A Counter is a metric which only ever increases.
+type Counter interface {
+	Add(delta float64)
+}
+
+// A Gauge is a metric which can go up and down.
+type Gauge interface {
+	Set(v float64)
+	Add(delta float64)
+}
+
+// A Histogram is a metric which samples observations into buckets.
+type Histogram interface {
+	Observe(v float64)
+}
+
+// Labels are key value pairs which identify a metric along with its name.
+type Labels map[string]string
+
+// String returns the labels in a stable key=value,key=value form.
+func (l Labels) String() string {
+	var b strings.Builder
+
+	for i, k := range slices.Sorted(maps.Keys(l)) {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(l[k])
+	}
+
+	return b.String()
+}
+
+// A Provider constructs metrics. Calling a constructor with the same name and labels more than once
+// returns the same metric.
+type Provider interface {
+	Counter(name string, labels Labels) Counter
+	Gauge(name string, labels Labels) Gauge
+	Histogram(name string, labels Labels) Histogram
+}
+
+var (
+	providerMtx sync.RWMutex
+	provider    Provider = DefaultRegistry()
+)
+
+// SetProvider sets the global Provider used to construct foundation metrics. Metrics which have already
+// been constructed are not moved to the new provider so this should be called before anything runs.
+func SetProvider(p Provider) {
+	providerMtx.Lock()
+	defer providerMtx.Unlock()
+
+	provider = p
+}
+
+// GlobalProvider returns the global metrics Provider.
+func GlobalProvider() Provider {
+	providerMtx.RLock()
+	defer providerMtx.RUnlock()
+
+	return provider
+}
+
+// NewCounter returns a Counter from the global Provider.
+func NewCounter(name string, labels Labels) Counter {
+	return GlobalProvider().Counter(name, labels)
+}
+
+// NewGauge returns a Gauge from the global Provider.
+func NewGauge(name string, labels Labels) Gauge {
+	return GlobalProvider().Gauge(name, labels)
+}
+
+// NewHistogram returns a Histogram from the global Provider.
+func NewHistogram(name string, labels Labels) Histogram {
+	return GlobalProvider().Histogram(name, labels)
+}
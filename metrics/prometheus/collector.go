@@ -0,0 +1,81 @@
+package prometheus
+
+import (
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.krak3n.io/foundation/metrics"
+)
+
+// start approximates the process start time.
+var start = time.Now()
+
+// GoCollector returns a Gatherer of Go runtime metrics such as goroutines, memory and garbage collection.
+func GoCollector() Gatherer {
+	return GathererFunc(func() []metrics.Sample {
+		var ms runtime.MemStats
+
+		runtime.ReadMemStats(&ms)
+
+		return []metrics.Sample{
+			gauge("go_goroutines", float64(runtime.NumGoroutine())),
+			gauge("go_gomaxprocs", float64(runtime.GOMAXPROCS(0))),
+			{Name: "go_info", Kind: metrics.KindGauge, Labels: metrics.Labels{"version": runtime.Version()}, Value: 1},
+			gauge("go_memstats_alloc_bytes", float64(ms.Alloc)),
+			gauge("go_memstats_heap_inuse_bytes", float64(ms.HeapInuse)),
+			gauge("go_memstats_heap_objects", float64(ms.HeapObjects)),
+			gauge("go_memstats_sys_bytes", float64(ms.Sys)),
+			counter("go_memstats_alloc_bytes_total", float64(ms.TotalAlloc)),
+			counter("go_gc_cycles_total", float64(ms.NumGC)),
+			counter("go_gc_pause_seconds_total", time.Duration(ms.PauseTotalNs).Seconds()),
+		}
+	})
+}
+
+// ProcessCollector returns a Gatherer of process metrics. CPU, memory and file descriptor metrics are
+// read from /proc so are only available on Linux.
+func ProcessCollector() Gatherer {
+	return GathererFunc(func() []metrics.Sample {
+		samples := []metrics.Sample{
+			gauge("process_start_time_seconds", float64(start.UnixNano())/1e9),
+		}
+
+		if b, err := os.ReadFile("/proc/self/stat"); err == nil {
+			// Fields following the command, which may contain spaces, in brackets. utime and stime are
+			// fields 14 and 15 in clock ticks, which are 100 per second on Linux.
+			if _, rest, ok := strings.Cut(string(b), ") "); ok {
+				if fields := strings.Fields(rest); len(fields) > 12 {
+					utime, _ := strconv.ParseFloat(fields[11], 64)
+					stime, _ := strconv.ParseFloat(fields[12], 64)
+
+					samples = append(samples, counter("process_cpu_seconds_total", (utime+stime)/100))
+				}
+			}
+		}
+
+		if b, err := os.ReadFile("/proc/self/statm"); err == nil {
+			if fields := strings.Fields(string(b)); len(fields) > 1 {
+				pages, _ := strconv.ParseFloat(fields[1], 64)
+
+				samples = append(samples, gauge("process_resident_memory_bytes", pages*float64(os.Getpagesize())))
+			}
+		}
+
+		if entries, err := os.ReadDir("/proc/self/fd"); err == nil {
+			samples = append(samples, gauge("process_open_fds", float64(len(entries))))
+		}
+
+		return samples
+	})
+}
+
+func gauge(name string, v float64) metrics.Sample {
+	return metrics.Sample{Name: name, Kind: metrics.KindGauge, Value: v}
+}
+
+func counter(name string, v float64) metrics.Sample {
+	return metrics.Sample{Name: name, Kind: metrics.KindCounter, Value: v}
+}
@@ -0,0 +1,195 @@
+// Package prometheus exports foundation metrics in the Prometheus text exposition format, along with Go
+// runtime and process metrics, without depending on the Prometheus client library.
+package prometheus
+
+import (
+	"bufio"
+	"cmp"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.krak3n.io/foundation/metrics"
+)
+
+// ContentType is the content type of the text exposition format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// A Gatherer gathers metric samples, *metrics.Registry is a Gatherer.
+type Gatherer interface {
+	Gather() []metrics.Sample
+}
+
+// The GathererFunc type is an adapter to allow the use of ordinary functions as Gatherers.
+type GathererFunc func() []metrics.Sample
+
+// Gather calls fn().
+func (fn GathererFunc) Gather() []metrics.Sample {
+	return fn()
+}
+
+// A Registry gathers samples from one or more Gatherers and writes them in the text exposition format.
+type Registry struct {
+	mtx       sync.RWMutex
+	gatherers []Gatherer
+}
+
+// NewRegistry returns a Registry gathering from the default foundation metrics registry, into which the
+// foundation runners record their metrics, and the Go runtime and process collectors, along with any
+// given Gatherers.
+func NewRegistry(gatherers ...Gatherer) *Registry {
+	return &Registry{
+		gatherers: append([]Gatherer{metrics.DefaultRegistry(), GoCollector(), ProcessCollector()}, gatherers...),
+	}
+}
+
+// Register adds Gatherers to the Registry.
+func (r *Registry) Register(gatherers ...Gatherer) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	r.gatherers = append(r.gatherers, gatherers...)
+}
+
+// Gather returns the samples of every Gatherer ordered by name.
+func (r *Registry) Gather() []metrics.Sample {
+	r.mtx.RLock()
+	gatherers := slices.Clone(r.gatherers)
+	r.mtx.RUnlock()
+
+	var samples []metrics.Sample
+
+	for g := range slices.Values(gatherers) {
+		samples = append(samples, g.Gather()...)
+	}
+
+	slices.SortStableFunc(samples, func(a, b metrics.Sample) int {
+		return cmp.Compare(name(a.Name), name(b.Name))
+	})
+
+	return samples
+}
+
+// WriteTo writes the samples in the text exposition format.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	cw := &countWriter{w: w}
+	bw := bufio.NewWriter(cw)
+
+	var last string
+
+	for s := range slices.Values(r.Gather()) {
+		n := name(s.Name)
+
+		if n != last {
+			fmt.Fprintf(bw, "# TYPE %s %s\n", n, s.Kind)
+			last = n
+		}
+
+		if s.Kind != metrics.KindHistogram {
+			fmt.Fprintf(bw, "%s%s %s\n", n, labels(s.Labels, ""), value(s.Value))
+
+			continue
+		}
+
+		for b := range slices.Values(s.Buckets) {
+			fmt.Fprintf(bw, "%s_bucket%s %d\n", n, labels(s.Labels, value(b.UpperBound)), b.Count)
+		}
+
+		fmt.Fprintf(bw, "%s_bucket%s %d\n", n, labels(s.Labels, "+Inf"), s.Count)
+		fmt.Fprintf(bw, "%s_sum%s %s\n", n, labels(s.Labels, ""), value(s.Sum))
+		fmt.Fprintf(bw, "%s_count%s %d\n", n, labels(s.Labels, ""), s.Count)
+	}
+
+	err := bw.Flush()
+
+	return cw.n, err
+}
+
+// ServeHTTP writes the samples in the text exposition format.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", ContentType)
+
+	if _, err := r.WriteTo(w); err != nil {
+		slog.ErrorContext(req.Context(), "failed to write metrics", slog.String("err", err.Error()))
+	}
+}
+
+// countWriter counts the bytes written.
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (w *countWriter) Write(b []byte) (int, error) {
+	n, err := w.w.Write(b)
+	w.n += int64(n)
+
+	return n, err
+}
+
+// name returns the metric name with characters not valid in a Prometheus metric name replaced by _.
+func name(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == ':':
+			return r
+		default:
+			return '_'
+		}
+	}, s)
+}
+
+// escaper escapes label values.
+var escaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// labels formats the labels, with the histogram le label if not empty, ordered by key.
+func labels(l metrics.Labels, le string) string {
+	if len(l) == 0 && le == "" {
+		return ""
+	}
+
+	var b strings.Builder
+
+	b.WriteByte('{')
+
+	for i, k := range slices.Sorted(maps.Keys(l)) {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+
+		fmt.Fprintf(&b, "%s=\"%s\"", name(k), escaper.Replace(l[k]))
+	}
+
+	if le != "" {
+		if len(l) > 0 {
+			b.WriteByte(',')
+		}
+
+		fmt.Fprintf(&b, "le=%q", le)
+	}
+
+	b.WriteByte('}')
+
+	return b.String()
+}
+
+// value formats a sample value.
+func value(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
@@ -0,0 +1,127 @@
+package prometheus
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"slices"
+	"time"
+
+	"go.krak3n.io/foundation"
+)
+
+// DefaultPath is the path metrics are served on unless configured with WithPath.
+const DefaultPath = "/metrics"
+
+// An Option configures the Prometheus Runner.
+type Option interface {
+	apply(*config)
+}
+
+// Options is one or more Option.
+type Options []Option
+
+func (opts Options) apply(cfg *config) {
+	for opt := range slices.Values(opts) {
+		if opt != nil {
+			opt.apply(cfg)
+		}
+	}
+}
+
+type optionFunc func(*config)
+
+func (f optionFunc) apply(cfg *config) {
+	f(cfg)
+}
+
+// config holds the Prometheus Runner configuration.
+type config struct {
+	registry *Registry
+	addr     string
+	path     string
+}
+
+// WithRegistry sets the Registry, defaults to NewRegistry().
+func WithRegistry(r *Registry) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.registry = r
+	})
+}
+
+// WithAddress serves the metrics with a HTTP server on the given address. Without an address no server
+// is run, the Registry can instead be mounted on an existing server, see Get.
+func WithAddress(addr string) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.addr = addr
+	})
+}
+
+// WithPath sets the path metrics are served on, defaults to DefaultPath.
+func WithPath(path string) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.path = path
+	})
+}
+
+// Run returns a foundation.Runner which stores the Registry in the value store, see Get, and, if
+// configured with WithAddress, serves it until stopped.
+func Run(opts ...Option) foundation.Runner {
+	return foundation.RunFunc(func(ctx context.Context, f foundation.F) {
+		cfg := config{
+			path: DefaultPath,
+		}
+
+		Options(opts).apply(&cfg)
+
+		if cfg.registry == nil {
+			cfg.registry = NewRegistry()
+		}
+
+		f.Values().Store(registryKey{}, cfg.registry)
+
+		if cfg.addr == "" {
+			return
+		}
+
+		mux := http.NewServeMux()
+		mux.Handle("GET "+cfg.path, cfg.registry)
+
+		server := &http.Server{
+			Handler:           mux,
+			ReadHeaderTimeout: 5 * time.Second,
+		}
+
+		lis, err := net.Listen("tcp", cfg.addr)
+		if err != nil {
+			f.Error(err)
+		}
+
+		f.On().Stop(func() {
+			ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+			defer cancel()
+
+			if err := server.Shutdown(ctx); err != nil {
+				f.Error(err)
+			}
+		})
+
+		slog.InfoContext(ctx, "serving prometheus metrics", slog.String("addr", lis.Addr().String()), slog.String("path", cfg.path))
+
+		f.Parallel()
+
+		if err := server.Serve(lis); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			f.Error(err)
+		}
+	})
+}
+
+// registryKey is the value store key the Registry is stored under.
+type registryKey struct{}
+
+// Get returns the Registry from the F's value store.
+func Get(f foundation.F) (*Registry, bool) {
+	return foundation.Value[*Registry](f, registryKey{})
+}
@@ -0,0 +1,235 @@
+package metrics
+
+import (
+	"expvar"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// A Kind is the type of a metric.
+type Kind uint8
+
+// Supported metric kinds.
+const (
+	KindCounter Kind = iota + 1
+	KindGauge
+	KindHistogram
+)
+
+func (k Kind) String() string {
+	var v string
+
+	switch k {
+	case KindCounter:
+		v = "counter"
+	case KindGauge:
+		v = "gauge"
+	case KindHistogram:
+		v = "histogram"
+	default:
+		v = "unknown"
+	}
+
+	return v
+}
+
+// DefaultBuckets are the histogram bucket upper bounds used by the Registry.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// A Bucket is a cumulative histogram bucket.
+type Bucket struct {
+	UpperBound float64 `json:"le"`
+	Count      uint64  `json:"count"`
+}
+
+// A Sample is a point in time snapshot of a metric held by a Registry.
+type Sample struct {
+	Name    string   `json:"name"`
+	Kind    Kind     `json:"-"`
+	Labels  Labels   `json:"labels,omitempty"`
+	Value   float64  `json:"value"`
+	Count   uint64   `json:"count,omitempty"`
+	Sum     float64  `json:"sum,omitempty"`
+	Buckets []Bucket `json:"buckets,omitempty"`
+}
+
+// A Registry is an in-memory Provider which holds every metric it has constructed so they can be
+// gathered and exported.
+type Registry struct {
+	mtx     sync.RWMutex
+	metrics map[string]*metric
+	kinds   map[string]Kind
+}
+
+var defaultRegistry = newDefaultRegistry()
+
+func newDefaultRegistry() *Registry {
+	r := NewRegistry()
+
+	expvar.Publish("foundation", expvar.Func(func() any {
+		return r.Gather()
+	}))
+
+	return r
+}
+
+// DefaultRegistry returns the Registry used as the global Provider unless SetProvider is called.
+// The default registry is published to expvar under the "foundation" key.
+func DefaultRegistry() *Registry {
+	return defaultRegistry
+}
+
+// NewRegistry constructs a new empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		metrics: make(map[string]*metric),
+		kinds:   make(map[string]Kind),
+	}
+}
+
+// Counter returns the Counter for the given name and labels. Like Gauge and Histogram it panics if the
+// name is already used by a metric of another kind, as exporters can only give a name one type.
+func (r *Registry) Counter(name string, labels Labels) Counter {
+	return r.get(name, KindCounter, labels)
+}
+
+// Gauge returns the Gauge for the given name and labels.
+func (r *Registry) Gauge(name string, labels Labels) Gauge {
+	return r.get(name, KindGauge, labels)
+}
+
+// Histogram returns the Histogram for the given name and labels.
+func (r *Registry) Histogram(name string, labels Labels) Histogram {
+	return r.get(name, KindHistogram, labels)
+}
+
+// Gather returns a snapshot of every metric in the registry ordered by name and labels.
+func (r *Registry) Gather() []Sample {
+	r.mtx.RLock()
+	keys := slices.Sorted(maps.Keys(r.metrics))
+	metrics := make([]*metric, 0, len(keys))
+
+	for _, k := range keys {
+		metrics = append(metrics, r.metrics[k])
+	}
+	r.mtx.RUnlock()
+
+	samples := make([]Sample, 0, len(metrics))
+
+	for _, m := range metrics {
+		samples = append(samples, m.sample())
+	}
+
+	return samples
+}
+
+func (r *Registry) get(name string, kind Kind, labels Labels) *metric {
+	key := strings.Join([]string{name, labels.String()}, "|")
+
+	r.mtx.RLock()
+	m, ok := r.metrics[key]
+	r.mtx.RUnlock()
+
+	if ok && m.kind == kind {
+		return m
+	}
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if k, ok := r.kinds[name]; ok && k != kind {
+		panic(fmt.Sprintf("metrics: %s is a %s, not a %s", name, k, kind))
+	}
+
+	if m, ok := r.metrics[key]; ok {
+		return m
+	}
+
+	m = &metric{
+		name:   name,
+		kind:   kind,
+		labels: maps.Clone(labels),
+	}
+
+	if kind == KindHistogram {
+		m.buckets = make([]atomic.Uint64, len(DefaultBuckets))
+	}
+
+	r.metrics[key] = m
+	r.kinds[name] = kind
+
+	return m
+}
+
+// metric is a single metric within a Registry. It implements Counter, Gauge and Histogram.
+type metric struct {
+	name    string
+	kind    Kind
+	labels  Labels
+	value   atomic.Uint64 // float64 bits
+	count   atomic.Uint64
+	sum     atomic.Uint64 // float64 bits
+	buckets []atomic.Uint64
+}
+
+func (m *metric) Set(v float64) {
+	m.value.Store(math.Float64bits(v))
+}
+
+func (m *metric) Add(delta float64) {
+	addFloat(&m.value, delta)
+}
+
+func (m *metric) Observe(v float64) {
+	m.count.Add(1)
+	addFloat(&m.sum, v)
+
+	if i := sort.SearchFloat64s(DefaultBuckets, v); i < len(m.buckets) {
+		m.buckets[i].Add(1)
+	}
+}
+
+func (m *metric) sample() Sample {
+	s := Sample{
+		Name:   m.name,
+		Kind:   m.kind,
+		Labels: m.labels,
+		Value:  math.Float64frombits(m.value.Load()),
+	}
+
+	if m.kind != KindHistogram {
+		return s
+	}
+
+	s.Count = m.count.Load()
+	s.Sum = math.Float64frombits(m.sum.Load())
+	s.Buckets = make([]Bucket, len(m.buckets))
+
+	var cumulative uint64
+
+	for i := range m.buckets {
+		cumulative += m.buckets[i].Load()
+		s.Buckets[i] = Bucket{
+			UpperBound: DefaultBuckets[i],
+			Count:      cumulative,
+		}
+	}
+
+	return s
+}
+
+// addFloat atomically adds delta to the float64 stored as bits in v.
+func addFloat(v *atomic.Uint64, delta float64) {
+	for {
+		old := v.Load()
+		if v.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+delta)) {
+			return
+		}
+	}
+}
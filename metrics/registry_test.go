@@ -0,0 +1,33 @@
+package metrics
+
+import "testing"
+
+func TestRegistryKindMismatch(t *testing.T) {
+	r := NewRegistry()
+
+	r.Counter("requests", Labels{"code": "200"})
+
+	defer func() {
+		if recover() == nil {
+			t.Error("want panic using a counter name for a histogram")
+		}
+	}()
+
+	r.Histogram("requests", Labels{"code": "500"})
+}
+
+func TestRegistrySameKind(t *testing.T) {
+	r := NewRegistry()
+
+	r.Histogram("latency", nil).Observe(0.2)
+	r.Histogram("latency", nil).Observe(0.3)
+
+	samples := r.Gather()
+	if len(samples) != 1 {
+		t.Fatalf("want 1 sample, got %d", len(samples))
+	}
+
+	if s := samples[0]; s.Kind != KindHistogram || s.Count != 2 {
+		t.Errorf("want histogram with 2 observations, got %s with %d", s.Kind, s.Count)
+	}
+}
@@ -0,0 +1,328 @@
+// Package statsd provides a metrics.Provider which sends foundation metrics to a StatsD or DogStatsD
+// agent over UDP.
+package statsd
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"maps"
+	"math"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.krak3n.io/foundation"
+	"go.krak3n.io/foundation/metrics"
+	"go.krak3n.io/foundation/tick"
+)
+
+// DefaultAddress is the default address of the agent.
+const DefaultAddress = "127.0.0.1:8125"
+
+// maxPacketSize keeps packets within a typical network MTU.
+const maxPacketSize = 1432
+
+// A Flavor is the wire format sent to the agent.
+type Flavor uint8
+
+const (
+	// DogStatsD sends labels as DogStatsD tags.
+	DogStatsD Flavor = iota
+	// StatsD sends plain StatsD, which has no tags, so label values are appended to the metric name
+	// ordered by label key, for example http_requests_total.GET.200.
+	StatsD
+)
+
+// An Option configures the Client.
+type Option interface {
+	apply(*config)
+}
+
+// Options is one or more Option.
+type Options []Option
+
+func (opts Options) apply(cfg *config) {
+	for opt := range slices.Values(opts) {
+		if opt != nil {
+			opt.apply(cfg)
+		}
+	}
+}
+
+type optionFunc func(*config)
+
+func (f optionFunc) apply(cfg *config) {
+	f(cfg)
+}
+
+// config holds the Client configuration.
+type config struct {
+	flavor   Flavor
+	prefix   string
+	tags     metrics.Labels
+	interval time.Duration
+}
+
+// WithFlavor sets the wire format, defaults to DogStatsD.
+func WithFlavor(flavor Flavor) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.flavor = flavor
+	})
+}
+
+// WithPrefix prefixes every metric name, for example "api.".
+func WithPrefix(prefix string) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.prefix = prefix
+	})
+}
+
+// WithTags adds tags to every metric, for example env and service.
+func WithTags(tags metrics.Labels) Option {
+	return optionFunc(func(cfg *config) {
+		maps.Copy(cfg.tags, tags)
+	})
+}
+
+// WithFlushInterval sets how often buffered metrics are sent when the Client is run, defaults to 1
+// second. Full packets are always sent immediately.
+func WithFlushInterval(d time.Duration) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.interval = d
+	})
+}
+
+// A Client is a metrics.Provider which buffers metrics into packets sent to a StatsD agent. Set it as
+// the global provider before constructing any runners so every foundation metric is sent to the agent:
+//
+//	client, err := statsd.New(statsd.DefaultAddress, statsd.WithTags(metrics.Labels{"service": "api"}))
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//
+//	metrics.SetProvider(client)
+//
+// A Client is also a foundation.Runner which flushes the buffer on a tick, and when stopped. Run it
+// first so it is stopped last, sending metrics recorded as everything else stops.
+type Client struct {
+	cfg  config
+	conn net.Conn
+
+	mtx     sync.Mutex
+	buf     bytes.Buffer
+	metrics map[string]*metric
+	dropped atomic.Uint64
+}
+
+// New returns a Client sending to the agent at the given address.
+func New(addr string, opts ...Option) (*Client, error) {
+	cfg := config{
+		tags:     make(metrics.Labels),
+		interval: time.Second,
+	}
+
+	Options(opts).apply(&cfg)
+
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Client{
+		cfg:     cfg,
+		conn:    conn,
+		metrics: make(map[string]*metric),
+	}, nil
+}
+
+// Counter returns the Counter for the given name and labels.
+func (c *Client) Counter(name string, labels metrics.Labels) metrics.Counter {
+	return c.get(name, labels, "c")
+}
+
+// Gauge returns the Gauge for the given name and labels.
+func (c *Client) Gauge(name string, labels metrics.Labels) metrics.Gauge {
+	return c.get(name, labels, "g")
+}
+
+// Histogram returns the Histogram for the given name and labels, sent as a DogStatsD histogram or a
+// StatsD timer.
+func (c *Client) Histogram(name string, labels metrics.Labels) metrics.Histogram {
+	if c.cfg.flavor == StatsD {
+		return c.get(name, labels, "ms")
+	}
+
+	return c.get(name, labels, "h")
+}
+
+// Dropped returns the number of packets which could not be sent.
+func (c *Client) Dropped() uint64 {
+	return c.dropped.Load()
+}
+
+// Run flushes the buffer until stopped, then flushes and closes the connection.
+func (c *Client) Run(ctx context.Context, f foundation.F) {
+	f.On().Stop(func() {
+		c.Flush()
+
+		if err := c.conn.Close(); err != nil {
+			slog.Warn("failed to close statsd connection", slog.String("err", err.Error()))
+		}
+	})
+
+	tick.Run(ctx, f, c.cfg.interval, func(context.Context, tick.Ticker) {
+		c.Flush()
+	})
+}
+
+// Flush sends any buffered metrics.
+func (c *Client) Flush() {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.flush()
+}
+
+// flush sends the buffer, the lock must be held.
+func (c *Client) flush() {
+	if c.buf.Len() == 0 {
+		return
+	}
+
+	// Trim the trailing newline.
+	if _, err := c.conn.Write(c.buf.Bytes()[:c.buf.Len()-1]); err != nil {
+		c.dropped.Add(1)
+		slog.Debug("failed to send statsd packet", slog.String("err", err.Error()))
+	}
+
+	c.buf.Reset()
+}
+
+// write buffers a line, sending the buffer first if the line would not fit.
+func (c *Client) write(line []byte) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if c.buf.Len()+len(line)+1 > maxPacketSize {
+		c.flush()
+	}
+
+	c.buf.Write(line)
+	c.buf.WriteByte('\n')
+}
+
+// get returns the metric for the name and labels.
+func (c *Client) get(name string, labels metrics.Labels, kind string) *metric {
+	key := strings.Join([]string{name, labels.String(), kind}, "|")
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if m, ok := c.metrics[key]; ok {
+		return m
+	}
+
+	m := &metric{
+		client: c,
+		prefix: c.line(name, labels),
+		suffix: "|" + kind + c.tags(labels),
+	}
+
+	c.metrics[key] = m
+
+	return m
+}
+
+// line returns the metric name as sent.
+func (c *Client) line(name string, labels metrics.Labels) string {
+	name = c.cfg.prefix + name
+
+	if c.cfg.flavor == StatsD {
+		for k := range slices.Values(slices.Sorted(maps.Keys(labels))) {
+			name += "." + labels[k]
+		}
+	}
+
+	return sanitize(name) + ":"
+}
+
+// tags returns the DogStatsD tags section.
+func (c *Client) tags(labels metrics.Labels) string {
+	if c.cfg.flavor == StatsD {
+		return ""
+	}
+
+	all := maps.Clone(c.cfg.tags)
+	maps.Copy(all, labels)
+
+	if len(all) == 0 {
+		return ""
+	}
+
+	tags := make([]string, 0, len(all))
+
+	for k := range slices.Values(slices.Sorted(maps.Keys(all))) {
+		tags = append(tags, sanitize(k)+":"+sanitize(all[k]))
+	}
+
+	return "|#" + strings.Join(tags, ",")
+}
+
+// sanitize replaces characters reserved by the protocol.
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ':', '|', '@', '#', ',', '\n':
+			return '_'
+		default:
+			return r
+		}
+	}, s)
+}
+
+// metric implements Counter, Gauge and Histogram. Counters send their deltas and histograms each
+// observation, whilst gauges track their value locally so Add sends the new absolute value, as
+// DogStatsD does not support relative gauges.
+type metric struct {
+	client *Client
+	prefix string
+	suffix string
+	value  atomic.Uint64 // float64 bits, gauges only
+}
+
+func (m *metric) send(v float64) {
+	m.client.write([]byte(m.prefix + strconv.FormatFloat(v, 'f', -1, 64) + m.suffix))
+}
+
+func (m *metric) Set(v float64) {
+	m.value.Store(math.Float64bits(v))
+	m.send(v)
+}
+
+func (m *metric) Add(delta float64) {
+	if !strings.HasPrefix(m.suffix, "|g") {
+		m.send(delta)
+
+		return
+	}
+
+	for {
+		old := m.value.Load()
+		v := math.Float64frombits(old) + delta
+
+		if m.value.CompareAndSwap(old, math.Float64bits(v)) {
+			m.send(v)
+
+			return
+		}
+	}
+}
+
+func (m *metric) Observe(v float64) {
+	m.send(v)
+}
@@ -0,0 +1,259 @@
+// Package otel manages the lifecycle of OpenTelemetry tracer, meter and logger providers, configured from
+// the standard OTEL_ environment variables.
+package otel
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.krak3n.io/foundation"
+)
+
+// Config is the OpenTelemetry configuration read from the environment, passed to the SetupFunc to
+// construct the providers.
+type Config struct {
+	// ServiceName is OTEL_SERVICE_NAME, defaulting to the name given to foundation.Run.
+	ServiceName string
+	// Endpoint is OTEL_EXPORTER_OTLP_ENDPOINT, empty to use the exporter default.
+	Endpoint string
+	// Protocol is OTEL_EXPORTER_OTLP_PROTOCOL, for example grpc or http/protobuf.
+	Protocol string
+	// Headers are OTEL_EXPORTER_OTLP_HEADERS, sent with every export.
+	Headers map[string]string
+	// Sampler is OTEL_TRACES_SAMPLER, defaulting to parentbased_always_on.
+	Sampler string
+	// SamplerRatio is OTEL_TRACES_SAMPLER_ARG for the ratio based samplers, defaulting to 1.
+	SamplerRatio float64
+	// Resource are the resource attributes from OTEL_RESOURCE_ATTRIBUTES and WithResourceAttributes,
+	// including service.name.
+	Resource map[string]string
+}
+
+// A Provider is an OpenTelemetry SDK provider, satisfied by *trace.TracerProvider, *metric.MeterProvider
+// and *log.LoggerProvider from go.opentelemetry.io/otel/sdk.
+type Provider interface {
+	ForceFlush(ctx context.Context) error
+	Shutdown(ctx context.Context) error
+}
+
+// Providers are the providers constructed by a SetupFunc, any may be nil.
+type Providers struct {
+	TracerProvider Provider
+	MeterProvider  Provider
+	LoggerProvider Provider
+}
+
+// A SetupFunc constructs the providers from the configuration, usually also setting them as the global
+// providers. Foundation does not depend on the OpenTelemetry SDK so the exporters and providers are
+// constructed by the SetupFunc:
+//
+//	func setup(ctx context.Context, cfg otel.Config) (otel.Providers, error) {
+//		exporter, err := otlptracegrpc.New(ctx)
+//		if err != nil {
+//			return otel.Providers{}, err
+//		}
+//
+//		tp := trace.NewTracerProvider(
+//			trace.WithBatcher(exporter),
+//			trace.WithResource(resource.NewSchemaless(attributes(cfg.Resource)...)),
+//		)
+//
+//		otelapi.SetTracerProvider(tp)
+//
+//		return otel.Providers{TracerProvider: tp}, nil
+//	}
+type SetupFunc func(ctx context.Context, cfg Config) (Providers, error)
+
+// An Option configures the OpenTelemetry Runner.
+type Option interface {
+	apply(*config)
+}
+
+// Options is one or more Option.
+type Options []Option
+
+func (opts Options) apply(cfg *config) {
+	for opt := range slices.Values(opts) {
+		if opt != nil {
+			opt.apply(cfg)
+		}
+	}
+}
+
+type optionFunc func(*config)
+
+func (f optionFunc) apply(cfg *config) {
+	f(cfg)
+}
+
+// config holds the OpenTelemetry Runner configuration.
+type config struct {
+	serviceName     string
+	resource        map[string]string
+	sampler         string
+	samplerRatio    float64
+	shutdownTimeout time.Duration
+}
+
+// WithServiceName sets the service name, overriding OTEL_SERVICE_NAME.
+func WithServiceName(name string) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.serviceName = name
+	})
+}
+
+// WithResourceAttributes adds resource attributes, overriding those from OTEL_RESOURCE_ATTRIBUTES.
+func WithResourceAttributes(attrs map[string]string) Option {
+	return optionFunc(func(cfg *config) {
+		maps.Copy(cfg.resource, attrs)
+	})
+}
+
+// WithSampler sets the trace sampler and its ratio for the ratio based samplers, overriding
+// OTEL_TRACES_SAMPLER and OTEL_TRACES_SAMPLER_ARG.
+func WithSampler(sampler string, ratio float64) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.sampler = sampler
+		cfg.samplerRatio = ratio
+	})
+}
+
+// WithShutdownTimeout bounds flushing and shutting down the providers, defaults to 5 seconds.
+func WithShutdownTimeout(d time.Duration) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.shutdownTimeout = d
+	})
+}
+
+// Run returns a foundation.Runner which constructs the providers with the SetupFunc, failing startup if
+// it errors, and stores them in the value store, see Get. Setting OTEL_SDK_DISABLED=true skips setup.
+//
+// The providers are flushed and shut down when stopped. As runners are stopped newest first, run it
+// first so the providers are shut down last, once everything using them has stopped.
+func Run(setup SetupFunc, opts ...Option) foundation.Runner {
+	return foundation.RunFunc(func(ctx context.Context, f foundation.F) {
+		cfg := config{
+			resource:        make(map[string]string),
+			shutdownTimeout: 5 * time.Second,
+		}
+
+		Options(opts).apply(&cfg)
+
+		if disabled, _ := strconv.ParseBool(os.Getenv("OTEL_SDK_DISABLED")); disabled {
+			slog.InfoContext(ctx, "opentelemetry disabled")
+
+			return
+		}
+
+		providers, err := setup(ctx, load(f, cfg))
+		if err != nil {
+			f.Error(fmt.Errorf("setup opentelemetry: %w", err))
+		}
+
+		f.Values().Store(providersKey{}, providers)
+
+		f.On().Stop(func() {
+			ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), cfg.shutdownTimeout)
+			defer cancel()
+
+			shutdown(ctx, "tracer", providers.TracerProvider)
+			shutdown(ctx, "meter", providers.MeterProvider)
+			// Shut the logger provider down last so logs about shutting down the others are exported.
+			shutdown(ctx, "logger", providers.LoggerProvider)
+		})
+	})
+}
+
+// shutdown flushes and shuts down the provider if not nil.
+func shutdown(ctx context.Context, name string, p Provider) {
+	if p == nil {
+		return
+	}
+
+	if err := p.ForceFlush(ctx); err != nil {
+		slog.WarnContext(ctx, "failed to flush opentelemetry provider", slog.String("provider", name), slog.String("err", err.Error()))
+	}
+
+	if err := p.Shutdown(ctx); err != nil {
+		slog.WarnContext(ctx, "failed to shutdown opentelemetry provider", slog.String("provider", name), slog.String("err", err.Error()))
+	}
+}
+
+// load reads the configuration from the environment.
+func load(f foundation.F, cfg config) Config {
+	c := Config{
+		ServiceName:  os.Getenv("OTEL_SERVICE_NAME"),
+		Endpoint:     os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"),
+		Protocol:     os.Getenv("OTEL_EXPORTER_OTLP_PROTOCOL"),
+		Headers:      pairs(os.Getenv("OTEL_EXPORTER_OTLP_HEADERS")),
+		Sampler:      os.Getenv("OTEL_TRACES_SAMPLER"),
+		SamplerRatio: 1,
+		Resource:     pairs(os.Getenv("OTEL_RESOURCE_ATTRIBUTES")),
+	}
+
+	if c.ServiceName == "" {
+		c.ServiceName = c.Resource["service.name"]
+	}
+
+	if c.ServiceName == "" {
+		c.ServiceName = foundation.ServiceName(f)
+	}
+
+	if cfg.serviceName != "" {
+		c.ServiceName = cfg.serviceName
+	}
+
+	if c.Sampler == "" {
+		c.Sampler = "parentbased_always_on"
+	}
+
+	if ratio, err := strconv.ParseFloat(os.Getenv("OTEL_TRACES_SAMPLER_ARG"), 64); err == nil {
+		c.SamplerRatio = ratio
+	}
+
+	if cfg.sampler != "" {
+		c.Sampler = cfg.sampler
+		c.SamplerRatio = cfg.samplerRatio
+	}
+
+	maps.Copy(c.Resource, cfg.resource)
+	c.Resource["service.name"] = c.ServiceName
+
+	return c
+}
+
+// pairs parses a list of comma separated, URL encoded key=value pairs.
+func pairs(s string) map[string]string {
+	m := make(map[string]string)
+
+	for pair := range strings.SplitSeq(s, ",") {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			continue
+		}
+
+		if v, err := url.QueryUnescape(strings.TrimSpace(value)); err == nil {
+			value = v
+		}
+
+		m[strings.TrimSpace(key)] = value
+	}
+
+	return m
+}
+
+// providersKey is the value store key the providers are stored under.
+type providersKey struct{}
+
+// Get returns the providers from the F's value store.
+func Get(f foundation.F) (Providers, bool) {
+	return foundation.Value[Providers](f, providersKey{})
+}
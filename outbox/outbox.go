@@ -0,0 +1,90 @@
+// Package outbox implements the transactional outbox pattern. Messages are inserted into an outbox
+// table in the same transaction as the state change which produced them, a Relay then polls the table
+// publishing messages and marking them sent, giving at least once delivery without distributed
+// transactions.
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// A Message is a row in the outbox table.
+type Message struct {
+	ID      int64
+	Topic   string
+	Key     []byte
+	Payload []byte
+}
+
+// A Publisher publishes messages relayed from the outbox, for example to a Kafka topic.
+type Publisher interface {
+	Publish(ctx context.Context, msg Message) error
+}
+
+// PublisherFunc is an adapter to allow the use of ordinary functions as a Publisher.
+type PublisherFunc func(ctx context.Context, msg Message) error
+
+// Publish calls f(ctx, msg).
+func (f PublisherFunc) Publish(ctx context.Context, msg Message) error {
+	return f(ctx, msg)
+}
+
+// A Locker ensures only one replica relays messages at a time, see WithLocker.
+type Locker interface {
+	// TryLock attempts to acquire the lock without blocking, returning true if the lock is held. Calling
+	// TryLock whilst the lock is held must return true.
+	TryLock(ctx context.Context) (bool, error)
+	// Unlock releases the lock.
+	Unlock(ctx context.Context) error
+}
+
+// A Placeholder returns the bind parameter placeholder for the nth, starting at 1, query argument.
+type Placeholder func(n int) string
+
+// Dollar returns PostgreSQL style placeholders: $1, $2.
+func Dollar(n int) string {
+	return fmt.Sprintf("$%d", n)
+}
+
+// Question returns MySQL and SQLite style placeholders: ?, ?.
+func Question(int) string {
+	return "?"
+}
+
+// An Execer executes a query, satisfied by *sql.DB, *sql.Tx and *sql.Conn.
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// Insert inserts a message into the outbox table, it should be called with the transaction making the
+// state change the message describes. The table is expected to have the columns:
+//
+//	id      auto incrementing primary key
+//	topic   text
+//	key     bytes, nullable
+//	payload bytes
+//	sent_at timestamp, nullable
+func Insert(ctx context.Context, exec Execer, table string, placeholder Placeholder, msg Message) error {
+	query := fmt.Sprintf("INSERT INTO %s (topic, key, payload) VALUES (%s, %s, %s)",
+		table, placeholder(1), placeholder(2), placeholder(3))
+
+	if _, err := exec.ExecContext(ctx, query, msg.Topic, msg.Key, msg.Payload); err != nil {
+		return fmt.Errorf("insert outbox message: %w", err)
+	}
+
+	return nil
+}
+
+// placeholders returns a comma separated list of n placeholders.
+func placeholders(placeholder Placeholder, offset, n int) string {
+	p := make([]string, n)
+
+	for i := range n {
+		p[i] = placeholder(offset + i + 1)
+	}
+
+	return strings.Join(p, ", ")
+}
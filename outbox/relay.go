@@ -0,0 +1,255 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"go.krak3n.io/foundation"
+	"go.krak3n.io/foundation/metrics"
+	"go.krak3n.io/foundation/tick"
+)
+
+// A RelayOption configures a Relay.
+type RelayOption interface {
+	applyRelayConfig(*relayConfig)
+}
+
+// RelayOptions is one or more RelayOption.
+type RelayOptions []RelayOption
+
+func (o RelayOptions) applyRelayConfig(cfg *relayConfig) {
+	for opt := range slices.Values(o) {
+		if opt != nil {
+			opt.applyRelayConfig(cfg)
+		}
+	}
+}
+
+type relayConfigFunc func(*relayConfig)
+
+func (f relayConfigFunc) applyRelayConfig(cfg *relayConfig) {
+	f(cfg)
+}
+
+// relayConfig holds the configuration for a Relay.
+type relayConfig struct {
+	table       string
+	placeholder Placeholder
+	interval    time.Duration
+	batchSize   int
+	locker      Locker
+}
+
+// WithTable sets the outbox table name, defaults to "outbox".
+func WithTable(table string) RelayOption {
+	return relayConfigFunc(func(cfg *relayConfig) {
+		cfg.table = table
+	})
+}
+
+// WithPlaceholder sets the bind parameter placeholder style of the database driver, defaults to Dollar.
+func WithPlaceholder(placeholder Placeholder) RelayOption {
+	return relayConfigFunc(func(cfg *relayConfig) {
+		cfg.placeholder = placeholder
+	})
+}
+
+// WithInterval sets how often the outbox is polled, defaults to 1 second. When a full batch is relayed
+// the next batch is polled immediately.
+func WithInterval(d time.Duration) RelayOption {
+	return relayConfigFunc(func(cfg *relayConfig) {
+		cfg.interval = d
+	})
+}
+
+// WithBatchSize sets the maximum number of messages relayed per transaction, defaults to 100.
+func WithBatchSize(n int) RelayOption {
+	return relayConfigFunc(func(cfg *relayConfig) {
+		cfg.batchSize = n
+	})
+}
+
+// WithLocker only relays messages whilst the lock is held, so when running multiple replicas only one
+// relays messages and ordering is preserved. The lock is released on stop. A lock.Lease satisfies Locker:
+//
+//	outbox.WithLocker(lock.NewLease(lock.Redis(client, "outbox"), 30*time.Second))
+func WithLocker(locker Locker) RelayOption {
+	return relayConfigFunc(func(cfg *relayConfig) {
+		cfg.locker = locker
+	})
+}
+
+// A Relay is a foundation.Runner which polls the outbox table publishing unsent messages in order.
+// Messages are marked sent in the same transaction they are read in, if the transaction fails to commit
+// after publishing the messages are published again on the next poll.
+type Relay struct {
+	db        *sql.DB
+	publisher Publisher
+	cfg       relayConfig
+
+	published metrics.Counter
+	failed    metrics.Counter
+}
+
+// Run returns a Relay which publishes messages from the outbox table in db using the publisher.
+func Run(db *sql.DB, publisher Publisher, opts ...RelayOption) *Relay {
+	cfg := relayConfig{
+		table:       "outbox",
+		placeholder: Dollar,
+		interval:    time.Second,
+		batchSize:   100,
+	}
+
+	RelayOptions(opts).applyRelayConfig(&cfg)
+
+	return &Relay{
+		db:        db,
+		publisher: publisher,
+		cfg:       cfg,
+		published: metrics.NewCounter("outbox_messages_total", metrics.Labels{"table": cfg.table, "status": "published"}),
+		failed:    metrics.NewCounter("outbox_messages_total", metrics.Labels{"table": cfg.table, "status": "failed"}),
+	}
+}
+
+// Insert inserts a message into the relays outbox table, see Insert.
+func (r *Relay) Insert(ctx context.Context, exec Execer, msg Message) error {
+	return Insert(ctx, exec, r.cfg.table, r.cfg.placeholder, msg)
+}
+
+// Run polls the outbox until told to stop.
+func (r *Relay) Run(ctx context.Context, f foundation.F) {
+	if locker := r.cfg.locker; locker != nil {
+		f.On().Stop(func() {
+			ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+			defer cancel()
+
+			if err := locker.Unlock(ctx); err != nil {
+				slog.Warn("failed to release outbox lock", slog.String("table", r.cfg.table), slog.String("err", err.Error()))
+			}
+		})
+	}
+
+	tick.Run(ctx, f, r.cfg.interval, func(ctx context.Context, _ tick.Ticker) {
+		if !r.leader(ctx) {
+			return
+		}
+
+		for ctx.Err() == nil {
+			n, err := r.relay(ctx)
+			if err != nil {
+				slog.ErrorContext(ctx, "failed to relay outbox messages", slog.String("table", r.cfg.table), slog.String("err", err.Error()))
+
+				return
+			}
+
+			if n < r.cfg.batchSize {
+				return
+			}
+		}
+	})
+}
+
+// leader reports whether this replica should relay messages.
+func (r *Relay) leader(ctx context.Context) bool {
+	if r.cfg.locker == nil {
+		return true
+	}
+
+	ok, err := r.cfg.locker.TryLock(ctx)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to acquire outbox lock", slog.String("table", r.cfg.table), slog.String("err", err.Error()))
+
+		return false
+	}
+
+	return ok
+}
+
+// relay relays a batch of messages, returning the number of messages published.
+func (r *Relay) relay(ctx context.Context) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("begin transaction: %w", err)
+	}
+
+	defer tx.Rollback() // Rollback is a no-op once committed.
+
+	msgs, err := r.pending(ctx, tx)
+	if err != nil {
+		return 0, err
+	}
+
+	var sent []any
+
+	for _, msg := range msgs {
+		// Stop at the first failure to preserve ordering, the message is retried on the next poll.
+		if err := r.publisher.Publish(ctx, msg); err != nil {
+			r.failed.Add(1)
+
+			slog.WarnContext(ctx, "failed to publish outbox message",
+				slog.String("table", r.cfg.table),
+				slog.Int64("id", msg.ID),
+				slog.String("topic", msg.Topic),
+				slog.String("err", err.Error()))
+
+			break
+		}
+
+		sent = append(sent, msg.ID)
+	}
+
+	if len(sent) == 0 {
+		return 0, nil
+	}
+
+	query := fmt.Sprintf("UPDATE %s SET sent_at = %s WHERE id IN (%s)",
+		r.cfg.table, r.cfg.placeholder(1), placeholders(r.cfg.placeholder, 1, len(sent)))
+
+	if _, err := tx.ExecContext(ctx, query, append([]any{time.Now().UTC()}, sent...)...); err != nil {
+		return 0, fmt.Errorf("mark messages sent: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit transaction: %w", err)
+	}
+
+	r.published.Add(float64(len(sent)))
+
+	// A short batch, including when publishing failed part way through, waits for the next poll.
+	return len(sent), nil
+}
+
+// pending reads a batch of unsent messages in insertion order.
+func (r *Relay) pending(ctx context.Context, tx *sql.Tx) ([]Message, error) {
+	query := fmt.Sprintf("SELECT id, topic, key, payload FROM %s WHERE sent_at IS NULL ORDER BY id LIMIT %d",
+		r.cfg.table, r.cfg.batchSize)
+
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("query outbox: %w", err)
+	}
+
+	defer rows.Close()
+
+	var msgs []Message
+
+	for rows.Next() {
+		var msg Message
+
+		if err := rows.Scan(&msg.ID, &msg.Topic, &msg.Key, &msg.Payload); err != nil {
+			return nil, fmt.Errorf("scan outbox message: %w", err)
+		}
+
+		msgs = append(msgs, msg)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("read outbox: %w", err)
+	}
+
+	return msgs, nil
+}
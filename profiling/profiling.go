@@ -0,0 +1,267 @@
+// Package profiling continuously captures runtime profiles and ships them to a Sink, so profiles from
+// around a production incident are always available.
+package profiling
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"runtime/pprof"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.krak3n.io/foundation"
+	"go.krak3n.io/foundation/tick"
+)
+
+// A Type is a profile type.
+type Type string
+
+// Supported profile types, other than CPU these are the names of runtime/pprof profiles.
+const (
+	CPU       Type = "cpu"
+	Heap      Type = "heap"
+	Allocs    Type = "allocs"
+	Goroutine Type = "goroutine"
+	Block     Type = "block"
+	Mutex     Type = "mutex"
+)
+
+// A Profile is a captured profile in the gzipped protobuf pprof format.
+type Profile struct {
+	// Service is the name given to foundation.Run.
+	Service string
+	Type    Type
+	// Start and End bound the period the profile covers, equal for point in time profiles such as heap.
+	Start time.Time
+	End   time.Time
+	Data  []byte
+}
+
+// A Sink stores or ships profiles.
+type Sink interface {
+	Write(ctx context.Context, p Profile) error
+}
+
+// The SinkFunc type is an adapter to allow the use of ordinary functions as Sinks.
+type SinkFunc func(ctx context.Context, p Profile) error
+
+// Write calls fn(ctx, p).
+func (fn SinkFunc) Write(ctx context.Context, p Profile) error {
+	return fn(ctx, p)
+}
+
+// An Option configures the profiling Runner.
+type Option interface {
+	apply(*config)
+}
+
+// Options is one or more Option.
+type Options []Option
+
+func (opts Options) apply(cfg *config) {
+	for opt := range slices.Values(opts) {
+		if opt != nil {
+			opt.apply(cfg)
+		}
+	}
+}
+
+type optionFunc func(*config)
+
+func (f optionFunc) apply(cfg *config) {
+	f(cfg)
+}
+
+// config holds the profiling Runner configuration.
+type config struct {
+	interval    time.Duration
+	cpuDuration time.Duration
+	types       []Type
+	limit       int
+	per         time.Duration
+	enabled     bool
+}
+
+// WithInterval sets how often profiles are captured, defaults to 1 minute.
+func WithInterval(d time.Duration) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.interval = d
+	})
+}
+
+// WithCPUDuration sets how long the CPU is profiled for each interval, defaults to 10 seconds.
+func WithCPUDuration(d time.Duration) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.cpuDuration = d
+	})
+}
+
+// WithTypes sets the profile types captured, defaults to CPU, Heap and Goroutine. Block and Mutex
+// profiles are empty unless enabled with runtime.SetBlockProfileRate and runtime.SetMutexProfileFraction.
+func WithTypes(types ...Type) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.types = types
+	})
+}
+
+// WithRateLimit captures at most n profiles per period, further captures are skipped until the period
+// has passed. Unlimited by default.
+func WithRateLimit(n int, per time.Duration) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.limit = n
+		cfg.per = per
+	})
+}
+
+// WithEnabled sets whether profiles are captured, defaults to PROFILING_ENABLED or true. Profiling can
+// also be switched on and off at runtime, see Runner.SetEnabled.
+func WithEnabled(enabled bool) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.enabled = enabled
+	})
+}
+
+// A Runner is a foundation.Runner which captures profiles on a tick, writing them to its Sink.
+type Runner struct {
+	sink    Sink
+	opts    []Option
+	cfg     config
+	enabled atomic.Bool
+
+	mtx     sync.Mutex
+	window  time.Time
+	written int
+}
+
+// Run returns a Runner writing profiles to the Sink.
+func Run(sink Sink, opts ...Option) *Runner {
+	return &Runner{
+		sink: sink,
+		opts: opts,
+	}
+}
+
+// SetEnabled switches profiling on or off.
+func (r *Runner) SetEnabled(enabled bool) {
+	r.enabled.Store(enabled)
+}
+
+// Run captures profiles until stopped.
+func (r *Runner) Run(ctx context.Context, f foundation.F) {
+	r.cfg = config{
+		interval:    time.Minute,
+		cpuDuration: 10 * time.Second,
+		types:       []Type{CPU, Heap, Goroutine},
+		enabled:     true,
+	}
+
+	if v, err := strconv.ParseBool(os.Getenv("PROFILING_ENABLED")); err == nil {
+		r.cfg.enabled = v
+	}
+
+	Options(r.opts).apply(&r.cfg)
+
+	r.enabled.Store(r.cfg.enabled)
+
+	service := foundation.ServiceName(f)
+
+	tick.Run(ctx, f, r.cfg.interval, func(ctx context.Context, _ tick.Ticker) {
+		if !r.enabled.Load() {
+			return
+		}
+
+		for t := range slices.Values(r.cfg.types) {
+			if !r.allow() {
+				slog.DebugContext(ctx, "profiling rate limited", slog.String("type", string(t)))
+
+				return
+			}
+
+			p, err := capture(ctx, t, r.cfg.cpuDuration)
+			if err != nil {
+				slog.WarnContext(ctx, "failed to capture profile", slog.String("type", string(t)), slog.String("err", err.Error()))
+
+				continue
+			}
+
+			// Stopped whilst profiling the CPU, the profile is partial so drop it.
+			if ctx.Err() != nil {
+				return
+			}
+
+			p.Service = service
+
+			if err := r.sink.Write(ctx, p); err != nil {
+				slog.WarnContext(ctx, "failed to write profile", slog.String("type", string(t)), slog.String("err", err.Error()))
+			}
+		}
+	})
+}
+
+// allow reports whether another profile may be captured within the rate limit.
+func (r *Runner) allow() bool {
+	if r.cfg.limit <= 0 {
+		return true
+	}
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if now := time.Now(); now.Sub(r.window) >= r.cfg.per {
+		r.window = now
+		r.written = 0
+	}
+
+	if r.written >= r.cfg.limit {
+		return false
+	}
+
+	r.written++
+
+	return true
+}
+
+// capture captures a profile of the given type, profiling the CPU for the duration or until the context
+// is done.
+func capture(ctx context.Context, t Type, d time.Duration) (Profile, error) {
+	var buf bytes.Buffer
+
+	p := Profile{
+		Type:  t,
+		Start: time.Now(),
+	}
+
+	if t == CPU {
+		// Fails if the CPU is already being profiled, for example through the pprof HTTP endpoints.
+		if err := pprof.StartCPUProfile(&buf); err != nil {
+			return p, err
+		}
+
+		select {
+		case <-time.After(d):
+		case <-ctx.Done():
+		}
+
+		pprof.StopCPUProfile()
+	} else {
+		profile := pprof.Lookup(string(t))
+		if profile == nil {
+			return p, fmt.Errorf("unknown profile %q", t)
+		}
+
+		if err := profile.WriteTo(&buf, 0); err != nil {
+			return p, err
+		}
+	}
+
+	p.End = time.Now()
+	p.Data = buf.Bytes()
+
+	return p, nil
+}
@@ -0,0 +1,105 @@
+package profiling
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// filename returns the name a profile is stored under, for example api-heap-1700000000.pb.gz.
+func filename(p Profile) string {
+	return fmt.Sprintf("%s-%s-%d.pb.gz", p.Service, p.Type, p.Start.Unix())
+}
+
+// Dir returns a Sink writing profiles as files in the directory, creating it if needed.
+func Dir(dir string) Sink {
+	return SinkFunc(func(_ context.Context, p Profile) error {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+
+		return os.WriteFile(filepath.Join(dir, filename(p)), p.Data, 0o644)
+	})
+}
+
+// An ObjectStore stores objects, for example a thin adapter over an S3 or GCS client.
+type ObjectStore interface {
+	PutObject(ctx context.Context, key string, data []byte) error
+}
+
+// Objects returns a Sink writing profiles to the ObjectStore under the key prefix, for example
+// profiles/api/.
+func Objects(store ObjectStore, prefix string) Sink {
+	return SinkFunc(func(ctx context.Context, p Profile) error {
+		return store.PutObject(ctx, prefix+filename(p), p.Data)
+	})
+}
+
+// Pyroscope returns a Sink pushing profiles to the ingest API of a Pyroscope server, for example
+// http://pyroscope:4040, naming the application after the service. A nil client uses http.DefaultClient.
+// Other profiling backends, such as Parca, can be pushed to with a SinkFunc.
+func Pyroscope(addr string, client *http.Client) Sink {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	addr = strings.TrimSuffix(addr, "/")
+
+	return SinkFunc(func(ctx context.Context, p Profile) error {
+		var body bytes.Buffer
+
+		mw := multipart.NewWriter(&body)
+
+		part, err := mw.CreateFormFile("profile", "profile.pprof")
+		if err != nil {
+			return err
+		}
+
+		if _, err := part.Write(p.Data); err != nil {
+			return err
+		}
+
+		if err := mw.Close(); err != nil {
+			return err
+		}
+
+		query := url.Values{
+			"name":       {fmt.Sprintf("%s.%s", p.Service, p.Type)},
+			"from":       {strconv.FormatInt(p.Start.Unix(), 10)},
+			"until":      {strconv.FormatInt(p.End.Unix(), 10)},
+			"format":     {"pprof"},
+			"spyName":    {"gospy"},
+			"sampleRate": {"100"},
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, addr+"/ingest?"+query.Encode(), &body)
+		if err != nil {
+			return err
+		}
+
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+
+		rsp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+
+		defer rsp.Body.Close()
+
+		if rsp.StatusCode < 200 || rsp.StatusCode > 299 {
+			msg, _ := io.ReadAll(io.LimitReader(rsp.Body, 1024))
+
+			return fmt.Errorf("%s: %s", rsp.Status, strings.TrimSpace(string(msg)))
+		}
+
+		return nil
+	})
+}
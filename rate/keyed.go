@@ -0,0 +1,88 @@
+package rate
+
+import (
+	"sync"
+	"time"
+
+	"go.krak3n.io/foundation/metrics"
+)
+
+// keyedSweepSize is the number of buckets held before full buckets are swept from memory.
+const keyedSweepSize = 1024
+
+// A Keyed limits the rate of events of each key with its own TokenBucket, for example per client. Buckets
+// which have refilled are equivalent to new ones so are swept from memory as the number of keys grows.
+type Keyed struct {
+	limit float64
+	burst int
+
+	mtx     sync.Mutex
+	buckets map[string]*TokenBucket
+
+	keys    metrics.Gauge
+	limited metrics.Counter
+}
+
+// NewKeyed returns a Keyed allowing each key limit events per second with bursts of up to burst events,
+// at least 1. The name is used to label metrics.
+func NewKeyed(name string, limit float64, burst int) *Keyed {
+	return &Keyed{
+		limit:   limit,
+		burst:   burst,
+		buckets: make(map[string]*TokenBucket),
+		keys:    metrics.NewGauge("rate_limiter_keys", metrics.Labels{"limiter": name}),
+		limited: metrics.NewCounter("rate_limiter_limited_total", metrics.Labels{"limiter": name}),
+	}
+}
+
+// Allow takes a token from the bucket of the key if one is available.
+func (k *Keyed) Allow(key string) bool {
+	return k.Take(key) == 0
+}
+
+// Take takes a token from the bucket of the key if one is available returning zero, otherwise returns how
+// long until one will be, see TokenBucket.Take.
+func (k *Keyed) Take(key string) time.Duration {
+	return k.take(key, time.Now())
+}
+
+func (k *Keyed) take(key string, now time.Time) time.Duration {
+	k.mtx.Lock()
+	defer k.mtx.Unlock()
+
+	if len(k.buckets) >= keyedSweepSize {
+		k.sweep(now)
+	}
+
+	b, ok := k.buckets[key]
+	if !ok {
+		b = newTokenBucket(k.limit, k.burst, now)
+		k.buckets[key] = b
+	}
+
+	k.keys.Set(float64(len(k.buckets)))
+
+	b.mtx.Lock()
+	wait := b.take(now)
+	b.mtx.Unlock()
+
+	if wait != 0 {
+		k.limited.Add(1)
+	}
+
+	return wait
+}
+
+// sweep removes the buckets which have refilled.
+func (k *Keyed) sweep(now time.Time) {
+	for key, b := range k.buckets {
+		b.mtx.Lock()
+		b.refill(now)
+		full := b.tokens >= b.burst
+		b.mtx.Unlock()
+
+		if full {
+			delete(k.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package rate
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestKeyedTake(t *testing.T) {
+	k := NewKeyed(t.Name(), 2, 2)
+
+	now := time.Now()
+
+	for range 2 {
+		if wait := k.take("a", now); wait != 0 {
+			t.Fatalf("want token within the burst, got wait %s", wait)
+		}
+	}
+
+	if wait := k.take("a", now); wait != 500*time.Millisecond {
+		t.Errorf("want wait 500ms over the burst, got %s", wait)
+	}
+
+	if wait := k.take("b", now); wait != 0 {
+		t.Errorf("want other keys unaffected, got wait %s", wait)
+	}
+
+	if wait := k.take("a", now.Add(500*time.Millisecond)); wait != 0 {
+		t.Errorf("want token once refilled, got wait %s", wait)
+	}
+}
+
+func TestKeyedSweep(t *testing.T) {
+	k := NewKeyed(t.Name(), 1, 1)
+
+	now := time.Now()
+
+	for i := range keyedSweepSize {
+		k.take(strconv.Itoa(i), now)
+	}
+
+	// Every bucket is spent, none can be swept.
+	k.take("spent", now)
+
+	if n := len(k.buckets); n != keyedSweepSize+1 {
+		t.Fatalf("want %d buckets, got %d", keyedSweepSize+1, n)
+	}
+
+	// Every bucket has refilled and is swept before the new key is added.
+	if wait := k.take("refilled", now.Add(time.Second)); wait != 0 {
+		t.Errorf("want token for a new key, got wait %s", wait)
+	}
+
+	if n := len(k.buckets); n != 1 {
+		t.Errorf("want refilled buckets swept, got %d buckets", n)
+	}
+}
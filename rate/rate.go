@@ -0,0 +1,244 @@
+// Package rate provides rate limiters capping the throughput of tickers, consumers and dispatchers
+// consistently. A TokenBucket allows bursts whilst limiting the average rate, a SlidingWindow strictly
+// caps the number of events within any window and a Keyed limits each key, for example each client, with
+// its own TokenBucket.
+//
+//	limiter := rate.NewTokenBucket(100, 10)
+//
+//	foundation.Run("worker", consumer.Run("orders", source, handler, consumer.WithRateLimit(limiter)))
+package rate
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+// A Limiter limits the rate of events.
+type Limiter interface {
+	// Allow takes an event if one is available now, returning false if not.
+	Allow() bool
+	// Wait blocks until an event is available, returning the context error if it is done first. Waiters
+	// are served in the order they called Wait.
+	Wait(ctx context.Context) error
+}
+
+// A TokenBucket is a Limiter allowing limit events per second on average in bursts of up to burst
+// events. Tokens are refilled continuously and each event takes one.
+type TokenBucket struct {
+	limit float64
+	burst float64
+
+	mtx    sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// NewTokenBucket returns a full TokenBucket allowing limit events per second with bursts of up to burst
+// events, at least 1.
+func NewTokenBucket(limit float64, burst int) *TokenBucket {
+	return newTokenBucket(limit, burst, time.Now())
+}
+
+func newTokenBucket(limit float64, burst int, now time.Time) *TokenBucket {
+	return &TokenBucket{
+		limit:  limit,
+		burst:  float64(max(burst, 1)),
+		tokens: float64(max(burst, 1)),
+		last:   now,
+	}
+}
+
+// Allow takes a token if one is available.
+func (b *TokenBucket) Allow() bool {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	b.refill(time.Now())
+
+	if b.tokens < 1 {
+		return false
+	}
+
+	b.tokens--
+
+	return true
+}
+
+// Take takes a token if one is available returning zero, otherwise returns how long until one will be
+// without taking it, for example to set a Retry-After header. A limit of zero or less never refills so a
+// negative duration is returned once the burst is spent.
+func (b *TokenBucket) Take() time.Duration {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	return b.take(time.Now())
+}
+
+func (b *TokenBucket) take(now time.Time) time.Duration {
+	b.refill(now)
+
+	if b.tokens >= 1 {
+		b.tokens--
+
+		return 0
+	}
+
+	if b.limit <= 0 {
+		return -1
+	}
+
+	return time.Duration((1 - b.tokens) / b.limit * float64(time.Second))
+}
+
+// Wait takes a token, waiting for one to be refilled if none are available. A limit of zero or less
+// never refills so Wait blocks until the context is done once the burst is spent.
+func (b *TokenBucket) Wait(ctx context.Context) error {
+	b.mtx.Lock()
+
+	b.refill(time.Now())
+
+	// Reserve the token by going into debt so later waiters queue behind this one.
+	b.tokens--
+
+	var wait time.Duration
+
+	if b.tokens < 0 {
+		if b.limit <= 0 {
+			wait = -1
+		} else {
+			wait = time.Duration(-b.tokens / b.limit * float64(time.Second))
+		}
+	}
+
+	b.mtx.Unlock()
+
+	if err := sleep(ctx, wait); err != nil {
+		b.mtx.Lock()
+		b.tokens = min(b.burst, b.tokens+1)
+		b.mtx.Unlock()
+
+		return err
+	}
+
+	return nil
+}
+
+// refill adds the tokens accrued since the last refill.
+func (b *TokenBucket) refill(now time.Time) {
+	if b.limit > 0 {
+		b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.limit)
+	}
+
+	b.last = now
+}
+
+// A SlidingWindow is a Limiter allowing at most n events within any window of the given duration,
+// without the bursts at window boundaries of fixed windows.
+type SlidingWindow struct {
+	n      int
+	window time.Duration
+
+	mtx sync.Mutex
+	// The times of the events within the window, oldest first, including those reserved by waiters.
+	events []time.Time
+}
+
+// NewSlidingWindow returns a SlidingWindow allowing at most n events, at least 1, within any window.
+func NewSlidingWindow(n int, window time.Duration) *SlidingWindow {
+	n = max(n, 1)
+
+	return &SlidingWindow{
+		n:      n,
+		window: window,
+		events: make([]time.Time, 0, n),
+	}
+}
+
+// Allow takes an event if fewer than n have been taken within the window.
+func (w *SlidingWindow) Allow() bool {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	now := time.Now()
+
+	w.expire(now)
+
+	if len(w.events) >= w.n {
+		return false
+	}
+
+	w.events = append(w.events, now)
+
+	return true
+}
+
+// Wait takes an event, waiting until the window has room for it.
+func (w *SlidingWindow) Wait(ctx context.Context) error {
+	w.mtx.Lock()
+
+	now := time.Now()
+
+	w.expire(now)
+
+	// Reserve the earliest time the window has room, once the nth most recent event has left it.
+	at := now
+
+	if len(w.events) >= w.n {
+		at = w.events[len(w.events)-w.n].Add(w.window)
+	}
+
+	w.events = append(w.events, at)
+
+	w.mtx.Unlock()
+
+	if err := sleep(ctx, at.Sub(now)); err != nil {
+		w.mtx.Lock()
+
+		if i := slices.Index(w.events, at); i >= 0 {
+			w.events = slices.Delete(w.events, i, i+1)
+		}
+
+		w.mtx.Unlock()
+
+		return err
+	}
+
+	return nil
+}
+
+// expire removes the events which have left the window.
+func (w *SlidingWindow) expire(now time.Time) {
+	i := 0
+
+	for i < len(w.events) && !w.events[i].After(now.Add(-w.window)) {
+		i++
+	}
+
+	w.events = slices.Delete(w.events, 0, i)
+}
+
+// sleep waits for the given duration or until the context is done, a negative duration waits until the
+// context is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d == 0 {
+		return ctx.Err()
+	}
+
+	if d < 0 {
+		<-ctx.Done()
+
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,162 @@
+package rate
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTokenBucketTake(t *testing.T) {
+	tests := []struct {
+		name  string
+		limit float64
+		burst int
+		taken int
+		wait  time.Duration
+	}{
+		{
+			name:  "within burst",
+			limit: 10,
+			burst: 3,
+			taken: 2,
+		},
+		{
+			name:  "over burst",
+			limit: 10,
+			burst: 3,
+			taken: 3,
+			wait:  100 * time.Millisecond,
+		},
+		{
+			name:  "burst at least one",
+			limit: 4,
+			burst: 0,
+			taken: 1,
+			wait:  250 * time.Millisecond,
+		},
+		{
+			name:  "never refills",
+			limit: 0,
+			burst: 1,
+			taken: 1,
+			wait:  -1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Now()
+			b := newTokenBucket(tt.limit, tt.burst, now)
+
+			for range tt.taken {
+				if wait := b.take(now); wait != 0 {
+					t.Fatalf("want token, got wait %s", wait)
+				}
+			}
+
+			if wait := b.take(now); wait != tt.wait {
+				t.Errorf("want wait %s, got %s", tt.wait, wait)
+			}
+		})
+	}
+}
+
+func TestTokenBucketRefill(t *testing.T) {
+	now := time.Now()
+	b := newTokenBucket(10, 2, now)
+
+	b.take(now)
+	b.take(now)
+
+	// Refilling is capped at the burst.
+	b.refill(now.Add(time.Hour))
+
+	if b.tokens != 2 {
+		t.Errorf("want 2 tokens, got %v", b.tokens)
+	}
+}
+
+func TestTokenBucketWait(t *testing.T) {
+	b := NewTokenBucket(100, 1)
+
+	if err := b.Wait(context.Background()); err != nil {
+		t.Fatalf("want token from the burst, got %v", err)
+	}
+
+	start := time.Now()
+
+	if err := b.Wait(context.Background()); err != nil {
+		t.Fatalf("want token once refilled, got %v", err)
+	}
+
+	if d := time.Since(start); d < 5*time.Millisecond {
+		t.Errorf("want wait for the refill, waited %s", d)
+	}
+}
+
+func TestTokenBucketWaitCanceled(t *testing.T) {
+	b := NewTokenBucket(0, 1)
+
+	if !b.Allow() {
+		t.Fatal("want token from the burst")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := b.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("want deadline exceeded, got %v", err)
+	}
+
+	// The reservation of the canceled waiter is returned.
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	if b.tokens != 0 {
+		t.Errorf("want 0 tokens, got %v", b.tokens)
+	}
+}
+
+func TestSlidingWindowAllow(t *testing.T) {
+	w := NewSlidingWindow(2, 50*time.Millisecond)
+
+	for range 2 {
+		if !w.Allow() {
+			t.Fatal("want event within the window")
+		}
+	}
+
+	if w.Allow() {
+		t.Fatal("want event over the window rejected")
+	}
+
+	time.Sleep(60 * time.Millisecond)
+
+	if !w.Allow() {
+		t.Error("want event once the window has passed")
+	}
+}
+
+func TestSlidingWindowWaitCanceled(t *testing.T) {
+	w := NewSlidingWindow(1, time.Hour)
+
+	if !w.Allow() {
+		t.Fatal("want event within the window")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := w.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("want deadline exceeded, got %v", err)
+	}
+
+	// The reservation of the canceled waiter is removed.
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	if n := len(w.events); n != 1 {
+		t.Errorf("want 1 event, got %d", n)
+	}
+}
@@ -0,0 +1,247 @@
+package foundation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A Check is a precondition of running, for example a required environment variable, see Require.
+type Check interface {
+	Name() string
+	Check(ctx context.Context) error
+}
+
+// check is a Check calling a function.
+type check struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+// NewCheck returns a Check with the name calling the function, which returns an error describing why the
+// precondition is not met.
+func NewCheck(name string, fn func(ctx context.Context) error) Check {
+	return check{
+		name: name,
+		fn:   fn,
+	}
+}
+
+// Name returns the name of the check.
+func (c check) Name() string {
+	return c.name
+}
+
+// Check calls the check function.
+func (c check) Check(ctx context.Context) error {
+	return c.fn(ctx)
+}
+
+// Require checks the preconditions before the runner runs, for example with EnvCheck, WritableCheck,
+// OpenFilesCheck and ClockCheck. Every check is run and if any fail the runner is not run, Wait returns a
+// PreconditionError reporting every failure so the environment can be fixed at once rather than
+// surprising the service mid runtime. Each check is given 10 seconds, a check which has not returned by
+// then fails so a hung check can not stop the service from starting.
+func Require(checks ...Check) RunOption {
+	return runConfigFunc(func(cfg *runConfig) {
+		cfg.checks = append(cfg.checks, checks...)
+	})
+}
+
+// A PreconditionError reports the checks which failed, see Require.
+type PreconditionError struct {
+	// Failures are the failures of the checks, each prefixed with the name of its check, in the order the
+	// checks were given.
+	Failures []error
+}
+
+// Unwrap returns the failures.
+func (err PreconditionError) Unwrap() []error {
+	return err.Failures
+}
+
+func (err PreconditionError) Error() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "%d precondition(s) failed:", len(err.Failures))
+
+	for failure := range slices.Values(err.Failures) {
+		fmt.Fprintf(&b, "\n  - %s", failure)
+	}
+
+	return b.String()
+}
+
+// checkTimeout is how long each precondition check is given.
+const checkTimeout = 10 * time.Second
+
+// checkPreconditions runs the checks concurrently, returning a PreconditionError if any fail.
+func checkPreconditions(ctx context.Context, checks []Check) error {
+	errs := make([]error, len(checks))
+
+	var wg sync.WaitGroup
+
+	for i, c := range checks {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			errs[i] = runCheck(ctx, c, checkTimeout)
+		}()
+	}
+
+	wg.Wait()
+
+	var failures []error
+
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+
+		// Checks of several things report each failure on its own line.
+		joined := []error{err}
+		if v, ok := err.(interface{ Unwrap() []error }); ok {
+			joined = v.Unwrap()
+		}
+
+		for err := range slices.Values(joined) {
+			failures = append(failures, fmt.Errorf("%s: %w", checks[i].Name(), err))
+		}
+	}
+
+	if len(failures) > 0 {
+		return PreconditionError{Failures: failures}
+	}
+
+	return nil
+}
+
+// runCheck runs the check, failing it if it has not returned within the timeout. A check which ignores
+// its context is left to return in the background.
+func runCheck(ctx context.Context, c Check, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	result := make(chan error, 1)
+
+	go func() {
+		defer func() {
+			if rec := recover(); rec != nil {
+				result <- PanicError{Cause: rec}
+			}
+		}()
+
+		result <- c.Check(ctx)
+	}()
+
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("not checked within %s: %w", timeout, context.Cause(ctx))
+	}
+}
+
+// EnvCheck returns a Check which fails if any of the environment variables are not set.
+func EnvCheck(names ...string) Check {
+	return NewCheck("env", func(context.Context) error {
+		var missing []string
+
+		for name := range slices.Values(names) {
+			if _, ok := os.LookupEnv(name); !ok {
+				missing = append(missing, name)
+			}
+		}
+
+		if len(missing) > 0 {
+			return fmt.Errorf("not set: %s", strings.Join(missing, ", "))
+		}
+
+		return nil
+	})
+}
+
+// WritableCheck returns a Check which fails if a file can not be created in any of the directories.
+func WritableCheck(dirs ...string) Check {
+	return NewCheck("writable", func(context.Context) error {
+		var errs []error
+
+		for dir := range slices.Values(dirs) {
+			fh, err := os.CreateTemp(dir, ".foundation-*")
+			if err != nil {
+				errs = append(errs, err)
+
+				continue
+			}
+
+			fh.Close()
+			os.Remove(fh.Name())
+		}
+
+		return errors.Join(errs...)
+	})
+}
+
+// OpenFilesCheck returns a Check which fails if the soft limit on open files, ulimit -n, is below n. It
+// always passes on platforms without the limit.
+func OpenFilesCheck(n uint64) Check {
+	return NewCheck("open files", func(context.Context) error {
+		limit, err := openFilesLimit()
+		if errors.Is(err, errors.ErrUnsupported) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if limit < n {
+			return fmt.Errorf("limit %d is below the required %d", limit, n)
+		}
+
+		return nil
+	})
+}
+
+// ClockCheck returns a Check which fails if the wall clock is before the given time, a sign it has not
+// been set. If the time is zero the commit time of the build is used, when the build has one.
+func ClockCheck(after time.Time) Check {
+	return NewCheck("clock", func(context.Context) error {
+		earliest := after
+		if earliest.IsZero() {
+			earliest = buildTime()
+		}
+
+		if now := time.Now(); now.Before(earliest) {
+			return fmt.Errorf("wall clock %s is before %s, is it set?", now.UTC().Format(time.RFC3339), earliest.UTC().Format(time.RFC3339))
+		}
+
+		return nil
+	})
+}
+
+// buildTime returns the commit time of the build, or the zero time if unknown.
+func buildTime() time.Time {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return time.Time{}
+	}
+
+	for setting := range slices.Values(info.Settings) {
+		if setting.Key == "vcs.time" {
+			t, _ := time.Parse(time.RFC3339, setting.Value)
+
+			return t
+		}
+	}
+
+	return time.Time{}
+}
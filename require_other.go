@@ -0,0 +1,10 @@
+//go:build !unix
+
+package foundation
+
+import "errors"
+
+// openFilesLimit returns errors.ErrUnsupported as there is no limit on open files on this platform.
+func openFilesLimit() (uint64, error) {
+	return 0, errors.ErrUnsupported
+}
@@ -0,0 +1,16 @@
+//go:build unix
+
+package foundation
+
+import "syscall"
+
+// openFilesLimit returns the soft limit on open files.
+func openFilesLimit() (uint64, error) {
+	var limit syscall.Rlimit
+
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &limit); err != nil {
+		return 0, err
+	}
+
+	return uint64(limit.Cur), nil
+}
@@ -3,22 +3,274 @@ package foundation
 import (
 	"context"
 	"errors"
+	"flag"
 	"log/slog"
 	"os"
 	"os/signal"
+	"slices"
 	"sync"
 	"syscall"
+	"time"
 )
 
-// Run runs a the given foundation runner.
-func Run(name string, runner Runner) {
+// A RunOption configures Run.
+type RunOption interface {
+	applyRunConfig(*runConfig)
+}
+
+// RunOptions is one or more RunOption.
+type RunOptions []RunOption
+
+func (o RunOptions) applyRunConfig(cfg *runConfig) {
+	for opt := range slices.Values(o) {
+		if opt != nil {
+			opt.applyRunConfig(cfg)
+		}
+	}
+}
+
+type runConfigFunc func(*runConfig)
+
+func (f runConfigFunc) applyRunConfig(cfg *runConfig) {
+	f(cfg)
+}
+
+// runConfig holds the configuration for Run.
+type runConfig struct {
+	exitHooks      []func(err error)
+	crashReporters []func(CrashReport)
+	flags          *flag.FlagSet
+	signals        bool
+	interceptors   []Interceptor
+	strict         bool
+	accounting     bool
+	signalActions  []signalAction
+	budget         time.Duration
+	crashDump      *crashDump
+	ctx            context.Context
+	checks         []Check
+	// Whether the instance is run with Run, which is guarded against running twice.
+	exclusive bool
+}
+
+// WithExitHook calls the given function once everything has stopped, just before the process exits or
+// Instance.Wait returns, with the first error encountered or nil if no error occurred.
+func WithExitHook(fn func(err error)) RunOption {
+	return runConfigFunc(func(cfg *runConfig) {
+		cfg.exitHooks = append(cfg.exitHooks, fn)
+	})
+}
+
+// WithoutSignals stops the foundation handling os signals, it is then only stopped by an error, every
+// runner returning or, when started with Start, Instance.Stop.
+func WithoutSignals() RunOption {
+	return runConfigFunc(func(cfg *runConfig) {
+		cfg.signals = false
+	})
+}
+
+// A CrashReport describes a RuntimeError or CleanupError, such as a panic in a Runner, for reporting to a
+// crash tracker, see WithCrashReporter.
+type CrashReport struct {
+	// Service is the name given to Run.
+	Service string
+	// Runner is the name of the F the error occurred in, for example api.1.2.
+	Runner string
+	// Err is the RuntimeError or CleanupError.
+	Err error
+	// Cause is the underlying error, a PanicError if a value other than an error was recovered.
+	Cause error
+	// Frames is the parsed stack trace, most recent call first.
+	Frames []Frame
+	// Attrs are the attributes of the error, for example request metadata.
+	Attrs []slog.Attr
+}
+
+// WithCrashReporter calls the given function with a CrashReport for every RuntimeError and CleanupError
+// encountered. Reporters are called before the process exits so may send reports synchronously, but
+// should bound the time they take.
+func WithCrashReporter(fn func(CrashReport)) RunOption {
+	return runConfigFunc(func(cfg *runConfig) {
+		cfg.crashReporters = append(cfg.crashReporters, fn)
+	})
+}
+
+// crashReport returns the CrashReport for the error if it is a RuntimeError or CleanupError.
+func crashReport(service string, err error) (CrashReport, bool) {
+	report := CrashReport{
+		Service: service,
+		Err:     err,
+	}
+
+	if v := new(RuntimeError); errors.As(err, v) {
+		report.Runner = v.Runner
+		report.Cause = v.Cause
+		report.Frames = ParseStack(v.Stack)
+		report.Attrs = v.Attrs
+
+		return report, true
+	}
+
+	if v := new(CleanupError); errors.As(err, v) {
+		report.Runner = v.Runner
+		report.Cause = v.Cause
+		report.Frames = ParseStack(v.Stack)
+
+		return report, true
+	}
+
+	return report, false
+}
+
+// Run runs a the given foundation runner, exiting the process once it has stopped, see RunE. Several
+// instances may be run in one process with different names, each handling signals itself unless
+// configured WithoutSignals, and the process exits once every instance handling signals has stopped,
+// including those started with Start.
+func Run(name string, runner Runner, opts ...RunOption) {
+	exitCode, err := RunE(name, runner, opts...)
+	if errors.Is(err, ErrAlreadyRunning) {
+		slog.Error(err.Error())
+	}
+
+	// Other instances in the process stop on the same signals, wait for them so exiting does not cut
+	// their shutdown short.
+	if waitSignalled() && exitCode == 0 {
+		exitCode = 1
+	}
+
+	// Call os.Exit once everything is done, if we erroed this will be a none zero exit code.
+	os.Exit(exitCode)
+}
+
+// RunE runs the given foundation runner as Run does, blocking until it has stopped, but returns the exit
+// code and the first error encountered rather than exiting the process, leaving that to the caller. The
+// exit code is 0 on success and 1 if an error occurred. If flags configured with WithFlags can not be
+// parsed the runner is not run and the exit code is 2, or 0 with flag.ErrHelp if help was requested.
+// Running an instance with the name of one already running fails with ErrAlreadyRunning.
+func RunE(name string, runner Runner, opts ...RunOption) (int, error) {
+	cfg := runConfig{
+		signals: true,
+	}
+
+	RunOptions(opts).applyRunConfig(&cfg)
+
+	cfg.exclusive = true
+
+	// Parse flags before anything runs, failing on help or invalid flags as the flag package does.
+	if cfg.flags != nil {
+		if err := parseFlags(cfg.flags, name, runner, os.Args[1:]); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return 0, err
+			}
+
+			return 2, err
+		}
+	}
+
+	if err := start(name, runner, cfg).Wait(); err != nil {
+		return 1, err
+	}
+
+	return 0, nil
+}
+
+// An Instance is a foundation started with Start.
+type Instance struct {
+	f       *f
+	stop    chan struct{}
+	once    sync.Once
+	done    chan struct{}
+	err     error
+	signals bool
+}
+
+// Start runs the given foundation runner as Run does but returns rather than exiting the process,
+// leaving the caller to stop it and wait for it to finish, for example in tests or when embedding a
+// foundation in a larger program. Start returns once the Runner has returned or marked itself as
+// parallel, see F.Parallel. Flags configured with WithFlags are parsed from os.Args[1:], if they cannot
+// be parsed the Runner is not run and Wait returns the error.
+func Start(name string, runner Runner, opts ...RunOption) *Instance {
+	cfg := runConfig{
+		signals: true,
+	}
+
+	RunOptions(opts).applyRunConfig(&cfg)
+
+	if cfg.flags != nil {
+		if err := parseFlags(cfg.flags, name, runner, os.Args[1:]); err != nil {
+			return failed(name, err)
+		}
+	}
+
+	return start(name, runner, cfg)
+}
+
+// F returns the root F of the instance, for example to read values from the value store.
+func (i *Instance) F() F {
+	return i.f
+}
+
+// Stop stops the instance as an os signal would, it does not wait for it to stop, see Wait.
+func (i *Instance) Stop() {
+	i.once.Do(func() {
+		close(i.stop)
+	})
+}
+
+// Done returns a channel closed once everything has stopped.
+func (i *Instance) Done() <-chan struct{} {
+	return i.done
+}
+
+// Wait waits for everything to stop, returning the first error encountered or nil if no error occurred.
+func (i *Instance) Wait() error {
+	<-i.done
+
+	return i.err
+}
+
+// start runs the runner returning once it has returned or marked itself as parallel.
+func start(name string, runner Runner, cfg runConfig) *Instance {
 	ctx := context.Background()
+	if cfg.ctx != nil {
+		ctx = cfg.ctx
+	}
+
+	// Check the preconditions before anything runs, see Require.
+	if err := checkPreconditions(ctx, cfg.checks); err != nil {
+		slog.Error(err.Error())
+
+		return failed(name, err)
+	}
 
 	// Initialise new foundation with the given service name.
 	f := newf(name)
+	f.interceptors = cfg.interceptors
 
-	// Exit code to use on exit when call os.Exit. 0 indicates success, any other value indicates error.
-	var exitCode int
+	// Record lifecycle events for the crash dump, see WithCrashDump.
+	if cfg.crashDump != nil {
+		f.interceptors = append(slices.Clone(cfg.interceptors), cfg.crashDump.record)
+	}
+	f.strict = cfg.strict
+	f.accounting = cfg.accounting
+	f.budget.total = cfg.budget
+
+	if cfg.flags != nil {
+		f.Values().Store(flagsKey{}, cfg.flags)
+	}
+
+	i := &Instance{
+		f:       f,
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+		signals: cfg.signals,
+	}
+
+	// Track the instance so Run waits for it before exiting the process, guarding against the same
+	// instance being run twice as the second would fight the first over its listeners and registrations.
+	if err := claim(name, i, cfg.exclusive); err != nil {
+		return failed(name, err)
+	}
 
 	// Create a wait group to ensure all go routines exit.
 	var wg sync.WaitGroup
@@ -48,47 +300,106 @@ func Run(name string, runner Runner) {
 
 			if v := new(RuntimeError); errors.As(err, v) {
 				attrs = append(attrs, slog.String("stack", string(v.Stack)))
+
+				for _, attr := range v.Attrs {
+					attrs = append(attrs, attr)
+				}
 			}
 
 			if v := new(CleanupError); errors.As(err, v) {
 				attrs = append(attrs, slog.String("stack", string(v.Stack)))
 			}
 
-			// Log the error.
-			slog.Error(err.Error(), attrs...)
+			// Transient errors which did not end a runner are logged without stopping, see Transient.
+			if escalates(err) {
+				// Log the error.
+				slog.Error(err.Error(), attrs...)
+
+				// Close the errd channel. This will cause the below go routine to unblock on the select and thus call Stop().
+				// It will also record the first error, returned by Wait.
+				once.Do(func() {
+					i.err = err
 
-			// Close the errd channel. This will cause the below go routine to unblock on the select and thus call Stop().
-			// It will also set the os.Exit code to a non zero value indicating an error during execution.
-			once.Do(func() {
-				exitCode = 1
-				close(errd)
-			})
+					// Write the crash dump before stopping so it captures the state the runners failed in.
+					if cfg.crashDump != nil {
+						cfg.crashDump.write(name, f, err)
+					}
+
+					close(errd)
+				})
+			} else {
+				slog.Warn(err.Error(), attrs...)
+			}
+
+			// Crashes are reported whether or not they stop the foundation, for example a recovered panic
+			// in a request handler.
+			if report, ok := crashReport(name, err); ok {
+				for fn := range slices.Values(cfg.crashReporters) {
+					fn(report)
+				}
+			}
 		}
 	}()
 
-	// Start a go routine which waits for an OS signal, an error is encountered, or all functions exit.
-	// Will always call Stop() so clean up functions are called.
-	go func() {
-		defer wg.Done()
+	// Channels to receive os signals, reload signals and action signals on, left nil so they never
+	// receive if the instance does not handle signals. Notified before running so no signal is missed.
+	var ch, hup, act chan os.Signal
 
-		// Channel to receive os signals on.
-		ch := make(chan os.Signal, 1)
+	if cfg.signals {
+		// Notify onto the channel SIGINT, SIGTERM, SIGQUIT events, less any mapped to a dump. Notify with
+		// no signals would notify every signal.
+		if sigs := cfg.stopSignals(); len(sigs) > 0 {
+			ch = make(chan os.Signal, 1)
+			signal.Notify(ch, sigs...)
+		}
 
-		// Notify onto the channel SIGINT, SIGTERM, SIGQUIT events
-		signal.Notify(ch, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+		hup = make(chan os.Signal, 1)
+		signal.Notify(hup, syscall.SIGHUP)
 
-		select {
-		case <-done:
-			// All functions exited normally so we do not need to wait so we can exit out.
-		case <-errd:
-			// An error occurred during runtime so we should stop.
-		case sig := <-ch:
-			// Received an os signal to explicitly exit.
-			slog.Debug("received os signal", slog.String("signal", sig.String()))
+		// Signals mapped to actions, see WithSignalAction.
+		if sigs := cfg.actionSignals(); len(sigs) > 0 {
+			act = make(chan os.Signal, 1)
+			signal.Notify(act, sigs...)
 		}
+	}
 
-		// Stop listening for OS Signals
-		signal.Stop(ch)
+	// Start a go routine which waits for an OS signal, an error is encountered, all functions exit or the
+	// instance is stopped. Will always call Stop() so clean up functions are called.
+	go func() {
+		defer wg.Done()
+
+		// Stop listening for OS Signals once stopping, stopping a nil channel is a no-op.
+		defer signal.Stop(act)
+		defer signal.Stop(hup)
+		defer signal.Stop(ch)
+
+	wait:
+		for {
+			select {
+			case <-done:
+				// All functions exited normally so we do not need to wait so we can exit out.
+				break wait
+			case <-errd:
+				// An error occurred during runtime so we should stop.
+				break wait
+			case <-i.stop:
+				// The instance was explicitly stopped.
+				slog.Debug("instance stopped")
+				break wait
+			case sig := <-ch:
+				// Received an os signal to explicitly exit.
+				slog.Debug("received os signal", slog.String("signal", sig.String()))
+				break wait
+			case <-hup:
+				// Received a reload signal, reload and carry on waiting.
+				slog.Info("received reload signal")
+				f.reload()
+			case sig := <-act:
+				// Received a signal mapped to actions, call them and carry on waiting.
+				slog.Info("received action signal", slog.String("signal", sig.String()))
+				cfg.signalled(ctx, f, sig)
+			}
+		}
 
 		// Stop anything that's running.
 		slog.Debug("stop foundation")
@@ -98,15 +409,23 @@ func Run(name string, runner Runner) {
 	// Run the given runner.
 	f.Run(ctx, runner)
 
-	// Wait for function to complete.
-	<-f.wait()
+	go func() {
+		// Wait for function to complete.
+		f.wait()
 
-	// Close the done channel.
-	close(done)
+		// Close the done channel.
+		close(done)
 
-	// Wait for go routines to exit
-	wg.Wait()
+		// Wait for go routines to exit
+		wg.Wait()
 
-	// Call os.Exit once everything is done, if we erroed this will be a none zero exit code.
-	os.Exit(exitCode)
+		for fn := range slices.Values(cfg.exitHooks) {
+			fn(i.err)
+		}
+
+		release(i)
+		close(i.done)
+	}()
+
+	return i
 }
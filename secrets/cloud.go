@@ -0,0 +1,66 @@
+package secrets
+
+import (
+	"context"
+	"strings"
+)
+
+// An AWSClient fetches secrets from AWS Secrets Manager. Foundation does not depend on the AWS SDK,
+// instead an AWSClient is a thin adapter over *secretsmanager.Client from
+// github.com/aws/aws-sdk-go-v2/service/secretsmanager:
+//
+//	type client struct{ *secretsmanager.Client }
+//
+//	func (c client) GetSecretString(ctx context.Context, id string) (string, error) {
+//		out, err := c.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{SecretId: &id})
+//		if err != nil {
+//			return "", err
+//		}
+//
+//		return aws.ToString(out.SecretString), nil
+//	}
+type AWSClient interface {
+	GetSecretString(ctx context.Context, id string) (string, error)
+}
+
+// AWS returns a Provider fetching secrets from AWS Secrets Manager, the reference name is the secret name
+// or ARN, for example secret://aws/prod/db.
+func AWS(client AWSClient) Provider {
+	return ProviderFunc(client.GetSecretString)
+}
+
+// A GCPClient fetches secret versions from GCP Secret Manager. Foundation does not depend on the Google
+// Cloud SDK, instead a GCPClient is a thin adapter over *secretmanager.Client from
+// cloud.google.com/go/secretmanager/apiv1:
+//
+//	type client struct{ *secretmanager.Client }
+//
+//	func (c client) AccessSecretVersion(ctx context.Context, name string) ([]byte, error) {
+//		rsp, err := c.Client.AccessSecretVersion(ctx, &secretmanagerpb.AccessSecretVersionRequest{Name: name})
+//		if err != nil {
+//			return nil, err
+//		}
+//
+//		return rsp.GetPayload().GetData(), nil
+//	}
+type GCPClient interface {
+	AccessSecretVersion(ctx context.Context, name string) ([]byte, error)
+}
+
+// GCP returns a Provider fetching secrets from GCP Secret Manager. The reference name is either a full
+// version name, for example secret://gcp/projects/p/secrets/db/versions/3, or a secret name resolved to
+// its latest version in the given project, for example secret://gcp/db.
+func GCP(client GCPClient, project string) Provider {
+	return ProviderFunc(func(ctx context.Context, name string) (string, error) {
+		if !strings.HasPrefix(name, "projects/") {
+			name = "projects/" + project + "/secrets/" + name + "/versions/latest"
+		}
+
+		b, err := client.AccessSecretVersion(ctx, name)
+		if err != nil {
+			return "", err
+		}
+
+		return string(b), nil
+	})
+}
@@ -0,0 +1,313 @@
+// Package secrets resolves secret references, such as secret://aws/prod/db-password, from cloud secret
+// managers so secrets are fetched at startup rather than living in environment variables or files.
+//
+// A reference has the form secret://<provider>/<name>[#<key>], where provider is the name a Provider is
+// registered under, name is the secret name understood by that Provider and the optional key selects a
+// field from a secret holding a JSON object:
+//
+//	secret://aws/prod/db#password
+//	secret://gcp/db-password
+//
+// A Resolver satisfies config.Resolver so references in configuration are resolved as it is loaded:
+//
+//	resolver := secrets.NewResolver(secrets.WithProvider("aws", secrets.AWS(client)))
+//
+//	f.Run(ctx, resolver, config.Run[Config](config.WithResolver(resolver)))
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go.krak3n.io/foundation"
+	"go.krak3n.io/foundation/tick"
+)
+
+// Scheme is the scheme of secret references.
+const Scheme = "secret://"
+
+// A Provider fetches secret values by name from a secret manager.
+type Provider interface {
+	GetSecret(ctx context.Context, name string) (string, error)
+}
+
+// The ProviderFunc type is an adapter to allow the use of ordinary functions as Providers.
+type ProviderFunc func(ctx context.Context, name string) (string, error)
+
+// GetSecret calls fn(ctx, name).
+func (fn ProviderFunc) GetSecret(ctx context.Context, name string) (string, error) {
+	return fn(ctx, name)
+}
+
+// IsRef reports whether the value is a secret reference.
+func IsRef(value string) bool {
+	return strings.HasPrefix(value, Scheme)
+}
+
+// ref is a parsed secret reference.
+type ref struct {
+	provider string
+	name     string
+	key      string
+}
+
+// parse parses a secret reference.
+func parse(value string) (ref, error) {
+	rest, ok := strings.CutPrefix(value, Scheme)
+	if !ok {
+		return ref{}, fmt.Errorf("not a secret reference")
+	}
+
+	rest, key, _ := strings.Cut(rest, "#")
+
+	provider, name, ok := strings.Cut(rest, "/")
+	if !ok || provider == "" || name == "" {
+		return ref{}, fmt.Errorf("invalid secret reference %q, expected %s<provider>/<name>", value, Scheme)
+	}
+
+	return ref{provider: provider, name: name, key: key}, nil
+}
+
+// An Option configures a Resolver.
+type Option interface {
+	apply(*config)
+}
+
+// Options is one or more Option.
+type Options []Option
+
+func (opts Options) apply(cfg *config) {
+	for opt := range slices.Values(opts) {
+		if opt != nil {
+			opt.apply(cfg)
+		}
+	}
+}
+
+type optionFunc func(*config)
+
+func (f optionFunc) apply(cfg *config) {
+	f(cfg)
+}
+
+// config holds the Resolver configuration.
+type config struct {
+	providers map[string]Provider
+	interval  time.Duration
+	timeout   time.Duration
+}
+
+// WithProvider registers the Provider for references with the given provider name.
+func WithProvider(name string, p Provider) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.providers[name] = p
+	})
+}
+
+// WithRefreshInterval sets how often cached secrets are fetched again when the Resolver is run, picking
+// up rotated secrets. Defaults to 5 minutes.
+func WithRefreshInterval(d time.Duration) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.interval = d
+	})
+}
+
+// WithTimeout bounds each fetch from a Provider, defaults to 10 seconds.
+func WithTimeout(d time.Duration) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.timeout = d
+	})
+}
+
+// A Resolver resolves secret references, caching fetched secrets so each secret is only fetched once.
+//
+// A Resolver is also a foundation.Runner which refreshes cached secrets on a tick. When a secret has been
+// rotated the change functions are called, see OnChange, followed by the Reload hooks, so dependents can
+// resolve the reference again to pick up the new value.
+type Resolver struct {
+	cfg config
+
+	mtx     sync.RWMutex
+	cache   map[string]string
+	changes []func(name string)
+}
+
+// NewResolver returns a Resolver for the given providers.
+func NewResolver(opts ...Option) *Resolver {
+	cfg := config{
+		providers: make(map[string]Provider),
+		interval:  5 * time.Minute,
+		timeout:   10 * time.Second,
+	}
+
+	Options(opts).apply(&cfg)
+
+	return &Resolver{
+		cfg:   cfg,
+		cache: make(map[string]string),
+	}
+}
+
+// Resolve returns the secret the value references, values which are not secret references are returned
+// unchanged.
+func (r *Resolver) Resolve(ctx context.Context, value string) (string, error) {
+	if !IsRef(value) {
+		return value, nil
+	}
+
+	ref, err := parse(value)
+	if err != nil {
+		return "", err
+	}
+
+	secret, err := r.secret(ctx, ref.provider, ref.name)
+	if err != nil {
+		return "", err
+	}
+
+	if ref.key == "" {
+		return secret, nil
+	}
+
+	var fields map[string]any
+
+	if err := json.Unmarshal([]byte(secret), &fields); err != nil {
+		return "", fmt.Errorf("secret %s/%s is not a JSON object: %w", ref.provider, ref.name, err)
+	}
+
+	v, ok := fields[ref.key]
+	if !ok {
+		return "", fmt.Errorf("secret %s/%s has no key %q", ref.provider, ref.name, ref.key)
+	}
+
+	if s, ok := v.(string); ok {
+		return s, nil
+	}
+
+	b, err := json.Marshal(v)
+
+	return string(b), err
+}
+
+// OnChange calls fn with the provider and name, for example aws/prod/db, of each cached secret which
+// changed when refreshed.
+func (r *Resolver) OnChange(fn func(name string)) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	r.changes = append(r.changes, fn)
+}
+
+// secret returns the cached secret, fetching it if not cached.
+func (r *Resolver) secret(ctx context.Context, provider, name string) (string, error) {
+	key := provider + "/" + name
+
+	r.mtx.RLock()
+	secret, ok := r.cache[key]
+	r.mtx.RUnlock()
+
+	if ok {
+		return secret, nil
+	}
+
+	secret, err := r.fetch(ctx, provider, name)
+	if err != nil {
+		return "", err
+	}
+
+	r.mtx.Lock()
+	r.cache[key] = secret
+	r.mtx.Unlock()
+
+	return secret, nil
+}
+
+// fetch fetches the secret from its provider.
+func (r *Resolver) fetch(ctx context.Context, provider, name string) (string, error) {
+	p, ok := r.cfg.providers[provider]
+	if !ok {
+		return "", fmt.Errorf("unknown secret provider %q", provider)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.cfg.timeout)
+	defer cancel()
+
+	secret, err := p.GetSecret(ctx, name)
+	if err != nil {
+		return "", fmt.Errorf("get secret %s/%s: %w", provider, name, err)
+	}
+
+	return secret, nil
+}
+
+// Run stores the Resolver in the value store, see Get, and refreshes cached secrets until stopped. Failed
+// refreshes are logged keeping the cached secret.
+func (r *Resolver) Run(ctx context.Context, f foundation.F) {
+	f.Values().Store(resolverKey{}, r)
+
+	tick.Run(ctx, f, r.cfg.interval, func(ctx context.Context, _ tick.Ticker) {
+		if r.refresh(ctx) {
+			foundation.Reload(f)
+		}
+	})
+}
+
+// refresh fetches every cached secret again, returning true if any changed.
+func (r *Resolver) refresh(ctx context.Context) bool {
+	r.mtx.RLock()
+	keys := slices.Sorted(maps.Keys(r.cache))
+	r.mtx.RUnlock()
+
+	var changed []string
+
+	for key := range slices.Values(keys) {
+		provider, name, _ := strings.Cut(key, "/")
+
+		secret, err := r.fetch(ctx, provider, name)
+		if err != nil {
+			slog.WarnContext(ctx, "failed to refresh secret", slog.String("secret", key), slog.String("err", err.Error()))
+
+			continue
+		}
+
+		r.mtx.Lock()
+		if r.cache[key] != secret {
+			r.cache[key] = secret
+			changed = append(changed, key)
+		}
+		r.mtx.Unlock()
+	}
+
+	if len(changed) == 0 {
+		return false
+	}
+
+	r.mtx.RLock()
+	fns := slices.Clone(r.changes)
+	r.mtx.RUnlock()
+
+	for key := range slices.Values(changed) {
+		slog.InfoContext(ctx, "secret rotated", slog.String("secret", key))
+
+		for fn := range slices.Values(fns) {
+			fn(key)
+		}
+	}
+
+	return true
+}
+
+// resolverKey is the value store key the Resolver is stored under.
+type resolverKey struct{}
+
+// Get returns the Resolver from the F's value store.
+func Get(f foundation.F) (*Resolver, bool) {
+	return foundation.Value[*Resolver](f, resolverKey{})
+}
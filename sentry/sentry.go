@@ -0,0 +1,290 @@
+// Package sentry reports foundation crashes, such as panics in runners, to Sentry using the Sentry
+// envelope HTTP API.
+package sentry
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"go.krak3n.io/foundation"
+)
+
+// An Option configures the Sentry reporter.
+type Option interface {
+	apply(*config)
+}
+
+// Options is one or more Option.
+type Options []Option
+
+func (opts Options) apply(cfg *config) {
+	for opt := range slices.Values(opts) {
+		if opt != nil {
+			opt.apply(cfg)
+		}
+	}
+}
+
+type optionFunc func(*config)
+
+func (f optionFunc) apply(cfg *config) {
+	f(cfg)
+}
+
+// config holds the Sentry reporter configuration.
+type config struct {
+	environment string
+	release     string
+	client      *http.Client
+	timeout     time.Duration
+}
+
+// WithEnvironment sets the environment, defaults to SENTRY_ENVIRONMENT.
+func WithEnvironment(env string) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.environment = env
+	})
+}
+
+// WithRelease sets the release, defaults to SENTRY_RELEASE.
+func WithRelease(release string) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.release = release
+	})
+}
+
+// WithHTTPClient sets the HTTP client used to send events, defaults to http.DefaultClient.
+func WithHTTPClient(client *http.Client) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.client = client
+	})
+}
+
+// WithTimeout bounds sending each event, defaults to 5 seconds.
+func WithTimeout(d time.Duration) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.timeout = d
+	})
+}
+
+// Reporter returns a foundation.RunOption which sends every crash to Sentry as a fatal event tagged
+// with the service and runner. An empty DSN uses SENTRY_DSN, if there is no DSN or it is invalid a warning
+// is logged and nothing is reported.
+//
+//	foundation.Run("api", runner, sentry.Reporter(""))
+func Reporter(dsn string, opts ...Option) foundation.RunOption {
+	cfg := config{
+		environment: os.Getenv("SENTRY_ENVIRONMENT"),
+		release:     os.Getenv("SENTRY_RELEASE"),
+		client:      http.DefaultClient,
+		timeout:     5 * time.Second,
+	}
+
+	Options(opts).apply(&cfg)
+
+	if dsn == "" {
+		dsn = os.Getenv("SENTRY_DSN")
+	}
+
+	endpoint, key, err := parseDSN(dsn)
+	if err != nil {
+		slog.Warn("sentry crash reporting disabled", slog.String("err", err.Error()))
+
+		return nil
+	}
+
+	return foundation.WithCrashReporter(func(report foundation.CrashReport) {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.timeout)
+		defer cancel()
+
+		if err := send(ctx, cfg, endpoint, key, dsn, report); err != nil {
+			slog.Warn("failed to send crash report to sentry", slog.String("err", err.Error()))
+		}
+	})
+}
+
+// parseDSN returns the envelope endpoint and public key of the DSN, for example
+// https://key@o1.ingest.sentry.io/123.
+func parseDSN(dsn string) (string, string, error) {
+	if dsn == "" {
+		return "", "", fmt.Errorf("no dsn")
+	}
+
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid dsn: %w", err)
+	}
+
+	key := u.User.Username()
+
+	path := strings.TrimSuffix(u.Path, "/")
+	i := strings.LastIndex(path, "/")
+
+	if key == "" || i < 0 || path[i+1:] == "" {
+		return "", "", fmt.Errorf("invalid dsn: expected scheme://key@host/project")
+	}
+
+	endpoint := fmt.Sprintf("%s://%s%s/api/%s/envelope/", u.Scheme, u.Host, path[:i], path[i+1:])
+
+	return endpoint, key, nil
+}
+
+// send sends the report as an event envelope.
+func send(ctx context.Context, cfg config, endpoint, key, dsn string, report foundation.CrashReport) error {
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+
+	event := newEvent(hex.EncodeToString(id), cfg, report)
+
+	var body bytes.Buffer
+
+	enc := json.NewEncoder(&body)
+
+	for v := range slices.Values([]any{
+		map[string]string{"event_id": event.EventID, "dsn": dsn, "sent_at": event.Timestamp},
+		map[string]string{"type": "event"},
+		event,
+	}) {
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, &body)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/x-sentry-envelope")
+	req.Header.Set("X-Sentry-Auth", fmt.Sprintf("Sentry sentry_version=7, sentry_client=foundation/1.0, sentry_key=%s", key))
+
+	rsp, err := cfg.client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer rsp.Body.Close()
+
+	if rsp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(rsp.Body, 1024))
+
+		return fmt.Errorf("%s: %s", rsp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	return nil
+}
+
+// event is a Sentry event.
+type event struct {
+	EventID     string            `json:"event_id"`
+	Timestamp   string            `json:"timestamp"`
+	Platform    string            `json:"platform"`
+	Level       string            `json:"level"`
+	Logger      string            `json:"logger"`
+	ServerName  string            `json:"server_name,omitempty"`
+	Release     string            `json:"release,omitempty"`
+	Environment string            `json:"environment,omitempty"`
+	Tags        map[string]string `json:"tags"`
+	Extra       map[string]any    `json:"extra,omitempty"`
+	Exception   struct {
+		Values []exception `json:"values"`
+	} `json:"exception"`
+}
+
+type exception struct {
+	Type       string `json:"type"`
+	Value      string `json:"value"`
+	Stacktrace struct {
+		Frames []frame `json:"frames"`
+	} `json:"stacktrace"`
+	Mechanism struct {
+		Type    string `json:"type"`
+		Handled bool   `json:"handled"`
+	} `json:"mechanism"`
+}
+
+type frame struct {
+	Function string `json:"function"`
+	Module   string `json:"module,omitempty"`
+	AbsPath  string `json:"abs_path"`
+	Lineno   int    `json:"lineno"`
+	InApp    bool   `json:"in_app"`
+}
+
+// newEvent builds the event for the report.
+func newEvent(id string, cfg config, report foundation.CrashReport) event {
+	e := event{
+		EventID:     id,
+		Timestamp:   time.Now().UTC().Format(time.RFC3339),
+		Platform:    "go",
+		Level:       "fatal",
+		Logger:      "foundation",
+		Release:     cfg.release,
+		Environment: cfg.environment,
+		Tags: map[string]string{
+			"service": report.Service,
+			"runner":  report.Runner,
+		},
+	}
+
+	e.ServerName, _ = os.Hostname()
+
+	if len(report.Attrs) > 0 {
+		e.Extra = make(map[string]any, len(report.Attrs))
+
+		for attr := range slices.Values(report.Attrs) {
+			e.Extra[attr.Key] = attr.Value.String()
+		}
+	}
+
+	cause := report.Cause
+	if cause == nil {
+		cause = report.Err
+	}
+
+	ex := exception{
+		Type:  fmt.Sprintf("%T", cause),
+		Value: cause.Error(),
+	}
+
+	ex.Mechanism.Type = "foundation"
+
+	// Sentry expects frames oldest first.
+	for _, f := range slices.Backward(report.Frames) {
+		ex.Stacktrace.Frames = append(ex.Stacktrace.Frames, newFrame(f))
+	}
+
+	e.Exception.Values = []exception{ex}
+
+	return e
+}
+
+// newFrame converts a stack frame, splitting the package path from the function name.
+func newFrame(f foundation.Frame) frame {
+	fr := frame{
+		Function: f.Function,
+		AbsPath:  f.File,
+		Lineno:   f.Line,
+		InApp:    !strings.HasPrefix(f.Function, "runtime.") && !strings.Contains(f.File, "/pkg/mod/"),
+	}
+
+	slash := strings.LastIndex(f.Function, "/") + 1
+	if dot := strings.Index(f.Function[slash:], "."); dot > 0 {
+		fr.Module = f.Function[:slash+dot]
+		fr.Function = f.Function[slash+dot+1:]
+	}
+
+	return fr
+}
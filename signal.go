@@ -0,0 +1,131 @@
+package foundation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"runtime/debug"
+	"runtime/pprof"
+	"slices"
+	"syscall"
+)
+
+// A SignalAction is an action taken when the foundation receives an os signal, see WithSignalAction. It
+// is called with the root F.
+type SignalAction func(ctx context.Context, f F)
+
+// signalAction is a SignalAction mapped to a signal.
+type signalAction struct {
+	sig    os.Signal
+	action SignalAction
+	// Whether the signal no longer stops the foundation, see WithDumpSignal.
+	keep bool
+}
+
+// WithSignalAction calls the action each time the foundation receives the signal, giving operators
+// levers such as draining readiness or dumping diagnostics without an admin network surface. Several
+// actions may be mapped to a signal and are called in the order given. Actions are called one at a time
+// and should return quickly. Signals which stop or reload the foundation continue to do so. Actions are
+// not called if the foundation does not handle signals, see WithoutSignals.
+//
+//	foundation.Run("api", runner,
+//		foundation.WithSignalAction(syscall.SIGUSR1, health.DrainAction()),
+//		foundation.WithSignalAction(syscall.SIGUSR2, foundation.DumpAction()))
+func WithSignalAction(sig os.Signal, action SignalAction) RunOption {
+	return runConfigFunc(func(cfg *runConfig) {
+		if sig == nil || action == nil {
+			return
+		}
+
+		cfg.signalActions = append(cfg.signalActions, signalAction{sig: sig, action: action})
+	})
+}
+
+// WithDumpSignal dumps the runner tree and the stacks of every go routine through slog each time the
+// foundation receives the signal and carries on running, see DumpAction, a safe diagnostic for production.
+// Unlike WithSignalAction the signal no longer stops the foundation, so SIGQUIT, which otherwise stops
+// the foundation and by default aborts a Go program dumping the stacks to stderr, may be used.
+//
+//	foundation.Run("api", runner, foundation.WithDumpSignal(syscall.SIGQUIT))
+func WithDumpSignal(sig os.Signal) RunOption {
+	return runConfigFunc(func(cfg *runConfig) {
+		if sig == nil {
+			return
+		}
+
+		cfg.signalActions = append(cfg.signalActions, signalAction{sig: sig, action: DumpAction(), keep: true})
+	})
+}
+
+// stopSignals returns the signals which stop the foundation, SIGINT, SIGTERM and SIGQUIT unless mapped
+// to a dump, see WithDumpSignal.
+func (cfg runConfig) stopSignals() []os.Signal {
+	return slices.DeleteFunc([]os.Signal{syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT}, func(sig os.Signal) bool {
+		return slices.ContainsFunc(cfg.signalActions, func(sa signalAction) bool {
+			return sa.keep && sa.sig == sig
+		})
+	})
+}
+
+// actionSignals returns the signals which have actions, see WithSignalAction.
+func (cfg runConfig) actionSignals() []os.Signal {
+	var sigs []os.Signal
+
+	for sa := range slices.Values(cfg.signalActions) {
+		if !slices.Contains(sigs, sa.sig) {
+			sigs = append(sigs, sa.sig)
+		}
+	}
+
+	return sigs
+}
+
+// signalled calls the actions mapped to the signal, logging rather than propagating a panic so an
+// operator's lever can not take down the service.
+func (cfg runConfig) signalled(ctx context.Context, f F, sig os.Signal) {
+	for sa := range slices.Values(cfg.signalActions) {
+		if sa.sig != sig {
+			continue
+		}
+
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					slog.ErrorContext(ctx, "signal action panicked",
+						slog.String("signal", sig.String()),
+						slog.String("err", fmt.Sprint(r)),
+						slog.String("stack", string(debug.Stack())))
+				}
+			}()
+
+			sa.action(ctx, f)
+		}()
+	}
+}
+
+// DumpAction returns a SignalAction which logs the runner tree, see Tree, and the stacks of every go
+// routine in full, as a panic would print them, for diagnosing a stuck service without attaching a
+// debugger.
+func DumpAction() SignalAction {
+	return func(ctx context.Context, f F) {
+		tree, err := json.Marshal(Tree(f))
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to dump runner tree", slog.String("err", err.Error()))
+		} else {
+			slog.InfoContext(ctx, "runner tree", slog.String("tree", string(tree)))
+		}
+
+		var buf bytes.Buffer
+
+		if err := pprof.Lookup("goroutine").WriteTo(&buf, 2); err != nil {
+			slog.ErrorContext(ctx, "failed to dump go routines", slog.String("err", err.Error()))
+
+			return
+		}
+
+		slog.InfoContext(ctx, "go routines", slog.String("profile", buf.String()))
+	}
+}
@@ -0,0 +1,322 @@
+// Package startup reports the progress of starting a foundation, logging which runners have started,
+// which are still pending and how long each took, warning of runners which are slow to start, so hung
+// boots can be diagnosed from logs alone.
+//
+//	r := startup.New(startup.WithSlowThreshold(5*time.Second), startup.WithSensor())
+//
+//	foundation.Run("service", runner, r.Option())
+//
+// A runner has started once it has returned or marked itself as parallel, see foundation.F.Parallel.
+// Startup is complete once the runner given to foundation.Run has started.
+package startup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go.krak3n.io/foundation"
+	"go.krak3n.io/foundation/health/probe"
+)
+
+// An Option configures the Reporter.
+type Option interface {
+	apply(*config)
+}
+
+// Options is one or more Option.
+type Options []Option
+
+func (opts Options) apply(cfg *config) {
+	for opt := range slices.Values(opts) {
+		if opt != nil {
+			opt.apply(cfg)
+		}
+	}
+}
+
+type optionFunc func(*config)
+
+func (f optionFunc) apply(cfg *config) {
+	f(cfg)
+}
+
+// config holds the Reporter configuration.
+type config struct {
+	slow       time.Duration
+	sensor     bool
+	sensorMode probe.Mode
+}
+
+// WithSlowThreshold sets how long a runner may take to start before a warning is logged listing the
+// runners still pending, defaults to 10 seconds.
+func WithSlowThreshold(d time.Duration) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.slow = d
+	})
+}
+
+// WithSensor registers a sensor in probe.StartupMode which fails, listing the pending runners, until
+// startup is complete.
+func WithSensor() Option {
+	return WithSensorMode(probe.StartupMode)
+}
+
+// WithSensorMode registers a sensor in the given mode which fails, listing the pending runners, until
+// startup is complete.
+func WithSensorMode(mode probe.Mode) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.sensor = true
+		cfg.sensorMode = mode
+	})
+}
+
+// runner is the startup progress of a runner.
+type runner struct {
+	name  string
+	began time.Time
+	took  time.Duration
+	done  bool
+	timer *time.Timer
+}
+
+// A Reporter reports the startup progress of a foundation from its lifecycle events, see Option.
+type Reporter struct {
+	cfg config
+
+	mtx      sync.Mutex
+	runners  map[string]*runner
+	order    []*runner
+	top      string
+	began    time.Time
+	took     time.Duration
+	complete bool
+}
+
+// New returns a Reporter, registering its sensor if configured, see WithSensor.
+func New(opts ...Option) *Reporter {
+	cfg := config{
+		slow: 10 * time.Second,
+	}
+
+	Options(opts).apply(&cfg)
+
+	r := &Reporter{
+		cfg:     cfg,
+		runners: make(map[string]*runner),
+	}
+
+	if cfg.sensor {
+		probe.Register(probe.NewSensor("startup", cfg.sensorMode, r.sense))
+	}
+
+	return r
+}
+
+// Option returns the RunOption which reports startup progress with the Reporter.
+func (r *Reporter) Option() foundation.RunOption {
+	return foundation.WithInterceptor(r.Intercept)
+}
+
+// Intercept records the lifecycle event, it is a foundation.Interceptor.
+func (r *Reporter) Intercept(e foundation.Event) {
+	switch e.Kind {
+	case foundation.EventStart:
+		r.start(e)
+	case foundation.EventParallel, foundation.EventDone:
+		r.started(e)
+	}
+}
+
+// start records a runner as pending, unless startup is complete. The first runner to start is the
+// runner given to foundation.Run.
+func (r *Reporter) start(e foundation.Event) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if r.complete {
+		return
+	}
+
+	if r.top == "" {
+		r.top = e.Runner
+		r.began = e.Time
+	}
+
+	v := &runner{
+		name:  e.Runner,
+		began: e.Time,
+	}
+
+	if r.cfg.slow > 0 {
+		v.timer = time.AfterFunc(r.cfg.slow, func() {
+			r.slow(v)
+		})
+	}
+
+	r.runners[e.Runner] = v
+	r.order = append(r.order, v)
+}
+
+// started records a pending runner as started, completing startup if it is the runner given to
+// foundation.Run.
+func (r *Reporter) started(e foundation.Event) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	v, ok := r.runners[e.Runner]
+	if !ok || v.done {
+		return
+	}
+
+	v.done = true
+	v.took = e.Time.Sub(v.began)
+
+	if v.timer != nil {
+		v.timer.Stop()
+	}
+
+	slog.Debug("runner started", slog.String("runner", v.name), slog.Duration("took", v.took))
+
+	if v.name != r.top {
+		return
+	}
+
+	r.complete = true
+	r.took = v.took
+
+	attrs := []any{
+		slog.Duration("took", r.took),
+		slog.Int("runners", len(r.order)),
+	}
+
+	if slowest := r.slowest(); slowest != nil {
+		attrs = append(attrs,
+			slog.String("slowest", slowest.name),
+			slog.Duration("slowest_took", slowest.took))
+	}
+
+	if pending := r.pending(); len(pending) > 0 {
+		attrs = append(attrs, slog.Any("pending", pending))
+	}
+
+	slog.Info("startup complete", attrs...)
+}
+
+// slow warns the runner is slow to start, listing the runners still pending.
+func (r *Reporter) slow(v *runner) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if v.done {
+		return
+	}
+
+	slog.Warn("runner slow to start",
+		slog.String("runner", v.name),
+		slog.Duration("elapsed", time.Since(v.began)),
+		slog.Any("pending", r.pending()))
+}
+
+// slowest returns the started runner which took longest to start, excluding the runner given to
+// foundation.Run which includes the time taken by every runner it runs sequentially.
+func (r *Reporter) slowest() *runner {
+	var slowest *runner
+
+	for v := range slices.Values(r.order) {
+		if !v.done || v.name == r.top {
+			continue
+		}
+
+		if slowest == nil || v.took > slowest.took {
+			slowest = v
+		}
+	}
+
+	return slowest
+}
+
+// pending returns the names of the runners which have not started, in the order they began.
+func (r *Reporter) pending() []string {
+	var names []string
+
+	for v := range slices.Values(r.order) {
+		if !v.done {
+			names = append(names, v.name)
+		}
+	}
+
+	return names
+}
+
+// A Progress is a snapshot of startup progress.
+type Progress struct {
+	// Complete reports whether startup is complete.
+	Complete bool
+	// Took is how long startup took, or has taken so far if not complete.
+	Took time.Duration
+	// Started lists the runners which have started with how long each took, in the order they began.
+	Started []Runner
+	// Pending lists the runners which have not started with how long each has taken so far, in the
+	// order they began.
+	Pending []Runner
+}
+
+// A Runner is the startup progress of a runner.
+type Runner struct {
+	// Name is the name of the runner's F, for example service.1.2.
+	Name string
+	// Took is how long the runner took to start, or has taken so far if pending.
+	Took time.Duration
+}
+
+// Progress returns a snapshot of startup progress.
+func (r *Reporter) Progress() Progress {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	now := time.Now()
+
+	p := Progress{
+		Complete: r.complete,
+		Took:     r.took,
+	}
+
+	if !r.complete && r.top != "" {
+		p.Took = now.Sub(r.began)
+	}
+
+	for v := range slices.Values(r.order) {
+		if v.done {
+			p.Started = append(p.Started, Runner{Name: v.name, Took: v.took})
+		} else {
+			p.Pending = append(p.Pending, Runner{Name: v.name, Took: now.Sub(v.began)})
+		}
+	}
+
+	return p
+}
+
+// sense fails until startup is complete.
+func (r *Reporter) sense(context.Context) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if r.complete {
+		return nil
+	}
+
+	if r.top == "" {
+		return errors.New("starting: no runners started")
+	}
+
+	pending := r.pending()
+
+	return fmt.Errorf("starting: %d/%d runners started, pending %s",
+		len(r.order)-len(pending), len(r.order), strings.Join(pending, ", "))
+}
@@ -0,0 +1,16 @@
+package foundation_test
+
+import (
+	"testing"
+
+	"go.krak3n.io/foundation/foundationtest/stress"
+)
+
+func TestStress(t *testing.T) {
+	n := 1000
+	if testing.Short() {
+		n = 100
+	}
+
+	stress.Run(t, n, stress.DefaultConfig())
+}
@@ -0,0 +1,82 @@
+package foundation
+
+import (
+	"fmt"
+	"slices"
+)
+
+// An InvariantError is reported in strict mode when the engine violates one of its invariants, see
+// WithStrict.
+type InvariantError struct {
+	// Runner is the name of the F the violation occurred in, for example api.1.2.
+	Runner string
+	// Violation describes the violated invariant.
+	Violation string
+}
+
+func (err InvariantError) Error() string {
+	return fmt.Sprintf("invariant violated in %s: %s", err.Runner, err.Violation)
+}
+
+// WithStrict validates the engine's invariants whilst running, reporting an InvariantError for every
+// violation:
+//
+//   - an F is stopped at most once, after every F run after it
+//   - nothing is run by an F once it has stopped its subs
+//   - every Stop hook is called exactly once, including hooks added whilst stopping
+//   - Done hooks are called exactly once
+//
+// Validating has a cost so strict mode is intended for tests, foundationtest.Start enables it.
+func WithStrict() RunOption {
+	return runConfigFunc(func(cfg *runConfig) {
+		cfg.strict = true
+	})
+}
+
+// violation reports an InvariantError if in strict mode.
+func (f *f) violation(format string, args ...any) {
+	err := InvariantError{
+		Runner:    f.name,
+		Violation: fmt.Sprintf(format, args...),
+	}
+
+	f.report(err)
+}
+
+// checkStop validates that the f has not already stopped.
+func (f *f) checkStop() {
+	if f.stopped.Load() {
+		f.violation("stopped more than once")
+	}
+}
+
+// checkStopOrder validates that every sub run after the sub at index i has stopped.
+func checkStopOrder(subs []*f, i int) {
+	for sub := range slices.Values(subs[i+1:]) {
+		if !sub.stopped.Load() {
+			subs[i].violation("stopped before %s which was run after it", sub.name)
+		}
+	}
+}
+
+// checkHooks validates that the hooks of the event have been called exactly once.
+func (f *f) checkHooks(event eventHook) {
+	f.mtx.RLock()
+	called := f.called[event]
+	f.mtx.RUnlock()
+
+	if n := len(f.hooks.get(event)); n != called {
+		f.violation("%d %s hooks added but %d called", n, event, called)
+	}
+}
+
+// checkSubs validates that no subs were run after the given number of subs were stopped.
+func (f *f) checkSubs(stopped int) {
+	f.mtx.RLock()
+	n := f.runs
+	f.mtx.RUnlock()
+
+	if n != stopped {
+		f.violation("%d runners run after stopping", n-stopped)
+	}
+}
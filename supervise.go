@@ -0,0 +1,286 @@
+package foundation
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"sync/atomic"
+	"time"
+)
+
+// classified is an error classified as fatal or transient.
+type classified struct {
+	err       error
+	transient bool
+}
+
+func (err classified) Error() string {
+	return err.err.Error()
+}
+
+func (err classified) Unwrap() error {
+	return err.err
+}
+
+// Fatal classifies the error as fatal, stopping the foundation when reported. Errors are fatal unless
+// classified otherwise so Fatal is only needed to override a transient error it wraps.
+func Fatal(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return classified{err: err}
+}
+
+// Transient classifies the error as transient, one which is expected to resolve itself. A Runner which
+// fails with a transient error is restarted if supervised, see Supervise, otherwise it stops the
+// foundation as a fatal error would. Transient errors reported without ending a Runner, see Report, are
+// logged without stopping the foundation.
+func Transient(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return classified{err: err, transient: true}
+}
+
+// IsTransient reports whether the outermost classification of the error is transient, see Transient.
+func IsTransient(err error) bool {
+	var c classified
+
+	return errors.As(err, &c) && c.transient
+}
+
+// escalates reports whether the error stops the foundation, transient errors only do so if they ended a
+// Runner.
+func escalates(err error) bool {
+	return !IsTransient(err) || ended(err)
+}
+
+// ended reports whether the error ended a Runner, a RuntimeError which is not itself classified as
+// transient. A RuntimeError classified as transient was recovered without ending its Runner, for example
+// a panic in a request handler.
+func ended(err error) bool {
+	if c := new(classified); errors.As(err, c) && c.transient && errors.As(c.err, new(RuntimeError)) {
+		return false
+	}
+
+	return errors.As(err, new(RuntimeError))
+}
+
+// A SuperviseOption configures a supervisor, see Supervise.
+type SuperviseOption interface {
+	applySupervise(*superviseConfig)
+}
+
+// SuperviseOptions is one or more SuperviseOption.
+type SuperviseOptions []SuperviseOption
+
+func (opts SuperviseOptions) applySupervise(cfg *superviseConfig) {
+	for opt := range slices.Values(opts) {
+		if opt != nil {
+			opt.applySupervise(cfg)
+		}
+	}
+}
+
+type superviseFunc func(*superviseConfig)
+
+func (fn superviseFunc) applySupervise(cfg *superviseConfig) {
+	fn(cfg)
+}
+
+// superviseConfig holds the supervisor configuration.
+type superviseConfig struct {
+	restarts  int
+	min, max  time.Duration
+	transient func(err error) bool
+}
+
+// WithMaxRestarts sets how many consecutive times the runner is restarted before its transient errors
+// escalate, defaults to 5. The count is reset once the runner has run for the maximum restart delay. A
+// negative n restarts the runner indefinitely.
+func WithMaxRestarts(n int) SuperviseOption {
+	return superviseFunc(func(cfg *superviseConfig) {
+		cfg.restarts = n
+	})
+}
+
+// WithRestartDelay sets the delay before restarting the runner, doubling from min to max with each
+// consecutive restart, defaults to 100ms and 30 seconds.
+func WithRestartDelay(min, max time.Duration) SuperviseOption {
+	return superviseFunc(func(cfg *superviseConfig) {
+		cfg.min = min
+		cfg.max = max
+	})
+}
+
+// WithTransient sets the function which classifies errors within the subtree as transient, defaults to
+// IsTransient. For example to treat timeouts as transient without classifying them where they occur.
+func WithTransient(fn func(err error) bool) SuperviseOption {
+	return superviseFunc(func(cfg *superviseConfig) {
+		cfg.transient = fn
+	})
+}
+
+// Supervise returns a Runner which runs the runner, restarting it with backoff when it, or any Runner
+// it runs, fails with a transient error, so transient failures are contained within the subtree. The
+// subtree is stopped before restarting. Fatal errors, and transient errors once the restarts are
+// exhausted, escalate as usual stopping the foundation. The supervisor is parallel whilst the runner, or
+// any Runner it runs, may be running.
+func Supervise(runner Runner, opts ...SuperviseOption) Runner {
+	return &supervisor{
+		runner: runner,
+		opts:   opts,
+	}
+}
+
+// supervisor restarts a runner which fails with a transient error.
+type supervisor struct {
+	runner Runner
+	opts   []SuperviseOption
+}
+
+func (s *supervisor) Run(ctx context.Context, v F) {
+	cfg := superviseConfig{
+		restarts:  5,
+		min:       100 * time.Millisecond,
+		max:       30 * time.Second,
+		transient: IsTransient,
+	}
+
+	SuperviseOptions(s.opts).applySupervise(&cfg)
+
+	f, ok := v.(*f)
+	if !ok {
+		v.Run(ctx, s.runner)
+
+		return
+	}
+
+	var (
+		failures atomic.Int64
+		started  atomic.Int64
+	)
+
+	failed := make(chan error, 1)
+
+	// Errors from the subtree are handed to the supervisor before escalating.
+	f.supervise = func(err error) (error, bool) {
+		if !cfg.transient(err) {
+			return err, false
+		}
+
+		// A transient error reported by a runner which is still running is logged.
+		if !ended(err) {
+			if !IsTransient(err) {
+				err = Transient(err)
+			}
+
+			return err, false
+		}
+
+		if time.Since(time.Unix(0, started.Load())) >= cfg.max {
+			failures.Store(0)
+		}
+
+		if cfg.restarts >= 0 && failures.Load() >= int64(cfg.restarts) {
+			return err, false
+		}
+
+		failures.Add(1)
+
+		select {
+		case failed <- err:
+		default:
+		}
+
+		return err, true
+	}
+
+	stopped := make(chan struct{})
+
+	f.On().Stop(func() {
+		close(stopped)
+	})
+
+	for {
+		started.Store(time.Now().UnixNano())
+
+		sub := f.run(ctx, s.runner, false)
+		if sub == nil {
+			return
+		}
+
+		var err error
+
+		select {
+		case err = <-failed:
+		case <-sub.signalC:
+		case <-sub.parallelC:
+		}
+
+		// Unless failed watch the subtree whilst any of it may still be running, the runner having
+		// returned without running anything leaves nothing to watch.
+		if err == nil {
+			select {
+			case err = <-failed:
+			default:
+				if returned(sub) {
+					return
+				}
+
+				f.Parallel()
+
+				select {
+				case err = <-failed:
+				case <-stopped:
+					return
+				}
+			}
+		}
+
+		// Stop what remains of the subtree before restarting it, dropping it so restarts do not grow the
+		// tree.
+		if sub.claimed.CompareAndSwap(false, true) {
+			sub.stop()
+		}
+
+		f.drop(sub)
+
+		delay := cfg.min
+
+		for i := int64(1); i < failures.Load() && delay < cfg.max; i++ {
+			delay *= 2
+		}
+
+		delay = min(delay, cfg.max)
+
+		slog.WarnContext(ctx, "restarting runner after transient error",
+			slog.String("runner", sub.name),
+			slog.Int64("restart", failures.Load()),
+			slog.Duration("delay", delay),
+			slog.String("err", err.Error()))
+
+		select {
+		case <-time.After(delay):
+		case <-stopped:
+			return
+		}
+	}
+}
+
+// returned reports whether the runner of the f returned without running anything.
+func returned(f *f) bool {
+	select {
+	case <-f.signalC:
+	default:
+		return false
+	}
+
+	f.mtx.RLock()
+	defer f.mtx.RUnlock()
+
+	return len(f.subs) == 0
+}
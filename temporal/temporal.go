@@ -0,0 +1,192 @@
+// Package temporal runs a Temporal, or Cadence, worker as a foundation.Runner.
+package temporal
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"go.krak3n.io/foundation"
+	"go.krak3n.io/foundation/health/probe"
+)
+
+// A Worker polls a task queue executing workflows and activities, satisfied by worker.Worker from
+// go.temporal.io/sdk/worker and go.uber.org/cadence/worker. Stop blocks until in flight activities have
+// finished or the worker stop timeout, configured when constructing the worker, has passed.
+type Worker interface {
+	RegisterWorkflow(w any)
+	RegisterActivity(a any)
+	Start() error
+	Stop()
+}
+
+// An Option configures the Temporal Runner.
+type Option interface {
+	apply(*config)
+}
+
+// Options is one or more Option.
+type Options []Option
+
+func (opts Options) apply(cfg *config) {
+	for opt := range slices.Values(opts) {
+		if opt != nil {
+			opt.apply(cfg)
+		}
+	}
+}
+
+type optionFunc func(*config)
+
+func (f optionFunc) apply(cfg *config) {
+	f(cfg)
+}
+
+// config holds the Temporal Runner configuration.
+type config struct {
+	workflows       []any
+	activities      []any
+	healthCheck     func(ctx context.Context) error
+	shutdownTimeout time.Duration
+	sensorMode      probe.Mode
+}
+
+// WithWorkflows registers workflows with the worker before it starts.
+func WithWorkflows(workflows ...any) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.workflows = append(cfg.workflows, workflows...)
+	})
+}
+
+// WithActivities registers activities, functions or structs whose methods are activities, with the
+// worker before it starts.
+func WithActivities(activities ...any) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.activities = append(cfg.activities, activities...)
+	})
+}
+
+// WithHealthCheck sets a function called by the sensor to check the server can be reached, for example:
+//
+//	temporal.WithHealthCheck(func(ctx context.Context) error {
+//		_, err := c.CheckHealth(ctx, &client.CheckHealthRequest{})
+//		return err
+//	})
+func WithHealthCheck(fn func(ctx context.Context) error) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.healthCheck = fn
+	})
+}
+
+// WithShutdownTimeout sets how long to wait on stop for the worker to stop, defaults to 30 seconds. It
+// should be longer than the worker stop timeout so in flight activities can finish.
+func WithShutdownTimeout(d time.Duration) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.shutdownTimeout = d
+	})
+}
+
+// WithSensorMode sets the mode of the worker sensor, defaults to probe.ReadinessMode.
+func WithSensorMode(mode probe.Mode) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.sensorMode = mode
+	})
+}
+
+// A Runner is a foundation.Runner which starts a Worker, registering a sensor for it, and stops it on stop.
+type Runner struct {
+	taskQueue string
+	worker    Worker
+	opts      []Option
+
+	f     atomic.Pointer[foundation.F]
+	fatal atomic.Pointer[error]
+}
+
+// Run returns a Runner for the worker polling the task queue, used to name the sensor.
+func Run(taskQueue string, w Worker, opts ...Option) *Runner {
+	return &Runner{
+		taskQueue: taskQueue,
+		worker:    w,
+		opts:      opts,
+	}
+}
+
+// Fatal records a fatal worker error, failing the sensor and reporting the error so the service stops.
+// Pass it as the OnFatalError worker option, the worker having stopped itself:
+//
+//	var r *temporal.Runner
+//
+//	w := worker.New(c, "orders", worker.Options{
+//		OnFatalError: func(err error) { r.Fatal(err) },
+//	})
+//
+//	r = temporal.Run("orders", w)
+func (r *Runner) Fatal(err error) {
+	err = fmt.Errorf("temporal worker %s: %w", r.taskQueue, err)
+
+	r.fatal.Store(&err)
+
+	if f := r.f.Load(); f != nil {
+		foundation.Report(*f, err)
+	}
+}
+
+// Run registers the workflows and activities and starts the worker, which polls in the background until
+// stopped.
+func (r *Runner) Run(ctx context.Context, f foundation.F) {
+	cfg := config{
+		shutdownTimeout: 30 * time.Second,
+		sensorMode:      probe.ReadinessMode,
+	}
+
+	Options(r.opts).apply(&cfg)
+
+	r.f.Store(&f)
+
+	for w := range slices.Values(cfg.workflows) {
+		r.worker.RegisterWorkflow(w)
+	}
+
+	for a := range slices.Values(cfg.activities) {
+		r.worker.RegisterActivity(a)
+	}
+
+	probe.RegisterContext(ctx, probe.NewSensor("temporal."+r.taskQueue, cfg.sensorMode, func(ctx context.Context) error {
+		if err := r.fatal.Load(); err != nil {
+			return *err
+		}
+
+		if cfg.healthCheck != nil {
+			return cfg.healthCheck(ctx)
+		}
+
+		return nil
+	}))
+
+	if err := r.worker.Start(); err != nil {
+		f.Error(fmt.Errorf("start temporal worker %s: %w", r.taskQueue, err))
+	}
+
+	slog.InfoContext(ctx, "temporal worker started", slog.String("task_queue", r.taskQueue))
+
+	f.On().Stop(func() {
+		stopped := make(chan struct{})
+
+		go func() {
+			defer close(stopped)
+
+			r.worker.Stop()
+		}()
+
+		select {
+		case <-stopped:
+			slog.InfoContext(ctx, "temporal worker stopped", slog.String("task_queue", r.taskQueue))
+		case <-time.After(cfg.shutdownTimeout):
+			f.Error(fmt.Errorf("temporal worker %s did not stop within %s", r.taskQueue, cfg.shutdownTimeout))
+		}
+	})
+}
@@ -46,6 +46,14 @@ func (opts BackoffOptions) applyBackoffConfig(cfg *backoffConfig) {
 	}
 }
 
+// WithJitter applies random jitter to the backoff wait duration. The jitter is a fraction of the wait
+// duration, for example 0.2 will produce a wait duration within 20% either side of the calculated duration.
+func WithJitter(jitter float64) BackoffOption {
+	return BackoffOptionFunc(func(cfg *backoffConfig) {
+		cfg.jitter = jitter
+	})
+}
+
 // LinearBackoff is a simple backoff that waits the given wait time in between each attempt.
 // To apply jitter use the WithJitter Option, if used will return a LinearBackoff with random jitter
 // between each attempt.
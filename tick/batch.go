@@ -0,0 +1,212 @@
+package tick
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"go.krak3n.io/foundation"
+	"go.krak3n.io/foundation/rate"
+)
+
+// A BatchFetchFunc fetches the next batch of items, returning no items once the source is empty.
+type BatchFetchFunc[T any] func(ctx context.Context) ([]T, error)
+
+// A BatchHandleFunc handles an item of a batch.
+type BatchHandleFunc[T any] func(ctx context.Context, item T) error
+
+// An ErrorPolicy decides what happens when a batch can not be fetched or an item can not be handled.
+type ErrorPolicy uint8
+
+// Error policies.
+const (
+	// ContinueOnError logs item errors and carries on handling the batch. Fetch errors are logged and the
+	// batch is fetched again on the next tick.
+	ContinueOnError ErrorPolicy = iota
+	// AbortOnError stops handling the batch on the first item error, waiting for items in flight, and
+	// fetches again on the next tick. Errors are logged.
+	AbortOnError
+	// FailOnError stops handling the batch on the first error as AbortOnError does, then errors the
+	// ticker, see Ticker.Error.
+	FailOnError
+)
+
+// A BatchOption configures a batch ticker, see Batch.
+type BatchOption interface {
+	applyBatch(*batchConfig)
+}
+
+// BatchOptions is one or more BatchOption.
+type BatchOptions []BatchOption
+
+func (opts BatchOptions) applyBatch(cfg *batchConfig) {
+	for opt := range slices.Values(opts) {
+		if opt != nil {
+			opt.applyBatch(cfg)
+		}
+	}
+}
+
+type batchFunc func(*batchConfig)
+
+func (f batchFunc) applyBatch(cfg *batchConfig) {
+	f(cfg)
+}
+
+// batchConfig holds the configuration of a batch ticker.
+type batchConfig struct {
+	concurrency int
+	policy      ErrorPolicy
+	opts        []Option
+	limiter     rate.Limiter
+}
+
+// WithConcurrency sets the number of items of a batch handled concurrently, defaults to 1.
+func WithConcurrency(n int) BatchOption {
+	return batchFunc(func(cfg *batchConfig) {
+		cfg.concurrency = n
+	})
+}
+
+// WithErrorPolicy sets what happens when a batch can not be fetched or an item can not be handled,
+// defaults to ContinueOnError.
+func WithErrorPolicy(policy ErrorPolicy) BatchOption {
+	return batchFunc(func(cfg *batchConfig) {
+		cfg.policy = policy
+	})
+}
+
+// WithRateLimit caps the rate items are handled at with the limiter, waiting for it before each item.
+func WithRateLimit(l rate.Limiter) BatchOption {
+	return batchFunc(func(cfg *batchConfig) {
+		cfg.limiter = l
+	})
+}
+
+// WithTickerOptions configures the underlying ticker, for example WithName or WithLock.
+func WithTickerOptions(opts ...Option) BatchOption {
+	return batchFunc(func(cfg *batchConfig) {
+		cfg.opts = append(cfg.opts, opts...)
+	})
+}
+
+// Batch starts a new linear ticker which on every tick of the given duration drains the source, fetching
+// batches and handling their items until a fetch returns no items. The ticker then pauses for the
+// duration before polling the source again, so a busy source is worked through back to back whilst an
+// empty one is polled once per tick.
+func Batch[T any](ctx context.Context, f foundation.F, d time.Duration, fetch BatchFetchFunc[T], handle BatchHandleFunc[T], opts ...BatchOption) {
+	cfg := batchConfig{
+		concurrency: 1,
+	}
+
+	BatchOptions(opts).applyBatch(&cfg)
+
+	Run(ctx, f, d, func(ctx context.Context, t Ticker) {
+		for ctx.Err() == nil {
+			items, err := fetch(ctx)
+			if err != nil {
+				cfg.fail(ctx, t, fmt.Errorf("fetch batch: %w", err))
+
+				return
+			}
+
+			if len(items) == 0 {
+				return
+			}
+
+			if err := handleBatch(ctx, t, items, handle, cfg); err != nil {
+				cfg.fail(ctx, t, err)
+
+				return
+			}
+		}
+	}, cfg.opts...)
+}
+
+// fail errors the ticker if the policy is FailOnError, otherwise logging the error.
+func (cfg batchConfig) fail(ctx context.Context, t Ticker, err error) {
+	if cfg.policy == FailOnError {
+		t.Error(err)
+
+		return
+	}
+
+	if ctx.Err() == nil {
+		slog.WarnContext(ctx, "batch failed", slog.String("ticker", t.Name()), slog.String("err", err.Error()))
+	}
+}
+
+// handleBatch handles the items with bounded concurrency. Unless the policy is ContinueOnError no more
+// items are handled once one fails and the errors of the items in flight are returned.
+func handleBatch[T any](ctx context.Context, t Ticker, items []T, handle BatchHandleFunc[T], cfg batchConfig) error {
+	var (
+		wg   sync.WaitGroup
+		mtx  sync.Mutex
+		errs []error
+		sem  = make(chan struct{}, max(cfg.concurrency, 1))
+	)
+
+	for item := range slices.Values(items) {
+		mtx.Lock()
+		failed := len(errs) > 0
+		mtx.Unlock()
+
+		if failed || ctx.Err() != nil {
+			break
+		}
+
+		if cfg.limiter != nil {
+			if err := cfg.limiter.Wait(ctx); err != nil {
+				break
+			}
+		}
+
+		sem <- struct{}{}
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			err := call(ctx, handle, item)
+			if err == nil {
+				return
+			}
+
+			if cfg.policy == ContinueOnError {
+				slog.WarnContext(ctx, "failed to handle batch item", slog.String("ticker", t.Name()), slog.String("err", err.Error()))
+
+				return
+			}
+
+			mtx.Lock()
+			errs = append(errs, fmt.Errorf("handle batch item: %w", err))
+			mtx.Unlock()
+		}()
+
+		// Without concurrency wait for the item so a failure stops the batch before the next is handled.
+		if cfg.concurrency <= 1 {
+			wg.Wait()
+		}
+	}
+
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// call calls the handler converting a panic into an error.
+func call[T any](ctx context.Context, handle BatchHandleFunc[T], item T) (err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = foundation.PanicError{Cause: rec}
+		}
+	}()
+
+	return handle(ctx, item)
+}
@@ -0,0 +1,45 @@
+package tick
+
+import "time"
+
+// A Clock tells the time and creates timers for the Runner, see WithClock. The default clock is the
+// system clock, tests can control time with a manual clock such as ticktest.Clock.
+type Clock interface {
+	Now() time.Time
+	NewTimer(d time.Duration) Timer
+}
+
+// A Timer is a timer created by a Clock, as time.Timer.
+type Timer interface {
+	// C returns the channel the time is sent on when the timer fires.
+	C() <-chan time.Time
+	// Stop stops the timer, returning false if it has already fired or been stopped.
+	Stop() bool
+}
+
+// WithClock sets the clock the Runner waits and records tick times with, defaults to the system clock.
+func WithClock(c Clock) Option {
+	return OptionFunc(func(r *Runner) {
+		r.clock = c
+	})
+}
+
+// systemClock is the Clock backed by the time package.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) NewTimer(d time.Duration) Timer {
+	return systemTimer{time.NewTimer(d)}
+}
+
+// systemTimer is a Timer backed by a *time.Timer.
+type systemTimer struct {
+	*time.Timer
+}
+
+func (t systemTimer) C() <-chan time.Time {
+	return t.Timer.C
+}
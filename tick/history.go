@@ -0,0 +1,171 @@
+package tick
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+)
+
+// An Outcome is the outcome of a tick.
+type Outcome string
+
+// Tick outcomes.
+const (
+	// OutcomeSuccess is the outcome of a tick whose function returned.
+	OutcomeSuccess Outcome = "success"
+	// OutcomeError is the outcome of a tick whose function errored, see Ticker.Error, or panicked.
+	OutcomeError Outcome = "error"
+	// OutcomeSkipped is the outcome of a tick skipped as the lock was held by another replica, see
+	// WithLock.
+	OutcomeSkipped Outcome = "skipped"
+)
+
+// An Execution is the record of a tick of a named ticker, see WithName.
+type Execution struct {
+	// Start is when the tick started.
+	Start time.Time `json:"start"`
+	// Duration is how long the function took.
+	Duration time.Duration `json:"duration"`
+	// Outcome is the outcome of the tick.
+	Outcome Outcome `json:"outcome"`
+	// Err is the error of a tick which errored.
+	Err string `json:"err,omitempty"`
+}
+
+// WithName names the ticker, recording the history of its last executions, see History. The name should
+// be unique, tickers with the same name share a history.
+func WithName(name string) Option {
+	return OptionFunc(func(r *Runner) {
+		r.name = name
+	})
+}
+
+// WithHistory sets how many executions of a named ticker are kept, defaults to 10.
+func WithHistory(n int) Option {
+	return OptionFunc(func(r *Runner) {
+		r.historySize = n
+	})
+}
+
+// ring is a bounded history of executions, overwriting the oldest once full.
+type ring struct {
+	mtx        sync.Mutex
+	executions []Execution
+	next       int
+	full       bool
+}
+
+// add records the execution.
+func (r *ring) add(e Execution) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if len(r.executions) == 0 {
+		return
+	}
+
+	r.executions[r.next] = e
+	r.next = (r.next + 1) % len(r.executions)
+
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// list returns the executions, oldest first.
+func (r *ring) list() []Execution {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if !r.full {
+		return slices.Clone(r.executions[:r.next])
+	}
+
+	return slices.Concat(r.executions[r.next:], r.executions[:r.next])
+}
+
+var histories = struct {
+	mtx   sync.RWMutex
+	rings map[string]*ring
+}{
+	rings: make(map[string]*ring),
+}
+
+// history returns the history of the named ticker, creating it with the size if it does not exist.
+func history(name string, size int) *ring {
+	histories.mtx.Lock()
+	defer histories.mtx.Unlock()
+
+	h, ok := histories.rings[name]
+	if !ok {
+		h = &ring{
+			executions: make([]Execution, max(size, 0)),
+		}
+
+		histories.rings[name] = h
+	}
+
+	return h
+}
+
+// History returns the last executions of the named ticker, oldest first, see WithName.
+func History(name string) []Execution {
+	histories.mtx.RLock()
+	h, ok := histories.rings[name]
+	histories.mtx.RUnlock()
+
+	if !ok {
+		return nil
+	}
+
+	return h.list()
+}
+
+// Histories returns the last executions of every named ticker, oldest first.
+func Histories() map[string][]Execution {
+	histories.mtx.RLock()
+	rings := maps.Clone(histories.rings)
+	histories.mtx.RUnlock()
+
+	m := make(map[string][]Execution, len(rings))
+
+	for name, h := range rings {
+		m[name] = h.list()
+	}
+
+	return m
+}
+
+// record records an execution started at the given time with the outcome of a recovered panic, if any.
+func (r *Runner) record(start time.Time, recovered any) {
+	if r.history == nil {
+		return
+	}
+
+	e := Execution{
+		Start:    start,
+		Duration: r.clock.Now().Sub(start),
+		Outcome:  OutcomeSuccess,
+	}
+
+	if recovered != nil {
+		e.Outcome = OutcomeError
+		e.Err = fmt.Sprint(recovered)
+	}
+
+	r.history.add(e)
+}
+
+// skip records a skipped execution.
+func (r *Runner) skip(start time.Time) {
+	if r.history == nil {
+		return
+	}
+
+	r.history.add(Execution{
+		Start:   start,
+		Outcome: OutcomeSkipped,
+	})
+}
@@ -7,9 +7,10 @@ import (
 )
 
 type eventHooks struct {
-	f        foundation.F
-	doneOnce sync.Once
-	stopOnce sync.Once
+	f           foundation.F
+	doneOnce    sync.Once
+	stopOnce    sync.Once
+	stopCtxOnce sync.Once
 }
 
 func newEventHooks(f foundation.F) *eventHooks {
@@ -19,7 +20,7 @@ func newEventHooks(f foundation.F) *eventHooks {
 }
 
 func (e *eventHooks) Done(fns ...foundation.EventHookFunc) {
-	e.stopOnce.Do(func() {
+	e.doneOnce.Do(func() {
 		e.f.On().Done(fns...)
 	})
 }
@@ -29,3 +30,13 @@ func (e *eventHooks) Stop(fns ...foundation.EventHookFunc) {
 		e.f.On().Stop(fns...)
 	})
 }
+
+func (e *eventHooks) StopCtx(fns ...foundation.StopCtxFunc) {
+	e.stopCtxOnce.Do(func() {
+		e.f.On().StopCtx(fns...)
+	})
+}
+
+func (e *eventHooks) Reload(fns ...foundation.EventHookFunc) {
+	e.f.On().Reload(fns...)
+}
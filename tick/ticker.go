@@ -2,10 +2,12 @@ package tick
 
 import (
 	"context"
+	"log/slog"
 	"sync"
 	"time"
 
 	"go.krak3n.io/foundation"
+	"go.krak3n.io/foundation/lock"
 )
 
 // Ticker is a limited subset of F providing ticker functionality.
@@ -57,6 +59,15 @@ func WithUntil(n uint8) Option {
 	})
 }
 
+// WithLock only executes the function whilst the lock is held, so when running multiple replicas only
+// one executes each tick. The lock is acquired or renewed for the TTL on each tick, so the TTL should be
+// longer than the tick interval, and released when the ticker stops.
+func WithLock(l lock.Lock, ttl time.Duration) Option {
+	return OptionFunc(func(r *Runner) {
+		r.lease = lock.NewLease(l, ttl)
+	})
+}
+
 // A TickFunc is a function called on each tickers tick.
 type TickFunc func(ctx context.Context, ticker Ticker)
 
@@ -105,19 +116,31 @@ type Runner struct {
 	maxRunCount uint8
 	runCount    uint8
 	hooks       *eventHooks
+	lease       *lock.Lease
+	clock       Clock
+	name        string
+	historySize int
+	history     *ring
+	trigger     *Trigger
 }
 
 // NewRunner constructs a new foundation.Runner for running tickers.
 // The Runner will execute the given function on every tick of the given duration.
 func NewRunner(fn TickFunc, backoff Backoff, opts ...Option) *Runner {
 	r := &Runner{
-		backoff: backoff,
-		fn:      fn,
-		stopped: true,
+		backoff:     backoff,
+		fn:          fn,
+		stopped:     true,
+		clock:       systemClock{},
+		historySize: 10,
 	}
 
 	Options(opts).apply(r)
 
+	if r.name != "" {
+		r.history = history(r.name, r.historySize)
+	}
+
 	return r
 }
 
@@ -151,6 +174,15 @@ func (r *Runner) Run(ctx context.Context, f foundation.F) {
 
 	f.On().Stop(func() {
 		r.Stop()
+
+		if r.lease != nil {
+			ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+			defer cancel()
+
+			if err := r.lease.Unlock(ctx); err != nil {
+				slog.Warn("failed to release ticker lock", slog.String("err", err.Error()))
+			}
+		}
 	})
 
 	r.mtx.Lock()
@@ -206,15 +238,16 @@ func (r *Runner) start(ctx context.Context) {
 
 	// Save state.
 	r.mtx.Lock()
-	r.started = time.Now()
+	r.started = r.clock.Now()
 	r.stopC = make(chan struct{})
 	r.stopped = false
+	stopC := r.stopC
 	r.mtx.Unlock()
 
 	ctx, cancel := context.WithCancel(ctx)
 
 	go func() {
-		<-r.stopC
+		<-stopC
 		cancel()
 	}()
 
@@ -240,36 +273,92 @@ func (r *Runner) start(ctx context.Context) {
 
 			r.mtx.RUnlock()
 
-			if err := wait(ctx, count, r.backoff); err != nil {
+			if err := wait(ctx, r.clock, count, r.backoff, r.trigger); err != nil {
 				return
 			}
 
+			if !r.leader(ctx) {
+				r.skip(r.clock.Now())
+
+				continue
+			}
+
 			r.mtx.Lock()
-			r.tick = time.Now()
+			r.tick = r.clock.Now()
 			r.runCount = count
+			tick := r.tick
 			r.mtx.Unlock()
 
-			r.fn(ctx, r)
+			r.exec(ctx, tick)
 		}
 	}
 }
 
-// Wait calculates the backoff wait duration based on the attempt number and Backoff given
-func wait(ctx context.Context, count uint8, backoff Backoff) error {
+// exec calls the function recording the execution, an error or panic is recorded before it continues
+// to unwind the ticker.
+func (r *Runner) exec(ctx context.Context, tick time.Time) {
+	if r.history == nil {
+		r.fn(ctx, r)
+
+		return
+	}
+
+	defer func() {
+		v := recover()
+
+		r.record(tick, v)
+
+		if v != nil {
+			panic(v)
+		}
+	}()
+
+	r.fn(ctx, r)
+}
+
+// leader reports whether the function should be executed, acquiring or renewing the lock if configured.
+func (r *Runner) leader(ctx context.Context) bool {
+	if r.lease == nil {
+		return true
+	}
+
+	ok, err := r.lease.TryLock(ctx)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to acquire ticker lock", slog.String("err", err.Error()))
+
+		return false
+	}
+
+	return ok
+}
+
+// Wait calculates the backoff wait duration based on the attempt number and Backoff given, returning
+// early if the trigger fires. Without a Backoff it waits only for the trigger.
+func wait(ctx context.Context, clock Clock, count uint8, backoff Backoff, trigger *Trigger) error {
+	if backoff == nil {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-trigger.fired():
+			return nil
+		}
+	}
+
 	wait := backoff.Wait(ctx, count)
 
 	if wait > 0 {
-		timer := time.NewTimer(wait)
+		timer := clock.NewTimer(wait)
 
 		select {
 		case <-ctx.Done():
-
-			if !timer.Stop() {
-				<-timer.C
-			}
+			timer.Stop()
 
 			return ctx.Err()
-		case <-timer.C:
+		case <-trigger.fired():
+			timer.Stop()
+
+			return nil
+		case <-timer.C():
 			return nil
 		}
 	}
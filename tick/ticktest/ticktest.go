@@ -0,0 +1,149 @@
+// Package ticktest provides a manual clock for testing tickers without real sleeps.
+//
+//	clock := ticktest.NewClock(time.Time{})
+//
+//	r := tick.NewRunner(fn, tick.LinearBackoff(time.Minute), tick.WithClock(clock), tick.WithUntil(3))
+//
+//	foundationtest.Start(t, r)
+//
+//	for range 3 {
+//		clock.BlockUntil(1)
+//		clock.Advance(time.Minute)
+//	}
+//
+// Runners also work within a testing/synctest bubble with the system clock, where time advances once
+// every goroutine is blocked.
+package ticktest
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"go.krak3n.io/foundation/tick"
+)
+
+// A Clock is a tick.Clock whose time only moves when advanced.
+type Clock struct {
+	mtx    sync.Mutex
+	cond   *sync.Cond
+	now    time.Time
+	timers []*timer
+	waits  []time.Duration
+}
+
+// NewClock returns a Clock set to the given time.
+func NewClock(now time.Time) *Clock {
+	c := &Clock{now: now}
+	c.cond = sync.NewCond(&c.mtx)
+
+	return c
+}
+
+// Now returns the clock's time.
+func (c *Clock) Now() time.Time {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	return c.now
+}
+
+// NewTimer returns a timer which fires once the clock has been advanced by d.
+func (c *Clock) NewTimer(d time.Duration) tick.Timer {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	t := &timer{
+		clock:    c,
+		deadline: c.now.Add(d),
+		ch:       make(chan time.Time, 1),
+	}
+
+	c.waits = append(c.waits, d)
+
+	if d <= 0 {
+		t.ch <- c.now
+
+		return t
+	}
+
+	c.timers = append(c.timers, t)
+	c.cond.Broadcast()
+
+	return t
+}
+
+// Advance moves the clock forward by d, firing every timer whose deadline has been reached.
+func (c *Clock) Advance(d time.Duration) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.now = c.now.Add(d)
+
+	c.timers = slices.DeleteFunc(c.timers, func(t *timer) bool {
+		if t.deadline.After(c.now) {
+			return false
+		}
+
+		t.ch <- c.now
+
+		return true
+	})
+}
+
+// BlockUntil blocks until at least n timers are waiting to fire, so the clock can be advanced once a
+// ticker is waiting for its next tick.
+func (c *Clock) BlockUntil(n int) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	for len(c.timers) < n {
+		c.cond.Wait()
+	}
+}
+
+// Waits returns the durations of every timer created, in order, for example to assert a backoff
+// schedule.
+func (c *Clock) Waits() []time.Duration {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	return slices.Clone(c.waits)
+}
+
+// timer is a tick.Timer fired by a Clock.
+type timer struct {
+	clock    *Clock
+	deadline time.Time
+	ch       chan time.Time
+}
+
+func (t *timer) C() <-chan time.Time {
+	return t.ch
+}
+
+func (t *timer) Stop() bool {
+	t.clock.mtx.Lock()
+	defer t.clock.mtx.Unlock()
+
+	n := len(t.clock.timers)
+
+	t.clock.timers = slices.DeleteFunc(t.clock.timers, func(other *timer) bool {
+		return other == t
+	})
+
+	return len(t.clock.timers) < n
+}
+
+// Schedule returns the waits of the backoff for the first n attempts, for asserting the schedule of a
+// backoff without jitter.
+func Schedule(backoff tick.Backoff, n uint8) []time.Duration {
+	waits := make([]time.Duration, 0, n)
+
+	for attempt := range n {
+		waits = append(waits, backoff.Wait(context.Background(), attempt+1))
+	}
+
+	return waits
+}
@@ -0,0 +1,58 @@
+package tick
+
+import (
+	"context"
+	"time"
+
+	"go.krak3n.io/foundation"
+)
+
+// A Trigger fires a ticker on demand, in addition to or instead of its schedule, see WithTrigger. Fires
+// whilst the ticker is waiting or executing are coalesced, so a burst results in a single tick.
+type Trigger struct {
+	c chan struct{}
+}
+
+// NewTrigger returns a new Trigger.
+func NewTrigger() *Trigger {
+	return &Trigger{
+		c: make(chan struct{}, 1),
+	}
+}
+
+// Fire fires the ticker without blocking. If the ticker has a fire pending it is a no-op.
+func (t *Trigger) Fire() {
+	select {
+	case t.c <- struct{}{}:
+	default:
+	}
+}
+
+// WithTrigger also executes the function when the trigger is fired, for example on a webhook, the next
+// scheduled tick is then waited for from that execution. A Runner with a nil Backoff only executes when
+// fired, see Triggered.
+func WithTrigger(t *Trigger) Option {
+	return OptionFunc(func(r *Runner) {
+		r.trigger = t
+	})
+}
+
+// Triggered starts a new ticker which executes the given function only when the trigger is fired.
+func Triggered(ctx context.Context, f foundation.F, t *Trigger, fn TickFunc, opts ...Option) {
+	f.Run(ctx, NewRunner(fn, nil, append(opts, WithTrigger(t))...))
+}
+
+// Every starts a new linear ticker which executes the given function on every tick of the given duration
+// and whenever the trigger is fired.
+func Every(ctx context.Context, f foundation.F, d time.Duration, t *Trigger, fn TickFunc, opts ...Option) {
+	Linear(ctx, f, d, fn, append(opts, WithTrigger(t))...)
+}
+
+// fired returns the channel the trigger fires on, nil without a trigger so it is never selected.
+func (t *Trigger) fired() <-chan struct{} {
+	if t == nil {
+		return nil
+	}
+
+	return t.c
+}
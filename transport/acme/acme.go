@@ -0,0 +1,227 @@
+// Package acme runs automatic certificate management, such as Let's Encrypt, for edge services run
+// outside a mesh. Foundation does not depend on golang.org/x/crypto, the certificate manager is given as
+// an interface satisfied by *autocert.Manager:
+//
+//	m := &autocert.Manager{
+//		Prompt:     autocert.AcceptTOS,
+//		Cache:      autocert.DirCache("/var/lib/certs"),
+//		HostPolicy: autocert.HostWhitelist("example.com"),
+//	}
+//
+//	certs := acme.Run(m, acme.WithHosts("example.com"))
+//
+//	foundation.Run("edge", certs, http.Run(handler, http.WtihServerAddress(":443"), certs.ServerOption()))
+//
+// The Runner answers HTTP-01 challenges on port 80, redirecting other requests to https, whilst
+// TLS-ALPN-01 challenges are answered by the HTTP runner the ServerOption is given to. Certificates for
+// the hosts are obtained on start and renewed in the background.
+package acme
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"go.krak3n.io/foundation"
+	"go.krak3n.io/foundation/health/probe"
+	"go.krak3n.io/foundation/tick"
+	transporthttp "go.krak3n.io/foundation/transport/http"
+)
+
+// A Manager obtains, caches and renews certificates, satisfied by *autocert.Manager.
+type Manager interface {
+	// GetCertificate returns the certificate for the server name of the hello, obtaining it if needed.
+	GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error)
+	// HTTPHandler answers HTTP-01 challenges, passing other requests to the fallback or redirecting them
+	// to https if it is nil.
+	HTTPHandler(fallback http.Handler) http.Handler
+	// TLSConfig returns a TLS configuration serving the managed certificates and answering TLS-ALPN-01
+	// challenges.
+	TLSConfig() *tls.Config
+}
+
+// An Option configures the acme Runner.
+type Option interface {
+	apply(*config)
+}
+
+// Options is one or more Option.
+type Options []Option
+
+func (opts Options) apply(cfg *config) {
+	for opt := range slices.Values(opts) {
+		if opt != nil {
+			opt.apply(cfg)
+		}
+	}
+}
+
+type optionFunc func(*config)
+
+func (f optionFunc) apply(cfg *config) {
+	f(cfg)
+}
+
+// config holds the acme Runner configuration.
+type config struct {
+	hosts     []string
+	interval  time.Duration
+	challenge string
+	fallback  http.Handler
+	mode      probe.Mode
+}
+
+// WithHosts sets the hosts whose certificates are obtained on start and renewed in the background.
+// Without hosts certificates are only obtained on the first TLS handshake for each host.
+func WithHosts(hosts ...string) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.hosts = append(cfg.hosts, hosts...)
+	})
+}
+
+// WithRenewInterval sets how often the certificates of the hosts are checked, renewing those close to
+// expiry, defaults to 12 hours.
+func WithRenewInterval(d time.Duration) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.interval = d
+	})
+}
+
+// WithChallengeAddress sets the address HTTP-01 challenges are answered on, defaults to :80. An empty
+// address disables the challenge server, leaving TLS-ALPN-01 challenges.
+func WithChallengeAddress(addr string) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.challenge = addr
+	})
+}
+
+// WithChallengeFallback sets the handler of requests to the challenge server which are not challenges,
+// defaults to redirecting them to https.
+func WithChallengeFallback(h http.Handler) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.fallback = h
+	})
+}
+
+// WithSensorMode sets the mode of the sensor which fails whilst a certificate for any of the hosts can
+// not be obtained, defaults to probe.ReadinessMode.
+func WithSensorMode(mode probe.Mode) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.mode = mode
+	})
+}
+
+// A Runner is a foundation.Runner which obtains and renews certificates with a Manager.
+type Runner struct {
+	m   Manager
+	cfg config
+
+	mtx  sync.RWMutex
+	errs map[string]error
+}
+
+// Run returns a Runner managing certificates with the manager.
+func Run(m Manager, opts ...Option) *Runner {
+	cfg := config{
+		interval:  12 * time.Hour,
+		challenge: ":80",
+		mode:      probe.ReadinessMode,
+	}
+
+	Options(opts).apply(&cfg)
+
+	return &Runner{
+		m:    m,
+		cfg:  cfg,
+		errs: make(map[string]error),
+	}
+}
+
+// ServerOption returns a HTTP RunnerOption serving TLS with the managed certificates, which also answers
+// TLS-ALPN-01 challenges. Clients connecting without a server name, such as probes dialing the
+// servers address, are served the certificate of the first host.
+func (r *Runner) ServerOption() transporthttp.RunnerOption {
+	return transporthttp.RunnerOptionFunc(func(s *http.Server) {
+		tlsConfig := r.m.TLSConfig()
+		getCertificate := tlsConfig.GetCertificate
+
+		tlsConfig.GetCertificate = func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+			if hello.ServerName == "" && len(r.cfg.hosts) > 0 {
+				named := *hello
+				named.ServerName = r.cfg.hosts[0]
+				hello = &named
+			}
+
+			return getCertificate(hello)
+		}
+
+		s.TLSConfig = tlsConfig
+	})
+}
+
+// Run serves HTTP-01 challenges and obtains the certificates of the hosts in the background, checking
+// them for renewal on the interval until stopped.
+func (r *Runner) Run(ctx context.Context, f foundation.F) {
+	cfg := r.cfg
+
+	if cfg.challenge != "" {
+		f.Run(ctx, transporthttp.Run(r.m.HTTPHandler(cfg.fallback), transporthttp.WtihServerAddress(cfg.challenge)))
+	}
+
+	if len(cfg.hosts) == 0 {
+		return
+	}
+
+	probe.RegisterContext(ctx, probe.NewSensor("acme", cfg.mode, func(context.Context) error {
+		return r.err(cfg.hosts)
+	}))
+
+	// Fire immediately so certificates are obtained on start without holding up the other runners.
+	obtain := tick.NewTrigger()
+	obtain.Fire()
+
+	tick.Every(ctx, f, cfg.interval, obtain, func(ctx context.Context, _ tick.Ticker) {
+		for host := range slices.Values(cfg.hosts) {
+			r.obtain(ctx, host)
+		}
+	})
+}
+
+// obtain obtains or renews the certificate of the host, recording the error if it fails.
+func (r *Runner) obtain(ctx context.Context, host string) {
+	_, err := r.m.GetCertificate(&tls.ClientHelloInfo{ServerName: host})
+	if err != nil {
+		slog.WarnContext(ctx, "failed to obtain certificate", slog.String("host", host), slog.String("err", err.Error()))
+	}
+
+	r.mtx.Lock()
+	r.errs[host] = err
+	r.mtx.Unlock()
+}
+
+// err returns an error for each host whose certificate has not been obtained.
+func (r *Runner) err(hosts []string) error {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	var errs []error
+
+	for host := range slices.Values(hosts) {
+		err, ok := r.errs[host]
+
+		switch {
+		case !ok:
+			errs = append(errs, fmt.Errorf("%s: certificate not yet obtained", host))
+		case err != nil:
+			errs = append(errs, fmt.Errorf("%s: %w", host, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
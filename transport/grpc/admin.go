@@ -0,0 +1,94 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"go.krak3n.io/foundation"
+	transporthttp "go.krak3n.io/foundation/transport/http"
+)
+
+// DefaultAdminAddress is the address the admin server listens on if no address is given.
+const DefaultAdminAddress = "127.0.0.1:3419"
+
+// An AdminFunc registers a debugging service for the server on the admin server, see WithReflection and
+// WithChannelz.
+type AdminFunc func(server, admin Server)
+
+// adminConfig holds the admin server configuration of the gRPC Runner.
+type adminConfig struct {
+	addr     string
+	server   Server
+	services []AdminFunc
+}
+
+// WithAdminServer serves admin, a second gRPC server, alongside the service server on the given address
+// for debugging services such as reflection and channelz. The address must be a loopback address so
+// the debugging services are never exposed on a public interface, if empty DefaultAdminAddress is used.
+func WithAdminServer(addr string, admin Server) RunnerOption {
+	return runnerConfigFunc(func(cfg *runnerConfig) {
+		if addr == "" {
+			addr = DefaultAdminAddress
+		}
+
+		cfg.admin.addr = addr
+		cfg.admin.server = admin
+	})
+}
+
+// WithReflection serves reflection of the servers services on the admin server, so tools such as
+// grpcurl can be used against it, registered by fn:
+//
+//	grpc.WithReflection(func(server, admin grpc.Server) {
+//		svc := reflection.NewServerV1(reflection.ServerOptions{Services: server.(*ggrpc.Server)})
+//		reflectionpb.RegisterServerReflectionServer(admin.(*ggrpc.Server), svc)
+//	})
+//
+// Reflection is off by default, enable it outside of production, for example unless
+// blueprint.ProfileFromEnv is blueprint.Prod. Requires WithAdminServer.
+func WithReflection(fn AdminFunc) RunnerOption {
+	return runnerConfigFunc(func(cfg *runnerConfig) {
+		cfg.admin.services = append(cfg.admin.services, fn)
+	})
+}
+
+// WithChannelz serves channelz on the admin server, registered by fn:
+//
+//	grpc.WithChannelz(func(_, admin grpc.Server) {
+//		service.RegisterChannelzServiceToServer(admin.(*ggrpc.Server))
+//	})
+//
+// Channelz is off by default, enable it outside of production as WithReflection. Requires
+// WithAdminServer.
+func WithChannelz(fn AdminFunc) RunnerOption {
+	return runnerConfigFunc(func(cfg *runnerConfig) {
+		cfg.admin.services = append(cfg.admin.services, fn)
+	})
+}
+
+// runAdmin registers the debugging services on the admin server and serves it, if configured.
+func runAdmin(ctx context.Context, f foundation.F, server Server, cfg runnerConfig) {
+	admin := cfg.admin
+
+	if admin.server == nil {
+		if len(admin.services) > 0 {
+			f.Error(errors.New("reflection and channelz require an admin server, see WithAdminServer"))
+		}
+
+		return
+	}
+
+	if err := transporthttp.CheckLoopback(admin.addr); err != nil {
+		f.Error(err)
+	}
+
+	for fn := range slices.Values(admin.services) {
+		fn(server, admin.server)
+	}
+
+	f.Run(ctx, Run(admin.addr, admin.server,
+		WithShutdownTimeout(cfg.shutdownTimeout),
+		WithListenFunc(cfg.listen),
+		WithoutSensor()))
+}
@@ -0,0 +1,298 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"runtime/debug"
+	"slices"
+	"time"
+
+	"go.krak3n.io/foundation"
+	"go.krak3n.io/foundation/metrics"
+)
+
+// ErrPanic is returned by the recovery interceptor when a handler panics, the panic itself is reported
+// through the F so is not exposed to the client. Map it to codes.Internal in the adapter if needed.
+var ErrPanic = errors.New("grpc handler panicked")
+
+// A Handler handles an RPC, see Interceptor.
+type Handler func(ctx context.Context) error
+
+// An Interceptor wraps the handling of the RPC to the method, for example "/pkg.Service/Method", and
+// must call next with the context the handler should see.
+type Interceptor func(ctx context.Context, method string, next Handler) error
+
+// A TraceFunc starts a span for the RPC to the method returning the context carrying it and a function
+// ending it with the error of the RPC, see WithTracing.
+type TraceFunc func(ctx context.Context, method string) (context.Context, func(err error))
+
+// An InterceptorOption configures the Interceptors.
+type InterceptorOption interface {
+	applyInterceptorConfig(*interceptorConfig)
+}
+
+// InterceptorOptions is one or more InterceptorOption.
+type InterceptorOptions []InterceptorOption
+
+func (o InterceptorOptions) applyInterceptorConfig(cfg *interceptorConfig) {
+	for opt := range slices.Values(o) {
+		if opt != nil {
+			opt.applyInterceptorConfig(cfg)
+		}
+	}
+}
+
+type interceptorConfigFunc func(*interceptorConfig)
+
+func (f interceptorConfigFunc) applyInterceptorConfig(cfg *interceptorConfig) {
+	f(cfg)
+}
+
+// interceptorConfig holds the configuration of the Interceptors.
+type interceptorConfig struct {
+	recovery     bool
+	logging      bool
+	metrics      bool
+	trace        TraceFunc
+	code         func(error) string
+	interceptors []Interceptor
+}
+
+// WithoutRecovery disables the panic recovery interceptor, panics will be handled by the gRPC server
+// instead.
+func WithoutRecovery() InterceptorOption {
+	return interceptorConfigFunc(func(cfg *interceptorConfig) {
+		cfg.recovery = false
+	})
+}
+
+// WithoutLogging disables the access logging interceptor.
+func WithoutLogging() InterceptorOption {
+	return interceptorConfigFunc(func(cfg *interceptorConfig) {
+		cfg.logging = false
+	})
+}
+
+// WithoutMetrics disables the metrics interceptor.
+func WithoutMetrics() InterceptorOption {
+	return interceptorConfigFunc(func(cfg *interceptorConfig) {
+		cfg.metrics = false
+	})
+}
+
+// WithTracing traces RPCs with the function, for example starting an OpenTelemetry span:
+//
+//	grpc.WithTracing(func(ctx context.Context, method string) (context.Context, func(error)) {
+//		ctx, span := tracer.Start(ctx, method, trace.WithSpanKind(trace.SpanKindServer))
+//
+//		return ctx, func(err error) {
+//			if err != nil {
+//				span.RecordError(err)
+//				span.SetStatus(codes.Error, err.Error())
+//			}
+//
+//			span.End()
+//		}
+//	})
+//
+// Tracing is the outermost interceptor so logs are written with the context carrying the span.
+func WithTracing(fn TraceFunc) InterceptorOption {
+	return interceptorConfigFunc(func(cfg *interceptorConfig) {
+		cfg.trace = fn
+	})
+}
+
+// WithCodeFunc sets the function returning the status code of the error of an RPC logs and metrics are
+// labelled with, for example status.Code(err).String(). Defaults to OK and Unknown.
+func WithCodeFunc(fn func(error) string) InterceptorOption {
+	return interceptorConfigFunc(func(cfg *interceptorConfig) {
+		cfg.code = fn
+	})
+}
+
+// WithInterceptor adds an interceptor run inside the default interceptors, the first given is the
+// outermost.
+func WithInterceptor(i ...Interceptor) InterceptorOption {
+	return interceptorConfigFunc(func(cfg *interceptorConfig) {
+		cfg.interceptors = append(cfg.interceptors, i...)
+	})
+}
+
+// Interceptors is a chain of server interceptors giving gRPC services parity with the HTTP runner:
+// tracing, slog access logging, metrics and panic recovery into foundation errors, outermost first.
+// Foundation does not depend on grpc-go so the chain is installed with adapters:
+//
+//	chain := grpc.NewInterceptors(f, grpc.WithCodeFunc(func(err error) string {
+//		return status.Code(err).String()
+//	}))
+//
+//	server := ggrpc.NewServer(
+//		ggrpc.UnaryInterceptor(func(ctx context.Context, req any, info *ggrpc.UnaryServerInfo, handler ggrpc.UnaryHandler) (any, error) {
+//			return chain.Unary(ctx, info.FullMethod, req, handler)
+//		}),
+//		ggrpc.StreamInterceptor(func(srv any, ss ggrpc.ServerStream, info *ggrpc.StreamServerInfo, handler ggrpc.StreamHandler) error {
+//			return chain.Stream(ss.Context(), info.FullMethod, func(ctx context.Context) error {
+//				return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+//			})
+//		}),
+//	)
+//
+// Where contextStream embeds the grpc.ServerStream overriding its Context method.
+type Interceptors struct {
+	chain []Interceptor
+}
+
+// NewInterceptors returns the interceptor chain, panics are reported through the F.
+func NewInterceptors(f foundation.F, opts ...InterceptorOption) *Interceptors {
+	cfg := interceptorConfig{
+		recovery: true,
+		logging:  true,
+		metrics:  true,
+		code:     defaultCode,
+	}
+
+	InterceptorOptions(opts).applyInterceptorConfig(&cfg)
+
+	var chain []Interceptor
+
+	if cfg.trace != nil {
+		chain = append(chain, tracing(cfg.trace))
+	}
+
+	if cfg.logging {
+		chain = append(chain, logging(cfg.code))
+	}
+
+	if cfg.metrics {
+		chain = append(chain, measure(cfg.code))
+	}
+
+	chain = append(chain, cfg.interceptors...)
+
+	if cfg.recovery {
+		chain = append(chain, recovery(f))
+	}
+
+	return &Interceptors{chain: chain}
+}
+
+// Unary handles a unary RPC to the method with the chain, handler is satisfied by grpc.UnaryHandler.
+func (i *Interceptors) Unary(ctx context.Context, method string, req any, handler func(ctx context.Context, req any) (any, error)) (any, error) {
+	var rsp any
+
+	err := i.Stream(ctx, method, func(ctx context.Context) error {
+		var err error
+
+		rsp, err = handler(ctx, req)
+
+		return err
+	})
+
+	return rsp, err
+}
+
+// Stream handles a streaming RPC to the method with the chain, the handler must give the context it is
+// called with to the stream so interceptors can propagate values such as spans.
+func (i *Interceptors) Stream(ctx context.Context, method string, handler Handler) error {
+	next := handler
+
+	for _, interceptor := range slices.Backward(i.chain) {
+		inner := next
+
+		next = func(ctx context.Context) error {
+			return interceptor(ctx, method, inner)
+		}
+	}
+
+	return next(ctx)
+}
+
+// defaultCode returns OK for a nil error and Unknown otherwise.
+func defaultCode(err error) string {
+	if err == nil {
+		return "OK"
+	}
+
+	return "Unknown"
+}
+
+func tracing(fn TraceFunc) Interceptor {
+	return func(ctx context.Context, method string, next Handler) error {
+		ctx, end := fn(ctx, method)
+
+		err := next(ctx)
+		end(err)
+
+		return err
+	}
+}
+
+func logging(code func(error) string) Interceptor {
+	return func(ctx context.Context, method string, next Handler) error {
+		start := time.Now()
+
+		err := next(ctx)
+
+		attrs := []slog.Attr{
+			slog.String("grpc.method", method),
+			slog.String("grpc.code", code(err)),
+			slog.Duration("duration", time.Since(start)),
+		}
+
+		level := slog.LevelInfo
+
+		if err != nil {
+			level = slog.LevelWarn
+			attrs = append(attrs, slog.String("err", err.Error()))
+		}
+
+		slog.LogAttrs(ctx, level, "handled grpc request", attrs...)
+
+		return err
+	}
+}
+
+func measure(code func(error) string) Interceptor {
+	return func(ctx context.Context, method string, next Handler) error {
+		start := time.Now()
+
+		err := next(ctx)
+
+		metrics.NewHistogram("grpc_server_handling_seconds", metrics.Labels{"method": method}).Observe(time.Since(start).Seconds())
+		metrics.NewCounter("grpc_server_handled_total", metrics.Labels{"method": method, "code": code(err)}).Add(1)
+
+		return err
+	}
+}
+
+func recovery(f foundation.F) Interceptor {
+	return func(ctx context.Context, method string, next Handler) (err error) {
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+
+			stack := debug.Stack()
+
+			cause, ok := rec.(error)
+			if !ok {
+				cause = foundation.PanicError{Cause: rec}
+			}
+
+			foundation.Report(f, foundation.RuntimeError{
+				Cause:  cause,
+				Stack:  stack,
+				Runner: f.Name(),
+				Attrs: []slog.Attr{
+					slog.String("grpc.method", method),
+				},
+			})
+
+			err = ErrPanic
+		}()
+
+		return next(ctx)
+	}
+}
@@ -0,0 +1,213 @@
+// Package grpc runs a gRPC server as a foundation.Runner. Foundation does not depend on grpc-go, the
+// server is given as an interface satisfied by *grpc.Server from google.golang.org/grpc. Interceptors
+// gives servers the default recovery, logging, tracing and metrics interceptors through small adapters.
+package grpc
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"net"
+	"slices"
+	"sync"
+	"time"
+
+	"go.krak3n.io/foundation"
+	"go.krak3n.io/foundation/health/probe"
+	"go.krak3n.io/foundation/transport/internal/boundaddr"
+)
+
+// A Server is a gRPC server, satisfied by *grpc.Server.
+type Server interface {
+	// Serve accepts connections on the listener until stopped.
+	Serve(lis net.Listener) error
+	// GracefulStop stops accepting connections and waits for in flight RPCs to finish.
+	GracefulStop()
+	// Stop closes all connections cancelling in flight RPCs.
+	Stop()
+}
+
+// A RunnerOption configures the gRPC Runner.
+type RunnerOption interface {
+	applyRunnerConfig(*runnerConfig)
+}
+
+// RunnerOptions is one or more RunnerOption.
+type RunnerOptions []RunnerOption
+
+func (o RunnerOptions) applyRunnerConfig(cfg *runnerConfig) {
+	for opt := range slices.Values(o) {
+		if opt != nil {
+			opt.applyRunnerConfig(cfg)
+		}
+	}
+}
+
+type runnerConfigFunc func(*runnerConfig)
+
+func (f runnerConfigFunc) applyRunnerConfig(cfg *runnerConfig) {
+	f(cfg)
+}
+
+// runnerConfig holds the configuration for the gRPC Runner.
+type runnerConfig struct {
+	shutdownTimeout time.Duration
+	sensor          bool
+	listen          ListenFunc
+	tls             *tls.Config
+	clientAuth      func(*tls.Config)
+	admin           adminConfig
+}
+
+// WithShutdownTimeout sets how long to wait on stop for in flight RPCs to finish before they are
+// cancelled, defaults to 10 seconds. RPCs are cancelled sooner if the runners share of the shutdown
+// budget runs out first, see foundation.WithShutdownBudget.
+func WithShutdownTimeout(d time.Duration) RunnerOption {
+	return runnerConfigFunc(func(cfg *runnerConfig) {
+		cfg.shutdownTimeout = d
+	})
+}
+
+// A ListenFunc listens on the network address, see WithListenFunc.
+type ListenFunc func(network, addr string) (net.Listener, error)
+
+// WithListenFunc sets the function the runner listens with, defaults to net.Listen. For example
+// upgrade.Listen to inherit the listener across an in place restart.
+func WithListenFunc(fn ListenFunc) RunnerOption {
+	return runnerConfigFunc(func(cfg *runnerConfig) {
+		cfg.listen = fn
+	})
+}
+
+// WithoutSensor stops the runner from registering a health probe sensor for the server.
+func WithoutSensor() RunnerOption {
+	return runnerConfigFunc(func(cfg *runnerConfig) {
+		cfg.sensor = false
+	})
+}
+
+// A Runner is a foundation.Runner which serves a gRPC server.
+type Runner struct {
+	addr   string
+	server Server
+	opts   []RunnerOption
+	mtx    sync.RWMutex
+	bound  string
+}
+
+// Run returns a Runner which serves the server on the given address.
+func Run(addr string, server Server, opts ...RunnerOption) *Runner {
+	return &Runner{
+		addr:   addr,
+		server: server,
+		opts:   opts,
+	}
+}
+
+// BoundAddr returns the address the server is listening on, which when configured to listen on port 0
+// will be the port assigned by the operating system. Returns an empty string until the server is
+// listening, which is guaranteed once F.Run has returned for the Runner.
+func (r *Runner) BoundAddr() string {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	return r.bound
+}
+
+// Run listens on the address and serves until told to stop, when in flight RPCs are given the shutdown
+// timeout to finish. Once listening the bound address is published to the value store, see BoundAddr,
+// and the runner marked as parallel.
+func (r *Runner) Run(ctx context.Context, f foundation.F) {
+	cfg := runnerConfig{
+		shutdownTimeout: 10 * time.Second,
+		sensor:          true,
+		listen:          net.Listen,
+	}
+
+	RunnerOptions(r.opts).applyRunnerConfig(&cfg)
+
+	ln, err := cfg.listen("tcp", r.addr)
+	if err != nil {
+		f.Error(fmt.Errorf("listen on %s: %w", r.addr, err))
+	}
+
+	ln, err = listenTLS(ln, cfg)
+	if err != nil {
+		f.Error(err)
+	}
+
+	bound := ln.Addr().String()
+
+	r.mtx.Lock()
+	r.bound = bound
+	r.mtx.Unlock()
+
+	boundaddr.Publish(f, "grpc", r, r.addr, bound)
+
+	// Run the admin server alongside the service server if one has been configured.
+	runAdmin(ctx, f, r.server, cfg)
+
+	f.On().StopCtx(func(ctx context.Context) {
+		stopped := make(chan struct{})
+
+		go func() {
+			defer close(stopped)
+
+			r.server.GracefulStop()
+		}()
+
+		select {
+		case <-stopped:
+		case <-ctx.Done():
+			slog.Warn("grpc server shutdown budget exceeded, cancelling rpcs", slog.String("addr", bound))
+			r.server.Stop()
+
+			<-stopped
+		case <-time.After(cfg.shutdownTimeout):
+			slog.Warn("grpc server shutdown timeout exceeded, cancelling rpcs", slog.String("addr", bound))
+			r.server.Stop()
+
+			<-stopped
+		}
+	})
+
+	if cfg.sensor {
+		probe.RegisterContext(ctx, Sensor(bound))
+	}
+
+	slog.InfoContext(ctx, "serving grpc", slog.String("addr", bound))
+
+	f.Parallel() // Mark the Runner as parallel now we are going start blocking
+
+	if err := r.server.Serve(ln); err != nil {
+		f.Error(fmt.Errorf("serve grpc: %w", err))
+	}
+}
+
+// BoundAddr returns the bound address of the server configured with the given address from the F's
+// value store, for example BoundAddr(f, "127.0.0.1:0"). Reports false if several servers are configured
+// with the address, their bound addresses are then only available from their Runner, see
+// Runner.BoundAddr.
+func BoundAddr(f foundation.F, addr string) (string, bool) {
+	return boundaddr.Lookup(f, "grpc", addr)
+}
+
+// Sensor returns a health probe sensor which dials the given address, the sensor is healthy if a
+// connection can be established.
+func Sensor(addr string) probe.Sensor {
+	return probe.NewSensor(fmt.Sprintf("grpc.server[%s]", addr), probe.AllModes, func(ctx context.Context) error {
+		var d net.Dialer
+
+		conn, err := d.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return fmt.Errorf("dial: %w", err)
+		}
+
+		if err := conn.Close(); err != nil {
+			return fmt.Errorf("close connection: %w", err)
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,58 @@
+package grpc
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"slices"
+
+	"go.krak3n.io/foundation/transport/mtls"
+)
+
+// WithTLS serves TLS with the configuration by wrapping the listener, for servers without transport
+// credentials of their own. Handlers see the connection as insecure, give the configuration to
+// grpc.Creds instead if they need the peers certificate.
+func WithTLS(tlsConfig *tls.Config) RunnerOption {
+	return runnerConfigFunc(func(cfg *runnerConfig) {
+		cfg.tls = tlsConfig
+	})
+}
+
+// WithClientAuth requires clients to present a certificate issued by a CA in the pool which passes any
+// verify functions, see mtls.Config. The server must serve TLS, see WithTLS. The sensor only dials the
+// server so is unaffected.
+func WithClientAuth(pool *x509.CertPool, opts ...mtls.Option) RunnerOption {
+	return runnerConfigFunc(func(cfg *runnerConfig) {
+		cfg.clientAuth = func(tlsConfig *tls.Config) {
+			mtls.Apply(tlsConfig, pool, opts...)
+		}
+	})
+}
+
+// listenTLS wraps the listener to serve TLS if configured, requiring client certificates if configured.
+// The listener is closed if the configuration is invalid.
+func listenTLS(ln net.Listener, cfg runnerConfig) (net.Listener, error) {
+	if cfg.tls == nil {
+		if cfg.clientAuth != nil {
+			ln.Close()
+
+			return nil, errors.New("client authentication requires tls, see WithTLS")
+		}
+
+		return ln, nil
+	}
+
+	tlsConfig := cfg.tls.Clone()
+
+	// gRPC clients require HTTP/2 to be negotiated.
+	if !slices.Contains(tlsConfig.NextProtos, "h2") {
+		tlsConfig.NextProtos = append(tlsConfig.NextProtos, "h2")
+	}
+
+	if cfg.clientAuth != nil {
+		cfg.clientAuth(tlsConfig)
+	}
+
+	return tls.NewListener(ln, tlsConfig), nil
+}
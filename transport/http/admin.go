@@ -0,0 +1,113 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/pprof"
+
+	"go.krak3n.io/foundation"
+)
+
+// DefaultAdminAddress is the address the admin server listens on if no address is given.
+const DefaultAdminAddress = "127.0.0.1:3418"
+
+// WithAdminServer runs an admin server alongside the service server on the given address exposing
+// pprof, expvar and the runner tree. The address must be a loopback address so the admin endpoints are
+// never exposed on a public interface, if empty DefaultAdminAddress is used.
+func WithAdminServer(addr string) RunnerOption {
+	return runnerConfigFunc(func(cfg *runnerConfig) {
+		if addr == "" {
+			addr = DefaultAdminAddress
+		}
+
+		cfg.admin = &http.Server{
+			Addr: addr,
+		}
+	})
+}
+
+// AdminHandler returns a http.Handler serving the admin endpoints:
+//
+//   - /debug/pprof/ the net/http/pprof profiles
+//   - /debug/vars the expvar variables
+//   - /debug/tree a JSON snapshot of the runner tree the given F belongs to
+func AdminHandler(f foundation.F) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("GET /debug/vars", expvar.Handler())
+	mux.Handle("GET /debug/tree", TreeHandler(f))
+
+	return mux
+}
+
+// TreeHandler returns a http.Handler which writes a JSON snapshot of the runner tree the given F belongs to.
+func TreeHandler(f foundation.F) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := json.Marshal(foundation.Tree(f))
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			slog.ErrorContext(r.Context(), "failed to marshal runner tree", slog.String("err", err.Error()))
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+
+		if _, err := w.Write(b); err != nil {
+			slog.ErrorContext(r.Context(), "failed to write runner tree", slog.String("err", err.Error()))
+		}
+	})
+}
+
+// runAdmin returns a foundation.Runner which serves the admin endpoints with the given server.
+func runAdmin(server *http.Server) foundation.Runner {
+	return foundation.RunFunc(func(ctx context.Context, f foundation.F) {
+		if err := CheckLoopback(server.Addr); err != nil {
+			f.Error(err)
+		}
+
+		server.Handler = AdminHandler(f)
+
+		f.On().Stop(func() {
+			if err := server.Shutdown(ctx); err != nil {
+				f.Error(err)
+			}
+		})
+
+		f.Parallel()
+
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			f.Error(err)
+		}
+	})
+}
+
+// CheckLoopback returns an error if the given address does not resolve to a loopback address.
+func CheckLoopback(addr string) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("invalid admin address %q: %w", addr, err)
+	}
+
+	if host == "localhost" {
+		return nil
+	}
+
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return nil
+	}
+
+	return fmt.Errorf("admin address %q is not a loopback address", addr)
+}
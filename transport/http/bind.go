@@ -0,0 +1,77 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"syscall"
+	"time"
+
+	"go.krak3n.io/foundation/tick"
+)
+
+// WithBindRetry retries listening when the address is in use, for example whilst the previous instance
+// is still shutting down during a fast redeploy on a shared host, up to attempts times waiting the backoff
+// between each before failing the runner. Other errors, such as a permission denied or an address which
+// is not available, fail the runner at once. Listening is not retried by default. Only the HTTP runner
+// retries binding, the TCP, UDP and gRPC runners fail on the first error.
+//
+//	http.WithBindRetry(5, tick.ExponentialBackoff(500*time.Millisecond))
+func WithBindRetry(attempts uint8, backoff tick.Backoff) RunnerOption {
+	return runnerConfigFunc(func(cfg *runnerConfig) {
+		cfg.bindRetries = attempts
+		cfg.bindBackoff = backoff
+	})
+}
+
+// bind listens on the address, retrying as configured, see WithBindRetry.
+func bind(ctx context.Context, cfg runnerConfig, addr string) (net.Listener, error) {
+	var attempt uint8
+
+	for {
+		ln, err := cfg.listen("tcp", addr)
+		if err == nil {
+			return ln, nil
+		}
+
+		// Only an address in use is expected to become free, anything else is a misconfiguration.
+		if !errors.Is(err, syscall.EADDRINUSE) || attempt >= cfg.bindRetries || cfg.bindBackoff == nil {
+			return nil, bindError(addr, err, attempt)
+		}
+
+		attempt++
+
+		wait := cfg.bindBackoff.Wait(ctx, attempt)
+
+		slog.WarnContext(ctx, "failed to bind http server, retrying",
+			slog.String("addr", addr),
+			slog.Int("attempt", int(attempt)),
+			slog.Duration("wait", wait),
+			slog.String("err", err.Error()))
+
+		timer := time.NewTimer(wait)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+
+			return nil, bindError(addr, err, attempt)
+		case <-timer.C:
+		}
+	}
+}
+
+// bindError returns the error of failing to listen on the address, explaining an address in use.
+func bindError(addr string, err error, retries uint8) error {
+	if errors.Is(err, syscall.EADDRINUSE) {
+		if retries > 0 {
+			return fmt.Errorf("listen on %s: address in use by another process, gave up after %d retries: %w", addr, retries, err)
+		}
+
+		return fmt.Errorf("listen on %s: address in use by another process: %w", addr, err)
+	}
+
+	return fmt.Errorf("listen on %s: %w", addr, err)
+}
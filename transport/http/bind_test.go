@@ -0,0 +1,112 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"go.krak3n.io/foundation/tick"
+)
+
+// failingListen returns a ListenFunc which fails with err the given number of times before listening,
+// counting its calls.
+func failingListen(t *testing.T, err error, failures int, calls *int) ListenFunc {
+	return func(network, addr string) (net.Listener, error) {
+		*calls++
+
+		if *calls <= failures {
+			return nil, &net.OpError{Op: "listen", Net: network, Err: os.NewSyscallError("bind", err)}
+		}
+
+		ln, err := net.Listen(network, "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		t.Cleanup(func() {
+			ln.Close()
+		})
+
+		return ln, nil
+	}
+}
+
+func TestBind(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		failures int
+		retries  uint8
+		calls    int
+		wantErr  bool
+	}{
+		{name: "in use then free", err: syscall.EADDRINUSE, failures: 2, retries: 3, calls: 3},
+		{name: "in use until retries exhausted", err: syscall.EADDRINUSE, failures: 10, retries: 3, calls: 4, wantErr: true},
+		{name: "in use without retries", err: syscall.EADDRINUSE, failures: 1, calls: 1, wantErr: true},
+		{name: "permission denied is not retried", err: syscall.EACCES, failures: 1, retries: 3, calls: 1, wantErr: true},
+		{name: "address not available is not retried", err: syscall.EADDRNOTAVAIL, failures: 1, retries: 3, calls: 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int
+
+			cfg := runnerConfig{
+				listen:      failingListen(t, tt.err, tt.failures, &calls),
+				bindRetries: tt.retries,
+				bindBackoff: tick.LinearBackoff(time.Millisecond),
+			}
+
+			ln, err := bind(context.Background(), cfg, "127.0.0.1:3000")
+
+			if calls != tt.calls {
+				t.Errorf("want %d listen calls, got %d", tt.calls, calls)
+			}
+
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("want error, got nil")
+				}
+
+				if !errors.Is(err, tt.err) {
+					t.Errorf("want error wrapping %v, got %v", tt.err, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if ln == nil {
+				t.Fatal("want listener, got nil")
+			}
+		})
+	}
+}
+
+func TestBindContextDone(t *testing.T) {
+	var calls int
+
+	cfg := runnerConfig{
+		listen:      failingListen(t, syscall.EADDRINUSE, 10, &calls),
+		bindRetries: 10,
+		bindBackoff: tick.LinearBackoff(time.Hour),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := bind(ctx, cfg, "127.0.0.1:3000"); !errors.Is(err, syscall.EADDRINUSE) {
+		t.Errorf("want address in use error, got %v", err)
+	}
+
+	if calls != 1 {
+		t.Errorf("want 1 listen call before the context is done, got %d", calls)
+	}
+}
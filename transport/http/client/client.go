@@ -0,0 +1,264 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"go.krak3n.io/foundation/breaker"
+	"go.krak3n.io/foundation/health/probe"
+	"go.krak3n.io/foundation/metrics"
+	"go.krak3n.io/foundation/tick"
+)
+
+// Option configures a client.
+type Option interface {
+	apply(*config)
+}
+
+// Options is one or more Option.
+type Options []Option
+
+func (opts Options) apply(cfg *config) {
+	for opt := range slices.Values(opts) {
+		if opt != nil {
+			opt.apply(cfg)
+		}
+	}
+}
+
+type optionFunc func(*config)
+
+func (f optionFunc) apply(cfg *config) {
+	f(cfg)
+}
+
+// config holds the client configuration.
+type config struct {
+	name      string
+	timeout   time.Duration
+	retries   uint8
+	backoff   tick.Backoff
+	transport *http.Transport
+	sensor    *sensorConfig
+	registry  *probe.Registry
+	breaker   *breaker.Breaker
+}
+
+type sensorConfig struct {
+	url  string
+	mode probe.Mode
+}
+
+// WithName sets the name of the client used to label metrics and name the sensor, defaults to "default".
+func WithName(name string) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.name = name
+	})
+}
+
+// WithTimeout sets the overall timeout of a request including retries, defaults to 30 seconds.
+func WithTimeout(d time.Duration) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.timeout = d
+	})
+}
+
+// WithRetries sets the maximum number of times an idempotent request is retried, defaults to 3.
+// Setting 0 disables retries.
+func WithRetries(n uint8) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.retries = n
+	})
+}
+
+// WithBackoff sets the backoff used to wait between retries, defaults to an exponential backoff with a
+// scalar of 100ms and 20% jitter.
+func WithBackoff(backoff tick.Backoff) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.backoff = backoff
+	})
+}
+
+// WithTransport sets the underlying transport, defaults to http.DefaultTransport. The transport is cloned
+// so its DialContext can be wrapped to track open connections without changing the given transport.
+func WithTransport(t *http.Transport) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.transport = t
+	})
+}
+
+// WithSensor registers a health probe sensor for the upstream which makes a GET request to the given
+// url with the client, any non 5xx response is healthy. The sensor is registered with the global registry
+// unless configured WithRegistry.
+func WithSensor(url string, mode probe.Mode) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.sensor = &sensorConfig{
+			url:  url,
+			mode: mode,
+		}
+	})
+}
+
+// WithRegistry sets the registry the sensor is registered with, see WithSensor, defaults to the global
+// registry. For example probe.RegistryFromContext(ctx) to register with the registry of a foundation
+// instance.
+func WithRegistry(r *probe.Registry) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.registry = r
+	})
+}
+
+// WithBreaker guards requests with the circuit breaker, a request including its retries is a single
+// call which fails on a network error or 5xx response. Whilst the breaker is open requests fail with
+// breaker.ErrOpen without being sent.
+func WithBreaker(b *breaker.Breaker) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.breaker = b
+	})
+}
+
+// New constructs a new *http.Client which retries idempotent requests which fail with a network error
+// or a 502, 503 or 504 response.
+func New(opts ...Option) *http.Client {
+	cfg := config{
+		name:    "default",
+		timeout: 30 * time.Second,
+		retries: 3,
+		backoff: tick.ExponentialBackoff(100*time.Millisecond, tick.WithJitter(0.2)),
+	}
+
+	Options(opts).apply(&cfg)
+
+	base := cfg.transport
+	if base == nil {
+		base = http.DefaultTransport.(*http.Transport)
+	}
+
+	transport := base.Clone()
+
+	transport.DialContext = countingDialer(transport.DialContext, metrics.NewGauge("http_client_open_connections", metrics.Labels{
+		"client": cfg.name,
+	}))
+
+	var rt http.RoundTripper = &retryTransport{
+		next:    transport,
+		retries: cfg.retries,
+		backoff: cfg.backoff,
+		retried: metrics.NewCounter("http_client_retries_total", metrics.Labels{"client": cfg.name}),
+		reused:  metrics.NewCounter("http_client_connections_reused_total", metrics.Labels{"client": cfg.name}),
+	}
+
+	if cfg.breaker != nil {
+		rt = &breakerTransport{
+			next:    rt,
+			breaker: cfg.breaker,
+		}
+	}
+
+	client := &http.Client{
+		Timeout:   cfg.timeout,
+		Transport: rt,
+	}
+
+	if s := cfg.sensor; s != nil {
+		registry := cfg.registry
+		if registry == nil {
+			registry = probe.RegistryFromContext(context.Background())
+		}
+
+		registry.Register(Sensor(fmt.Sprintf("http.client[%s]", cfg.name), s.mode, client, s.url))
+	}
+
+	return client
+}
+
+// Sensor returns a health probe sensor which makes a GET request to the given url with the given client,
+// any non 5xx response is healthy.
+func Sensor(name string, mode probe.Mode, client *http.Client, url string) probe.Sensor {
+	return probe.NewSensor(name, mode, func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return fmt.Errorf("construct http request: %w", err)
+		}
+
+		rsp, err := client.Do(req)
+		if err != nil {
+			return fmt.Errorf("make client request: %w", err)
+		}
+
+		if err := rsp.Body.Close(); err != nil {
+			return fmt.Errorf("close response body: %w", err)
+		}
+
+		if code := rsp.StatusCode; code >= http.StatusInternalServerError {
+			return fmt.Errorf("invalid status code %d", code)
+		}
+
+		return nil
+	})
+}
+
+type dialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// countingDialer wraps the dial function tracking the number of open connections with the gauge.
+func countingDialer(dial dialFunc, open metrics.Gauge) dialFunc {
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+
+		open.Add(1)
+
+		return &countedConn{Conn: conn, open: open}, nil
+	}
+}
+
+// countedConn decrements the open connections gauge when closed.
+type countedConn struct {
+	net.Conn
+	open metrics.Gauge
+	once sync.Once
+}
+
+func (c *countedConn) Close() error {
+	c.once.Do(func() {
+		c.open.Add(-1)
+	})
+
+	return c.Conn.Close()
+}
+
+// breakerTransport guards requests with a circuit breaker.
+type breakerTransport struct {
+	next    http.RoundTripper
+	breaker *breaker.Breaker
+}
+
+func (t *breakerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.breaker.Allow(); err != nil {
+		return nil, fmt.Errorf("%s: %w", t.breaker.Name(), err)
+	}
+
+	rsp, err := t.next.RoundTrip(req)
+
+	switch {
+	case err != nil:
+		t.breaker.Done(err)
+	case rsp.StatusCode >= http.StatusInternalServerError:
+		t.breaker.Done(fmt.Errorf("invalid status code %d", rsp.StatusCode))
+	default:
+		t.breaker.Done(nil)
+	}
+
+	return rsp, err
+}
@@ -0,0 +1,122 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"time"
+
+	"go.krak3n.io/foundation/metrics"
+	"go.krak3n.io/foundation/tick"
+)
+
+// retryTransport retries idempotent requests using a backoff between attempts.
+type retryTransport struct {
+	next    http.RoundTripper
+	retries uint8
+	backoff tick.Backoff
+	retried metrics.Counter
+	reused  metrics.Counter
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := httptrace.WithClientTrace(req.Context(), &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			if info.Reused {
+				t.reused.Add(1)
+			}
+		},
+	})
+
+	req = req.WithContext(ctx)
+
+	if !retryable(req) {
+		return t.next.RoundTrip(req)
+	}
+
+	var attempt uint8
+
+	for {
+		rsp, err := t.next.RoundTrip(req)
+
+		if attempt >= t.retries || !shouldRetry(rsp, err) {
+			return rsp, err
+		}
+
+		attempt++
+
+		// Discard the response so the connection can be reused.
+		if rsp != nil {
+			_, _ = io.Copy(io.Discard, rsp.Body)
+			rsp.Body.Close()
+		}
+
+		if err := wait(ctx, attempt, t.backoff); err != nil {
+			return nil, err
+		}
+
+		if req, err = rewind(req); err != nil {
+			return nil, err
+		}
+
+		t.retried.Add(1)
+	}
+}
+
+// retryable returns true if the request is idempotent and its body, if any, can be replayed.
+func retryable(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+	default:
+		return false
+	}
+
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// shouldRetry returns true if the request failed with a network error or a response indicating the
+// upstream is temporarily unavailable.
+func shouldRetry(rsp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+
+	switch rsp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+
+	return false
+}
+
+// rewind returns a copy of the request with a fresh body.
+func rewind(req *http.Request) (*http.Request, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return req, nil
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, fmt.Errorf("get request body: %w", err)
+	}
+
+	req = req.Clone(req.Context())
+	req.Body = body
+
+	return req, nil
+}
+
+// wait waits for the backoff duration of the attempt or until the context is done.
+func wait(ctx context.Context, attempt uint8, backoff tick.Backoff) error {
+	timer := time.NewTimer(backoff.Wait(ctx, attempt))
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,94 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"go.krak3n.io/foundation/metrics"
+)
+
+// drainPollInterval is how often in-flight requests are checked whilst draining.
+const drainPollInterval = 10 * time.Millisecond
+
+// WithDrain enables connection draining on stop. Keep-alives are disabled and the runner waits for
+// in-flight requests to complete, up to the given timeout, before shutting the server down. The timeout
+// also bounds the server shutdown. If the timeout is exceeded the context of in-flight requests is
+// cancelled.
+func WithDrain(timeout time.Duration) RunnerOption {
+	return runnerConfigFunc(func(cfg *runnerConfig) {
+		cfg.drain = timeout
+	})
+}
+
+// drainingKey is the request context key the servers draining channel is stored under.
+type drainingKey struct{}
+
+// IsDraining returns true if the server handling the request the given context belongs to has started
+// to stop. Handlers can use this to finish quickly or refuse to start long operations.
+func IsDraining(ctx context.Context) bool {
+	draining := drainingC(ctx)
+	if draining == nil {
+		return false
+	}
+
+	select {
+	case <-draining:
+		return true
+	default:
+		return false
+	}
+}
+
+// drainingC returns the draining channel of the server handling the request the given context belongs
+// to, nil if there is none.
+func drainingC(ctx context.Context) <-chan struct{} {
+	draining, ok := ctx.Value(drainingKey{}).(chan struct{})
+	if !ok {
+		return nil
+	}
+
+	return draining
+}
+
+// inflight tracks the number of in-flight requests to a server.
+type inflight struct {
+	n     atomic.Int64
+	gauge metrics.Gauge
+}
+
+func newInflight(addr string) *inflight {
+	return &inflight{
+		gauge: metrics.NewGauge("http_server_inflight_requests", metrics.Labels{"addr": addr}),
+	}
+}
+
+// Handler wraps the given handler tracking requests whilst they are in-flight.
+func (i *inflight) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		i.gauge.Set(float64(i.n.Add(1)))
+
+		defer func() {
+			i.gauge.Set(float64(i.n.Add(-1)))
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Wait blocks until there are no in-flight requests or the context is done.
+func (i *inflight) Wait(ctx context.Context) error {
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+
+	for i.n.Load() > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+
+	return nil
+}
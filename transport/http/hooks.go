@@ -0,0 +1,77 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// A ServerEventKind is the kind of a ServerEvent.
+type ServerEventKind uint8
+
+const (
+	// ListenStarted is emitted once the server is listening, just before it starts serving.
+	ListenStarted ServerEventKind = iota + 1
+	// ShutdownStarted is emitted when the server begins shutting down.
+	ShutdownStarted
+	// ShutdownFinished is emitted once the server has shut down, with the error shutting down if any.
+	ShutdownFinished
+)
+
+func (k ServerEventKind) String() string {
+	switch k {
+	case ListenStarted:
+		return "listen_started"
+	case ShutdownStarted:
+		return "shutdown_started"
+	case ShutdownFinished:
+		return "shutdown_finished"
+	default:
+		return "unknown"
+	}
+}
+
+// A ServerEvent is a lifecycle event of the HTTP server, see WithServerEvents.
+type ServerEvent struct {
+	Kind ServerEventKind
+	// Addr is the address the server is bound to.
+	Addr string
+	Time time.Time
+	// Err is the error shutting down the server, only set for ShutdownFinished.
+	Err error
+}
+
+// A ServerEventFunc receives the lifecycle events of the HTTP server.
+type ServerEventFunc func(ctx context.Context, e ServerEvent)
+
+// WithServerHook calls fn with the *http.Server once the runner has configured it, with its handler
+// wrapped and TLS set up, just before it starts serving. Use it to set fields such as ConnState or
+// ConnContext for custom instrumentation, the handler should not be replaced.
+func WithServerHook(fn func(*http.Server)) RunnerOption {
+	return runnerConfigFunc(func(cfg *runnerConfig) {
+		cfg.hooks = append(cfg.hooks, fn)
+	})
+}
+
+// WithServerEvents calls fn with the lifecycle events of the server, see ServerEventKind. Events are
+// delivered synchronously so fn should not block.
+func WithServerEvents(fn ServerEventFunc) RunnerOption {
+	return runnerConfigFunc(func(cfg *runnerConfig) {
+		cfg.events = append(cfg.events, fn)
+	})
+}
+
+// emit calls the configured event functions with an event of the kind.
+func emit(ctx context.Context, cfg runnerConfig, kind ServerEventKind, addr string, err error) {
+	e := ServerEvent{
+		Kind: kind,
+		Addr: addr,
+		Time: time.Now(),
+		Err:  err,
+	}
+
+	for fn := range slices.Values(cfg.events) {
+		fn(ctx, e)
+	}
+}
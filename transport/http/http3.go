@@ -0,0 +1,41 @@
+package http
+
+import (
+	"context"
+	"net/http"
+)
+
+// A HTTP3Server serves HTTP/3 over QUIC, for example *http3.Server from github.com/quic-go/quic-go/http3.
+type HTTP3Server interface {
+	// ListenAndServe listens on the servers UDP address and serves HTTP/3 until the server is shutdown.
+	ListenAndServe() error
+	// Shutdown gracefully shuts the server down.
+	Shutdown(ctx context.Context) error
+	// SetQUICHeaders sets the Alt-Svc header advertising the HTTP/3 server.
+	SetQUICHeaders(http.Header) error
+}
+
+// WithHTTP3 serves the runners handler over HTTP/3 alongside TCP. The given function constructs the
+// HTTP/3 server for the handler, which includes the runners middleware. Responses served over TCP
+// advertise the HTTP/3 server with an Alt-Svc header and both servers are shut down together on stop.
+//
+//	http.Run(handler, http.WithHTTP3(func(h stdhttp.Handler) http.HTTP3Server {
+//		return &http3.Server{Addr: ":443", Handler: h, TLSConfig: tlsConfig}
+//	}))
+func WithHTTP3(fn func(http.Handler) HTTP3Server) RunnerOption {
+	return runnerConfigFunc(func(cfg *runnerConfig) {
+		cfg.http3 = fn
+	})
+}
+
+// altSvc wraps the handler setting the Alt-Svc header advertising the HTTP/3 server.
+func altSvc(h3 HTTP3Server, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Only advertise over TCP, requests served over HTTP/3 already use it.
+		if r.ProtoMajor < 3 {
+			_ = h3.SetQUICHeaders(w.Header())
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,383 @@
+// Package httpcache caches responses of GET endpoints in memory, or a pluggable Store, for read heavy
+// internal APIs. Entries expire after a TTL and are invalidated by path, directly or by publishing on
+// the bus so any handler can invalidate what it changed.
+//
+//	responses := httpcache.New(httpcache.WithTTL(30 * time.Second))
+//
+//	foundation.Run("orders", bus.Run(), responses, http.Run(mux, http.WithMiddleware(responses.Middleware)))
+//
+//	// Once an order has been updated:
+//	httpcache.Invalidate(ctx, f, "/orders/"+id, "/orders")
+package httpcache
+
+import (
+	"bytes"
+	"context"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.krak3n.io/foundation"
+	"go.krak3n.io/foundation/bus"
+	"go.krak3n.io/foundation/cache"
+	"go.krak3n.io/foundation/metrics"
+)
+
+// InvalidationTopic is the bus topic invalidations are published on, see Invalidate.
+var InvalidationTopic = bus.NewTopic[Invalidation]("httpcache.invalidation")
+
+// An Invalidation invalidates the cached responses of the paths.
+type Invalidation struct {
+	Paths []string
+}
+
+// A Response is a cached response.
+type Response struct {
+	Status int
+	Header http.Header
+	Body   []byte
+	// Query is the raw query of the request the response was for.
+	Query string
+	// Vary holds the values of the request headers the response varies by, keyed by canonical name.
+	Vary map[string]string
+	// Stored is when the response was cached.
+	Stored time.Time
+	// Expires is when the response expires.
+	Expires time.Time
+}
+
+// A Store stores the cached responses of each path, one per query and varied request headers. The Cache
+// serialises its access so a Store need not.
+type Store interface {
+	// Get returns the responses of the path.
+	Get(path string) []*Response
+	// Set replaces the responses of the path.
+	Set(path string, responses []*Response)
+	// Delete deletes the responses of the path.
+	Delete(path string)
+}
+
+// An Option configures the Cache.
+type Option interface {
+	apply(*config)
+}
+
+// Options is one or more Option.
+type Options []Option
+
+func (opts Options) apply(cfg *config) {
+	for opt := range slices.Values(opts) {
+		if opt != nil {
+			opt.apply(cfg)
+		}
+	}
+}
+
+type optionFunc func(*config)
+
+func (f optionFunc) apply(cfg *config) {
+	f(cfg)
+}
+
+// config holds the Cache configuration.
+type config struct {
+	ttl     time.Duration
+	store   Store
+	maxBody int
+	name    string
+}
+
+// WithTTL sets how long responses are cached, defaults to 1 minute.
+func WithTTL(d time.Duration) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.ttl = d
+	})
+}
+
+// WithStore sets the store responses are cached in, defaults to an in-memory cache.Cache.
+func WithStore(s Store) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.store = s
+	})
+}
+
+// WithMaxBodySize sets the size of the largest response body cached, defaults to 1 MiB.
+func WithMaxBodySize(n int) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.maxBody = n
+	})
+}
+
+// WithName sets the name the caches metrics are labelled with, defaults to "http".
+func WithName(name string) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.name = name
+	})
+}
+
+// A Cache is a response caching middleware and a foundation.Runner which invalidates cached responses
+// published on the bus, see Invalidate.
+//
+// Successful GET responses are cached unless they set cookies, vary by every header or have a
+// Cache-Control of no-store or private. Requests with a Cache-Control of no-cache bypass the cache,
+// refreshing the response. Responses are served with an X-Cache header of HIT or MISS.
+type Cache struct {
+	cfg config
+
+	mtx sync.Mutex
+
+	hits   metrics.Counter
+	misses metrics.Counter
+}
+
+// New returns a Cache.
+func New(opts ...Option) *Cache {
+	cfg := config{
+		ttl:     time.Minute,
+		maxBody: 1 << 20,
+		name:    "http",
+	}
+
+	Options(opts).apply(&cfg)
+
+	if cfg.store == nil {
+		cfg.store = memoryStore{cache.New[string, []*Response](0)}
+	}
+
+	labels := func(result string) metrics.Labels {
+		return metrics.Labels{"cache": cfg.name, "result": result}
+	}
+
+	return &Cache{
+		cfg:    cfg,
+		hits:   metrics.NewCounter("http_cache_requests_total", labels("hit")),
+		misses: metrics.NewCounter("http_cache_requests_total", labels("miss")),
+	}
+}
+
+// Run subscribes to invalidations published on the bus, which must be run first, until stopped.
+func (c *Cache) Run(ctx context.Context, f foundation.F) {
+	if err := bus.Subscribe(ctx, f, InvalidationTopic, func(_ context.Context, inv Invalidation) {
+		c.Invalidate(inv.Paths...)
+	}); err != nil {
+		f.Error(err)
+	}
+}
+
+// Invalidate publishes an invalidation of the paths on the bus of the F's value store.
+func Invalidate(ctx context.Context, f foundation.F, paths ...string) error {
+	return bus.Publish(ctx, f, InvalidationTopic, Invalidation{Paths: paths})
+}
+
+// Invalidate deletes the cached responses of the paths, whatever their query.
+func (c *Cache) Invalidate(paths ...string) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	for path := range slices.Values(paths) {
+		c.cfg.store.Delete(path)
+	}
+}
+
+// Middleware serves cached responses to GET requests, caching the responses of the handler.
+func (c *Cache) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			next.ServeHTTP(w, r)
+
+			return
+		}
+
+		now := time.Now()
+
+		if !directive(r.Header.Get("Cache-Control"), "no-cache") {
+			if rsp := c.lookup(r, now); rsp != nil {
+				c.hits.Add(1)
+				serve(w, rsp, now)
+
+				return
+			}
+		}
+
+		c.misses.Add(1)
+
+		rec := &recorder{ResponseWriter: w, max: c.cfg.maxBody, status: http.StatusOK}
+		rec.Header().Set("X-Cache", "MISS")
+
+		next.ServeHTTP(rec, r)
+
+		if rsp := c.response(r, rec, now); rsp != nil {
+			c.store(r.URL.Path, rsp, now)
+		}
+	})
+}
+
+// lookup returns the cached response matching the request, nil if there is none.
+func (c *Cache) lookup(r *http.Request, now time.Time) *Response {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	for rsp := range slices.Values(c.cfg.store.Get(r.URL.Path)) {
+		if rsp.Query == r.URL.RawQuery && now.Before(rsp.Expires) && varies(rsp, r) {
+			return rsp
+		}
+	}
+
+	return nil
+}
+
+// store caches the response replacing any it varies the same as and dropping those which have expired.
+func (c *Cache) store(path string, rsp *Response, now time.Time) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	responses := slices.DeleteFunc(slices.Clone(c.cfg.store.Get(path)), func(v *Response) bool {
+		return !now.Before(v.Expires) || (v.Query == rsp.Query && maps.Equal(v.Vary, rsp.Vary))
+	})
+
+	c.cfg.store.Set(path, append(responses, rsp))
+}
+
+// response returns the recorded response to cache, nil if it can not be cached.
+func (c *Cache) response(r *http.Request, rec *recorder, now time.Time) *Response {
+	header := rec.Header()
+
+	cc := header.Get("Cache-Control")
+
+	if rec.status != http.StatusOK || rec.overflow || header.Get("Set-Cookie") != "" ||
+		directive(cc, "no-store") || directive(cc, "private") {
+		return nil
+	}
+
+	vary := make(map[string]string)
+
+	for name := range slices.Values(header.Values("Vary")) {
+		for field := range strings.SplitSeq(name, ",") {
+			field = http.CanonicalHeaderKey(strings.TrimSpace(field))
+			if field == "*" {
+				return nil
+			}
+
+			if field != "" {
+				vary[field] = r.Header.Get(field)
+			}
+		}
+	}
+
+	header = header.Clone()
+	header.Del("X-Cache")
+
+	return &Response{
+		Status:  rec.status,
+		Header:  header,
+		Body:    rec.body.Bytes(),
+		Query:   r.URL.RawQuery,
+		Vary:    vary,
+		Stored:  now,
+		Expires: now.Add(c.cfg.ttl),
+	}
+}
+
+// varies reports whether the request has the header values the response varies by.
+func varies(rsp *Response, r *http.Request) bool {
+	for name, value := range rsp.Vary {
+		if r.Header.Get(name) != value {
+			return false
+		}
+	}
+
+	return true
+}
+
+// serve writes the cached response.
+func serve(w http.ResponseWriter, rsp *Response, now time.Time) {
+	header := w.Header()
+
+	maps.Copy(header, rsp.Header)
+
+	header.Set("X-Cache", "HIT")
+	header.Set("Age", strconv.Itoa(int(now.Sub(rsp.Stored).Seconds())))
+
+	w.WriteHeader(rsp.Status)
+	_, _ = w.Write(rsp.Body)
+}
+
+// directive reports whether the Cache-Control header value has the directive.
+func directive(cc, name string) bool {
+	for d := range strings.SplitSeq(cc, ",") {
+		if strings.EqualFold(strings.TrimSpace(d), name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// recorder records the status and body of a response as it is written, up to the maximum body size.
+type recorder struct {
+	http.ResponseWriter
+	max      int
+	status   int
+	wrote    bool
+	body     bytes.Buffer
+	overflow bool
+}
+
+func (r *recorder) WriteHeader(code int) {
+	if !r.wrote {
+		r.status = code
+		r.wrote = true
+	}
+
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	r.wrote = true
+
+	if !r.overflow {
+		if r.body.Len()+len(b) > r.max {
+			r.overflow = true
+			r.body = bytes.Buffer{}
+		} else {
+			r.body.Write(b)
+		}
+	}
+
+	return r.ResponseWriter.Write(b)
+}
+
+// Flush flushes the underlying ResponseWriter, a flushed response is streamed so not cached.
+func (r *recorder) Flush() {
+	r.overflow = true
+
+	_ = http.NewResponseController(r.ResponseWriter).Flush()
+}
+
+// Unwrap returns the underlying ResponseWriter for http.ResponseController.
+func (r *recorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// memoryStore is a Store backed by a cache.Cache.
+type memoryStore struct {
+	c *cache.Cache[string, []*Response]
+}
+
+func (s memoryStore) Get(path string) []*Response {
+	responses, _ := s.c.Get(path)
+
+	return responses
+}
+
+func (s memoryStore) Set(path string, responses []*Response) {
+	s.c.Set(path, responses)
+}
+
+func (s memoryStore) Delete(path string) {
+	s.c.Delete(path)
+}
@@ -0,0 +1,39 @@
+// Package httptestx runs the HTTP transport Runner in tests, as httptest.Server does for a handler but
+// with the Runner's middleware, sensor and shutdown behaviour.
+package httptestx
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"go.krak3n.io/foundation/foundationtest"
+	transporthttp "go.krak3n.io/foundation/transport/http"
+)
+
+// Start serves the handler with the HTTP Runner on a random loopback port within a foundation started
+// with foundationtest.Start, returning the servers base URL, for example http://127.0.0.1:53144, and a
+// client for it. The server is shut down when the test finishes.
+//
+//	url, client := httptestx.Start(t, handler)
+//
+//	rsp, err := client.Get(url + "/orders")
+func Start(t testing.TB, handler http.Handler, opts ...transporthttp.RunnerOption) (string, *http.Client) {
+	t.Helper()
+
+	r := transporthttp.Run(handler, append([]transporthttp.RunnerOption{
+		transporthttp.WtihServerAddress("127.0.0.1:0"),
+	}, opts...)...)
+
+	foundationtest.Start(t, r)
+
+	client := &http.Client{
+		Timeout:   10 * time.Second,
+		Transport: http.DefaultTransport.(*http.Transport).Clone(),
+	}
+
+	// Cleanups run last in first out so idle connections are closed before the server shuts down.
+	t.Cleanup(client.CloseIdleConnections)
+
+	return "http://" + r.BoundAddr(), client
+}
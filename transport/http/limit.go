@@ -0,0 +1,112 @@
+package http
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+
+	"go.krak3n.io/foundation/metrics"
+	"go.krak3n.io/foundation/rate"
+)
+
+// A KeyFunc returns the key a request is rate limited by.
+type KeyFunc func(*http.Request) string
+
+// ClientIP is a KeyFunc which rate limits requests by the IP address of the client.
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+// HeaderKey returns a KeyFunc which rate limits requests by the value of the given header.
+func HeaderKey(name string) KeyFunc {
+	return func(r *http.Request) string {
+		return r.Header.Get(name)
+	}
+}
+
+// WithRateLimit applies token bucket rate limiting to requests, each key given by the KeyFunc may make
+// limit requests per second with bursts of up to burst requests. Requests over the limit receive a
+// 429 Too Many Requests response with a Retry-After header. If key is nil ClientIP is used.
+func WithRateLimit(limit float64, burst int, key KeyFunc) RunnerOption {
+	if key == nil {
+		key = ClientIP
+	}
+
+	return runnerConfigFunc(func(cfg *runnerConfig) {
+		cfg.middleware = append(cfg.middleware, func(addr string) Middleware {
+			return rateLimit(rate.NewKeyed(fmt.Sprintf("http.server[%s]", addr), limit, burst), key, shedCounter(addr, "rate_limit"))
+		})
+	})
+}
+
+// WithConcurrencyLimit limits the number of requests being handled concurrently, zero or less does not
+// limit them. Requests over the limit receive a 503 Service Unavailable response with a Retry-After
+// header.
+func WithConcurrencyLimit(max int) RunnerOption {
+	return runnerConfigFunc(func(cfg *runnerConfig) {
+		cfg.middleware = append(cfg.middleware, func(addr string) Middleware {
+			return concurrencyLimit(max, shedCounter(addr, "concurrency_limit"))
+		})
+	})
+}
+
+// shedCounter returns the counter incremented each time a request is shed for the given reason.
+func shedCounter(addr, reason string) metrics.Counter {
+	return metrics.NewCounter("http_server_shed_requests_total", metrics.Labels{
+		"addr":   addr,
+		"reason": reason,
+	})
+}
+
+func rateLimit(limiter *rate.Keyed, key KeyFunc, shed metrics.Counter) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if wait := limiter.Take(key(r)); wait != 0 {
+				shed.Add(1)
+
+				// A limit of zero or less never refills, the client is asked to back off regardless.
+				w.Header().Set("Retry-After", strconv.Itoa(max(int(math.Ceil(wait.Seconds())), 1)))
+				w.WriteHeader(http.StatusTooManyRequests)
+
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func concurrencyLimit(max int, shed metrics.Counter) Middleware {
+	if max <= 0 {
+		return func(next http.Handler) http.Handler {
+			return next
+		}
+	}
+
+	sem := make(chan struct{}, max)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			default:
+				shed.Add(1)
+
+				w.Header().Set("Retry-After", "1")
+				w.WriteHeader(http.StatusServiceUnavailable)
+
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
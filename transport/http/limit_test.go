@@ -0,0 +1,130 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"go.krak3n.io/foundation/rate"
+)
+
+// counter is a metrics.Counter recording its value, it is not safe for concurrent use.
+type counter float64
+
+func (c *counter) Add(delta float64) {
+	*c += counter(delta)
+}
+
+func TestRateLimit(t *testing.T) {
+	tests := []struct {
+		name       string
+		limit      float64
+		retryAfter string
+	}{
+		{
+			name:       "refilling",
+			limit:      0.5,
+			retryAfter: "2",
+		},
+		{
+			name:       "never refilling",
+			limit:      0,
+			retryAfter: "1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var shed counter
+
+			handler := rateLimit(rate.NewKeyed(t.Name(), tt.limit, 2), HeaderKey("X-Client"), &shed)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusNoContent)
+			}))
+
+			serve := func(client string) *httptest.ResponseRecorder {
+				r := httptest.NewRequest(http.MethodGet, "/", nil)
+				r.Header.Set("X-Client", client)
+
+				w := httptest.NewRecorder()
+				handler.ServeHTTP(w, r)
+
+				return w
+			}
+
+			for range 2 {
+				if w := serve("a"); w.Code != http.StatusNoContent {
+					t.Fatalf("want status %d within the burst, got %d", http.StatusNoContent, w.Code)
+				}
+			}
+
+			w := serve("a")
+			if w.Code != http.StatusTooManyRequests {
+				t.Fatalf("want status %d over the burst, got %d", http.StatusTooManyRequests, w.Code)
+			}
+
+			if got := w.Header().Get("Retry-After"); got != tt.retryAfter {
+				t.Errorf("want Retry-After %s, got %s", tt.retryAfter, got)
+			}
+
+			if w := serve("b"); w.Code != http.StatusNoContent {
+				t.Errorf("want other keys unaffected, got status %d", w.Code)
+			}
+
+			if shed != 1 {
+				t.Errorf("want 1 shed request, got %v", shed)
+			}
+		})
+	}
+}
+
+func TestConcurrencyLimit(t *testing.T) {
+	var shed counter
+
+	entered := make(chan struct{})
+	release := make(chan struct{})
+
+	handler := concurrencyLimit(1, &shed)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		entered <- struct{}{}
+		<-release
+
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}()
+
+	<-entered
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if w.Code != http.StatusServiceUnavailable || shed != 1 {
+		t.Errorf("want status %d over the limit, got %d", http.StatusServiceUnavailable, w.Code)
+	}
+
+	if got := w.Header().Get("Retry-After"); got != "1" {
+		t.Errorf("want Retry-After 1, got %s", got)
+	}
+
+	close(release)
+	wg.Wait()
+
+	go func() {
+		<-entered
+	}()
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if w.Code != http.StatusNoContent {
+		t.Errorf("want status %d once under the limit, got %d", http.StatusNoContent, w.Code)
+	}
+}
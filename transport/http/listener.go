@@ -0,0 +1,88 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+
+	"go.krak3n.io/foundation/health/probe"
+	"go.krak3n.io/foundation/metrics"
+)
+
+// A listener is a net.Listener tracking whether it is accepting connections, so the server can be
+// probed in process rather than dialing itself.
+type listener struct {
+	net.Listener
+
+	mtx       sync.Mutex
+	accepting bool
+	closed    bool
+	// The error of the last accept if no connection has been accepted since.
+	err error
+
+	accepted metrics.Counter
+	failed   metrics.Counter
+}
+
+// newListener returns a listener tracking the state of the net.Listener.
+func newListener(ln net.Listener, addr string) *listener {
+	return &listener{
+		Listener: ln,
+		accepted: metrics.NewCounter("http_server_accepts_total", metrics.Labels{"addr": addr, "status": "ok"}),
+		failed:   metrics.NewCounter("http_server_accepts_total", metrics.Labels{"addr": addr, "status": "error"}),
+	}
+}
+
+func (l *listener) Accept() (net.Conn, error) {
+	l.mtx.Lock()
+	l.accepting = true
+	l.mtx.Unlock()
+
+	conn, err := l.Listener.Accept()
+
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	switch {
+	case err == nil:
+		l.err = nil
+		l.accepted.Add(1)
+	case l.closed || errors.Is(err, net.ErrClosed):
+	default:
+		l.err = err
+		l.failed.Add(1)
+	}
+
+	return conn, err
+}
+
+func (l *listener) Close() error {
+	l.mtx.Lock()
+	l.closed = true
+	l.mtx.Unlock()
+
+	return l.Listener.Close()
+}
+
+// Sensor returns a sensor which fails unless the server is accepting connections, either because it has
+// not started serving, the listener has been closed or accepting is failing, for example as the
+// process has run out of file descriptors.
+func (l *listener) Sensor(name string) probe.Sensor {
+	return probe.NewSensor(name, probe.AllModes, func(context.Context) error {
+		l.mtx.Lock()
+		defer l.mtx.Unlock()
+
+		switch {
+		case l.closed:
+			return errors.New("listener closed")
+		case !l.accepting:
+			return errors.New("not yet accepting connections")
+		case l.err != nil:
+			return fmt.Errorf("accept: %w", l.err)
+		}
+
+		return nil
+	})
+}
@@ -2,8 +2,11 @@ package http
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strings"
 
 	"go.krak3n.io/foundation/health/probe"
 )
@@ -11,10 +14,21 @@ import (
 // Sensor returns a health probe sensor for HTTP servers.
 // The sensor makes a HTTP GET request to the given url, the response must be a 200 OK for the sensor
 // to return a healthy status.
+// The sensor is named after the host of the given url so multiple servers each have a unique sensor.
+// For https urls the servers certificate is not verified, the sensor checks the server is serving, not
+// its identity, and the certificate is rarely issued for the address it is reached on.
 func Sensor(url string) probe.Sensor {
 	client := http.DefaultClient
 
-	return probe.NewSensor("http.server", probe.AllModes, func(ctx context.Context) error {
+	if strings.HasPrefix(url, "https://") {
+		client = &http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+			},
+		}
+	}
+
+	return probe.NewSensor(sensorName(url), probe.AllModes, func(ctx context.Context) error {
 		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 		if err != nil {
 			return fmt.Errorf("construct http request: %w", err)
@@ -36,3 +50,13 @@ func Sensor(url string) probe.Sensor {
 		return nil
 	})
 }
+
+// sensorName returns a sensor name unique to the host of the given url.
+func sensorName(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return "http.server"
+	}
+
+	return fmt.Sprintf("http.server[%s]", u.Host)
+}
@@ -0,0 +1,144 @@
+package http
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"go.krak3n.io/foundation/metrics"
+)
+
+// WithMaxBodySize limits request bodies to n bytes, replacing any previous limit, zero or less removes
+// it. Requests declaring a larger Content-Length receive a 413 Request Entity Too Large response without
+// calling the handler, otherwise reading beyond the limit fails with a *http.MaxBytesError and an error
+// response written by the handler becomes a 413. The limit is enforced before any other middleware.
+func WithMaxBodySize(n int64) RunnerOption {
+	return runnerConfigFunc(func(cfg *runnerConfig) {
+		cfg.maxBodySize = n
+	})
+}
+
+// WithHeaderLimits limits requests to count header values each of at most size bytes, name included,
+// replacing any previous limits, a limit of zero or less is not enforced. Requests over either limit
+// receive a 431 Request Header Fields Too Large response. This complements the servers MaxHeaderBytes,
+// which only bounds the total size, so a request can not reach a handler with thousands of small
+// headers or a single huge one. The limits are enforced before any other middleware.
+func WithHeaderLimits(count, size int) RunnerOption {
+	return runnerConfigFunc(func(cfg *runnerConfig) {
+		cfg.headerCount = count
+		cfg.headerSize = size
+	})
+}
+
+// protect wraps the handler with the configured body and header limits.
+func protect(handler http.Handler, cfg runnerConfig, addr string) http.Handler {
+	if cfg.maxBodySize > 0 {
+		handler = maxBodySize(cfg.maxBodySize, shedCounter(addr, "body_size"))(handler)
+	}
+
+	if cfg.headerCount > 0 || cfg.headerSize > 0 {
+		handler = headerLimits(cfg.headerCount, cfg.headerSize, shedCounter(addr, "header_limit"))(handler)
+	}
+
+	return handler
+}
+
+func maxBodySize(n int64, shed metrics.Counter) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > n {
+				shed.Add(1)
+
+				w.Header().Set("Connection", "close")
+				http.Error(w, "request body too large, limit "+strconv.FormatInt(n, 10)+" bytes", http.StatusRequestEntityTooLarge)
+
+				return
+			}
+
+			if r.Body == nil || r.Body == http.NoBody {
+				next.ServeHTTP(w, r)
+
+				return
+			}
+
+			lw := &bodyLimitWriter{ResponseWriter: w, shed: shed}
+			r.Body = &bodyLimitReader{ReadCloser: http.MaxBytesReader(w, r.Body, n), w: lw}
+
+			next.ServeHTTP(lw, r)
+		})
+	}
+}
+
+func headerLimits(count, size int, shed metrics.Counter) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var n int
+
+			for name, values := range r.Header {
+				n += len(values)
+
+				for _, v := range values {
+					if size > 0 && len(name)+len(v) > size {
+						shed.Add(1)
+						http.Error(w, "request header "+name+" too large", http.StatusRequestHeaderFieldsTooLarge)
+
+						return
+					}
+				}
+			}
+
+			if count > 0 && n > count {
+				shed.Add(1)
+				http.Error(w, "too many request headers", http.StatusRequestHeaderFieldsTooLarge)
+
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// bodyLimitReader records on its writer when the body limit has been exceeded.
+type bodyLimitReader struct {
+	io.ReadCloser
+	w *bodyLimitWriter
+}
+
+func (r *bodyLimitReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) && !r.w.exceeded {
+		r.w.exceeded = true
+		r.w.shed.Add(1)
+	}
+
+	return n, err
+}
+
+// bodyLimitWriter replaces error statuses written once the body limit has been exceeded with a 413.
+type bodyLimitWriter struct {
+	http.ResponseWriter
+	shed     metrics.Counter
+	exceeded bool
+}
+
+func (w *bodyLimitWriter) WriteHeader(code int) {
+	if w.exceeded && code >= http.StatusBadRequest {
+		code = http.StatusRequestEntityTooLarge
+	}
+
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Flush flushes the underlying ResponseWriter if it supports flushing.
+func (w *bodyLimitWriter) Flush() {
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap returns the underlying ResponseWriter for http.ResponseController.
+func (w *bodyLimitWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
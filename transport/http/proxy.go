@@ -0,0 +1,300 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"go.krak3n.io/foundation"
+	"go.krak3n.io/foundation/health/probe"
+	"go.krak3n.io/foundation/tick"
+)
+
+// A ProxyOption configures a ReverseProxy.
+type ProxyOption interface {
+	applyProxyConfig(*proxyConfig)
+}
+
+// ProxyOptions is one or more ProxyOption.
+type ProxyOptions []ProxyOption
+
+func (o ProxyOptions) applyProxyConfig(cfg *proxyConfig) {
+	for opt := range slices.Values(o) {
+		if opt != nil {
+			opt.applyProxyConfig(cfg)
+		}
+	}
+}
+
+type proxyConfigFunc func(*proxyConfig)
+
+func (f proxyConfigFunc) applyProxyConfig(cfg *proxyConfig) {
+	f(cfg)
+}
+
+// proxyConfig holds the configuration for a ReverseProxy.
+type proxyConfig struct {
+	healthPath     string
+	healthInterval time.Duration
+	healthTimeout  time.Duration
+	sensorMode     probe.Mode
+	transport      http.RoundTripper
+}
+
+// WithHealthCheckPath sets the path requested on each upstream to check its health, defaults to "/".
+// Any response other than a 5xx is considered healthy.
+func WithHealthCheckPath(path string) ProxyOption {
+	return proxyConfigFunc(func(cfg *proxyConfig) {
+		cfg.healthPath = path
+	})
+}
+
+// WithHealthCheckInterval sets how often upstreams are health checked, defaults to 5 seconds.
+func WithHealthCheckInterval(d time.Duration) ProxyOption {
+	return proxyConfigFunc(func(cfg *proxyConfig) {
+		cfg.healthInterval = d
+	})
+}
+
+// WithUpstreamSensorMode sets the mode of the sensor registered for each upstream, defaults to
+// probe.ReadinessMode.
+func WithUpstreamSensorMode(mode probe.Mode) ProxyOption {
+	return proxyConfigFunc(func(cfg *proxyConfig) {
+		cfg.sensorMode = mode
+	})
+}
+
+// WithProxyTransport sets the transport used to make requests to upstreams, defaults to
+// http.DefaultTransport.
+func WithProxyTransport(rt http.RoundTripper) ProxyOption {
+	return proxyConfigFunc(func(cfg *proxyConfig) {
+		cfg.transport = rt
+	})
+}
+
+// A ReverseProxy is a http.Handler which load balances requests across healthy upstreams.
+// It is also a foundation.Runner which health checks the upstreams and registers a sensor per upstream,
+// unhealthy upstreams are removed from rotation until they become healthy again.
+type ReverseProxy struct {
+	cfg       proxyConfig
+	upstreams []*upstream
+	next      atomic.Uint64
+	proxy     *httputil.ReverseProxy
+}
+
+// upstream is a single upstream of a ReverseProxy.
+type upstream struct {
+	url     *url.URL
+	healthy atomic.Bool
+}
+
+// inboundKey is the context key the inbound request is stored under on outbound proxy requests.
+type inboundKey struct{}
+
+// Proxy constructs a new ReverseProxy for the given upstream URLs. Upstreams are considered healthy until
+// the first health check, which happens when the ReverseProxy is run.
+func Proxy(upstreams []string, opts ...ProxyOption) (*ReverseProxy, error) {
+	cfg := proxyConfig{
+		healthPath:     "/",
+		healthInterval: 5 * time.Second,
+		healthTimeout:  2 * time.Second,
+		sensorMode:     probe.ReadinessMode,
+		transport:      http.DefaultTransport,
+	}
+
+	ProxyOptions(opts).applyProxyConfig(&cfg)
+
+	p := &ReverseProxy{
+		cfg: cfg,
+	}
+
+	for _, raw := range upstreams {
+		u, err := url.Parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("parse upstream %q: %w", raw, err)
+		}
+
+		up := &upstream{url: u}
+		up.healthy.Store(true)
+
+		p.upstreams = append(p.upstreams, up)
+	}
+
+	if len(p.upstreams) == 0 {
+		return nil, errors.New("proxy requires at least one upstream")
+	}
+
+	p.proxy = &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.SetXForwarded()
+			pr.Out = pr.Out.WithContext(context.WithValue(pr.Out.Context(), inboundKey{}, pr.In))
+
+			if up := p.pick(nil); up != nil {
+				pr.SetURL(up.url)
+			}
+		},
+		Transport: &retryTransport{proxy: p},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			slog.ErrorContext(r.Context(), "proxy request failed", slog.String("err", err.Error()))
+			w.WriteHeader(http.StatusBadGateway)
+		},
+	}
+
+	return p, nil
+}
+
+// ServeHTTP proxies the request to a healthy upstream.
+func (p *ReverseProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.proxy.ServeHTTP(w, r)
+}
+
+// Run registers a sensor per upstream and health checks the upstreams on every tick.
+func (p *ReverseProxy) Run(ctx context.Context, f foundation.F) {
+	for _, up := range p.upstreams {
+		probe.RegisterContext(ctx, probe.NewSensor(fmt.Sprintf("http.proxy[%s]", up.url.Host), p.cfg.sensorMode, func(context.Context) error {
+			if !up.healthy.Load() {
+				return fmt.Errorf("upstream %s is unhealthy", up.url)
+			}
+
+			return nil
+		}))
+	}
+
+	p.check(ctx)
+
+	tick.Run(ctx, f, p.cfg.healthInterval, func(ctx context.Context, _ tick.Ticker) {
+		p.check(ctx)
+	})
+}
+
+// check health checks every upstream updating its health state.
+func (p *ReverseProxy) check(ctx context.Context) {
+	client := &http.Client{
+		Transport: p.cfg.transport,
+		Timeout:   p.cfg.healthTimeout,
+	}
+
+	for _, up := range p.upstreams {
+		healthy := p.checkUpstream(ctx, client, up) == nil
+
+		if was := up.healthy.Swap(healthy); was != healthy {
+			slog.InfoContext(ctx, "proxy upstream health changed",
+				slog.String("upstream", up.url.String()),
+				slog.Bool("healthy", healthy))
+		}
+	}
+}
+
+func (p *ReverseProxy) checkUpstream(ctx context.Context, client *http.Client, up *upstream) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, up.url.JoinPath(p.cfg.healthPath).String(), nil)
+	if err != nil {
+		return fmt.Errorf("construct http request: %w", err)
+	}
+
+	rsp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("make client request: %w", err)
+	}
+
+	if err := rsp.Body.Close(); err != nil {
+		return fmt.Errorf("close response body: %w", err)
+	}
+
+	if code := rsp.StatusCode; code >= http.StatusInternalServerError {
+		return fmt.Errorf("invalid status code %d", code)
+	}
+
+	return nil
+}
+
+// pick returns the next healthy upstream round robin, skipping the excluded upstreams.
+// Returns nil if there are no healthy upstreams.
+func (p *ReverseProxy) pick(exclude []*upstream) *upstream {
+	n := uint64(len(p.upstreams))
+	start := p.next.Add(1)
+
+	for i := range n {
+		up := p.upstreams[(start+i)%n]
+
+		if up.healthy.Load() && !slices.Contains(exclude, up) {
+			return up
+		}
+	}
+
+	return nil
+}
+
+// lookup returns the upstream for the given URL.
+func (p *ReverseProxy) lookup(u *url.URL) *upstream {
+	for _, up := range p.upstreams {
+		if up.url.Scheme == u.Scheme && up.url.Host == u.Host {
+			return up
+		}
+	}
+
+	return nil
+}
+
+// retryTransport retries requests on another upstream when connecting to an upstream fails.
+// Requests with a body are not retried as the transport consumes the body on failure.
+type retryTransport struct {
+	proxy *ReverseProxy
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Host == "" {
+		return nil, errors.New("no healthy upstreams")
+	}
+
+	var tried []*upstream
+
+	for {
+		rsp, err := t.proxy.cfg.transport.RoundTrip(req)
+		if err == nil || !isDialError(err) {
+			return rsp, err
+		}
+
+		up := t.proxy.lookup(req.URL)
+		if up == nil {
+			return nil, err
+		}
+
+		// Passively remove the upstream from rotation until the next health check.
+		up.healthy.Store(false)
+		tried = append(tried, up)
+
+		in, ok := req.Context().Value(inboundKey{}).(*http.Request)
+		if !ok || req.Body != nil {
+			return nil, err
+		}
+
+		next := t.proxy.pick(tried)
+		if next == nil {
+			return nil, err
+		}
+
+		out := req.Clone(req.Context())
+		out.URL = new(url.URL)
+		*out.URL = *in.URL
+
+		pr := httputil.ProxyRequest{In: in, Out: out}
+		pr.SetURL(next.url)
+
+		req = pr.Out
+	}
+}
+
+// isDialError returns true if the error occurred connecting to the upstream.
+func isDialError(err error) bool {
+	var opErr *net.OpError
+
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
@@ -0,0 +1,138 @@
+package http
+
+import (
+	"bufio"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"runtime/debug"
+
+	"go.krak3n.io/foundation"
+)
+
+// WithoutRecovery disables the default panic recovery middleware, panics will be handled by the
+// net/http server instead.
+func WithoutRecovery() RunnerOption {
+	return runnerConfigFunc(func(cfg *runnerConfig) {
+		cfg.recovery = false
+	})
+}
+
+// WithStopOnPanic stops the foundation when a handler panics, rather than logging the panic and carrying
+// on serving, see Recovery. Any request which panics then stops the service, so only opt in if a panic
+// means the service can not be trusted to carry on.
+func WithStopOnPanic() RunnerOption {
+	return runnerConfigFunc(func(cfg *runnerConfig) {
+		cfg.stopOnPanic = true
+	})
+}
+
+// Recovery returns a Middleware which recovers handler panics responding with a 500 Internal Server Error,
+// unless the handler has already written the response headers or hijacked the connection, and reporting
+// a foundation.RuntimeError, with the request metadata attached, through the given F. The error is
+// classified as transient so it is logged and passed to crash reporters without stopping the foundation,
+// one bad request can not take down the service, see WithStopOnPanic. Panics with http.ErrAbortHandler
+// are re-raised so the server can abort the response.
+func Recovery(f foundation.F) Middleware {
+	return recovery(f, false)
+}
+
+// recovery returns the recovery Middleware, stopping the foundation on a panic if stop is true.
+func recovery(f foundation.F, stop bool) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rw := &recoveryWriter{ResponseWriter: w}
+
+			defer func() {
+				rec := recover()
+				if rec == nil {
+					return
+				}
+
+				if err, ok := rec.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+					panic(rec)
+				}
+
+				stack := debug.Stack()
+
+				// A hijacked connection belongs to the handler, nothing can be written to it.
+				if !rw.wroteHeader && !rw.hijacked {
+					w.WriteHeader(http.StatusInternalServerError)
+				}
+
+				cause, ok := rec.(error)
+				if !ok {
+					cause = foundation.PanicError{Cause: rec}
+				}
+
+				var err error = foundation.RuntimeError{
+					Cause:  cause,
+					Stack:  stack,
+					Runner: f.Name(),
+					Attrs: []slog.Attr{
+						slog.String("http.method", r.Method),
+						slog.String("http.path", r.URL.Path),
+						slog.String("http.remote_addr", r.RemoteAddr),
+						slog.String("http.user_agent", r.UserAgent()),
+					},
+				}
+
+				if !stop {
+					err = foundation.Transient(err)
+				}
+
+				foundation.Report(f, err)
+			}()
+
+			next.ServeHTTP(rw, r)
+		})
+	}
+}
+
+// recoveryWriter records whether the response headers have been written or the connection hijacked.
+type recoveryWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+	hijacked    bool
+}
+
+func (w *recoveryWriter) WriteHeader(code int) {
+	// Informational responses are followed by the final response headers.
+	if code >= http.StatusOK || code == http.StatusSwitchingProtocols {
+		w.wroteHeader = true
+	}
+
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *recoveryWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush flushes the underlying ResponseWriter if it supports flushing.
+func (w *recoveryWriter) Flush() {
+	w.wroteHeader = true
+
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Hijack hijacks the connection of the underlying ResponseWriter if it supports hijacking, for example to
+// upgrade to a WebSocket.
+func (w *recoveryWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	w.hijacked = true
+
+	return conn, rw, nil
+}
+
+// Unwrap returns the underlying ResponseWriter for http.ResponseController.
+func (w *recoveryWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,154 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go.krak3n.io/foundation"
+	"go.krak3n.io/foundation/foundationtest"
+)
+
+// blocking is a parallel runner which blocks until stopped.
+var blocking = foundation.RunFunc(func(_ context.Context, f foundation.F) {
+	done := make(chan struct{})
+
+	f.On().Stop(func() {
+		close(done)
+	})
+
+	f.Parallel()
+
+	<-done
+})
+
+// startReporting starts a foundation returning its F and a channel receiving the errors it reports.
+func startReporting(t *testing.T) (foundation.F, <-chan error) {
+	t.Helper()
+
+	reports := make(chan error, 10)
+
+	i := foundationtest.Start(t, blocking, foundation.WithCrashReporter(func(r foundation.CrashReport) {
+		reports <- r.Err
+	}))
+
+	return i.F(), reports
+}
+
+func TestRecovery(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		status  int
+	}{
+		{
+			name: "panic",
+			handler: func(http.ResponseWriter, *http.Request) {
+				panic("boom")
+			},
+			status: http.StatusInternalServerError,
+		},
+		{
+			name: "panic after writing headers",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusAccepted)
+				panic("boom")
+			},
+			status: http.StatusAccepted,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, reports := startReporting(t)
+
+			srv := httptest.NewServer(Recovery(f)(tt.handler))
+			defer srv.Close()
+
+			rsp, err := http.Get(srv.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rsp.Body.Close()
+
+			if rsp.StatusCode != tt.status {
+				t.Errorf("want status %d, got %d", tt.status, rsp.StatusCode)
+			}
+
+			assertReported(t, reports)
+		})
+	}
+}
+
+func TestRecoveryHijacked(t *testing.T) {
+	f, reports := startReporting(t)
+
+	srv := httptest.NewServer(Recovery(f)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		hj, ok := w.(http.Hijacker)
+		if !ok {
+			t.Error("recovery writer is not a http.Hijacker")
+
+			return
+		}
+
+		conn, _, err := hj.Hijack()
+		if err != nil {
+			t.Error(err)
+
+			return
+		}
+
+		defer conn.Close()
+
+		panic("boom")
+	})))
+	defer srv.Close()
+
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer conn.Close()
+
+	if _, err := io.WriteString(conn, "GET / HTTP/1.1\r\nHost: test\r\n\r\n"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := conn.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Nothing is written to the hijacked connection before the handler closes it.
+	b, err := io.ReadAll(bufio.NewReader(conn))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(b) > 0 {
+		t.Errorf("want nothing written to the hijacked connection, got %q", b)
+	}
+
+	assertReported(t, reports)
+}
+
+// assertReported fails the test unless a transient runtime error caused by a panic is reported.
+func assertReported(t *testing.T, reports <-chan error) {
+	t.Helper()
+
+	select {
+	case err := <-reports:
+		if !foundation.IsTransient(err) || !strings.Contains(err.Error(), "boom") {
+			t.Errorf("want transient panic error, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("panic was not reported")
+	}
+}
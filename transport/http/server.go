@@ -2,33 +2,99 @@ package http
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
+	"fmt"
+	"log/slog"
+	"net"
 	"net/http"
-	"net/url"
 	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
 
 	"go.krak3n.io/foundation"
 	"go.krak3n.io/foundation/health/probe"
+	"go.krak3n.io/foundation/tick"
+	"go.krak3n.io/foundation/transport/internal/boundaddr"
 )
 
+// A RunnerOption configures the HTTP Runner.
 type RunnerOption interface {
-	applyHTTPServer(*http.Server)
+	applyRunnerConfig(*runnerConfig)
 }
 
+// RunnerOptions is one or more RunnerOption.
 type RunnerOptions []RunnerOption
 
-func (o RunnerOptions) applyHTTPServer(srv *http.Server) {
+func (o RunnerOptions) applyRunnerConfig(cfg *runnerConfig) {
 	for opt := range slices.Values(o) {
 		if opt != nil {
-			opt.applyHTTPServer(srv)
+			opt.applyRunnerConfig(cfg)
 		}
 	}
 }
 
+// The RunnerOptionFunc type is an adapter to allow the use of ordinary functions
+// as a RunnerOption. If f is a function with the appropriate signature,
+// RunnerOptionFunc(f) is a RunnerOption that calls f with the *http.Server.
 type RunnerOptionFunc func(*http.Server)
 
-func (f RunnerOptionFunc) applyHTTPServer(srv *http.Server) {
-	f(srv)
+func (f RunnerOptionFunc) applyRunnerConfig(cfg *runnerConfig) {
+	f(cfg.server)
+}
+
+// runnerConfigFunc is a RunnerOption which configures the runner rather than the *http.Server.
+type runnerConfigFunc func(*runnerConfig)
+
+func (f runnerConfigFunc) applyRunnerConfig(cfg *runnerConfig) {
+	f(cfg)
+}
+
+// DefaultSensorPath is the path the sensor endpoint is served on unless configured with WithSensorPath.
+const DefaultSensorPath = "/_sensor"
+
+// runnerConfig holds the configuration for the HTTP Runner.
+type runnerConfig struct {
+	server      *http.Server
+	admin       *http.Server
+	sensorPath  string
+	sensor      bool
+	drain       time.Duration
+	websockets  time.Duration
+	http3       func(http.Handler) HTTP3Server
+	recovery    bool
+	stopOnPanic bool
+	middleware  []func(addr string) Middleware
+	listen      ListenFunc
+	certs       *CertReloader
+	clientAuth  func(*tls.Config)
+	hooks       []func(*http.Server)
+	events      []ServerEventFunc
+
+	bindRetries uint8
+	bindBackoff tick.Backoff
+
+	maxBodySize int64
+	headerCount int
+	headerSize  int
+
+	// Errors in the options, reported when the runner runs.
+	errs []error
+}
+
+// A Middleware wraps a http.Handler.
+type Middleware func(http.Handler) http.Handler
+
+// WithMiddleware wraps the handler with the given middleware, the first middleware given is the outermost.
+// Middleware does not wrap the sensor endpoint.
+func WithMiddleware(mw ...Middleware) RunnerOption {
+	return runnerConfigFunc(func(cfg *runnerConfig) {
+		for _, m := range mw {
+			cfg.middleware = append(cfg.middleware, func(string) Middleware { return m })
+		}
+	})
 }
 
 func WtihServerAddress(addr string) RunnerOption {
@@ -37,39 +103,316 @@ func WtihServerAddress(addr string) RunnerOption {
 	})
 }
 
-func Run(handler http.Handler, opts ...RunnerOption) foundation.Runner {
-	return foundation.RunFunc(func(ctx context.Context, f foundation.F) {
-		mux := http.NewServeMux()
-		mux.Handle("GET /", handler)
-		mux.Handle("GET /_sensor", http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
-			w.WriteHeader(http.StatusOK)
-		}))
+// WithSensorPath sets the path the sensor endpoint is served on, useful if the default path collides with
+// the handlers own routes. The path must begin with a slash and be a valid http.ServeMux path without a
+// method or host, otherwise the runner fails when run.
+func WithSensorPath(path string) RunnerOption {
+	return runnerConfigFunc(func(cfg *runnerConfig) {
+		if err := validSensorPath(path); err != nil {
+			cfg.errs = append(cfg.errs, fmt.Errorf("sensor path %q: %w", path, err))
+
+			return
+		}
+
+		cfg.sensorPath = path
+	})
+}
+
+// validSensorPath returns an error if the path can not be served as the sensor endpoint.
+func validSensorPath(path string) (err error) {
+	if !strings.HasPrefix(path, "/") {
+		return errors.New("must begin with /")
+	}
+
+	if strings.ContainsFunc(path, unicode.IsSpace) {
+		return errors.New("must not contain spaces")
+	}
+
+	// The mux panics on patterns it can not parse, for example an unclosed wildcard.
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("invalid pattern: %v", rec)
+		}
+	}()
+
+	http.NewServeMux().Handle("GET "+path, http.NotFoundHandler())
+
+	return nil
+}
+
+// A ListenFunc listens on the network address, see WithListenFunc.
+type ListenFunc func(network, addr string) (net.Listener, error)
+
+// WithListenFunc sets the function the runner listens with, defaults to net.Listen. For example
+// upgrade.Listen to inherit the listener across an in place restart.
+func WithListenFunc(fn ListenFunc) RunnerOption {
+	return runnerConfigFunc(func(cfg *runnerConfig) {
+		cfg.listen = fn
+	})
+}
+
+// WithoutSensor stops the runner from registering a health probe sensor for the server.
+// The sensor endpoint will still be served.
+func WithoutSensor() RunnerOption {
+	return runnerConfigFunc(func(cfg *runnerConfig) {
+		cfg.sensor = false
+	})
+}
+
+// A Runner is a foundation.Runner which serves a handler with a HTTP server.
+type Runner struct {
+	handler http.Handler
+	opts    []RunnerOption
+	mtx     sync.RWMutex
+	addr    string
+}
+
+// Run returns a Runner which serves the given handler with a HTTP server. The handler receives
+// requests for all methods and paths, so it may be a *http.ServeMux with its own routing, except for the
+// sensor endpoint which is served by an internal mux.
+func Run(handler http.Handler, opts ...RunnerOption) *Runner {
+	return &Runner{
+		handler: handler,
+		opts:    opts,
+	}
+}
+
+// BoundAddr returns the address the server is listening on, which when configured to listen on port 0
+// will be the port assigned by the operating system. Returns an empty string until the server is
+// listening, which is guaranteed once F.Run has returned for the Runner.
+func (r *Runner) BoundAddr() string {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	return r.addr
+}
+
+// Run runs the HTTP server. Once the server is listening the bound address is published to the value
+// store, see BoundAddr, and the runner marked as parallel.
+func (r *Runner) Run(ctx context.Context, f foundation.F) {
+	mux := http.NewServeMux()
 
-		server := &http.Server{
+	cfg := runnerConfig{
+		server: &http.Server{
 			Addr:    "127.0.0.1:3000",
 			Handler: mux,
+		},
+		sensorPath: DefaultSensorPath,
+		sensor:     true,
+		recovery:   true,
+		listen:     net.Listen,
+	}
+
+	RunnerOptions(r.opts).applyRunnerConfig(&cfg)
+
+	if err := errors.Join(cfg.errs...); err != nil {
+		f.Error(err)
+	}
+
+	server := cfg.server
+
+	if certs := cfg.certs; certs != nil {
+		if err := certs.Reload(); err != nil {
+			f.Error(err)
 		}
 
-		RunnerOptions(opts).applyHTTPServer(server)
+		if server.TLSConfig == nil {
+			server.TLSConfig = &tls.Config{}
+		}
+
+		server.TLSConfig.GetCertificate = certs.GetCertificate
 
-		f.On().Stop(func() {
-			if err := server.Shutdown(ctx); err != nil {
-				f.Error(err)
+		f.On().Reload(func() {
+			if err := certs.Reload(); err != nil {
+				slog.ErrorContext(ctx, "tls certificate reload failed, keeping last good certificate", slog.String("err", err.Error()))
 			}
 		})
 
-		url := url.URL{
-			Scheme: "http", // TODO: configurable according to the servers TLS config
-			Host:   server.Addr,
-			Path:   "/_sensor",
+		tick.Run(ctx, f, certPollInterval, func(ctx context.Context, _ tick.Ticker) {
+			certs.check(ctx)
+		})
+	}
+
+	if cfg.clientAuth != nil {
+		if !servesTLS(server) {
+			f.Error(errors.New("client authentication requires tls, see WithCertReloader"))
 		}
 
-		probe.Register(Sensor(url.String()))
+		cfg.clientAuth(server.TLSConfig)
+	}
+
+	addr := server.Addr
+	if addr == "" {
+		addr = ":http"
+	}
+
+	ln, err := bind(ctx, cfg, addr)
+	if err != nil {
+		f.Error(err)
+	}
+
+	bound := ln.Addr().String()
 
-		f.Parallel() // Mark the Runner as parallel now we are going start blocking
+	tracked := newListener(ln, bound)
+	ln = tracked
 
-		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+	r.mtx.Lock()
+	r.addr = bound
+	r.mtx.Unlock()
+
+	boundaddr.Publish(f, "http", r, server.Addr, bound)
+
+	// Middleware only wraps the handler so the sensor endpoint is never constrained by it.
+	handler := r.handler
+
+	for _, mw := range slices.Backward(cfg.middleware) {
+		handler = mw(bound)(handler)
+	}
+
+	handler = protect(handler, cfg, bound)
+
+	if cfg.clientAuth != nil {
+		handler = requireClientCert(handler)
+	}
+
+	if cfg.recovery {
+		handler = recovery(f, cfg.stopOnPanic)(handler)
+	}
+
+	var websockets *websockets
+
+	if cfg.websockets > 0 {
+		websockets = newWebSockets(bound)
+		handler = websockets.Middleware(handler)
+	}
+
+	mux.Handle("/", handler)
+	mux.Handle(fmt.Sprintf("GET %s", cfg.sensorPath), http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	inflight := newInflight(bound)
+	server.Handler = inflight.Handler(server.Handler)
+
+	// Serve the same handler over HTTP/3 if configured, advertising it on TCP responses.
+	var h3 HTTP3Server
+
+	if cfg.http3 != nil {
+		h3 = cfg.http3(server.Handler)
+		server.Handler = altSvc(h3, server.Handler)
+	}
+
+	// Derive request contexts from the runner so handlers can observe draining and cancellation,
+	// unless the server has been given its own base context.
+	draining := make(chan struct{})
+	base, cancelBase := context.WithCancel(context.WithValue(ctx, drainingKey{}, draining))
+	base = context.WithValue(base, streamsKey{}, newStreams(bound))
+
+	if server.BaseContext == nil {
+		server.BaseContext = func(net.Listener) context.Context {
+			return base
+		}
+	}
+
+	// Run the admin server alongside the service server if one has been configured.
+	if admin := cfg.admin; admin != nil {
+		f.Run(ctx, runAdmin(admin))
+	}
+
+	// Shutdown returns immediately with a cancelled context so the runners context is not used, the stop
+	// context is only bounded by the runners share of the shutdown budget, if any.
+	f.On().StopCtx(func(ctx context.Context) {
+		emit(ctx, cfg, ShutdownStarted, bound, nil)
+
+		defer cancelBase()
+
+		close(draining)
+
+		// Close websockets first as they would otherwise hold up draining until the deadline.
+		if websockets != nil {
+			ctx, cancel := context.WithTimeout(ctx, cfg.websockets)
+			websockets.Close(ctx)
+			cancel()
+		}
+
+		if cfg.drain > 0 {
+			var cancel context.CancelFunc
+
+			ctx, cancel = context.WithTimeout(ctx, cfg.drain)
+			defer cancel()
+
+			server.SetKeepAlivesEnabled(false)
+
+			if err := inflight.Wait(ctx); err != nil {
+				slog.WarnContext(ctx, "http server drain deadline exceeded with requests in-flight",
+					slog.String("addr", bound),
+					slog.Int64("inflight", inflight.n.Load()))
+
+				cancelBase()
+			}
+		}
+
+		var errs []error
+
+		if h3 != nil {
+			if err := h3.Shutdown(ctx); err != nil {
+				errs = append(errs, fmt.Errorf("shutdown http3 server: %w", err))
+			}
+		}
+
+		if err := server.Shutdown(ctx); err != nil {
+			server.Close()
+			errs = append(errs, err)
+		}
+
+		err := errors.Join(errs...)
+
+		emit(ctx, cfg, ShutdownFinished, bound, err)
+
+		if err != nil {
 			f.Error(err)
 		}
 	})
+
+	// The sensor checks the listener in process rather than dialing the sensor endpoint, which remains
+	// served for external probes.
+	if cfg.sensor {
+		probe.RegisterContext(ctx, tracked.Sensor(fmt.Sprintf("http.server[%s]", bound)))
+	}
+
+	f.Parallel() // Mark the Runner as parallel now we are going start blocking
+
+	if h3 != nil {
+		go func() {
+			if err := h3.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				foundation.Report(f, fmt.Errorf("http3 server: %w", err))
+			}
+		}()
+	}
+
+	for hook := range slices.Values(cfg.hooks) {
+		hook(server)
+	}
+
+	emit(ctx, cfg, ListenStarted, bound, nil)
+
+	serve := server.Serve
+
+	if servesTLS(server) {
+		serve = func(ln net.Listener) error {
+			return server.ServeTLS(ln, "", "")
+		}
+	}
+
+	if err := serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		f.Error(err)
+	}
+}
+
+// BoundAddr returns the bound address of the server configured with the given address from the F's
+// value store, for example BoundAddr(f, "127.0.0.1:0"). Reports false if several servers are configured
+// with the address, their bound addresses are then only available from their Runner, see
+// Runner.BoundAddr.
+func BoundAddr(f foundation.F, addr string) (string, bool) {
+	return boundaddr.Lookup(f, "http", addr)
 }
@@ -0,0 +1,48 @@
+package http
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"go.krak3n.io/foundation"
+)
+
+func TestWithSensorPath(t *testing.T) {
+	tests := []struct {
+		path  string
+		valid bool
+	}{
+		{path: "/_sensor", valid: true},
+		{path: "/internal/health", valid: true},
+		{path: ""},
+		{path: "_sensor"},
+		{path: "/with space"},
+		{path: "GET /_sensor"},
+		{path: "example.com/_sensor"},
+		{path: "/{unclosed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			var cfg runnerConfig
+
+			WithSensorPath(tt.path).applyRunnerConfig(&cfg)
+
+			if valid := len(cfg.errs) == 0; valid != tt.valid {
+				t.Errorf("want valid %t, got errors %v", tt.valid, cfg.errs)
+			}
+		})
+	}
+}
+
+func TestRunInvalidSensorPath(t *testing.T) {
+	i := foundation.Start(t.Name(), Run(http.NotFoundHandler(), WtihServerAddress("127.0.0.1:0"), WithSensorPath("no slash")),
+		foundation.WithoutSignals())
+
+	i.Stop()
+
+	if err := i.Wait(); err == nil || !strings.Contains(err.Error(), "sensor path") {
+		t.Errorf("want sensor path error, got %v", err)
+	}
+}
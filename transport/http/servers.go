@@ -0,0 +1,80 @@
+package http
+
+import (
+	"cmp"
+	"context"
+	"net/http"
+	"slices"
+
+	"go.krak3n.io/foundation"
+)
+
+// A ServerRole determines the order a server is drained in when run with RunServers.
+type ServerRole uint8
+
+// Supported server roles in drain order, public servers drain first and admin servers last so
+// operational endpoints remain available for as long as possible.
+const (
+	PublicServer ServerRole = iota
+	InternalServer
+	AdminServer
+)
+
+// A ServerSpec describes a server run by RunServers.
+type ServerSpec struct {
+	// Handler serves the servers requests.
+	Handler http.Handler
+	// Options for the server, applied after the options shared by all servers, see SharedOptions.
+	Options []RunnerOption
+	// Role of the server, defaults to PublicServer.
+	Role ServerRole
+
+	// Set if the spec only holds options shared by every server.
+	shared bool
+}
+
+// SharedOptions returns a ServerSpec which does not run a server but gives the options to every server
+// run by RunServers, applied before the options of each server's own spec.
+//
+//	http.RunServers(
+//		http.SharedOptions(http.WithDrain(10*time.Second)),
+//		http.ServerSpec{Handler: api, Options: []http.RunnerOption{http.WtihServerAddress(":8080")}},
+//		http.ServerSpec{Handler: admin, Role: http.AdminServer, Options: []http.RunnerOption{http.WtihServerAddress(":9090")}},
+//	)
+func SharedOptions(opts ...RunnerOption) ServerSpec {
+	return ServerSpec{
+		Options: opts,
+		shared:  true,
+	}
+}
+
+// RunServers returns a foundation.Runner which runs a HTTP server for each of the given specs as sibling
+// parallel runners, sharing the options of any SharedOptions. On stop servers are drained one at a time
+// by role, public servers first and admin servers last, servers of the same role drain in reverse order
+// to how they were given.
+func RunServers(specs ...ServerSpec) foundation.Runner {
+	var shared []RunnerOption
+
+	servers := make([]ServerSpec, 0, len(specs))
+
+	for spec := range slices.Values(specs) {
+		if spec.shared {
+			shared = append(shared, spec.Options...)
+
+			continue
+		}
+
+		servers = append(servers, spec)
+	}
+
+	// Foundation stops runners in reverse order so run the last to drain first.
+	slices.SortStableFunc(servers, func(a, b ServerSpec) int {
+		return cmp.Compare(b.Role, a.Role)
+	})
+
+	return foundation.RunFunc(func(ctx context.Context, f foundation.F) {
+		for spec := range slices.Values(servers) {
+			f.Run(ctx, Run(spec.Handler, slices.Concat(shared, spec.Options)...))
+		}
+	})
+}
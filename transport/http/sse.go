@@ -0,0 +1,168 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.krak3n.io/foundation/metrics"
+)
+
+// ShutdownEvent is the name of the event sent to SSE streams closed because the server is stopping,
+// clients may reconnect on receiving it, ideally to another instance.
+const ShutdownEvent = "shutdown"
+
+// An Event is a server-sent event.
+type Event struct {
+	// ID sets the last event ID the client reconnects with, optional.
+	ID string
+	// Name is the event type, optional, clients receive unnamed events as message events.
+	Name string
+	// Data is the event payload, each line of which is sent as a data field.
+	Data string
+	// Retry sets how long the client waits before reconnecting, optional.
+	Retry time.Duration
+}
+
+// A Stream sends server-sent events to a client, it is safe for concurrent use.
+type Stream struct {
+	mtx sync.Mutex
+	w   http.ResponseWriter
+	rc  *http.ResponseController
+}
+
+// Send writes the event to the client and flushes it.
+func (s *Stream) Send(e Event) error {
+	var b strings.Builder
+
+	if e.ID != "" {
+		fmt.Fprintf(&b, "id: %s\n", e.ID)
+	}
+
+	if e.Name != "" {
+		fmt.Fprintf(&b, "event: %s\n", e.Name)
+	}
+
+	if e.Retry > 0 {
+		fmt.Fprintf(&b, "retry: %d\n", e.Retry.Milliseconds())
+	}
+
+	for line := range strings.Lines(e.Data) {
+		fmt.Fprintf(&b, "data: %s\n", strings.TrimRight(line, "\r\n"))
+	}
+
+	if e.Data == "" {
+		b.WriteString("data:\n")
+	}
+
+	b.WriteString("\n")
+
+	return s.write(b.String())
+}
+
+// Comment writes a comment to the client, ignored by clients but useful as a heartbeat to keep idle
+// streams open through proxies.
+func (s *Stream) Comment(text string) error {
+	return s.write(": " + text + "\n\n")
+}
+
+func (s *Stream) write(msg string) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if _, err := s.w.Write([]byte(msg)); err != nil {
+		return fmt.Errorf("write event: %w", err)
+	}
+
+	if err := s.rc.Flush(); err != nil {
+		return fmt.Errorf("flush event: %w", err)
+	}
+
+	return nil
+}
+
+// A StreamFunc sends events to a Stream until it returns. The context is cancelled when the client goes
+// away or the server starts to stop, so it must return promptly once the context is done.
+type StreamFunc func(ctx context.Context, s *Stream)
+
+// SSE returns a handler serving a server-sent events stream with the function. Streams are exempt from
+// the servers write timeout and are drain aware, when the server starts to stop the context of the
+// function is cancelled and, once it has returned, a ShutdownEvent is sent before the stream is closed, so
+// long lived streams do not hold up a graceful shutdown.
+func SSE(fn StreamFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rc := http.NewResponseController(w)
+
+		// Clear the deadline set from the servers write timeout, which would otherwise close the stream.
+		if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			slog.WarnContext(r.Context(), "failed to clear sse write deadline", slog.String("err", err.Error()))
+		}
+
+		header := w.Header()
+		header.Set("Content-Type", "text/event-stream")
+		header.Set("Cache-Control", "no-cache")
+		header.Set("X-Accel-Buffering", "no")
+
+		w.WriteHeader(http.StatusOK)
+
+		if err := rc.Flush(); err != nil {
+			slog.ErrorContext(r.Context(), "sse stream not supported", slog.String("err", err.Error()))
+
+			return
+		}
+
+		if s, ok := r.Context().Value(streamsKey{}).(*streams); ok {
+			s.add(1)
+			defer s.add(-1)
+		}
+
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+
+		var shutdown atomic.Bool
+
+		if draining := drainingC(ctx); draining != nil {
+			go func() {
+				select {
+				case <-draining:
+					shutdown.Store(true)
+					cancel()
+				case <-ctx.Done():
+				}
+			}()
+		}
+
+		stream := &Stream{w: w, rc: rc}
+
+		fn(ctx, stream)
+
+		if shutdown.Load() {
+			_ = stream.Send(Event{Name: ShutdownEvent, Data: ShutdownEvent})
+		}
+	})
+}
+
+// streamsKey is the request context key the servers streams tracker is stored under.
+type streamsKey struct{}
+
+// streams tracks the number of open SSE streams of a server.
+type streams struct {
+	n     atomic.Int64
+	gauge metrics.Gauge
+}
+
+func newStreams(addr string) *streams {
+	return &streams{
+		gauge: metrics.NewGauge("http_server_open_sse_streams", metrics.Labels{"addr": addr}),
+	}
+}
+
+func (s *streams) add(delta int64) {
+	s.gauge.Set(float64(s.n.Add(delta)))
+}
@@ -0,0 +1,233 @@
+package http
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+// A StaticOption configures the static file handler.
+type StaticOption interface {
+	applyStaticConfig(*staticConfig)
+}
+
+// StaticOptions is one or more StaticOption.
+type StaticOptions []StaticOption
+
+func (o StaticOptions) applyStaticConfig(cfg *staticConfig) {
+	for opt := range slices.Values(o) {
+		if opt != nil {
+			opt.applyStaticConfig(cfg)
+		}
+	}
+}
+
+type staticConfigFunc func(*staticConfig)
+
+func (f staticConfigFunc) applyStaticConfig(cfg *staticConfig) {
+	f(cfg)
+}
+
+// staticConfig holds the configuration for the static file handler.
+type staticConfig struct {
+	index  string
+	spa    bool
+	maxAge time.Duration
+}
+
+// WithSPA enables single page application behaviour, requests for paths which do not exist and do not
+// have a file extension are served the index file so client side routing can handle them.
+func WithSPA() StaticOption {
+	return staticConfigFunc(func(cfg *staticConfig) {
+		cfg.spa = true
+	})
+}
+
+// WithMaxAge sets the Cache-Control max-age for files other than the index file, which is always served
+// with no-cache so new deploys are picked up. Defaults to one hour.
+func WithMaxAge(d time.Duration) StaticOption {
+	return staticConfigFunc(func(cfg *staticConfig) {
+		cfg.maxAge = d
+	})
+}
+
+// WithIndex sets the name of the index file served for directories, defaults to index.html.
+func WithIndex(name string) StaticOption {
+	return staticConfigFunc(func(cfg *staticConfig) {
+		cfg.index = name
+	})
+}
+
+// Static returns a http.Handler which serves the files in the given directory.
+// See StaticFS for details.
+func Static(dir string, opts ...StaticOption) http.Handler {
+	return StaticFS(os.DirFS(dir), opts...)
+}
+
+// StaticFS returns a http.Handler which serves the files in the given file system, for example an
+// embed.FS. Precompressed variants of a file with a .br or .gz extension are served when the client
+// accepts the encoding, brotli is preferred over gzip.
+func StaticFS(fsys fs.FS, opts ...StaticOption) http.Handler {
+	cfg := staticConfig{
+		index:  "index.html",
+		maxAge: time.Hour,
+	}
+
+	StaticOptions(opts).applyStaticConfig(&cfg)
+
+	return &staticHandler{
+		fsys: fsys,
+		cfg:  cfg,
+	}
+}
+
+// staticHandler serves static files from a file system.
+type staticHandler struct {
+	fsys fs.FS
+	cfg  staticConfig
+}
+
+// staticEncodings are the supported precompressed encodings in order of preference.
+var staticEncodings = []struct {
+	encoding string
+	ext      string
+}{
+	{encoding: "br", ext: ".br"},
+	{encoding: "gzip", ext: ".gz"},
+}
+
+func (h *staticHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	name, ok := h.resolve(r.URL.Path)
+	if !ok {
+		http.NotFound(w, r)
+
+		return
+	}
+
+	if ct := mime.TypeByExtension(path.Ext(name)); ct != "" {
+		w.Header().Set("Content-Type", ct)
+	}
+
+	if path.Base(name) == h.cfg.index {
+		w.Header().Set("Cache-Control", "no-cache")
+	} else {
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(h.cfg.maxAge.Seconds())))
+	}
+
+	w.Header().Add("Vary", "Accept-Encoding")
+
+	accepted := acceptedEncodings(r.Header.Get("Accept-Encoding"))
+
+	for _, enc := range staticEncodings {
+		if !slices.Contains(accepted, enc.encoding) {
+			continue
+		}
+
+		if h.serve(w, r, name+enc.ext, enc.encoding) {
+			return
+		}
+	}
+
+	if !h.serve(w, r, name, "") {
+		http.NotFound(w, r)
+	}
+}
+
+// resolve returns the name of the file within the file system to serve for the given url path.
+func (h *staticHandler) resolve(urlPath string) (string, bool) {
+	name := strings.TrimPrefix(path.Clean("/"+urlPath), "/")
+	if name == "" {
+		name = "."
+	}
+
+	info, err := fs.Stat(h.fsys, name)
+
+	switch {
+	case err == nil && info.IsDir():
+		name = path.Join(name, h.cfg.index)
+		if _, err := fs.Stat(h.fsys, name); err == nil {
+			return name, true
+		}
+	case err == nil:
+		return name, true
+	case !errors.Is(err, fs.ErrNotExist):
+		return "", false
+	}
+
+	// Fall back to the root index for client side routes, paths with an extension are assets.
+	if h.cfg.spa && path.Ext(name) == "" {
+		if _, err := fs.Stat(h.fsys, h.cfg.index); err == nil {
+			return h.cfg.index, true
+		}
+	}
+
+	return "", false
+}
+
+// serve serves the named file with the given content encoding, returns false if the file does not exist.
+func (h *staticHandler) serve(w http.ResponseWriter, r *http.Request, name, encoding string) bool {
+	f, err := h.fsys.Open(name)
+	if err != nil {
+		return false
+	}
+
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		return false
+	}
+
+	content, ok := f.(io.ReadSeeker)
+	if !ok {
+		b, err := io.ReadAll(f)
+		if err != nil {
+			return false
+		}
+
+		content = bytes.NewReader(b)
+	}
+
+	if encoding != "" {
+		w.Header().Set("Content-Encoding", encoding)
+	}
+
+	http.ServeContent(w, r, name, info.ModTime(), content)
+
+	return true
+}
+
+// acceptedEncodings parses an Accept-Encoding header returning the accepted encodings.
+func acceptedEncodings(header string) []string {
+	var encodings []string
+
+	for part := range strings.SplitSeq(header, ",") {
+		encoding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok && strings.Trim(q, "0.") == "" {
+			continue // q=0 means not acceptable
+		}
+
+		if encoding != "" {
+			encodings = append(encodings, strings.ToLower(encoding))
+		}
+	}
+
+	return encodings
+}
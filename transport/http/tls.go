@@ -0,0 +1,162 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.krak3n.io/foundation/transport/mtls"
+)
+
+// certPollInterval is how often a CertReloader run by the Runner checks its files for changes.
+const certPollInterval = 10 * time.Second
+
+// WithCertReloader serves TLS with the certificate and key loaded from the given PEM files, reloading
+// them when they change or the Reload hooks are called, see CertReloader. Failing to load them on
+// start fails the runner.
+func WithCertReloader(certFile, keyFile string) RunnerOption {
+	return runnerConfigFunc(func(cfg *runnerConfig) {
+		cfg.certs = NewCertReloader(certFile, keyFile)
+	})
+}
+
+// WithClientAuth requires clients to present a certificate issued by a CA in the pool which passes
+// any verify functions, see mtls.Config. The server must serve TLS, see WithCertReloader. Requests
+// without a verified certificate are rejected with 403 Forbidden, except to the sensor endpoint so
+// platform probes need not hold a certificate.
+func WithClientAuth(pool *x509.CertPool, opts ...mtls.Option) RunnerOption {
+	return runnerConfigFunc(func(cfg *runnerConfig) {
+		cfg.clientAuth = func(tlsConfig *tls.Config) {
+			mtls.Apply(tlsConfig, pool, opts...)
+
+			// Certificates are required per request rather than per connection so the sensor is exempt.
+			tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+		}
+	})
+}
+
+// requireClientCert rejects requests whose connection did not present a verified client certificate.
+func requireClientCert(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !mtls.Verified(r.TLS) {
+			http.Error(w, "client certificate required", http.StatusForbidden)
+
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// servesTLS reports whether the server has a certificate to serve TLS with.
+func servesTLS(server *http.Server) bool {
+	cfg := server.TLSConfig
+
+	return cfg != nil && (len(cfg.Certificates) > 0 || cfg.GetCertificate != nil || cfg.GetConfigForClient != nil)
+}
+
+// A CertReloader serves a certificate and key loaded from PEM files through GetCertificate, so rotated
+// certificates are served to new connections on existing listeners without a restart. Should changed
+// files fail to load the last good certificate is kept.
+//
+// The Runner reloads its CertReloader itself, see WithCertReloader, it may also be set as the
+// GetCertificate function of other TLS configurations such as a HTTP/3 server.
+type CertReloader struct {
+	certFile string
+	keyFile  string
+
+	cert atomic.Pointer[tls.Certificate]
+
+	mtx  sync.Mutex
+	hash []byte
+}
+
+// NewCertReloader returns a CertReloader for the certificate and key files, which must be loaded with
+// Reload before use.
+func NewCertReloader(certFile, keyFile string) *CertReloader {
+	return &CertReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+	}
+}
+
+// Reload loads the certificate and key, replacing the certificate served if they load.
+func (c *CertReloader) Reload() error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	return c.load(c.checksum())
+}
+
+// GetCertificate returns the last loaded certificate, as tls.Config.GetCertificate.
+func (c *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cert := c.cert.Load()
+	if cert == nil {
+		return nil, errors.New("tls certificate not loaded")
+	}
+
+	return cert, nil
+}
+
+// check reloads the certificate and key if their files have changed.
+func (c *CertReloader) check(ctx context.Context) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	hash := c.checksum()
+	if bytes.Equal(hash, c.hash) {
+		return
+	}
+
+	if err := c.load(hash); err != nil {
+		slog.ErrorContext(ctx, "tls certificate reload failed, keeping last good certificate", slog.String("err", err.Error()))
+
+		return
+	}
+
+	slog.InfoContext(ctx, "tls certificate reloaded", slog.String("cert", c.certFile))
+}
+
+// load loads the certificate and key recording the hash of their files, which is recorded even if they
+// fail to load so a bad pair is only reported once.
+func (c *CertReloader) load(hash []byte) error {
+	c.hash = hash
+
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return fmt.Errorf("load tls certificate: %w", err)
+	}
+
+	c.cert.Store(&cert)
+
+	return nil
+}
+
+// checksum returns a hash of the contents of the certificate and key files.
+func (c *CertReloader) checksum() []byte {
+	h := sha256.New()
+
+	for _, file := range []string{c.certFile, c.keyFile} {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			h.Write([]byte{0})
+
+			continue
+		}
+
+		h.Write([]byte{1})
+		h.Write(b)
+	}
+
+	return h.Sum(nil)
+}
@@ -0,0 +1,205 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.krak3n.io/foundation/metrics"
+)
+
+// WithWebSockets tracks WebSocket connections so they are closed gracefully on stop rather than being
+// left open by the servers shutdown, which ignores hijacked connections. On stop the close functions
+// registered with TrackWebSocket are called and the runner waits up to the given timeout for the
+// connections to finish before forcibly closing them.
+func WithWebSockets(timeout time.Duration) RunnerOption {
+	return runnerConfigFunc(func(cfg *runnerConfig) {
+		cfg.websockets = timeout
+	})
+}
+
+// TrackWebSocket registers a function which gracefully closes the WebSocket connection upgraded from the
+// request, for example by sending a close frame with the 1001 going away status code. The returned
+// function must be called once the connection is finished with, it is safe to call more than once.
+// This is a no-op if the runner was not configured WithWebSockets.
+func TrackWebSocket(r *http.Request, close func(ctx context.Context) error) (done func()) {
+	conn, ok := r.Context().Value(websocketKey{}).(*websocket)
+	if !ok {
+		return func() {}
+	}
+
+	conn.mtx.Lock()
+	conn.close = close
+	conn.mtx.Unlock()
+
+	conn.tracker.add(conn)
+
+	return func() {
+		conn.tracker.remove(conn)
+	}
+}
+
+// websocketKey is the request context key a requests websocket is stored under.
+type websocketKey struct{}
+
+// websocket is a tracked WebSocket connection.
+type websocket struct {
+	tracker  *websockets
+	mtx      sync.Mutex
+	close    func(ctx context.Context) error
+	raw      net.Conn
+	hijacked bool
+}
+
+// websockets tracks open WebSocket connections.
+type websockets struct {
+	mtx   sync.Mutex
+	conns map[*websocket]struct{}
+	open  metrics.Gauge
+}
+
+func newWebSockets(addr string) *websockets {
+	return &websockets{
+		conns: make(map[*websocket]struct{}),
+		open:  metrics.NewGauge("http_server_open_websockets", metrics.Labels{"addr": addr}),
+	}
+}
+
+// Middleware tracks connections which are hijacked or registered with TrackWebSocket. Hijacked
+// connections are tracked until they are closed or marked as done, as they are often handed to their own
+// go routines, others until the handler returns or they are marked as done.
+func (t *websockets) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn := &websocket{tracker: t}
+
+		defer func() {
+			conn.mtx.Lock()
+			hijacked := conn.hijacked
+			conn.mtx.Unlock()
+
+			if !hijacked {
+				t.remove(conn)
+			}
+		}()
+
+		next.ServeHTTP(&hijackWriter{ResponseWriter: w, conn: conn}, r.WithContext(context.WithValue(r.Context(), websocketKey{}, conn)))
+	})
+}
+
+// Close gracefully closes all tracked connections waiting until they are done or the context is done,
+// at which point any remaining hijacked connections are forcibly closed.
+func (t *websockets) Close(ctx context.Context) {
+	for _, conn := range t.snapshot() {
+		conn.mtx.Lock()
+		close := conn.close
+		conn.mtx.Unlock()
+
+		if close == nil {
+			continue
+		}
+
+		go func() {
+			if err := close(ctx); err != nil {
+				slog.WarnContext(ctx, "failed to gracefully close websocket", slog.String("err", err.Error()))
+			}
+		}()
+	}
+
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+
+	for len(t.snapshot()) > 0 {
+		select {
+		case <-ctx.Done():
+			for _, conn := range t.snapshot() {
+				conn.mtx.Lock()
+				raw := conn.raw
+				conn.mtx.Unlock()
+
+				if raw != nil {
+					raw.Close()
+				}
+			}
+
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (t *websockets) add(conn *websocket) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	t.conns[conn] = struct{}{}
+	t.open.Set(float64(len(t.conns)))
+}
+
+func (t *websockets) remove(conn *websocket) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	delete(t.conns, conn)
+	t.open.Set(float64(len(t.conns)))
+}
+
+func (t *websockets) snapshot() []*websocket {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	conns := make([]*websocket, 0, len(t.conns))
+
+	for conn := range t.conns {
+		conns = append(conns, conn)
+	}
+
+	return conns
+}
+
+// hijackWriter is a http.ResponseWriter which tracks the connection when hijacked.
+type hijackWriter struct {
+	http.ResponseWriter
+	conn *websocket
+}
+
+func (w *hijackWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	raw, rw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tracked := &hijackedConn{Conn: raw, conn: w.conn}
+
+	w.conn.mtx.Lock()
+	w.conn.raw = tracked
+	w.conn.hijacked = true
+	w.conn.mtx.Unlock()
+
+	w.conn.tracker.add(w.conn)
+
+	return tracked, rw, nil
+}
+
+func (w *hijackWriter) Flush() {
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *hijackWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// hijackedConn is a hijacked connection which stops being tracked once closed.
+type hijackedConn struct {
+	net.Conn
+	conn *websocket
+}
+
+func (c *hijackedConn) Close() error {
+	defer c.conn.tracker.remove(c.conn)
+
+	return c.Conn.Close()
+}
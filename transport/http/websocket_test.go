@@ -0,0 +1,178 @@
+package http
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// upgrade returns a handler which hijacks the connection, handing it to its own go routine which echoes
+// until closed, and tracks it closing it with a goodbye if graceful.
+func upgrade(t *testing.T, graceful bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, rw, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			t.Error(err)
+
+			return
+		}
+
+		done := TrackWebSocket(r, func(context.Context) error {
+			if !graceful {
+				return nil
+			}
+
+			_, err := io.WriteString(conn, "bye")
+
+			return err
+		})
+
+		if _, err := io.WriteString(rw, "HTTP/1.1 101 Switching Protocols\r\n\r\n"); err != nil {
+			t.Error(err)
+		}
+
+		if err := rw.Flush(); err != nil {
+			t.Error(err)
+		}
+
+		go func() {
+			defer done()
+			defer conn.Close()
+
+			if graceful {
+				// Wait for the goodbye to be acknowledged by the client closing.
+				_, _ = io.Copy(io.Discard, conn)
+			} else {
+				_, _ = io.Copy(conn, conn)
+			}
+		}()
+	})
+}
+
+// dial connects to the server upgrading the connection, returning it once upgraded.
+func dial(t *testing.T, addr string) net.Conn {
+	t.Helper()
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		conn.Close()
+	})
+
+	if _, err := io.WriteString(conn, "GET / HTTP/1.1\r\nHost: test\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := conn.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		t.Fatal(err)
+	}
+
+	b := make([]byte, len("HTTP/1.1 101 Switching Protocols\r\n\r\n"))
+	if _, err := io.ReadFull(conn, b); err != nil {
+		t.Fatal(err)
+	}
+
+	return conn
+}
+
+func TestWebSocketsCloseGracefully(t *testing.T) {
+	ws := newWebSockets("test")
+
+	srv := httptest.NewServer(ws.Middleware(upgrade(t, true)))
+	defer srv.Close()
+
+	conn := dial(t, srv.Listener.Addr().String())
+
+	// The handler has returned, the hijacked connection must still be tracked.
+	if n := len(ws.snapshot()); n != 1 {
+		t.Fatalf("want 1 tracked connection, got %d", n)
+	}
+
+	closed := make(chan struct{})
+
+	go func() {
+		defer close(closed)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		ws.Close(ctx)
+	}()
+
+	b := make([]byte, 3)
+	if _, err := io.ReadFull(conn, b); err != nil {
+		t.Fatal(err)
+	}
+
+	if string(b) != "bye" {
+		t.Errorf("want bye, got %q", b)
+	}
+
+	conn.Close()
+
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("close did not return once the connection was closed")
+	}
+
+	if n := len(ws.snapshot()); n != 0 {
+		t.Errorf("want no tracked connections, got %d", n)
+	}
+}
+
+func TestWebSocketsCloseForcibly(t *testing.T) {
+	ws := newWebSockets("test")
+
+	srv := httptest.NewServer(ws.Middleware(upgrade(t, false)))
+	defer srv.Close()
+
+	conn := dial(t, srv.Listener.Addr().String())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+
+	ws.Close(ctx)
+
+	if took := time.Since(start); took > time.Second {
+		t.Errorf("close took %s, want it bounded by the context", took)
+	}
+
+	// The connection was forcibly closed so the client reads EOF.
+	if _, err := conn.Read(make([]byte, 1)); err != io.EOF {
+		t.Errorf("want EOF, got %v", err)
+	}
+
+	if n := len(ws.snapshot()); n != 0 {
+		t.Errorf("want no tracked connections, got %d", n)
+	}
+}
+
+func TestWebSocketsUntrackedOnReturn(t *testing.T) {
+	ws := newWebSockets("test")
+
+	srv := httptest.NewServer(ws.Middleware(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		TrackWebSocket(r, func(context.Context) error { return nil })
+	})))
+	defer srv.Close()
+
+	rsp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rsp.Body.Close()
+
+	if n := len(ws.snapshot()); n != 0 {
+		t.Errorf("want no tracked connections once the handler returned, got %d", n)
+	}
+}
@@ -0,0 +1,56 @@
+// Package boundaddr publishes the addresses servers are bound to in the value store of their F, so a server
+// configured to listen on port 0 can be found by the runners which depend on it.
+package boundaddr
+
+import (
+	"log/slog"
+	"sync"
+
+	"go.krak3n.io/foundation"
+)
+
+// key is the value store key the bound address of a server is stored under, keyed by its transport and
+// configured address.
+type key struct {
+	transport string
+	addr      string
+}
+
+// published is the bound address of the runner, empty if several runners of the transport are
+// configured with the same address.
+type published struct {
+	runner any
+	addr   string
+}
+
+// mtx guards publishing bound addresses so servers configured with the same address are detected.
+var mtx sync.Mutex
+
+// Publish stores the address the runner of the transport configured with addr is bound to in the F's
+// value store, see Lookup. The address is ambiguous if another runner of the transport is configured with
+// the same address, for example two servers on port 0, so neither is published rather than one
+// overwriting the other. The runner must be comparable, for example a pointer.
+func Publish(f foundation.F, transport string, runner any, addr, bound string) {
+	mtx.Lock()
+	defer mtx.Unlock()
+
+	k := key{transport: transport, addr: addr}
+
+	if v, ok := foundation.Value[published](f, k); ok && (v.runner != runner || v.addr == "") {
+		slog.Warn("servers configured with the same address, use Runner.BoundAddr for their bound address",
+			slog.String("transport", transport),
+			slog.String("addr", addr))
+
+		bound = ""
+	}
+
+	f.Values().Store(k, published{runner: runner, addr: bound})
+}
+
+// Lookup returns the bound address of the server of the transport configured with addr from the F's
+// value store. Reports false if none has been published, or several are configured with the address.
+func Lookup(f foundation.F, transport, addr string) (string, bool) {
+	v, ok := foundation.Value[published](f, key{transport: transport, addr: addr})
+
+	return v.addr, ok && v.addr != ""
+}
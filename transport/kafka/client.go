@@ -0,0 +1,50 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// A TopicPartition identifies a partition of a topic.
+type TopicPartition struct {
+	Topic     string
+	Partition int32
+}
+
+func (tp TopicPartition) String() string {
+	return fmt.Sprintf("%s/%d", tp.Topic, tp.Partition)
+}
+
+// A Record is a message consumed from a Kafka topic partition.
+type Record struct {
+	Topic     string
+	Partition int32
+	Offset    int64
+	Key       []byte
+	Value     []byte
+	Headers   map[string][]byte
+	Timestamp time.Time
+}
+
+// TopicPartition returns the topic partition the record was consumed from.
+func (r Record) TopicPartition() TopicPartition {
+	return TopicPartition{Topic: r.Topic, Partition: r.Partition}
+}
+
+// A Client is a Kafka consumer group member. Foundation does not depend on a Kafka library, instead
+// a Client is implemented as a thin adapter over a library such as franz-go, sarama or kafka-go.
+//
+// Adapters which are notified of partitions being revoked during a rebalance should call
+// Consumer.Revoke from their callback so in-flight records for those partitions are processed and
+// committed before the partitions are reassigned.
+type Client interface {
+	// Poll blocks until records are available or the context is done, joining the group on first call.
+	Poll(ctx context.Context) ([]Record, error)
+	// Commit commits the given offsets, which are the offset of the next record to consume.
+	Commit(ctx context.Context, offsets map[TopicPartition]int64) error
+	// Lag returns the number of records each assigned partition is behind the partitions high watermark.
+	Lag(ctx context.Context) (map[TopicPartition]int64, error)
+	// Close leaves the group and closes the client.
+	Close() error
+}
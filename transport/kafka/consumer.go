@@ -0,0 +1,422 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.krak3n.io/foundation"
+	"go.krak3n.io/foundation/consumer"
+	"go.krak3n.io/foundation/health/probe"
+	"go.krak3n.io/foundation/metrics"
+	"go.krak3n.io/foundation/tick"
+)
+
+// A HandlerFunc handles a record. Returning an error causes the record to be retried with backoff.
+type HandlerFunc func(ctx context.Context, record Record) error
+
+// Option configures a Consumer.
+type Option interface {
+	apply(*config)
+}
+
+// Options is one or more Option.
+type Options []Option
+
+func (opts Options) apply(cfg *config) {
+	for opt := range slices.Values(opts) {
+		if opt != nil {
+			opt.apply(cfg)
+		}
+	}
+}
+
+type optionFunc func(*config)
+
+func (f optionFunc) apply(cfg *config) {
+	f(cfg)
+}
+
+// config holds the Consumer configuration.
+type config struct {
+	name            string
+	backoff         tick.Backoff
+	retries         uint8
+	commitInterval  time.Duration
+	shutdownTimeout time.Duration
+	queueSize       int
+	concurrency     int
+	lagThreshold    int64
+	sensorMode      probe.Mode
+}
+
+// WithName sets the name of the consumer, typically the group id, used to label metrics and name the
+// sensor. Defaults to "default".
+func WithName(name string) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.name = name
+	})
+}
+
+// WithBackoff sets the backoff used between poll attempts on broker errors and between handler retries,
+// defaults to an exponential backoff with a scalar of 100ms and 20% jitter.
+func WithBackoff(backoff tick.Backoff) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.backoff = backoff
+	})
+}
+
+// WithRetries sets the number of times a record is retried when the handler returns an error, once
+// exhausted the failure is logged and the record skipped. Defaults to 3.
+func WithRetries(n uint8) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.retries = n
+	})
+}
+
+// WithCommitInterval sets how often processed offsets are committed and lag is measured, defaults to
+// 5 seconds. Offsets are always committed on stop and when partitions are revoked.
+func WithCommitInterval(d time.Duration) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.commitInterval = d
+	})
+}
+
+// WithShutdownTimeout sets how long to wait on stop for queued records to be processed before the
+// handler context is cancelled, defaults to 30 seconds.
+func WithShutdownTimeout(d time.Duration) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.shutdownTimeout = d
+	})
+}
+
+// WithQueueSize sets how many records can be queued per worker before polling blocks, defaults to 256.
+func WithQueueSize(n int) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.queueSize = n
+	})
+}
+
+// WithConcurrency sets the number of workers handling records, defaults to 8. Each partition is handled
+// by one worker so its records are handled in order, partitions are spread across the workers.
+func WithConcurrency(n int) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.concurrency = n
+	})
+}
+
+// WithLagThreshold registers a sensor which fails when the total lag across assigned partitions exceeds
+// the threshold. By default lag is not sensed.
+func WithLagThreshold(n int64) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.lagThreshold = n
+	})
+}
+
+// WithSensorMode sets the mode of the consumers sensor, defaults to probe.ReadinessMode.
+func WithSensorMode(mode probe.Mode) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.sensorMode = mode
+	})
+}
+
+// A Consumer is a foundation.Runner which polls a Client handing records to a consumer.Runner, which
+// handles them with retries and drains them on stop. Records within a partition are handled in order,
+// partitions are handled concurrently. A record the handler fails to handle once retries are exhausted
+// is logged and skipped once a later record of its partition has been handled.
+type Consumer struct {
+	client  Client
+	handler HandlerFunc
+	cfg     config
+	runner  *consumer.Runner
+
+	offsetsMtx sync.Mutex
+	offsets    map[TopicPartition]int64
+	committed  map[TopicPartition]int64
+	// The number of records polled but not yet handled per partition, see Revoke.
+	outstanding map[TopicPartition]int
+	// Closed and replaced whenever a record is handled, see Revoke.
+	handled chan struct{}
+
+	lag atomic.Int64
+
+	processed metrics.Counter
+	failed    metrics.Counter
+}
+
+// NewConsumer constructs a new Consumer which calls the handler for each record polled from the client.
+func NewConsumer(client Client, handler HandlerFunc, opts ...Option) *Consumer {
+	cfg := config{
+		name:            "default",
+		backoff:         tick.ExponentialBackoff(100*time.Millisecond, tick.WithJitter(0.2)),
+		retries:         3,
+		commitInterval:  5 * time.Second,
+		shutdownTimeout: 30 * time.Second,
+		queueSize:       256,
+		concurrency:     8,
+		sensorMode:      probe.ReadinessMode,
+	}
+
+	Options(opts).apply(&cfg)
+
+	c := &Consumer{
+		client:      client,
+		handler:     handler,
+		cfg:         cfg,
+		offsets:     make(map[TopicPartition]int64),
+		committed:   make(map[TopicPartition]int64),
+		outstanding: make(map[TopicPartition]int),
+		handled:     make(chan struct{}),
+		processed:   metrics.NewCounter("kafka_consumer_records_total", metrics.Labels{"consumer": cfg.name, "status": "success"}),
+		failed:      metrics.NewCounter("kafka_consumer_records_total", metrics.Labels{"consumer": cfg.name, "status": "failed"}),
+	}
+
+	c.runner = consumer.Run(cfg.name, (*source)(c), consumer.HandlerFunc(func(ctx context.Context, msg consumer.Message) error {
+		return handler(ctx, msg.(*message).record)
+	}),
+		consumer.WithConcurrency(cfg.concurrency),
+		consumer.WithQueueSize(cfg.queueSize),
+		consumer.WithRetries(cfg.retries),
+		consumer.WithBackoff(cfg.backoff),
+		consumer.WithShutdownTimeout(cfg.shutdownTimeout),
+		consumer.WithSensorName(fmt.Sprintf("kafka.consumer[%s]", cfg.name)),
+		consumer.WithSensorMode(cfg.sensorMode),
+		consumer.WithOrdering(func(msg consumer.Message) string {
+			return msg.(*message).record.TopicPartition().String()
+		}))
+
+	return c
+}
+
+// Run polls the client until told to stop. On stop polling ends, queued records are processed, offsets
+// committed and the client closed which leaves the group.
+func (c *Consumer) Run(ctx context.Context, f foundation.F) {
+	if c.cfg.lagThreshold > 0 {
+		probe.RegisterContext(ctx, probe.NewSensor(fmt.Sprintf("kafka.consumer.lag[%s]", c.cfg.name), c.cfg.sensorMode, c.sense))
+	}
+
+	tick.Run(ctx, f, c.cfg.commitInterval, func(ctx context.Context, _ tick.Ticker) {
+		if err := c.commit(ctx); err != nil {
+			slog.WarnContext(ctx, "failed to commit kafka offsets", slog.String("consumer", c.cfg.name), slog.String("err", err.Error()))
+		}
+
+		c.measureLag(ctx)
+	})
+
+	c.runner.Run(ctx, f)
+}
+
+// Revoke waits for the polled records of the given partitions to be handled and commits their offsets.
+// Client adapters should call this from their partitions revoked callback before the rebalance completes.
+func (c *Consumer) Revoke(ctx context.Context, partitions ...TopicPartition) error {
+	for {
+		c.offsetsMtx.Lock()
+
+		pending := slices.ContainsFunc(partitions, func(tp TopicPartition) bool {
+			return c.outstanding[tp] > 0
+		})
+
+		handled := c.handled
+
+		c.offsetsMtx.Unlock()
+
+		if !pending {
+			break
+		}
+
+		select {
+		case <-handled:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	if err := c.commit(ctx); err != nil {
+		return err
+	}
+
+	c.offsetsMtx.Lock()
+	defer c.offsetsMtx.Unlock()
+
+	for _, tp := range partitions {
+		delete(c.offsets, tp)
+		delete(c.committed, tp)
+		delete(c.outstanding, tp)
+	}
+
+	return nil
+}
+
+// settle records the record as handled, advancing the offset of its partition if processed.
+func (c *Consumer) settle(record Record, processed bool) {
+	c.offsetsMtx.Lock()
+	defer c.offsetsMtx.Unlock()
+
+	tp := record.TopicPartition()
+
+	if processed {
+		c.offsets[tp] = record.Offset + 1
+	}
+
+	if c.outstanding[tp]--; c.outstanding[tp] <= 0 {
+		delete(c.outstanding, tp)
+	}
+
+	close(c.handled)
+	c.handled = make(chan struct{})
+}
+
+// commit commits offsets which have been processed since the last commit.
+func (c *Consumer) commit(ctx context.Context) error {
+	c.offsetsMtx.Lock()
+	pending := make(map[TopicPartition]int64)
+
+	for tp, offset := range c.offsets {
+		if c.committed[tp] != offset {
+			pending[tp] = offset
+		}
+	}
+	c.offsetsMtx.Unlock()
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	if err := c.client.Commit(ctx, pending); err != nil {
+		return err
+	}
+
+	c.offsetsMtx.Lock()
+	defer c.offsetsMtx.Unlock()
+
+	maps.Copy(c.committed, pending)
+
+	return nil
+}
+
+// measureLag records the lag of each assigned partition.
+func (c *Consumer) measureLag(ctx context.Context) {
+	lag, err := c.client.Lag(ctx)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to measure kafka consumer lag", slog.String("consumer", c.cfg.name), slog.String("err", err.Error()))
+
+		return
+	}
+
+	var total int64
+
+	for tp, n := range lag {
+		total += n
+
+		metrics.NewGauge("kafka_consumer_lag", metrics.Labels{
+			"consumer":  c.cfg.name,
+			"topic":     tp.Topic,
+			"partition": strconv.Itoa(int(tp.Partition)),
+		}).Set(float64(n))
+	}
+
+	c.lag.Store(total)
+}
+
+// sense is the consumers lag sensor function.
+func (c *Consumer) sense(context.Context) error {
+	if lag, threshold := c.lag.Load(), c.cfg.lagThreshold; lag > threshold {
+		return fmt.Errorf("consumer lag %d exceeds threshold %d", lag, threshold)
+	}
+
+	return nil
+}
+
+// source is the consumer.Source of the Consumer, polling the client.
+type source Consumer
+
+// Receive polls the client for records.
+func (s *source) Receive(ctx context.Context) ([]consumer.Message, error) {
+	records, err := s.client.Poll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("poll: %w", err)
+	}
+
+	msgs := make([]consumer.Message, 0, len(records))
+
+	s.offsetsMtx.Lock()
+	defer s.offsetsMtx.Unlock()
+
+	for _, record := range records {
+		s.outstanding[record.TopicPartition()]++
+
+		msgs = append(msgs, &message{record: record, consumer: (*Consumer)(s)})
+	}
+
+	return msgs, nil
+}
+
+// Close commits the processed offsets and closes the client, leaving the group.
+func (s *source) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.shutdownTimeout)
+	defer cancel()
+
+	var errs []error
+
+	if err := (*Consumer)(s).commit(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("commit offsets: %w", err))
+	}
+
+	if err := s.client.Close(); err != nil {
+		errs = append(errs, fmt.Errorf("close client: %w", err))
+	}
+
+	return errors.Join(errs...)
+}
+
+// message is a Record as a consumer.Message. Acking it advances the offset of its partition, a nacked
+// record is skipped once a later record of the partition is acked, otherwise it is redelivered to the
+// next consumer of the partition.
+type message struct {
+	record   Record
+	consumer *Consumer
+}
+
+// ID returns the topic, partition and offset of the record.
+func (m *message) ID() string {
+	return fmt.Sprintf("%s@%d", m.record.TopicPartition(), m.record.Offset)
+}
+
+// Body returns the value of the record.
+func (m *message) Body() []byte {
+	return m.record.Value
+}
+
+// Metadata returns the headers of the record.
+func (m *message) Metadata() map[string]string {
+	md := make(map[string]string, len(m.record.Headers))
+
+	for k, v := range m.record.Headers {
+		md[k] = string(v)
+	}
+
+	return md
+}
+
+// Ack marks the record processed.
+func (m *message) Ack(context.Context) error {
+	m.consumer.processed.Add(1)
+	m.consumer.settle(m.record, true)
+
+	return nil
+}
+
+// Nack marks the record handled without processing it.
+func (m *message) Nack(context.Context) error {
+	m.consumer.failed.Add(1)
+	m.consumer.settle(m.record, false)
+
+	return nil
+}
@@ -0,0 +1,163 @@
+// Package mtls configures TLS servers to require and verify client certificates, so internal services
+// enforce mutual TLS consistently. It is used by the HTTP and gRPC runners, see their WithClientAuth
+// options, and Config may be given to any other TLS server.
+//
+//	pool := x509.NewCertPool()
+//	pool.AppendCertsFromPEM(caPEM)
+//
+//	http.Run(handler, http.WithCertReloader(certFile, keyFile), http.WithClientAuth(pool,
+//		mtls.WithVerify(mtls.NotRevoked(crls.Current))))
+package mtls
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// ErrRevoked is returned by NotRevoked when a client certificate has been revoked.
+var ErrRevoked = errors.New("certificate revoked")
+
+// ErrRevocationList is returned by NotRevoked when the revocation list of the issuer of a client
+// certificate is not signed by it, so whether the certificate has been revoked can not be trusted.
+var ErrRevocationList = errors.New("revocation list not signed by issuer")
+
+// A VerifyFunc checks the verified chain of a client certificate, leaf first, once it has been verified
+// against the CA pool. Returning an error rejects the client, for example after checking a CRL or an
+// OCSP responder.
+type VerifyFunc func(chain []*x509.Certificate) error
+
+// An Option configures client certificate verification.
+type Option interface {
+	apply(*config)
+}
+
+// Options is one or more Option.
+type Options []Option
+
+func (opts Options) apply(cfg *config) {
+	for opt := range slices.Values(opts) {
+		if opt != nil {
+			opt.apply(cfg)
+		}
+	}
+}
+
+type optionFunc func(*config)
+
+func (f optionFunc) apply(cfg *config) {
+	f(cfg)
+}
+
+// config holds the client certificate verification configuration.
+type config struct {
+	verify []VerifyFunc
+}
+
+// WithVerify adds a function checking each verified client certificate chain, functions are called in
+// the order they are given until one fails.
+func WithVerify(fn VerifyFunc) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.verify = append(cfg.verify, fn)
+	})
+}
+
+// Config returns a TLS configuration requiring clients to present a certificate issued by a CA in the
+// pool which passes the verify functions. Server certificates must be added to the configuration.
+func Config(pool *x509.CertPool, opts ...Option) *tls.Config {
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+
+	Apply(cfg, pool, opts...)
+
+	return cfg
+}
+
+// Apply configures the TLS configuration to require and verify client certificates as Config does.
+func Apply(tlsConfig *tls.Config, pool *x509.CertPool, opts ...Option) {
+	var cfg config
+
+	Options(opts).apply(&cfg)
+
+	tlsConfig.ClientCAs = pool
+	tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	tlsConfig.VerifyConnection = verifyConnection(cfg.verify)
+}
+
+// verifyConnection returns a tls.Config.VerifyConnection function calling the verify functions with the
+// verified chains of connections which presented a certificate. A connection is accepted if any of its
+// chains pass.
+func verifyConnection(verify []VerifyFunc) func(tls.ConnectionState) error {
+	return func(state tls.ConnectionState) error {
+		if len(verify) == 0 || len(state.VerifiedChains) == 0 {
+			return nil
+		}
+
+		var errs []error
+
+		for chain := range slices.Values(state.VerifiedChains) {
+			err := verifyChain(verify, chain)
+			if err == nil {
+				return nil
+			}
+
+			errs = append(errs, err)
+		}
+
+		return fmt.Errorf("verify client certificate: %w", errors.Join(errs...))
+	}
+}
+
+// verifyChain calls each verify function with the chain until one fails.
+func verifyChain(verify []VerifyFunc, chain []*x509.Certificate) error {
+	for fn := range slices.Values(verify) {
+		if err := fn(chain); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// NotRevoked returns a VerifyFunc rejecting client certificates revoked by the revocation list returned
+// by fn, which is called for each connection so the list may be refreshed in the background. Lists of
+// another issuer do not apply to the certificate and are ignored, as is a nil list, but a list naming
+// the issuer of the certificate which is not signed by it rejects the client with ErrRevocationList.
+func NotRevoked(fn func() *x509.RevocationList) VerifyFunc {
+	return func(chain []*x509.Certificate) error {
+		crl := fn()
+		if crl == nil || len(chain) == 0 {
+			return nil
+		}
+
+		leaf := chain[0]
+
+		if !bytes.Equal(crl.RawIssuer, leaf.RawIssuer) {
+			return nil
+		}
+
+		// A chain of one certificate is a trusted self signed certificate, its own issuer.
+		issuer := chain[min(1, len(chain)-1)]
+
+		if err := crl.CheckSignatureFrom(issuer); err != nil {
+			return fmt.Errorf("%w: %w", ErrRevocationList, err)
+		}
+
+		for entry := range slices.Values(crl.RevokedCertificateEntries) {
+			if entry.SerialNumber != nil && entry.SerialNumber.Cmp(leaf.SerialNumber) == 0 {
+				return fmt.Errorf("%w: serial %s", ErrRevoked, leaf.SerialNumber)
+			}
+		}
+
+		return nil
+	}
+}
+
+// Verified reports whether the client of the connection presented a certificate which was verified.
+func Verified(state *tls.ConnectionState) bool {
+	return state != nil && len(state.VerifiedChains) > 0
+}
@@ -0,0 +1,328 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"go.krak3n.io/foundation"
+	"go.krak3n.io/foundation/consumer"
+	"go.krak3n.io/foundation/health/probe"
+	"go.krak3n.io/foundation/metrics"
+	"go.krak3n.io/foundation/tick"
+)
+
+// A HandlerFunc handles a message. The message is acked if the handler returns nil and nacked if it
+// returns an error or panics. The context is cancelled once the messages ack deadline can no longer be
+// extended or the shutdown timeout is exceeded.
+type HandlerFunc func(ctx context.Context, msg Message) error
+
+// A RunnerOption configures the Pub/Sub Runner.
+type RunnerOption interface {
+	applyRunnerConfig(*runnerConfig)
+}
+
+// RunnerOptions is one or more RunnerOption.
+type RunnerOptions []RunnerOption
+
+func (o RunnerOptions) applyRunnerConfig(cfg *runnerConfig) {
+	for opt := range slices.Values(o) {
+		if opt != nil {
+			opt.applyRunnerConfig(cfg)
+		}
+	}
+}
+
+type runnerConfigFunc func(*runnerConfig)
+
+func (f runnerConfigFunc) applyRunnerConfig(cfg *runnerConfig) {
+	f(cfg)
+}
+
+// runnerConfig holds the configuration for the Pub/Sub Runner.
+type runnerConfig struct {
+	settings        ReceiveSettings
+	backoff         tick.Backoff
+	shutdownTimeout time.Duration
+	sensorMode      probe.Mode
+}
+
+// WithMaxOutstandingMessages limits the number of messages being handled at once, defaults to 1000.
+func WithMaxOutstandingMessages(n int) RunnerOption {
+	return runnerConfigFunc(func(cfg *runnerConfig) {
+		cfg.settings.MaxOutstandingMessages = n
+	})
+}
+
+// WithMaxOutstandingBytes limits the total size of messages being handled at once, defaults to 1GB.
+func WithMaxOutstandingBytes(n int) RunnerOption {
+	return runnerConfigFunc(func(cfg *runnerConfig) {
+		cfg.settings.MaxOutstandingBytes = n
+	})
+}
+
+// WithMaxExtension sets the maximum time a messages ack deadline is extended whilst it is being handled,
+// once exceeded the handlers context is cancelled and the message nacked. Defaults to 60 minutes.
+func WithMaxExtension(d time.Duration) RunnerOption {
+	return runnerConfigFunc(func(cfg *runnerConfig) {
+		cfg.settings.MaxExtension = d
+	})
+}
+
+// WithNumGoroutines sets the number of go routines pulling messages, defaults to 10.
+func WithNumGoroutines(n int) RunnerOption {
+	return runnerConfigFunc(func(cfg *runnerConfig) {
+		cfg.settings.NumGoroutines = n
+	})
+}
+
+// WithBackoff sets the backoff used between receive attempts when receiving fails, defaults to an
+// exponential backoff with a scalar of 100ms and 20% jitter.
+func WithBackoff(backoff tick.Backoff) RunnerOption {
+	return runnerConfigFunc(func(cfg *runnerConfig) {
+		cfg.backoff = backoff
+	})
+}
+
+// WithShutdownTimeout sets how long to wait on stop for outstanding messages to be acked before the
+// handler context is cancelled, defaults to 30 seconds.
+func WithShutdownTimeout(d time.Duration) RunnerOption {
+	return runnerConfigFunc(func(cfg *runnerConfig) {
+		cfg.shutdownTimeout = d
+	})
+}
+
+// WithSensorMode sets the mode of the subscriptions sensor, defaults to probe.ReadinessMode.
+func WithSensorMode(mode probe.Mode) RunnerOption {
+	return runnerConfigFunc(func(cfg *runnerConfig) {
+		cfg.sensorMode = mode
+	})
+}
+
+// A Runner is a foundation.Runner which receives messages from a Pub/Sub subscription, handing them to a
+// consumer.Runner which handles them and drains them on stop.
+type Runner struct {
+	sub     Subscription
+	handler HandlerFunc
+	opts    []RunnerOption
+
+	acked   metrics.Counter
+	nacked  metrics.Counter
+	pending metrics.Gauge
+}
+
+// Run returns a Runner which receives messages from the subscription calling the handler for each.
+func Run(sub Subscription, handler HandlerFunc, opts ...RunnerOption) *Runner {
+	labels := func(status string) metrics.Labels {
+		return metrics.Labels{"subscription": sub.ID(), "status": status}
+	}
+
+	return &Runner{
+		sub:     sub,
+		handler: handler,
+		opts:    opts,
+		acked:   metrics.NewCounter("pubsub_messages_total", labels("acked")),
+		nacked:  metrics.NewCounter("pubsub_messages_total", labels("nacked")),
+		pending: metrics.NewGauge("pubsub_outstanding_messages", metrics.Labels{"subscription": sub.ID()}),
+	}
+}
+
+// Run verifies the subscription exists and receives messages until told to stop. On stop no further
+// messages are received and outstanding messages are handled and acked before the runner exits.
+func (r *Runner) Run(ctx context.Context, f foundation.F) {
+	cfg := runnerConfig{
+		settings: ReceiveSettings{
+			MaxOutstandingMessages: 1000,
+			MaxOutstandingBytes:    1e9,
+			MaxExtension:           60 * time.Minute,
+			NumGoroutines:          10,
+		},
+		backoff:         tick.ExponentialBackoff(100*time.Millisecond, tick.WithJitter(0.2)),
+		shutdownTimeout: 30 * time.Second,
+		sensorMode:      probe.ReadinessMode,
+	}
+
+	RunnerOptions(r.opts).applyRunnerConfig(&cfg)
+
+	id := r.sub.ID()
+
+	src := &source{
+		runner:   r,
+		settings: cfg.settings,
+		msgs:     make(chan *message),
+	}
+
+	// Messages are not retried, a nacked message is redelivered by Pub/Sub.
+	handler := consumer.HandlerFunc(func(ctx context.Context, msg consumer.Message) error {
+		ctx, cancel := context.WithTimeout(ctx, cfg.settings.MaxExtension)
+		defer cancel()
+
+		return r.handler(ctx, msg.(*message).msg)
+	})
+
+	consumer.Run(id, src, handler,
+		consumer.WithConcurrency(max(cfg.settings.MaxOutstandingMessages, 1)),
+		consumer.WithRetries(0),
+		consumer.WithBackoff(cfg.backoff),
+		consumer.WithShutdownTimeout(cfg.shutdownTimeout),
+		consumer.WithSensorName(fmt.Sprintf("pubsub.subscription[%s]", id)),
+		consumer.WithSensorMode(cfg.sensorMode),
+	).Run(ctx, f)
+}
+
+// verify checks the subscription exists and is accessible.
+func (r *Runner) verify(ctx context.Context) error {
+	ok, err := r.sub.Exists(ctx)
+	if err != nil {
+		return fmt.Errorf("check subscription exists: %w", err)
+	}
+
+	if !ok {
+		return fmt.Errorf("subscription %s does not exist", r.sub.ID())
+	}
+
+	return nil
+}
+
+// source is the consumer.Source of the Runner. Receiving starts Receive on the subscription in the
+// background, each message it calls back with is held until it has been acked or nacked so the
+// subscription's flow control applies.
+type source struct {
+	runner   *Runner
+	settings ReceiveSettings
+	msgs     chan *message
+
+	mtx sync.Mutex
+	// Receives the error Receive on the subscription returned with, nil if not receiving.
+	errC chan error
+}
+
+// Receive returns the next message, starting Receive on the subscription if not already receiving. The
+// subscription is verified before receiving, a missing subscription or lack of permission is returned
+// rather than surfacing as repeated receive errors.
+func (s *source) Receive(ctx context.Context) ([]consumer.Message, error) {
+	s.mtx.Lock()
+	errC := s.errC
+	s.mtx.Unlock()
+
+	if errC == nil {
+		if err := s.runner.verify(ctx); err != nil {
+			return nil, err
+		}
+
+		errC = make(chan error, 1)
+
+		s.mtx.Lock()
+		s.errC = errC
+		s.mtx.Unlock()
+
+		go func() {
+			errC <- s.runner.sub.Receive(ctx, s.settings, s.receive)
+		}()
+	}
+
+	select {
+	case msg := <-s.msgs:
+		return []consumer.Message{msg}, nil
+	case err := <-errC:
+		s.mtx.Lock()
+		s.errC = nil
+		s.mtx.Unlock()
+
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		if err == nil {
+			err = errors.New("receive returned unexpectedly")
+		}
+
+		return nil, err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// receive hands the message to Receive, returning once it has been acked or nacked.
+func (s *source) receive(ctx context.Context, msg Message) {
+	s.runner.pending.Add(1)
+	defer s.runner.pending.Add(-1)
+
+	m := &message{
+		msg:     msg,
+		runner:  s.runner,
+		settled: make(chan struct{}),
+	}
+
+	select {
+	case s.msgs <- m:
+	case <-ctx.Done():
+		_ = m.Nack(ctx)
+
+		return
+	}
+
+	<-m.settled
+}
+
+// Close waits for Receive on the subscription to return, which it does once every message has been
+// acked or nacked.
+func (s *source) Close() error {
+	s.mtx.Lock()
+	errC := s.errC
+	s.mtx.Unlock()
+
+	if errC != nil {
+		<-errC
+	}
+
+	return nil
+}
+
+// message is a Message as a consumer.Message.
+type message struct {
+	msg     Message
+	runner  *Runner
+	settled chan struct{}
+	once    sync.Once
+}
+
+// ID returns the server assigned message id.
+func (m *message) ID() string {
+	return m.msg.ID()
+}
+
+// Body returns the message payload.
+func (m *message) Body() []byte {
+	return m.msg.Data()
+}
+
+// Metadata returns the message attributes.
+func (m *message) Metadata() map[string]string {
+	return m.msg.Attributes()
+}
+
+// Ack acknowledges the message.
+func (m *message) Ack(context.Context) error {
+	m.once.Do(func() {
+		m.msg.Ack()
+		m.runner.acked.Add(1)
+		close(m.settled)
+	})
+
+	return nil
+}
+
+// Nack negatively acknowledges the message so it is redelivered.
+func (m *message) Nack(context.Context) error {
+	m.once.Do(func() {
+		m.msg.Nack()
+		m.runner.nacked.Add(1)
+		close(m.settled)
+	})
+
+	return nil
+}
@@ -0,0 +1,47 @@
+package pubsub
+
+import (
+	"context"
+	"time"
+)
+
+// A Message is a message received from a subscription.
+type Message interface {
+	// ID returns the server assigned message id.
+	ID() string
+	// Data returns the message payload.
+	Data() []byte
+	// Attributes returns the message attributes.
+	Attributes() map[string]string
+	// Ack acknowledges the message, it will not be redelivered.
+	Ack()
+	// Nack negatively acknowledges the message, it will be redelivered.
+	Nack()
+}
+
+// ReceiveSettings configures how messages are received from a subscription, adapters map these onto
+// their client libraries receive settings.
+type ReceiveSettings struct {
+	// MaxOutstandingMessages is the maximum number of messages received but not yet acked.
+	MaxOutstandingMessages int
+	// MaxOutstandingBytes is the maximum size of messages received but not yet acked.
+	MaxOutstandingBytes int
+	// MaxExtension is the maximum period the ack deadline of a message is extended for.
+	MaxExtension time.Duration
+	// NumGoroutines is the number of go routines pulling messages.
+	NumGoroutines int
+}
+
+// A Subscription is a Pub/Sub subscription. Foundation does not depend on the Pub/Sub client library,
+// instead a Subscription is implemented as a thin adapter over *pubsub.Subscription from
+// cloud.google.com/go/pubsub which converts received messages to Message.
+type Subscription interface {
+	// ID returns the subscription id.
+	ID() string
+	// Exists reports whether the subscription exists, an error is returned if the caller does not have
+	// permission to access the subscription.
+	Exists(ctx context.Context) (bool, error)
+	// Receive calls fn for each message until the context is done or an unrecoverable error occurs,
+	// returning once all calls to fn have returned.
+	Receive(ctx context.Context, settings ReceiveSettings, fn func(context.Context, Message)) error
+}
@@ -0,0 +1,28 @@
+package redisstream
+
+import (
+	"context"
+	"time"
+)
+
+// An Entry is an entry read from a stream.
+type Entry struct {
+	ID     string
+	Values map[string]any
+}
+
+// A Client issues stream commands to Redis. Foundation does not depend on a Redis library, instead a
+// Client is implemented as a thin adapter over a library such as go-redis or rueidis.
+type Client interface {
+	// CreateGroup creates the consumer group and the stream if it does not exist, creating a group
+	// which already exists is not an error.
+	CreateGroup(ctx context.Context, stream, group string) error
+	// ReadGroup reads up to count new entries for the consumer, blocking for up to block or until the
+	// context is done when no entries are available (XREADGROUP ... STREAMS stream >).
+	ReadGroup(ctx context.Context, stream, group, consumer string, count int64, block time.Duration) ([]Entry, error)
+	// Ack acknowledges the entries removing them from the groups pending entries list (XACK).
+	Ack(ctx context.Context, stream, group string, ids ...string) error
+	// AutoClaim transfers up to count pending entries idle for at least minIdle to the consumer starting
+	// from the given cursor, returning the claimed entries and the next cursor (XAUTOCLAIM).
+	AutoClaim(ctx context.Context, stream, group, consumer string, minIdle time.Duration, start string, count int64) ([]Entry, string, error)
+}
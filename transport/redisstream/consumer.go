@@ -0,0 +1,327 @@
+package redisstream
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"time"
+
+	"go.krak3n.io/foundation"
+	"go.krak3n.io/foundation/consumer"
+	"go.krak3n.io/foundation/health/probe"
+	"go.krak3n.io/foundation/metrics"
+	"go.krak3n.io/foundation/tick"
+)
+
+// A HandlerFunc handles a stream entry. The entry is acked if the handler returns nil, on error it is
+// retried with backoff and left pending once retries are exhausted so it can later be claimed.
+type HandlerFunc func(ctx context.Context, entry Entry) error
+
+// A RunnerOption configures the Redis Streams Runner.
+type RunnerOption interface {
+	applyRunnerConfig(*runnerConfig)
+}
+
+// RunnerOptions is one or more RunnerOption.
+type RunnerOptions []RunnerOption
+
+func (o RunnerOptions) applyRunnerConfig(cfg *runnerConfig) {
+	for opt := range slices.Values(o) {
+		if opt != nil {
+			opt.applyRunnerConfig(cfg)
+		}
+	}
+}
+
+type runnerConfigFunc func(*runnerConfig)
+
+func (f runnerConfigFunc) applyRunnerConfig(cfg *runnerConfig) {
+	f(cfg)
+}
+
+// runnerConfig holds the configuration for the Redis Streams Runner.
+type runnerConfig struct {
+	consumer        string
+	count           int64
+	block           time.Duration
+	concurrency     int
+	retries         uint8
+	backoff         tick.Backoff
+	claimInterval   time.Duration
+	claimMinIdle    time.Duration
+	shutdownTimeout time.Duration
+	sensorMode      probe.Mode
+}
+
+// WithConsumer sets the consumer name within the group, defaults to the hostname and process id.
+// Consumer names should be stable across restarts so pending entries are not orphaned.
+func WithConsumer(name string) RunnerOption {
+	return runnerConfigFunc(func(cfg *runnerConfig) {
+		cfg.consumer = name
+	})
+}
+
+// WithCount sets the maximum number of entries read at once, defaults to 10.
+func WithCount(n int64) RunnerOption {
+	return runnerConfigFunc(func(cfg *runnerConfig) {
+		cfg.count = n
+	})
+}
+
+// WithBlock sets how long a read blocks waiting for new entries, defaults to 5 seconds.
+func WithBlock(d time.Duration) RunnerOption {
+	return runnerConfigFunc(func(cfg *runnerConfig) {
+		cfg.block = d
+	})
+}
+
+// WithConcurrency sets the number of entries handled concurrently, defaults to 1 which handles entries
+// in stream order.
+func WithConcurrency(n int) RunnerOption {
+	return runnerConfigFunc(func(cfg *runnerConfig) {
+		cfg.concurrency = n
+	})
+}
+
+// WithRetries sets the number of times an entry is retried when the handler returns an error, defaults
+// to 3.
+func WithRetries(n uint8) RunnerOption {
+	return runnerConfigFunc(func(cfg *runnerConfig) {
+		cfg.retries = n
+	})
+}
+
+// WithBackoff sets the backoff used between handler retries and read attempts on error, defaults to an
+// exponential backoff with a scalar of 100ms and 20% jitter.
+func WithBackoff(backoff tick.Backoff) RunnerOption {
+	return runnerConfigFunc(func(cfg *runnerConfig) {
+		cfg.backoff = backoff
+	})
+}
+
+// WithClaim sets how often pending entries are claimed and how long an entry must have been idle
+// before it is claimed from another consumer, defaults to every 30 seconds for entries idle for a minute.
+func WithClaim(interval, minIdle time.Duration) RunnerOption {
+	return runnerConfigFunc(func(cfg *runnerConfig) {
+		cfg.claimInterval = interval
+		cfg.claimMinIdle = minIdle
+	})
+}
+
+// WithShutdownTimeout sets how long to wait on stop for in flight entries to be handled before the
+// handler context is cancelled, defaults to 30 seconds.
+func WithShutdownTimeout(d time.Duration) RunnerOption {
+	return runnerConfigFunc(func(cfg *runnerConfig) {
+		cfg.shutdownTimeout = d
+	})
+}
+
+// WithSensorMode sets the mode of the consumers sensor, defaults to probe.ReadinessMode.
+func WithSensorMode(mode probe.Mode) RunnerOption {
+	return runnerConfigFunc(func(cfg *runnerConfig) {
+		cfg.sensorMode = mode
+	})
+}
+
+// A Runner is a foundation.Runner which consumes a Redis Stream as a member of a consumer group, handing
+// entries to a consumer.Runner which handles them with retries and drains them on stop.
+type Runner struct {
+	client  Client
+	stream  string
+	group   string
+	handler HandlerFunc
+	opts    []RunnerOption
+
+	acked   metrics.Counter
+	failed  metrics.Counter
+	claimed metrics.Counter
+}
+
+// Run returns a Runner which consumes the stream as part of the group calling the handler for each entry.
+func Run(client Client, stream, group string, handler HandlerFunc, opts ...RunnerOption) *Runner {
+	labels := func(status string) metrics.Labels {
+		return metrics.Labels{"stream": stream, "group": group, "status": status}
+	}
+
+	return &Runner{
+		client:  client,
+		stream:  stream,
+		group:   group,
+		handler: handler,
+		opts:    opts,
+		acked:   metrics.NewCounter("redis_stream_entries_total", labels("acked")),
+		failed:  metrics.NewCounter("redis_stream_entries_total", labels("failed")),
+		claimed: metrics.NewCounter("redis_stream_entries_total", labels("claimed")),
+	}
+}
+
+// Run creates the consumer group and consumes the stream until told to stop. Pending entries idle for
+// too long, for example those of a consumer which died, are periodically claimed. On stop reading ends
+// and in flight entries are handled before the runner exits.
+func (r *Runner) Run(ctx context.Context, f foundation.F) {
+	hostname, _ := os.Hostname()
+
+	cfg := runnerConfig{
+		consumer:        fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+		count:           10,
+		block:           5 * time.Second,
+		concurrency:     1,
+		retries:         3,
+		backoff:         tick.ExponentialBackoff(100*time.Millisecond, tick.WithJitter(0.2)),
+		claimInterval:   30 * time.Second,
+		claimMinIdle:    time.Minute,
+		shutdownTimeout: 30 * time.Second,
+		sensorMode:      probe.ReadinessMode,
+	}
+
+	RunnerOptions(r.opts).applyRunnerConfig(&cfg)
+
+	if err := r.client.CreateGroup(ctx, r.stream, r.group); err != nil {
+		f.Error(fmt.Errorf("create group %s on stream %s: %w", r.group, r.stream, err))
+	}
+
+	src := &source{
+		runner: r,
+		cfg:    cfg,
+		claim:  time.Now().Add(cfg.claimInterval),
+	}
+
+	handler := consumer.HandlerFunc(func(ctx context.Context, msg consumer.Message) error {
+		return r.handler(ctx, msg.(*message).entry)
+	})
+
+	consumer.Run(fmt.Sprintf("%s/%s", r.stream, r.group), src, handler,
+		consumer.WithConcurrency(cfg.concurrency),
+		consumer.WithQueueSize(int(cfg.count)),
+		consumer.WithRetries(cfg.retries),
+		consumer.WithBackoff(cfg.backoff),
+		consumer.WithShutdownTimeout(cfg.shutdownTimeout),
+		consumer.WithSensorName(fmt.Sprintf("redis.stream[%s/%s]", r.stream, r.group)),
+		consumer.WithSensorMode(cfg.sensorMode),
+	).Run(ctx, f)
+}
+
+// source is the consumer.Source of the Runner, reading new entries for the consumer and periodically
+// claiming entries which have been pending for longer than the minimum idle time.
+type source struct {
+	runner *Runner
+	cfg    runnerConfig
+	// When pending entries are next claimed.
+	claim time.Time
+}
+
+// Receive claims pending entries if due, otherwise reads new entries.
+func (s *source) Receive(ctx context.Context) ([]consumer.Message, error) {
+	r := s.runner
+
+	if !time.Now().Before(s.claim) {
+		s.claim = time.Now().Add(s.cfg.claimInterval)
+
+		if msgs := s.claimPending(ctx); len(msgs) > 0 {
+			return msgs, nil
+		}
+	}
+
+	entries, err := r.client.ReadGroup(ctx, r.stream, r.group, s.cfg.consumer, s.cfg.count, s.cfg.block)
+	if err != nil {
+		return nil, fmt.Errorf("read: %w", err)
+	}
+
+	return s.messages(entries), nil
+}
+
+// claimPending claims entries which have been pending for longer than the minimum idle time, claim
+// failures are logged as new entries can still be read.
+func (s *source) claimPending(ctx context.Context) []consumer.Message {
+	r := s.runner
+
+	var msgs []consumer.Message
+
+	cursor := "0-0"
+
+	for {
+		entries, next, err := r.client.AutoClaim(ctx, r.stream, r.group, s.cfg.consumer, s.cfg.claimMinIdle, cursor, s.cfg.count)
+		if err != nil {
+			if ctx.Err() == nil {
+				slog.WarnContext(ctx, "redis stream claim failed", slog.String("stream", r.stream), slog.String("group", r.group), slog.String("err", err.Error()))
+			}
+
+			return msgs
+		}
+
+		r.claimed.Add(float64(len(entries)))
+
+		msgs = append(msgs, s.messages(entries)...)
+
+		if next == "0-0" || next == "" {
+			return msgs
+		}
+
+		cursor = next
+	}
+}
+
+// messages returns the entries as messages.
+func (s *source) messages(entries []Entry) []consumer.Message {
+	msgs := make([]consumer.Message, 0, len(entries))
+
+	for _, entry := range entries {
+		msgs = append(msgs, &message{entry: entry, runner: s.runner})
+	}
+
+	return msgs
+}
+
+// Close does nothing, entries left unacked remain pending to be claimed.
+func (s *source) Close() error {
+	return nil
+}
+
+// message is an Entry as a consumer.Message. Nacking it leaves it pending so it can later be claimed.
+type message struct {
+	entry  Entry
+	runner *Runner
+}
+
+// ID returns the id of the entry.
+func (m *message) ID() string {
+	return m.entry.ID
+}
+
+// Body returns nil, the entry has values rather than a payload.
+func (m *message) Body() []byte {
+	return nil
+}
+
+// Metadata returns the values of the entry formatted as strings.
+func (m *message) Metadata() map[string]string {
+	md := make(map[string]string, len(m.entry.Values))
+
+	for k, v := range m.entry.Values {
+		md[k] = fmt.Sprint(v)
+	}
+
+	return md
+}
+
+// Ack acknowledges the entry removing it from the groups pending entries list.
+func (m *message) Ack(ctx context.Context) error {
+	r := m.runner
+
+	if err := r.client.Ack(ctx, r.stream, r.group, m.entry.ID); err != nil {
+		return err
+	}
+
+	r.acked.Add(1)
+
+	return nil
+}
+
+// Nack leaves the entry pending.
+func (m *message) Nack(context.Context) error {
+	m.runner.failed.Add(1)
+
+	return nil
+}
@@ -0,0 +1,343 @@
+package tcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"runtime/debug"
+	"slices"
+	"sync"
+	"time"
+
+	"go.krak3n.io/foundation"
+	"go.krak3n.io/foundation/health/probe"
+	"go.krak3n.io/foundation/metrics"
+	"go.krak3n.io/foundation/transport/internal/boundaddr"
+)
+
+// A HandlerFunc handles a connection. The context is cancelled when the runner is told to stop, the
+// handler should then finish up and return. The connection is closed once the handler returns, or if it
+// panics, the panic being reported as a transient foundation.RuntimeError so one bad connection can not
+// take down the service.
+type HandlerFunc func(ctx context.Context, conn net.Conn)
+
+// A RunnerOption configures the TCP Runner.
+type RunnerOption interface {
+	applyRunnerConfig(*runnerConfig)
+}
+
+// RunnerOptions is one or more RunnerOption.
+type RunnerOptions []RunnerOption
+
+func (o RunnerOptions) applyRunnerConfig(cfg *runnerConfig) {
+	for opt := range slices.Values(o) {
+		if opt != nil {
+			opt.applyRunnerConfig(cfg)
+		}
+	}
+}
+
+type runnerConfigFunc func(*runnerConfig)
+
+func (f runnerConfigFunc) applyRunnerConfig(cfg *runnerConfig) {
+	f(cfg)
+}
+
+// runnerConfig holds the configuration for the TCP Runner.
+type runnerConfig struct {
+	maxConns        int
+	shutdownTimeout time.Duration
+	sensor          bool
+	listen          ListenFunc
+}
+
+// WithMaxConns limits the number of connections handled concurrently, once the limit is reached new
+// connections are not accepted until an existing connection is closed.
+func WithMaxConns(n int) RunnerOption {
+	return runnerConfigFunc(func(cfg *runnerConfig) {
+		cfg.maxConns = n
+	})
+}
+
+// WithShutdownTimeout sets how long to wait on stop for handlers to return before their connections are
+// forcibly closed, defaults to 10 seconds.
+func WithShutdownTimeout(d time.Duration) RunnerOption {
+	return runnerConfigFunc(func(cfg *runnerConfig) {
+		cfg.shutdownTimeout = d
+	})
+}
+
+// A ListenFunc listens on the network address, see WithListenFunc.
+type ListenFunc func(network, addr string) (net.Listener, error)
+
+// WithListenFunc sets the function the runner listens with, defaults to net.Listen. For example
+// upgrade.Listen to inherit the listener across an in place restart.
+func WithListenFunc(fn ListenFunc) RunnerOption {
+	return runnerConfigFunc(func(cfg *runnerConfig) {
+		cfg.listen = fn
+	})
+}
+
+// WithoutSensor stops the runner from registering a health probe sensor for the server.
+func WithoutSensor() RunnerOption {
+	return runnerConfigFunc(func(cfg *runnerConfig) {
+		cfg.sensor = false
+	})
+}
+
+// A Runner is a foundation.Runner which accepts TCP connections calling a handler for each connection
+// in its own go routine.
+type Runner struct {
+	addr    string
+	handler HandlerFunc
+	opts    []RunnerOption
+	mtx     sync.RWMutex
+	bound   string
+}
+
+// Run returns a Runner which listens on the given address calling the handler for each connection.
+func Run(addr string, handler HandlerFunc, opts ...RunnerOption) *Runner {
+	return &Runner{
+		addr:    addr,
+		handler: handler,
+		opts:    opts,
+	}
+}
+
+// BoundAddr returns the address the server is listening on, which when configured to listen on port 0
+// will be the port assigned by the operating system. Returns an empty string until the server is
+// listening, which is guaranteed once F.Run has returned for the Runner.
+func (r *Runner) BoundAddr() string {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	return r.bound
+}
+
+// Run listens on the address and accepts connections until told to stop. Once listening the bound
+// address is published to the value store, see BoundAddr, and the runner marked as parallel.
+func (r *Runner) Run(ctx context.Context, f foundation.F) {
+	cfg := runnerConfig{
+		shutdownTimeout: 10 * time.Second,
+		sensor:          true,
+		listen:          net.Listen,
+	}
+
+	RunnerOptions(r.opts).applyRunnerConfig(&cfg)
+
+	ln, err := cfg.listen("tcp", r.addr)
+	if err != nil {
+		f.Error(fmt.Errorf("listen on %s: %w", r.addr, err))
+	}
+
+	bound := ln.Addr().String()
+
+	r.mtx.Lock()
+	r.bound = bound
+	r.mtx.Unlock()
+
+	boundaddr.Publish(f, "tcp", r, r.addr, bound)
+
+	srv := &server{
+		f:       f,
+		handler: r.handler,
+		served:  make(chan struct{}),
+		conns:   make(map[net.Conn]struct{}),
+		open:    metrics.NewGauge("tcp_server_open_connections", metrics.Labels{"addr": bound}),
+	}
+
+	if cfg.maxConns > 0 {
+		srv.sem = make(chan struct{}, cfg.maxConns)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+
+	f.On().Stop(func() {
+		// Stop accepting new connections then tell handlers to finish up.
+		if err := ln.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
+			slog.Warn("failed to close tcp listener", slog.String("addr", bound), slog.String("err", err.Error()))
+		}
+
+		cancel()
+
+		// No more connections are tracked once the accept loop has exited.
+		<-srv.served
+
+		if !srv.wait(cfg.shutdownTimeout) {
+			slog.Warn("tcp server shutdown timeout exceeded, closing connections", slog.String("addr", bound))
+			srv.closeAll()
+		}
+	})
+
+	if cfg.sensor {
+		probe.RegisterContext(ctx, Sensor(bound))
+	}
+
+	f.Parallel() // Mark the Runner as parallel now we are going start blocking
+
+	srv.serve(ctx, ln)
+}
+
+// BoundAddr returns the bound address of the server configured with the given address from the F's
+// value store, for example BoundAddr(f, "127.0.0.1:0"). Reports false if several servers are configured
+// with the address, their bound addresses are then only available from their Runner, see
+// Runner.BoundAddr.
+func BoundAddr(f foundation.F, addr string) (string, bool) {
+	return boundaddr.Lookup(f, "tcp", addr)
+}
+
+// Sensor returns a health probe sensor which dials the given address, the sensor is healthy if a
+// connection can be established.
+func Sensor(addr string) probe.Sensor {
+	return probe.NewSensor(fmt.Sprintf("tcp.server[%s]", addr), probe.AllModes, func(ctx context.Context) error {
+		var d net.Dialer
+
+		conn, err := d.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return fmt.Errorf("dial: %w", err)
+		}
+
+		if err := conn.Close(); err != nil {
+			return fmt.Errorf("close connection: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// server accepts connections and tracks them until their handler returns.
+type server struct {
+	f       foundation.F
+	handler HandlerFunc
+	served  chan struct{}
+	sem     chan struct{}
+	wg      sync.WaitGroup
+	mtx     sync.Mutex
+	conns   map[net.Conn]struct{}
+	open    metrics.Gauge
+}
+
+// serve accepts connections until the listener is closed.
+func (s *server) serve(ctx context.Context, ln net.Listener) {
+	defer close(s.served)
+
+	var delay time.Duration
+
+	for {
+		if s.sem != nil {
+			select {
+			case s.sem <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		conn, err := ln.Accept()
+		if err != nil {
+			s.release()
+
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+
+			// Back off on accept errors, for example running out of file descriptors.
+			delay = min(max(delay*2, 5*time.Millisecond), time.Second)
+
+			slog.Warn("tcp accept error", slog.String("err", err.Error()), slog.Duration("retry", delay))
+
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return
+			}
+
+			continue
+		}
+
+		delay = 0
+
+		s.track(conn)
+
+		go func() {
+			defer s.release()
+			defer s.untrack(conn)
+			defer s.recoverHandler(conn)
+
+			s.handler(ctx, conn)
+		}()
+	}
+}
+
+// recoverHandler reports a panic in the handler of the connection.
+func (s *server) recoverHandler(conn net.Conn) {
+	rec := recover()
+	if rec == nil {
+		return
+	}
+
+	foundation.Report(s.f, foundation.Transient(foundation.RuntimeError{
+		Cause:  foundation.PanicError{Cause: rec},
+		Stack:  debug.Stack(),
+		Runner: s.f.Name(),
+		Attrs: []slog.Attr{
+			slog.String("tcp.remote_addr", conn.RemoteAddr().String()),
+		},
+	}))
+}
+
+func (s *server) release() {
+	if s.sem != nil {
+		<-s.sem
+	}
+}
+
+func (s *server) track(conn net.Conn) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.wg.Add(1)
+	s.conns[conn] = struct{}{}
+	s.open.Set(float64(len(s.conns)))
+}
+
+func (s *server) untrack(conn net.Conn) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if err := conn.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
+		slog.Debug("failed to close tcp connection", slog.String("err", err.Error()))
+	}
+
+	delete(s.conns, conn)
+	s.open.Set(float64(len(s.conns)))
+	s.wg.Done()
+}
+
+// wait waits for all handlers to return, returns false if the timeout is exceeded.
+func (s *server) wait(timeout time.Duration) bool {
+	done := make(chan struct{})
+
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// closeAll forcibly closes all open connections.
+func (s *server) closeAll() {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	for conn := range s.conns {
+		conn.Close()
+	}
+}
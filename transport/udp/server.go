@@ -0,0 +1,311 @@
+package udp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"runtime"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.krak3n.io/foundation"
+	"go.krak3n.io/foundation/health/probe"
+	"go.krak3n.io/foundation/metrics"
+	"go.krak3n.io/foundation/transport/internal/boundaddr"
+)
+
+// A Packet is a datagram received by the server.
+type Packet struct {
+	// Data is the datagram payload, it is owned by the handler.
+	Data []byte
+	// Addr is the address the datagram was received from.
+	Addr net.Addr
+	// Conn is the servers connection which can be used to reply to Addr.
+	Conn net.PacketConn
+}
+
+// A HandlerFunc handles a packet. The context is cancelled if the shutdown timeout is exceeded.
+type HandlerFunc func(ctx context.Context, pkt Packet)
+
+// A RunnerOption configures the UDP Runner.
+type RunnerOption interface {
+	applyRunnerConfig(*runnerConfig)
+}
+
+// RunnerOptions is one or more RunnerOption.
+type RunnerOptions []RunnerOption
+
+func (o RunnerOptions) applyRunnerConfig(cfg *runnerConfig) {
+	for opt := range slices.Values(o) {
+		if opt != nil {
+			opt.applyRunnerConfig(cfg)
+		}
+	}
+}
+
+type runnerConfigFunc func(*runnerConfig)
+
+func (f runnerConfigFunc) applyRunnerConfig(cfg *runnerConfig) {
+	f(cfg)
+}
+
+// runnerConfig holds the configuration for the UDP Runner.
+type runnerConfig struct {
+	workers         int
+	queueSize       int
+	bufferSize      int
+	shutdownTimeout time.Duration
+	sensor          bool
+}
+
+// WithWorkers sets the number of workers processing packets, defaults to the number of CPUs.
+func WithWorkers(n int) RunnerOption {
+	return runnerConfigFunc(func(cfg *runnerConfig) {
+		cfg.workers = n
+	})
+}
+
+// WithQueueSize sets the number of packets which can be queued waiting for a worker, packets received
+// whilst the queue is full are dropped. Defaults to 1024.
+func WithQueueSize(n int) RunnerOption {
+	return runnerConfigFunc(func(cfg *runnerConfig) {
+		cfg.queueSize = n
+	})
+}
+
+// WithBufferSize sets the maximum size of a datagram, larger datagrams are truncated. Defaults to 65535.
+func WithBufferSize(n int) RunnerOption {
+	return runnerConfigFunc(func(cfg *runnerConfig) {
+		cfg.bufferSize = n
+	})
+}
+
+// WithShutdownTimeout sets how long to wait on stop for queued packets to be processed before the
+// handler context is cancelled, defaults to 10 seconds.
+func WithShutdownTimeout(d time.Duration) RunnerOption {
+	return runnerConfigFunc(func(cfg *runnerConfig) {
+		cfg.shutdownTimeout = d
+	})
+}
+
+// WithoutSensor stops the runner from registering a health probe sensor for the server.
+func WithoutSensor() RunnerOption {
+	return runnerConfigFunc(func(cfg *runnerConfig) {
+		cfg.sensor = false
+	})
+}
+
+// A Runner is a foundation.Runner which reads UDP datagrams passing them to a pool of workers.
+type Runner struct {
+	addr    string
+	handler HandlerFunc
+	opts    []RunnerOption
+	mtx     sync.RWMutex
+	bound   string
+}
+
+// Run returns a Runner which listens on the given address calling the handler for each packet.
+func Run(addr string, handler HandlerFunc, opts ...RunnerOption) *Runner {
+	return &Runner{
+		addr:    addr,
+		handler: handler,
+		opts:    opts,
+	}
+}
+
+// BoundAddr returns the address the server is listening on. Returns an empty string until the server is
+// listening, which is guaranteed once F.Run has returned for the Runner.
+func (r *Runner) BoundAddr() string {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	return r.bound
+}
+
+// Run listens on the address and reads packets until told to stop, on stop queued packets are processed
+// before the runner exits.
+func (r *Runner) Run(ctx context.Context, f foundation.F) {
+	cfg := runnerConfig{
+		workers:         runtime.NumCPU(),
+		queueSize:       1024,
+		bufferSize:      65535,
+		shutdownTimeout: 10 * time.Second,
+		sensor:          true,
+	}
+
+	RunnerOptions(r.opts).applyRunnerConfig(&cfg)
+
+	conn, err := net.ListenPacket("udp", r.addr)
+	if err != nil {
+		f.Error(fmt.Errorf("listen on %s: %w", r.addr, err))
+	}
+
+	bound := conn.LocalAddr().String()
+
+	r.mtx.Lock()
+	r.bound = bound
+	r.mtx.Unlock()
+
+	boundaddr.Publish(f, "udp", r, r.addr, bound)
+
+	srv := &server{
+		conn:    conn,
+		handler: r.handler,
+		queue:   make(chan Packet, cfg.queueSize),
+		dropped: metrics.NewCounter("udp_server_dropped_packets_total", metrics.Labels{"addr": bound}),
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+
+	f.On().Stop(func() {
+		// Stop reading, the read loop then closes the queue letting the workers finish.
+		srv.stopping.Store(true)
+
+		if err := conn.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
+			slog.Warn("failed to close udp connection", slog.String("addr", bound), slog.String("err", err.Error()))
+		}
+
+		if !srv.wait(cfg.shutdownTimeout) {
+			slog.Warn("udp server shutdown timeout exceeded, cancelling handlers", slog.String("addr", bound))
+		}
+
+		cancel()
+	})
+
+	if cfg.sensor {
+		probe.RegisterContext(ctx, srv.sensor(bound))
+	}
+
+	for range cfg.workers {
+		srv.wg.Add(1)
+
+		go srv.work(ctx)
+	}
+
+	f.Parallel() // Mark the Runner as parallel now we are going start blocking
+
+	srv.read(cfg.bufferSize)
+}
+
+// BoundAddr returns the bound address of the server configured with the given address from the F's
+// value store, for example BoundAddr(f, "127.0.0.1:0"). Reports false if several servers are configured
+// with the address, their bound addresses are then only available from their Runner, see
+// Runner.BoundAddr.
+func BoundAddr(f foundation.F, addr string) (string, bool) {
+	return boundaddr.Lookup(f, "udp", addr)
+}
+
+// server reads packets and dispatches them to workers.
+type server struct {
+	conn     net.PacketConn
+	handler  HandlerFunc
+	queue    chan Packet
+	wg       sync.WaitGroup
+	reading  atomic.Bool
+	stopping atomic.Bool
+	err      atomic.Pointer[error]
+	dropped  metrics.Counter
+}
+
+// read reads packets until the connection is closed.
+func (s *server) read(size int) {
+	defer close(s.queue)
+
+	s.reading.Store(true)
+	defer s.reading.Store(false)
+
+	buf := make([]byte, size)
+
+	var delay time.Duration
+
+	for {
+		n, addr, err := s.conn.ReadFrom(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+
+			s.err.Store(&err)
+
+			// Back off on read errors so a persistent error does not spin the loop.
+			delay = min(max(delay*2, 5*time.Millisecond), time.Second)
+
+			slog.Warn("udp read error", slog.String("err", err.Error()), slog.Duration("retry", delay))
+			time.Sleep(delay)
+
+			continue
+		}
+
+		delay = 0
+
+		s.err.Store(nil)
+
+		pkt := Packet{
+			Data: slices.Clone(buf[:n]),
+			Addr: addr,
+			Conn: s.conn,
+		}
+
+		select {
+		case s.queue <- pkt:
+		default:
+			s.dropped.Add(1)
+		}
+	}
+}
+
+// work processes packets from the queue until it is closed.
+func (s *server) work(ctx context.Context) {
+	defer s.wg.Done()
+
+	for pkt := range s.queue {
+		s.handle(ctx, pkt)
+	}
+}
+
+// handle calls the handler recovering any panic so a bad packet does not kill the worker.
+func (s *server) handle(ctx context.Context, pkt Packet) {
+	defer func() {
+		if r := recover(); r != nil {
+			slog.ErrorContext(ctx, "udp handler panic", slog.Any("panic", r), slog.String("addr", pkt.Addr.String()))
+		}
+	}()
+
+	s.handler(ctx, pkt)
+}
+
+// wait waits for all workers to exit, returns false if the timeout is exceeded.
+func (s *server) wait(timeout time.Duration) bool {
+	done := make(chan struct{})
+
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// sensor returns a health probe sensor which is healthy whilst the socket is being read without error.
+func (s *server) sensor(addr string) probe.Sensor {
+	return probe.NewSensor(fmt.Sprintf("udp.server[%s]", addr), probe.AllModes, func(context.Context) error {
+		if !s.reading.Load() && !s.stopping.Load() {
+			return errors.New("udp socket is not being read")
+		}
+
+		if err := s.err.Load(); err != nil {
+			return fmt.Errorf("udp socket read: %w", *err)
+		}
+
+		return nil
+	})
+}
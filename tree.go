@@ -0,0 +1,66 @@
+package foundation
+
+// A Node is a point in time snapshot of an F within the runner tree.
+type Node struct {
+	// Name of the F.
+	Name string `json:"name"`
+	// Parallel indicates the F has been marked as a parallel routine.
+	Parallel bool `json:"parallel"`
+	// Stopped indicates the F has been told to stop.
+	Stopped bool `json:"stopped"`
+	// Done indicates the F has stopped and finished execution.
+	Done bool `json:"done"`
+	// Erred indicates the F, or one of its children, encountered an error.
+	Erred bool `json:"erred"`
+	// Goroutines is the number of go routines run by the F and its sub functions, only counted when
+	// accounting, see WithAccounting.
+	Goroutines int `json:"goroutines,omitempty"`
+	// Children are the sub functions of the F in the order they were run.
+	Children []Node `json:"children,omitempty"`
+}
+
+// Tree returns a snapshot of the whole runner tree the given F belongs to starting at the root.
+// If the F was not created by foundation an empty Node is returned.
+func Tree(v F) Node {
+	f, ok := v.(*f)
+	if !ok {
+		return Node{}
+	}
+
+	for f.parent != nil {
+		f = f.parent
+	}
+
+	var counts map[string]int
+
+	if f.accounting {
+		counts = goroutines()
+	}
+
+	return f.node(counts)
+}
+
+// node builds a snapshot of f and its sub functions, counting their go routines from the given counts
+// per runner name.
+func (f *f) node(counts map[string]int) Node {
+	f.mtx.RLock()
+	defer f.mtx.RUnlock()
+
+	n := Node{
+		Name:       f.name,
+		Parallel:   f.parallel,
+		Stopped:    f.stopped.Load(),
+		Done:       f.done.Load(),
+		Erred:      f.erred.Load(),
+		Goroutines: counts[f.name],
+	}
+
+	for _, sub := range f.subs {
+		child := sub.node(counts)
+
+		n.Goroutines += child.Goroutines
+		n.Children = append(n.Children, child)
+	}
+
+	return n
+}
@@ -0,0 +1,19 @@
+//go:build !unix
+
+package upgrade
+
+import (
+	"errors"
+	"os"
+)
+
+// supported reports whether upgrades are supported on this platform.
+const supported = false
+
+// upgradeSignal is nil as there are no user signals on this platform, see WithSignal.
+var upgradeSignal os.Signal
+
+// terminate is not supported on this platform.
+func terminate() error {
+	return errors.ErrUnsupported
+}
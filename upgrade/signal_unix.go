@@ -0,0 +1,19 @@
+//go:build unix
+
+package upgrade
+
+import (
+	"os"
+	"syscall"
+)
+
+// supported reports whether upgrades are supported on this platform.
+const supported = true
+
+// upgradeSignal is the default signal which triggers an upgrade, see WithSignal.
+var upgradeSignal os.Signal = syscall.SIGUSR2
+
+// terminate stops the process as a supervisor would, through foundation's signal handling.
+func terminate() error {
+	return syscall.Kill(os.Getpid(), syscall.SIGTERM)
+}
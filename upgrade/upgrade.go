@@ -0,0 +1,421 @@
+// Package upgrade restarts a service in place with a new binary without dropping connections, for zero
+// downtime deploys outside of Kubernetes such as on VMs managed by systemd.
+//
+// Servers listen with Listen, for example with http.WithListenFunc(upgrade.Listen), so their listeners
+// can be handed to a new process. On upgrade the running binary is executed again, typically having
+// been replaced on disk, inheriting the listeners. Once the new process is ready the old process stops,
+// draining in flight requests, whilst the new process accepts connections on the same sockets.
+//
+//	foundation.Run("service", foundation.RunFunc(func(ctx context.Context, f foundation.F) {
+//		f.Run(ctx,
+//			http.Run(handler, http.WithListenFunc(upgrade.Listen)),
+//			upgrade.Run(upgrade.WithPIDFile("/run/service.pid")))
+//	}))
+//
+// The Runner should be run after the servers so the new process only reports ready once they are
+// listening. Upgrades are only supported on unix platforms.
+package upgrade
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.krak3n.io/foundation"
+)
+
+// Environment variables passing the inherited listeners and readiness pipe to the new process.
+const (
+	// EnvListeners lists the inherited listeners as network://address, in the order of their file
+	// descriptors from 3.
+	EnvListeners = "FOUNDATION_UPGRADE_LISTENERS"
+	// EnvReady is the file descriptor the new process writes to once ready.
+	EnvReady = "FOUNDATION_UPGRADE_READY"
+)
+
+// ErrUpgrading is returned by Upgrade if an upgrade is already in progress.
+var ErrUpgrading = errors.New("upgrade already in progress")
+
+// An Option configures the Runner.
+type Option interface {
+	apply(*config)
+}
+
+// Options is one or more Option.
+type Options []Option
+
+func (opts Options) apply(cfg *config) {
+	for opt := range slices.Values(opts) {
+		if opt != nil {
+			opt.apply(cfg)
+		}
+	}
+}
+
+type optionFunc func(*config)
+
+func (f optionFunc) apply(cfg *config) {
+	f(cfg)
+}
+
+// config holds the Runner configuration.
+type config struct {
+	signal  os.Signal
+	timeout time.Duration
+	pidFile string
+}
+
+// WithSignal sets the signal which triggers an upgrade, defaults to SIGUSR2. Note SIGUSR2 is also used
+// by logging.WithLevelSignals.
+func WithSignal(sig os.Signal) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.signal = sig
+	})
+}
+
+// WithReadyTimeout sets how long the new process is given to become ready before it is killed and the
+// upgrade abandoned, defaults to 1 minute.
+func WithReadyTimeout(d time.Duration) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.timeout = d
+	})
+}
+
+// WithPIDFile writes the process id to the file once ready, so supervisors such as systemd with
+// PIDFile= follow the service to the new process.
+func WithPIDFile(path string) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.pidFile = path
+	})
+}
+
+// listener is a listener created with Listen.
+type listener struct {
+	key string
+	ln  net.Listener
+}
+
+var (
+	mtx       sync.Mutex
+	listeners []listener
+	inherited map[string]*os.File
+	upgrading bool
+	once      sync.Once
+)
+
+// Listen listens on the network address, as net.Listen does, inheriting the listener from the previous
+// process if started by an upgrade. The listener is passed on to the next process on upgrade. Only
+// listeners which are backed by a file, such as TCP and unix listeners, can be passed on.
+func Listen(network, addr string) (net.Listener, error) {
+	once.Do(inherit)
+
+	key := network + "://" + addr
+
+	mtx.Lock()
+	defer mtx.Unlock()
+
+	var (
+		ln  net.Listener
+		err error
+	)
+
+	if file, ok := inherited[key]; ok {
+		delete(inherited, key)
+
+		ln, err = net.FileListener(file)
+		file.Close()
+
+		if err != nil {
+			return nil, fmt.Errorf("inherit listener %s: %w", key, err)
+		}
+
+		slog.Info("inherited listener", slog.String("listener", key))
+	} else {
+		if ln, err = net.Listen(network, addr); err != nil {
+			return nil, err
+		}
+	}
+
+	listeners = append(listeners, listener{key: key, ln: ln})
+
+	return ln, nil
+}
+
+// Inherited reports whether the process was started by an upgrade.
+func Inherited() bool {
+	_, ok := os.LookupEnv(EnvReady)
+
+	return ok
+}
+
+// inherit reads the listeners inherited from the previous process.
+func inherit() {
+	mtx.Lock()
+	defer mtx.Unlock()
+
+	inherited = make(map[string]*os.File)
+
+	v := os.Getenv(EnvListeners)
+	if v == "" {
+		return
+	}
+
+	for i, key := range strings.Split(v, ",") {
+		inherited[key] = os.NewFile(uintptr(3+i), key)
+	}
+}
+
+// Ready tells the previous process this process is ready, so it stops, closing any inherited listeners
+// which have not been listened on. It is a no-op if the process was not started by an upgrade.
+func Ready() error {
+	once.Do(inherit)
+
+	v, ok := os.LookupEnv(EnvReady)
+	if !ok {
+		return nil
+	}
+
+	// Unset so the environment is not passed on.
+	os.Unsetenv(EnvReady)
+	os.Unsetenv(EnvListeners)
+
+	mtx.Lock()
+
+	for key, file := range inherited {
+		slog.Warn("closing unused inherited listener", slog.String("listener", key))
+		file.Close()
+	}
+
+	clear(inherited)
+
+	mtx.Unlock()
+
+	fd, err := strconv.Atoi(v)
+	if err != nil {
+		return fmt.Errorf("invalid ready file descriptor %q: %w", v, err)
+	}
+
+	ready := os.NewFile(uintptr(fd), "ready")
+	defer ready.Close()
+
+	if _, err := ready.Write([]byte{1}); err != nil {
+		return fmt.Errorf("signal ready: %w", err)
+	}
+
+	return nil
+}
+
+// Upgrade executes the running binary as a new process, passing it the listeners created with Listen,
+// and waits for it to call Ready. If the context is done first the new process is killed. The caller
+// is responsible for stopping this process once Upgrade returns without error, see Runner.
+func Upgrade(ctx context.Context) error {
+	if !supported {
+		return fmt.Errorf("upgrade: %w", errors.ErrUnsupported)
+	}
+
+	mtx.Lock()
+
+	if upgrading {
+		mtx.Unlock()
+
+		return ErrUpgrading
+	}
+
+	upgrading = true
+
+	var (
+		keys  []string
+		files []*os.File
+	)
+
+	for l := range slices.Values(listeners) {
+		filer, ok := l.ln.(interface{ File() (*os.File, error) })
+		if !ok {
+			continue
+		}
+
+		// A listener which has been closed can not be passed on.
+		file, err := filer.File()
+		if err != nil {
+			continue
+		}
+
+		keys = append(keys, l.key)
+		files = append(files, file)
+	}
+
+	mtx.Unlock()
+
+	defer func() {
+		for file := range slices.Values(files) {
+			file.Close()
+		}
+
+		mtx.Lock()
+		upgrading = false
+		mtx.Unlock()
+	}()
+
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("find executable: %w", err)
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		return fmt.Errorf("create ready pipe: %w", err)
+	}
+
+	defer r.Close()
+
+	env := slices.DeleteFunc(os.Environ(), func(v string) bool {
+		return strings.HasPrefix(v, EnvListeners+"=") || strings.HasPrefix(v, EnvReady+"=")
+	})
+
+	cmd := exec.Command(exe, os.Args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.ExtraFiles = append(slices.Clone(files), w)
+	cmd.Env = append(env,
+		EnvListeners+"="+strings.Join(keys, ","),
+		EnvReady+"="+strconv.Itoa(3+len(files)))
+
+	err = cmd.Start()
+
+	// The new process has its own copy of the write end, close ours so the read fails if it exits.
+	w.Close()
+
+	if err != nil {
+		return fmt.Errorf("start %s: %w", exe, err)
+	}
+
+	pid := cmd.Process.Pid
+
+	slog.InfoContext(ctx, "started upgraded process", slog.Int("pid", pid), slog.Any("listeners", keys))
+
+	ready := make(chan error, 1)
+
+	go func() {
+		_, err := r.Read(make([]byte, 1))
+		ready <- err
+	}()
+
+	select {
+	case err := <-ready:
+		if err != nil {
+			_ = cmd.Wait()
+
+			return fmt.Errorf("upgraded process exited before ready: %w", err)
+		}
+	case <-ctx.Done():
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+
+		return fmt.Errorf("upgraded process not ready: %w", ctx.Err())
+	}
+
+	// The new process is not waited for as it outlives this one, release its resources.
+	_ = cmd.Process.Release()
+
+	slog.InfoContext(ctx, "upgraded process ready", slog.Int("pid", pid))
+
+	return nil
+}
+
+// A Runner is a foundation.Runner which upgrades the process when signalled, stopping this process
+// once the new process is ready as SIGTERM would.
+type Runner struct {
+	opts []Option
+}
+
+// Run returns a Runner which upgrades the process when signalled, see Upgrade.
+func Run(opts ...Option) *Runner {
+	return &Runner{
+		opts: opts,
+	}
+}
+
+// Run tells the previous process this process is ready if started by an upgrade, writing the pid file
+// if configured, then upgrades the process each time it is signalled until stopped.
+func (r *Runner) Run(ctx context.Context, f foundation.F) {
+	cfg := config{
+		signal:  upgradeSignal,
+		timeout: time.Minute,
+	}
+
+	Options(r.opts).apply(&cfg)
+
+	if err := writePIDFile(cfg.pidFile); err != nil {
+		f.Error(err)
+	}
+
+	if err := Ready(); err != nil {
+		f.Error(err)
+	}
+
+	if cfg.signal == nil {
+		slog.WarnContext(ctx, "upgrades are not supported on this platform")
+
+		return
+	}
+
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, cfg.signal)
+
+	done := make(chan struct{})
+
+	f.On().Stop(func() {
+		signal.Stop(ch)
+		close(done)
+	})
+
+	f.Parallel()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ch:
+			slog.InfoContext(ctx, "received upgrade signal")
+
+			ctx, cancel := context.WithTimeout(ctx, cfg.timeout)
+			err := Upgrade(ctx)
+			cancel()
+
+			if err != nil {
+				slog.ErrorContext(ctx, "failed to upgrade", slog.String("err", err.Error()))
+
+				continue
+			}
+
+			// The new process is ready, stop this one.
+			if err := terminate(); err != nil {
+				slog.ErrorContext(ctx, "failed to stop after upgrade", slog.String("err", err.Error()))
+			}
+		}
+	}
+}
+
+// writePIDFile writes the process id to the file, if a path is given.
+func writePIDFile(path string) error {
+	if path == "" {
+		return nil
+	}
+
+	if err := os.WriteFile(path, []byte(strconv.Itoa(os.Getpid())+"\n"), 0o644); err != nil {
+		return fmt.Errorf("write pid file: %w", err)
+	}
+
+	return nil
+}
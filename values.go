@@ -0,0 +1,52 @@
+package foundation
+
+import "sync"
+
+// A ValueStore holds values shared between every F within a Run. Keys should be comparable and, like
+// context keys, of an unexported type to avoid collisions between packages.
+type ValueStore interface {
+	// Load returns the value stored for the key and whether a value was present.
+	Load(key any) (any, bool)
+	// Store sets the value for the key.
+	Store(key, value any)
+	// Delete deletes the value for the key.
+	Delete(key any)
+}
+
+// Value returns the value stored for the given key in the F's value store if it is present and of type T.
+func Value[T any](f F, key any) (T, bool) {
+	var zero T
+
+	v, ok := f.Values().Load(key)
+	if !ok {
+		return zero, false
+	}
+
+	t, ok := v.(T)
+	if !ok {
+		return zero, false
+	}
+
+	return t, true
+}
+
+// values is an implementation of ValueStore.
+type values struct {
+	m sync.Map
+}
+
+func newValues() *values {
+	return &values{}
+}
+
+func (v *values) Load(key any) (any, bool) {
+	return v.m.Load(key)
+}
+
+func (v *values) Store(key, value any) {
+	v.m.Store(key, value)
+}
+
+func (v *values) Delete(key any) {
+	v.m.Delete(key)
+}
@@ -0,0 +1,387 @@
+// Package vault fetches secrets from HashiCorp Vault using the Vault HTTP API, keeping leased secrets
+// such as dynamic database credentials renewed for the lifetime of the process.
+package vault
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go.krak3n.io/foundation"
+	"go.krak3n.io/foundation/health/probe"
+	"go.krak3n.io/foundation/tick"
+)
+
+// A Secret is a secret read from Vault.
+type Secret struct {
+	// Path is the path the secret was read from.
+	Path string
+	// LeaseID is the lease of the secret, empty for secrets without a lease such as KV secrets.
+	LeaseID string
+	// Renewable reports whether the lease can be renewed.
+	Renewable bool
+	// Data is the secret data.
+	Data map[string]any
+	// Expires is when the lease expires, zero for secrets without a lease.
+	Expires time.Time
+	// lease is the duration of the lease when last read or renewed.
+	lease time.Duration
+}
+
+// An Option configures the Vault Runner.
+type Option interface {
+	apply(*config)
+}
+
+// Options is one or more Option.
+type Options []Option
+
+func (opts Options) apply(cfg *config) {
+	for opt := range slices.Values(opts) {
+		if opt != nil {
+			opt.apply(cfg)
+		}
+	}
+}
+
+type optionFunc func(*config)
+
+func (f optionFunc) apply(cfg *config) {
+	f(cfg)
+}
+
+// config holds the Vault Runner configuration.
+type config struct {
+	addr      string
+	token     string
+	namespace string
+	client    *http.Client
+	secrets   map[string]string
+	interval  time.Duration
+}
+
+// WithAddress sets the Vault address, defaults to VAULT_ADDR or https://127.0.0.1:8200.
+func WithAddress(addr string) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.addr = addr
+	})
+}
+
+// WithToken sets the Vault token, defaults to VAULT_TOKEN.
+func WithToken(token string) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.token = token
+	})
+}
+
+// WithNamespace sets the Vault Enterprise namespace, defaults to VAULT_NAMESPACE.
+func WithNamespace(namespace string) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.namespace = namespace
+	})
+}
+
+// WithHTTPClient sets the HTTP client used to call Vault, defaults to a client with a 10 second timeout.
+func WithHTTPClient(client *http.Client) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.client = client
+	})
+}
+
+// WithSecret reads the secret at path, for example database/creds/api, storing it under name.
+func WithSecret(name, path string) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.secrets[name] = path
+	})
+}
+
+// WithRenewInterval sets how often leases are checked, leases are renewed once a third of their
+// duration remains. Defaults to 10 seconds.
+func WithRenewInterval(d time.Duration) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.interval = d
+	})
+}
+
+// A Runner is a foundation.Runner which reads secrets from Vault at startup and keeps their leases
+// renewed. When a lease can no longer be renewed the secret is read again and, as the credentials have
+// rotated, the Reload hooks are called so dependents can pick up the new secret. Leases are revoked on
+// stop.
+type Runner struct {
+	opts []Option
+	cfg  config
+
+	mtx     sync.RWMutex
+	secrets map[string]Secret
+	errs    map[string]error
+}
+
+// Run returns a Runner which reads the configured secrets.
+func Run(opts ...Option) *Runner {
+	return &Runner{
+		opts:    opts,
+		secrets: make(map[string]Secret),
+		errs:    make(map[string]error),
+	}
+}
+
+// Secret returns the current secret stored under name.
+func (r *Runner) Secret(name string) (Secret, bool) {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	secret, ok := r.secrets[name]
+
+	return secret, ok
+}
+
+// Run reads the secrets, failing startup if any cannot be read, then renews their leases until stopped.
+func (r *Runner) Run(ctx context.Context, f foundation.F) {
+	r.cfg = config{
+		addr:      "https://127.0.0.1:8200",
+		token:     os.Getenv("VAULT_TOKEN"),
+		namespace: os.Getenv("VAULT_NAMESPACE"),
+		client:    &http.Client{Timeout: 10 * time.Second},
+		secrets:   make(map[string]string),
+		interval:  10 * time.Second,
+	}
+
+	if addr := os.Getenv("VAULT_ADDR"); addr != "" {
+		r.cfg.addr = addr
+	}
+
+	Options(r.opts).apply(&r.cfg)
+
+	r.cfg.addr = strings.TrimSuffix(r.cfg.addr, "/")
+
+	for name, path := range r.cfg.secrets {
+		secret, err := r.read(ctx, path)
+		if err != nil {
+			f.Error(fmt.Errorf("read vault secret %s: %w", name, err))
+		}
+
+		r.store(f, name, secret)
+	}
+
+	f.On().Stop(func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		defer cancel()
+
+		r.mtx.RLock()
+		defer r.mtx.RUnlock()
+
+		for name, secret := range r.secrets {
+			if secret.LeaseID == "" {
+				continue
+			}
+
+			if err := r.call(ctx, http.MethodPut, "/v1/sys/leases/revoke", map[string]string{"lease_id": secret.LeaseID}, nil); err != nil {
+				slog.Warn("failed to revoke vault lease", slog.String("secret", name), slog.String("err", err.Error()))
+			}
+		}
+	})
+
+	probe.RegisterContext(ctx, probe.NewSensor("vault", probe.ReadinessMode, r.sense))
+
+	tick.Run(ctx, f, r.cfg.interval, func(ctx context.Context, _ tick.Ticker) {
+		if r.renew(ctx, f) {
+			foundation.Reload(f)
+		}
+	})
+}
+
+// renew renews leases which are due, reading secrets again whose lease cannot be renewed. Returns true
+// if any secret rotated.
+func (r *Runner) renew(ctx context.Context, f foundation.F) bool {
+	r.mtx.RLock()
+	secrets := make(map[string]Secret, len(r.secrets))
+	for name, secret := range r.secrets {
+		secrets[name] = secret
+	}
+	r.mtx.RUnlock()
+
+	var rotated bool
+
+	for name, secret := range secrets {
+		if secret.LeaseID == "" || time.Until(secret.Expires) > secret.lease/3 {
+			continue
+		}
+
+		log := slog.With(slog.String("secret", name), slog.String("path", secret.Path))
+
+		if secret.Renewable {
+			renewed, err := r.renewLease(ctx, secret)
+			if err == nil && renewed.lease > 0 && time.Until(renewed.Expires) > r.cfg.interval {
+				r.store(f, name, renewed)
+
+				continue
+			}
+
+			if err != nil {
+				log.WarnContext(ctx, "failed to renew vault lease, reading secret again", slog.String("err", err.Error()))
+			}
+		}
+
+		// The lease cannot be renewed any further, for example it has reached its max TTL, so read the
+		// secret again which issues new credentials.
+		fresh, err := r.read(ctx, secret.Path)
+		if err != nil {
+			log.ErrorContext(ctx, "failed to read vault secret", slog.String("err", err.Error()))
+
+			r.mtx.Lock()
+			r.errs[name] = err
+			r.mtx.Unlock()
+
+			continue
+		}
+
+		log.InfoContext(ctx, "vault secret rotated")
+
+		r.store(f, name, fresh)
+		rotated = true
+	}
+
+	return rotated
+}
+
+// store stores the secret, clearing any error, and publishes it to the value store.
+func (r *Runner) store(f foundation.F, name string, secret Secret) {
+	r.mtx.Lock()
+	r.secrets[name] = secret
+	delete(r.errs, name)
+	r.mtx.Unlock()
+
+	f.Values().Store(secretKey(name), secret)
+}
+
+// sense fails if a secret could not be renewed or read, or a lease has expired.
+func (r *Runner) sense(context.Context) error {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	var errs []error
+
+	for name, err := range r.errs {
+		errs = append(errs, fmt.Errorf("secret %s: %w", name, err))
+	}
+
+	for name, secret := range r.secrets {
+		if secret.LeaseID != "" && time.Now().After(secret.Expires) {
+			errs = append(errs, fmt.Errorf("secret %s: lease expired", name))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// leaseResponse is the lease fields of a Vault response.
+type leaseResponse struct {
+	LeaseID       string         `json:"lease_id"`
+	LeaseDuration int64          `json:"lease_duration"`
+	Renewable     bool           `json:"renewable"`
+	Data          map[string]any `json:"data"`
+}
+
+// read reads the secret at path.
+func (r *Runner) read(ctx context.Context, path string) (Secret, error) {
+	var rsp leaseResponse
+
+	if err := r.call(ctx, http.MethodGet, "/v1/"+strings.TrimPrefix(path, "/"), nil, &rsp); err != nil {
+		return Secret{}, err
+	}
+
+	secret := Secret{
+		Path:      path,
+		LeaseID:   rsp.LeaseID,
+		Renewable: rsp.Renewable,
+		Data:      rsp.Data,
+		lease:     time.Duration(rsp.LeaseDuration) * time.Second,
+	}
+
+	if secret.LeaseID != "" {
+		secret.Expires = time.Now().Add(secret.lease)
+	}
+
+	return secret, nil
+}
+
+// renewLease renews the secrets lease by its original duration.
+func (r *Runner) renewLease(ctx context.Context, secret Secret) (Secret, error) {
+	var rsp leaseResponse
+
+	body := map[string]any{
+		"lease_id":  secret.LeaseID,
+		"increment": int64(secret.lease.Seconds()),
+	}
+
+	if err := r.call(ctx, http.MethodPut, "/v1/sys/leases/renew", body, &rsp); err != nil {
+		return secret, err
+	}
+
+	secret.Renewable = rsp.Renewable
+	secret.Expires = time.Now().Add(time.Duration(rsp.LeaseDuration) * time.Second)
+
+	return secret, nil
+}
+
+// call makes a request to the Vault API decoding the response into out if not nil.
+func (r *Runner) call(ctx context.Context, method, path string, body, out any) error {
+	var rd io.Reader = http.NoBody
+
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+
+		rd = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, r.cfg.addr+path, rd)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("X-Vault-Token", r.cfg.token)
+
+	if r.cfg.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", r.cfg.namespace)
+	}
+
+	rsp, err := r.cfg.client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer rsp.Body.Close()
+
+	if rsp.StatusCode < 200 || rsp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(rsp.Body, 1024))
+
+		return fmt.Errorf("%s: %s", rsp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	if out == nil || rsp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+
+	return json.NewDecoder(rsp.Body).Decode(out)
+}
+
+// secretKey is the value store key a secret is stored under, keyed by the secrets name.
+type secretKey string
+
+// GetSecret returns the current secret with the given name from the F's value store.
+func GetSecret(f foundation.F, name string) (Secret, bool) {
+	return foundation.Value[Secret](f, secretKey(name))
+}
@@ -0,0 +1,192 @@
+// Package warmup runs warmup steps, such as pre-establishing connections or pre-filling caches, after
+// startup but before the process reports itself ready, reducing latency spikes after deploys.
+package warmup
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"go.krak3n.io/foundation"
+	"go.krak3n.io/foundation/health/probe"
+)
+
+// A Step is a warmup step.
+type Step struct {
+	// Name names the step in logs and sensor errors.
+	Name string
+	// Timeout bounds the step, defaults to the runners default timeout.
+	Timeout time.Duration
+	// Optional steps are logged and skipped on failure rather than failing startup.
+	Optional bool
+	// Fn performs the step.
+	Fn func(ctx context.Context) error
+}
+
+// Connections returns a step function which calls dial n times concurrently, for example to fill a
+// connection pool.
+func Connections(n int, dial func(ctx context.Context) error) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		errs := make(chan error, n)
+
+		var wg sync.WaitGroup
+
+		for range n {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				errs <- dial(ctx)
+			}()
+		}
+
+		wg.Wait()
+		close(errs)
+
+		for err := range errs {
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
+
+// An Option configures the warmup Runner.
+type Option interface {
+	apply(*config)
+}
+
+// Options is one or more Option.
+type Options []Option
+
+func (opts Options) apply(cfg *config) {
+	for opt := range slices.Values(opts) {
+		if opt != nil {
+			opt.apply(cfg)
+		}
+	}
+}
+
+type optionFunc func(*config)
+
+func (f optionFunc) apply(cfg *config) {
+	f(cfg)
+}
+
+// config holds the warmup Runner configuration.
+type config struct {
+	timeout    time.Duration
+	sensorMode probe.Mode
+}
+
+// WithTimeout sets the default timeout of steps which do not set their own, defaults to 30 seconds.
+func WithTimeout(d time.Duration) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.timeout = d
+	})
+}
+
+// WithSensorMode sets the mode of the warmup sensor, defaults to probe.StartupMode and
+// probe.ReadinessMode.
+func WithSensorMode(mode probe.Mode) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.sensorMode = mode
+	})
+}
+
+// A Runner is a foundation.Runner which runs warmup steps in order in the background. Its sensor fails,
+// reporting progress, until all steps have completed. A failed step which is not optional fails startup.
+type Runner struct {
+	steps []Step
+	opts  []Option
+
+	mtx     sync.RWMutex
+	current string
+	done    int
+}
+
+// Run returns a Runner which runs the given steps.
+func Run(steps []Step, opts ...Option) *Runner {
+	return &Runner{
+		steps: steps,
+		opts:  opts,
+	}
+}
+
+// Run registers the warmup sensor and runs the steps.
+func (r *Runner) Run(ctx context.Context, f foundation.F) {
+	cfg := config{
+		timeout:    30 * time.Second,
+		sensorMode: probe.StartupMode | probe.ReadinessMode,
+	}
+
+	Options(r.opts).apply(&cfg)
+
+	probe.RegisterContext(ctx, probe.NewSensor("warmup", cfg.sensorMode, r.sense))
+
+	f.Parallel() // Mark the Runner as parallel now we are going start blocking
+
+	start := time.Now()
+
+	for _, step := range r.steps {
+		r.mtx.Lock()
+		r.current = step.Name
+		r.mtx.Unlock()
+
+		if err := r.run(ctx, step, cfg.timeout); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+
+			if !step.Optional {
+				f.Error(fmt.Errorf("warmup step %s: %w", step.Name, err))
+			}
+
+			slog.WarnContext(ctx, "optional warmup step failed", slog.String("step", step.Name), slog.String("err", err.Error()))
+		}
+
+		r.mtx.Lock()
+		r.done++
+		r.mtx.Unlock()
+	}
+
+	slog.InfoContext(ctx, "warmup complete", slog.Int("steps", len(r.steps)), slog.Duration("duration", time.Since(start)))
+}
+
+// run runs a step with its timeout.
+func (r *Runner) run(ctx context.Context, step Step, timeout time.Duration) error {
+	if step.Timeout > 0 {
+		timeout = step.Timeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+
+	if err := step.Fn(ctx); err != nil {
+		return err
+	}
+
+	slog.DebugContext(ctx, "warmup step complete", slog.String("step", step.Name), slog.Duration("duration", time.Since(start)))
+
+	return nil
+}
+
+// sense fails until all steps have completed.
+func (r *Runner) sense(context.Context) error {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	if r.done < len(r.steps) {
+		return fmt.Errorf("warming up: %d/%d steps complete, running %s", r.done, len(r.steps), r.current)
+	}
+
+	return nil
+}
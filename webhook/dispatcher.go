@@ -0,0 +1,344 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"sync"
+	"time"
+
+	"go.krak3n.io/foundation"
+	"go.krak3n.io/foundation/health/probe"
+	"go.krak3n.io/foundation/metrics"
+	"go.krak3n.io/foundation/rate"
+	"go.krak3n.io/foundation/tick"
+)
+
+// A Dispatcher queues and delivers webhooks. It is a foundation.Runner which must be run for deliveries
+// to be sent.
+type Dispatcher struct {
+	cfg config
+
+	mtx      sync.Mutex
+	queue    []Delivery
+	inflight int
+	limits   map[string]*rate.TokenBucket
+	wg       sync.WaitGroup
+
+	delivered metrics.Counter
+	retried   metrics.Counter
+	failed    metrics.Counter
+	dropped   metrics.Counter
+	backlog   metrics.Gauge
+	duration  metrics.Histogram
+}
+
+// New returns a Dispatcher.
+func New(opts ...Option) *Dispatcher {
+	cfg := config{
+		client:          http.DefaultClient,
+		timeout:         10 * time.Second,
+		concurrency:     8,
+		retries:         5,
+		backoff:         tick.ExponentialBackoff(time.Second, tick.WithJitter(0.2)),
+		maxBacklog:      10000,
+		interval:        100 * time.Millisecond,
+		shutdownTimeout: 30 * time.Second,
+	}
+
+	Options(opts).apply(&cfg)
+
+	status := func(s string) metrics.Labels {
+		return metrics.Labels{"status": s}
+	}
+
+	return &Dispatcher{
+		cfg:       cfg,
+		limits:    make(map[string]*rate.TokenBucket),
+		delivered: metrics.NewCounter("webhook_deliveries_total", status("delivered")),
+		retried:   metrics.NewCounter("webhook_deliveries_total", status("retried")),
+		failed:    metrics.NewCounter("webhook_deliveries_total", status("failed")),
+		dropped:   metrics.NewCounter("webhook_deliveries_total", status("dropped")),
+		backlog:   metrics.NewGauge("webhook_backlog", metrics.Labels{}),
+		duration:  metrics.NewHistogram("webhook_delivery_duration_seconds", metrics.Labels{}),
+	}
+}
+
+// Send queues the delivery, returning ErrBacklogFull if the backlog is full.
+func (d *Dispatcher) Send(_ context.Context, del Delivery) error {
+	if _, err := url.Parse(del.URL); err != nil {
+		return fmt.Errorf("webhook url: %w", err)
+	}
+
+	if del.ID == "" {
+		b := make([]byte, 16)
+		if _, err := rand.Read(b); err != nil {
+			return err
+		}
+
+		del.ID = hex.EncodeToString(b)
+	}
+
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	if len(d.queue) >= d.cfg.maxBacklog {
+		return ErrBacklogFull
+	}
+
+	d.queue = append(d.queue, del)
+	d.backlog.Set(float64(len(d.queue)))
+
+	return nil
+}
+
+// Backlog returns the number of queued deliveries, including those awaiting a retry.
+func (d *Dispatcher) Backlog() int {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	return len(d.queue)
+}
+
+// Run restores persisted deliveries and delivers queued deliveries until stopped. On stop in flight
+// deliveries are waited for, then those still queued are persisted or dropped. A readiness sensor fails
+// whilst the backlog is full.
+func (d *Dispatcher) Run(ctx context.Context, f foundation.F) {
+	if d.cfg.persister != nil {
+		restored, err := d.cfg.persister.Restore(ctx)
+		if err != nil {
+			f.Error(fmt.Errorf("restore webhook deliveries: %w", err))
+		}
+
+		d.mtx.Lock()
+		d.queue = append(restored, d.queue...)
+		d.backlog.Set(float64(len(d.queue)))
+		d.mtx.Unlock()
+	}
+
+	probe.RegisterContext(ctx, probe.NewSensor("webhook", probe.ReadinessMode, func(context.Context) error {
+		if n := d.Backlog(); n >= d.cfg.maxBacklog {
+			return fmt.Errorf("webhook backlog full: %d deliveries", n)
+		}
+
+		return nil
+	}))
+
+	f.On().Stop(func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), d.cfg.shutdownTimeout)
+		defer cancel()
+
+		d.drain(ctx)
+	})
+
+	tick.Run(ctx, f, d.cfg.interval, func(ctx context.Context, _ tick.Ticker) {
+		d.dispatch(context.WithoutCancel(ctx))
+	})
+}
+
+// dispatch sends the due deliveries the concurrency and rate limits allow.
+func (d *Dispatcher) dispatch(ctx context.Context) {
+	now := time.Now()
+
+	d.mtx.Lock()
+
+	var due []Delivery
+
+	d.queue = slices.DeleteFunc(d.queue, func(del Delivery) bool {
+		if d.inflight+len(due) >= d.cfg.concurrency || del.Due.After(now) || !d.allow(del.URL) {
+			return false
+		}
+
+		due = append(due, del)
+
+		return true
+	})
+
+	d.inflight += len(due)
+	d.backlog.Set(float64(len(d.queue)))
+
+	d.mtx.Unlock()
+
+	for del := range slices.Values(due) {
+		d.wg.Add(1)
+
+		go func() {
+			defer d.wg.Done()
+
+			d.deliver(ctx, del)
+		}()
+	}
+}
+
+// allow takes a token from the endpoint hosts bucket, returning false if none are available.
+func (d *Dispatcher) allow(endpoint string) bool {
+	if d.cfg.limit <= 0 {
+		return true
+	}
+
+	var host string
+
+	if u, err := url.Parse(endpoint); err == nil {
+		host = u.Host
+	}
+
+	l, ok := d.limits[host]
+	if !ok {
+		l = rate.NewTokenBucket(d.cfg.limit, d.cfg.burst)
+		d.limits[host] = l
+	}
+
+	return l.Allow()
+}
+
+// deliver attempts the delivery, requeuing it for a retry if it fails and retries remain.
+func (d *Dispatcher) deliver(ctx context.Context, del Delivery) {
+	if d.cfg.limiter != nil {
+		// The context is not cancelled whilst delivering so the wait can not fail.
+		_ = d.cfg.limiter.Wait(ctx)
+	}
+
+	start := time.Now()
+
+	retry, err := d.post(ctx, del)
+
+	d.duration.Observe(time.Since(start).Seconds())
+
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	d.inflight--
+
+	if err == nil {
+		d.delivered.Add(1)
+
+		return
+	}
+
+	del.Attempts++
+
+	if retry && del.Attempts <= d.cfg.retries {
+		del.Due = time.Now().Add(d.cfg.backoff.Wait(ctx, del.Attempts))
+
+		d.queue = append(d.queue, del)
+		d.backlog.Set(float64(len(d.queue)))
+		d.retried.Add(1)
+
+		return
+	}
+
+	d.failed.Add(1)
+
+	slog.WarnContext(ctx, "failed to deliver webhook",
+		slog.String("id", del.ID),
+		slog.String("url", del.URL),
+		slog.Int("attempts", int(del.Attempts)),
+		slog.String("err", err.Error()))
+
+	if d.cfg.failure != nil {
+		d.cfg.failure(ctx, del, err)
+	}
+}
+
+// post sends the delivery, returning whether a failed delivery should be retried.
+func (d *Dispatcher) post(ctx context.Context, del Delivery) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, d.cfg.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, del.URL, bytes.NewReader(del.Payload))
+	if err != nil {
+		return false, err
+	}
+
+	for k, v := range del.Header {
+		req.Header[k] = v
+	}
+
+	if req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	req.Header.Set("Webhook-Id", del.ID)
+
+	if del.Event != "" {
+		req.Header.Set("Webhook-Event", del.Event)
+	}
+
+	if d.cfg.secret != nil {
+		mac := hmac.New(sha256.New, d.cfg.secret)
+		mac.Write(del.Payload)
+
+		req.Header.Set("Webhook-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	rsp, err := d.cfg.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+
+	defer rsp.Body.Close()
+
+	// Drain the body so the connection can be reused.
+	_, _ = io.Copy(io.Discard, io.LimitReader(rsp.Body, 64<<10))
+
+	switch {
+	case rsp.StatusCode >= 200 && rsp.StatusCode <= 299:
+		return false, nil
+	case rsp.StatusCode == http.StatusRequestTimeout, rsp.StatusCode == http.StatusTooManyRequests, rsp.StatusCode >= 500:
+		return true, errors.New(rsp.Status)
+	default:
+		return false, errors.New(rsp.Status)
+	}
+}
+
+// drain waits for in flight deliveries then persists or drops the queued deliveries.
+func (d *Dispatcher) drain(ctx context.Context) {
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		d.wg.Wait()
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		slog.WarnContext(ctx, "timed out waiting for in flight webhook deliveries")
+	}
+
+	d.mtx.Lock()
+	queue := d.queue
+	d.queue = nil
+	d.backlog.Set(0)
+	d.mtx.Unlock()
+
+	if len(queue) == 0 {
+		return
+	}
+
+	if d.cfg.persister != nil {
+		err := d.cfg.persister.Persist(ctx, queue)
+		if err == nil {
+			slog.InfoContext(ctx, "persisted queued webhook deliveries", slog.Int("deliveries", len(queue)))
+
+			return
+		}
+
+		slog.ErrorContext(ctx, "failed to persist queued webhook deliveries", slog.String("err", err.Error()))
+	}
+
+	d.dropped.Add(float64(len(queue)))
+
+	slog.WarnContext(ctx, "dropped queued webhook deliveries", slog.Int("deliveries", len(queue)))
+}
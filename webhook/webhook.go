@@ -0,0 +1,182 @@
+// Package webhook dispatches outgoing webhook deliveries. Deliveries are queued in memory and sent in
+// the background, rate limited per endpoint and retried with backoff, with a choice of persisting or
+// dropping those still queued on stop.
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"time"
+
+	"go.krak3n.io/foundation/rate"
+	"go.krak3n.io/foundation/tick"
+)
+
+// ErrBacklogFull is returned by Send when the backlog has reached its maximum size, see WithMaxBacklog.
+var ErrBacklogFull = errors.New("webhook backlog full")
+
+// A Delivery is a webhook to deliver.
+type Delivery struct {
+	// ID identifies the delivery to the receiver, sent as the Webhook-Id header. Generated if empty.
+	ID string
+	// URL is the endpoint the delivery is POSTed to.
+	URL string
+	// Event is the event type, sent as the Webhook-Event header.
+	Event string
+	// Payload is the request body.
+	Payload []byte
+	// Header are additional request headers, Content-Type defaults to application/json.
+	Header http.Header
+	// Attempts is the number of failed attempts so far.
+	Attempts uint8
+	// Due is when the delivery is next attempted, the zero time is immediately.
+	Due time.Time
+}
+
+// A Persister stores deliveries queued when the Dispatcher stops so they are delivered once it restarts,
+// for example in a database table or a durable queue.
+type Persister interface {
+	// Persist stores the deliveries.
+	Persist(ctx context.Context, deliveries []Delivery) error
+	// Restore returns and removes the stored deliveries.
+	Restore(ctx context.Context) ([]Delivery, error)
+}
+
+// A FailureFunc is called with a delivery which could not be delivered once retries are exhausted or the
+// endpoint rejected it.
+type FailureFunc func(ctx context.Context, d Delivery, err error)
+
+// An Option configures the Dispatcher.
+type Option interface {
+	apply(*config)
+}
+
+// Options is one or more Option.
+type Options []Option
+
+func (opts Options) apply(cfg *config) {
+	for opt := range slices.Values(opts) {
+		if opt != nil {
+			opt.apply(cfg)
+		}
+	}
+}
+
+type optionFunc func(*config)
+
+func (f optionFunc) apply(cfg *config) {
+	f(cfg)
+}
+
+// config holds the Dispatcher configuration.
+type config struct {
+	client          *http.Client
+	timeout         time.Duration
+	concurrency     int
+	retries         uint8
+	backoff         tick.Backoff
+	limit           float64
+	burst           int
+	limiter         rate.Limiter
+	maxBacklog      int
+	interval        time.Duration
+	secret          []byte
+	persister       Persister
+	failure         FailureFunc
+	shutdownTimeout time.Duration
+}
+
+// WithHTTPClient sets the client deliveries are sent with, defaults to http.DefaultClient.
+func WithHTTPClient(c *http.Client) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.client = c
+	})
+}
+
+// WithTimeout bounds each delivery attempt, defaults to 10 seconds.
+func WithTimeout(d time.Duration) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.timeout = d
+	})
+}
+
+// WithConcurrency sets the number of deliveries sent concurrently, defaults to 8.
+func WithConcurrency(n int) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.concurrency = n
+	})
+}
+
+// WithRetries sets the number of times a failed delivery is retried, defaults to 5, and the backoff
+// between attempts, defaults to an exponential backoff with a scalar of 1 second and 20% jitter.
+// Deliveries are retried on network errors, 408, 429 and 5xx responses.
+func WithRetries(n uint8, backoff tick.Backoff) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.retries = n
+		cfg.backoff = backoff
+	})
+}
+
+// WithRateLimit limits the deliveries sent to each endpoint host to limit per second with bursts of up
+// to burst deliveries. Unlimited by default.
+func WithRateLimit(limit float64, burst int) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.limit = limit
+		cfg.burst = burst
+	})
+}
+
+// WithLimiter caps the rate delivery attempts are sent at across every endpoint with the limiter, each
+// waits for it once dispatched, occupying one of the concurrent deliveries.
+func WithLimiter(l rate.Limiter) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.limiter = l
+	})
+}
+
+// WithMaxBacklog sets the number of queued deliveries at which Send returns ErrBacklogFull and the
+// sensor fails, defaults to 10000.
+func WithMaxBacklog(n int) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.maxBacklog = n
+	})
+}
+
+// WithPollInterval sets how often the backlog is checked for due deliveries, defaults to 100ms.
+func WithPollInterval(d time.Duration) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.interval = d
+	})
+}
+
+// WithSigningSecret signs each delivery, sending the hex encoded HMAC-SHA256 of the payload with the
+// secret as the Webhook-Signature header in the form sha256=<signature>.
+func WithSigningSecret(secret []byte) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.secret = secret
+	})
+}
+
+// WithPersister persists deliveries still queued on stop, restoring them on start. Without a Persister
+// they are dropped.
+func WithPersister(p Persister) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.persister = p
+	})
+}
+
+// WithFailureFunc sets the function called with deliveries which could not be delivered.
+func WithFailureFunc(fn FailureFunc) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.failure = fn
+	})
+}
+
+// WithShutdownTimeout sets how long to wait on stop for in flight deliveries, defaults to 30 seconds.
+func WithShutdownTimeout(d time.Duration) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.shutdownTimeout = d
+	})
+}